@@ -5,6 +5,7 @@ import (
 	"dklautomationgo/auth/service"
 	"dklautomationgo/database/repository"
 	"dklautomationgo/models"
+	"dklautomationgo/services/i18n"
 	"log"
 	"net/http"
 
@@ -67,14 +68,14 @@ func (h *AuthHandler) RegisterRoutes(r *gin.Engine) {
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req models.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Ongeldige invoer"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidRequest)
 		return
 	}
 
 	tokens, err := h.authService.Login(req.Email, req.Password)
 	if err != nil {
 		log.Printf("[AuthHandler] Login error: %v", err)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		serviceError(c, http.StatusUnauthorized, err, i18n.MsgInvalidCredentials)
 		return
 	}
 
@@ -85,14 +86,14 @@ func (h *AuthHandler) Login(c *gin.Context) {
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	var req models.RefreshTokenRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Ongeldige invoer"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidRequest)
 		return
 	}
 
 	tokens, err := h.authService.RefreshToken(req.RefreshToken)
 	if err != nil {
 		log.Printf("[AuthHandler] Refresh token error: %v", err)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		serviceError(c, http.StatusUnauthorized, err, i18n.MsgInvalidToken)
 		return
 	}
 
@@ -103,38 +104,38 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 func (h *AuthHandler) Logout(c *gin.Context) {
 	var req models.RefreshTokenRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Ongeldige invoer"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidRequest)
 		return
 	}
 
 	if err := h.authService.Logout(req.RefreshToken); err != nil {
 		log.Printf("[AuthHandler] Logout error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Fout bij uitloggen"})
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgLogoutFailed)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Succesvol uitgelogd"})
+	c.JSON(http.StatusOK, gin.H{"message": i18n.Message(c, i18n.MsgLoggedOut)})
 }
 
 // ForgotPassword handelt wachtwoord vergeten verzoeken af
 func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 	var req models.ForgotPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Ongeldige invoer"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidRequest)
 		return
 	}
 
 	token, err := h.authService.ForgotPassword(req.Email)
 	if err != nil {
 		log.Printf("[AuthHandler] Forgot password error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Fout bij verwerken van verzoek"})
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgRequestFailed)
 		return
 	}
 
 	// In een echte applicatie zou je hier een email sturen met de reset link
 	// Voor nu geven we de token terug in de response (alleen voor ontwikkeling)
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Wachtwoord reset link is verzonden",
+		"message": i18n.Message(c, i18n.MsgPasswordResetSent),
 		"token":   token, // Verwijder dit in productie!
 	})
 }
@@ -143,54 +144,54 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	var req models.ResetPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Ongeldige invoer"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidRequest)
 		return
 	}
 
 	if err := h.authService.ResetPassword(req.Token, req.NewPassword); err != nil {
 		log.Printf("[AuthHandler] Reset password error: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		serviceError(c, http.StatusBadRequest, err, i18n.MsgRequestFailed)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Wachtwoord succesvol gewijzigd"})
+	c.JSON(http.StatusOK, gin.H{"message": i18n.Message(c, i18n.MsgPasswordChanged)})
 }
 
 // ChangePassword handelt wachtwoord wijziging verzoeken af
 func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	var req models.ChangePasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Ongeldige invoer"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidRequest)
 		return
 	}
 
 	user := middleware.GetUserFromContext(c)
 	if user == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Niet ingelogd"})
+		i18n.Error(c, http.StatusUnauthorized, i18n.MsgAuthRequired)
 		return
 	}
 
 	if err := h.authService.ChangePassword(user.ID, req.CurrentPassword, req.NewPassword); err != nil {
 		log.Printf("[AuthHandler] Change password error: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		serviceError(c, http.StatusBadRequest, err, i18n.MsgRequestFailed)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Wachtwoord succesvol gewijzigd"})
+	c.JSON(http.StatusOK, gin.H{"message": i18n.Message(c, i18n.MsgPasswordChanged)})
 }
 
 // CreateUser handelt gebruiker aanmaak verzoeken af
 func (h *AuthHandler) CreateUser(c *gin.Context) {
 	var req models.CreateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Ongeldige invoer"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidRequest)
 		return
 	}
 
 	user, err := h.authService.CreateUser(req.Email, req.Password, req.Role)
 	if err != nil {
 		log.Printf("[AuthHandler] Create user error: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		serviceError(c, http.StatusBadRequest, err, i18n.MsgRequestFailed)
 		return
 	}
 
@@ -202,7 +203,7 @@ func (h *AuthHandler) GetUsers(c *gin.Context) {
 	users, err := h.authService.GetAllUsers()
 	if err != nil {
 		log.Printf("[AuthHandler] Get users error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Fout bij ophalen gebruikers"})
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgUsersFetchFailed)
 		return
 	}
 
@@ -219,19 +220,19 @@ func (h *AuthHandler) GetUsers(c *gin.Context) {
 func (h *AuthHandler) GetUser(c *gin.Context) {
 	id, err := parseUUID(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Ongeldige gebruiker ID"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidUserID)
 		return
 	}
 
 	user, err := h.authService.GetUserByID(id)
 	if err != nil {
 		log.Printf("[AuthHandler] Get user error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Fout bij ophalen gebruiker"})
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgUserFetchFailed)
 		return
 	}
 
 	if user == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Gebruiker niet gevonden"})
+		i18n.Error(c, http.StatusNotFound, i18n.MsgUserNotFound)
 		return
 	}
 
@@ -242,19 +243,19 @@ func (h *AuthHandler) GetUser(c *gin.Context) {
 func (h *AuthHandler) UpdateUser(c *gin.Context) {
 	id, err := parseUUID(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Ongeldige gebruiker ID"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidUserID)
 		return
 	}
 
 	var req models.UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Ongeldige invoer"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidRequest)
 		return
 	}
 
 	if err := h.authService.UpdateUser(id, &req); err != nil {
 		log.Printf("[AuthHandler] Update user error: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		serviceError(c, http.StatusBadRequest, err, i18n.MsgRequestFailed)
 		return
 	}
 
@@ -266,19 +267,19 @@ func (h *AuthHandler) UpdateUser(c *gin.Context) {
 func (h *AuthHandler) ApproveUser(c *gin.Context) {
 	id, err := parseUUID(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Ongeldige gebruiker ID"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidUserID)
 		return
 	}
 
 	approver := middleware.GetUserFromContext(c)
 	if approver == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Niet ingelogd"})
+		i18n.Error(c, http.StatusUnauthorized, i18n.MsgAuthRequired)
 		return
 	}
 
 	if err := h.authService.ApproveUser(id, approver.ID); err != nil {
 		log.Printf("[AuthHandler] Approve user error: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		serviceError(c, http.StatusBadRequest, err, i18n.MsgRequestFailed)
 		return
 	}
 
@@ -405,42 +406,42 @@ func (h *AuthHandler) CreateBeheerderUser(c *gin.Context) {
 func (h *AuthHandler) DeleteUser(c *gin.Context) {
 	id, err := parseUUID(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Ongeldige gebruiker ID"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidUserID)
 		return
 	}
 
 	deleter := middleware.GetUserFromContext(c)
 	if deleter == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Niet ingelogd"})
+		i18n.Error(c, http.StatusUnauthorized, i18n.MsgAuthRequired)
 		return
 	}
 
 	if err := h.authService.DeleteUser(id, deleter.ID); err != nil {
 		log.Printf("[AuthHandler] Delete user error: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		serviceError(c, http.StatusBadRequest, err, i18n.MsgRequestFailed)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Gebruiker succesvol verwijderd"})
+	c.JSON(http.StatusOK, gin.H{"message": i18n.Message(c, i18n.MsgUserDeleted)})
 }
 
 // FindUserByEmail zoekt een gebruiker op basis van email
 func (h *AuthHandler) FindUserByEmail(c *gin.Context) {
 	email := c.Query("email")
 	if email == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Email parameter is vereist"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgEmailParamRequired)
 		return
 	}
 
 	user, err := h.authService.GetUserByEmail(email)
 	if err != nil {
 		log.Printf("[AuthHandler] Find user by email error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Fout bij zoeken gebruiker"})
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgUserFetchFailed)
 		return
 	}
 
 	if user == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Gebruiker niet gevonden"})
+		i18n.Error(c, http.StatusNotFound, i18n.MsgUserNotFound)
 		return
 	}
 
@@ -502,27 +503,27 @@ func (h *AuthHandler) RenameBeheerder(c *gin.Context) {
 func (h *AuthHandler) AdminChangePassword(c *gin.Context) {
 	id, err := parseUUID(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Ongeldige gebruiker ID"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidUserID)
 		return
 	}
 
 	var req models.AdminChangePasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Ongeldige invoer"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidRequest)
 		return
 	}
 
 	admin := middleware.GetUserFromContext(c)
 	if admin == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Niet ingelogd"})
+		i18n.Error(c, http.StatusUnauthorized, i18n.MsgAuthRequired)
 		return
 	}
 
 	if err := h.authService.AdminChangePassword(id, admin.ID, req.NewPassword); err != nil {
 		log.Printf("[AuthHandler] Admin change password error: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		serviceError(c, http.StatusBadRequest, err, i18n.MsgRequestFailed)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Wachtwoord succesvol gewijzigd"})
+	c.JSON(http.StatusOK, gin.H{"message": i18n.Message(c, i18n.MsgPasswordChanged)})
 }
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"dklautomationgo/auth/service"
 	"dklautomationgo/models"
 	"dklautomationgo/tests/fixtures"
 	"dklautomationgo/tests/mocks"
@@ -104,6 +105,42 @@ func TestLogin_InvalidCredentials(t *testing.T) {
 	mockAuthService.AssertExpectations(t)
 }
 
+func TestLogin_InvalidCredentials_English(t *testing.T) {
+	// Setup
+	mockAuthService, _, handler, router := setupTest()
+
+	// Configure router
+	router.POST("/api/auth/login", handler.Login)
+
+	// Setup mock expectations
+	mockAuthService.On("Login", "test@example.com", "wrongpassword").Return(nil, service.ErrInvalidCredentials)
+
+	// Create request
+	loginRequest := models.LoginRequest{
+		Email:    "test@example.com",
+		Password: "wrongpassword",
+	}
+	jsonBody, _ := json.Marshal(loginRequest)
+	req, _ := http.NewRequest("POST", "/api/auth/login", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "en-GB,en;q=0.9,nl;q=0.8")
+
+	// Perform request
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Assertions
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	var response map[string]string
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Invalid email or password", response["error"])
+
+	// Verify mock
+	mockAuthService.AssertExpectations(t)
+}
+
 func TestLogin_InvalidInput(t *testing.T) {
 	// Setup
 	_, _, handler, router := setupTest()
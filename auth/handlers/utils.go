@@ -1,6 +1,11 @@
 package handlers
 
 import (
+	"dklautomationgo/auth/service"
+	"dklautomationgo/services/i18n"
+	"errors"
+
+	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
@@ -8,3 +13,53 @@ import (
 func parseUUID(id string) (uuid.UUID, error) {
 	return uuid.Parse(id)
 }
+
+// serviceErrors koppelt de fouten van de AuthService aan hun bericht
+var serviceErrors = []struct {
+	err error
+	key i18n.Key
+}{
+	{service.ErrInvalidCredentials, i18n.MsgInvalidCredentials},
+	{service.ErrUserNotFound, i18n.MsgUserNotFound},
+	{service.ErrUserNotActive, i18n.MsgUserNotActive},
+	{service.ErrInvalidToken, i18n.MsgInvalidToken},
+	{service.ErrInvalidJWT, i18n.MsgInvalidToken},
+	{service.ErrTokenExpired, i18n.MsgTokenExpired},
+	{service.ErrPasswordResetExpired, i18n.MsgPasswordResetExpired},
+	{service.ErrEmailInUse, i18n.MsgEmailInUse},
+	{service.ErrAdminOnly, i18n.MsgAdminOnly},
+	{service.ErrPasswordTooWeak, i18n.MsgPasswordTooWeak},
+}
+
+// passwordRules koppelt de eisen uit een PasswordError aan hun bericht
+var passwordRules = map[string]i18n.Key{
+	service.PasswordRuleUppercase: i18n.MsgPasswordNeedsUppercase,
+	service.PasswordRuleLowercase: i18n.MsgPasswordNeedsLowercase,
+	service.PasswordRuleNumber:    i18n.MsgPasswordNeedsNumber,
+	service.PasswordRuleSpecial:   i18n.MsgPasswordNeedsSpecial,
+}
+
+// serviceError stuurt de vertaalde foutmelding voor een fout van de
+// AuthService. Voor een onbekende fout wordt het fallback bericht gebruikt,
+// zodat interne fouten niet bij de client terechtkomen.
+func serviceError(c *gin.Context, status int, err error, fallback i18n.Key) {
+	var passwordErr *service.PasswordError
+	if errors.As(err, &passwordErr) {
+		if passwordErr.Rule == service.PasswordRuleLength {
+			i18n.Error(c, status, i18n.MsgPasswordTooShort, passwordErr.MinLength)
+			return
+		}
+		if key, ok := passwordRules[passwordErr.Rule]; ok {
+			i18n.Error(c, status, key)
+			return
+		}
+	}
+
+	for _, known := range serviceErrors {
+		if errors.Is(err, known.err) {
+			i18n.Error(c, status, known.key)
+			return
+		}
+	}
+	i18n.Error(c, status, fallback)
+}
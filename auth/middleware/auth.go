@@ -4,6 +4,7 @@ import (
 	"dklautomationgo/auth/service"
 	"dklautomationgo/database/repository"
 	"dklautomationgo/models"
+	"dklautomationgo/services/i18n"
 	"log"
 	"net/http"
 	"strings"
@@ -30,25 +31,29 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Haal token uit Authorization header
 		authHeader := c.GetHeader("Authorization")
+		var tokenString string
 		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authenticatie vereist"})
-			return
-		}
-
-		// Controleer Bearer token format
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Ongeldige authenticatie header"})
-			return
+			// EventSource en WebSocket clients kunnen geen headers meesturen
+			tokenString = streamQueryToken(c)
+			if tokenString == "" {
+				i18n.Abort(c, http.StatusUnauthorized, i18n.MsgAuthRequired)
+				return
+			}
+		} else {
+			// Controleer Bearer token format
+			parts := strings.Split(authHeader, " ")
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				i18n.Abort(c, http.StatusUnauthorized, i18n.MsgInvalidAuthHeader)
+				return
+			}
+			tokenString = parts[1]
 		}
 
-		tokenString := parts[1]
-
 		// Valideer token
 		claims, err := m.tokenService.ValidateToken(tokenString)
 		if err != nil {
 			log.Printf("[AuthMiddleware] Token validation error: %v", err)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Ongeldige of verlopen token"})
+			i18n.Abort(c, http.StatusUnauthorized, i18n.MsgInvalidOrExpiredToken)
 			return
 		}
 
@@ -56,25 +61,25 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 		userID, err := m.tokenService.GetUserIDFromToken(tokenString)
 		if err != nil {
 			log.Printf("[AuthMiddleware] Error getting user ID from token: %v", err)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Ongeldige token"})
+			i18n.Abort(c, http.StatusUnauthorized, i18n.MsgInvalidToken)
 			return
 		}
 
 		user, err := m.userRepo.FindByID(userID)
 		if err != nil {
 			log.Printf("[AuthMiddleware] Error finding user: %v", err)
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Serverfout"})
+			i18n.Abort(c, http.StatusInternalServerError, i18n.MsgServerError)
 			return
 		}
 
 		if user == nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Gebruiker niet gevonden"})
+			i18n.Abort(c, http.StatusUnauthorized, i18n.MsgUserNotFound)
 			return
 		}
 
 		// Controleer of gebruiker actief is
 		if user.Status != models.StatusActive {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Gebruiker is niet actief"})
+			i18n.Abort(c, http.StatusForbidden, i18n.MsgUserNotActive)
 			return
 		}
 
@@ -92,14 +97,14 @@ func (m *AuthMiddleware) RequireRole(roles ...models.UserRole) gin.HandlerFunc {
 		// Haal gebruiker uit context
 		user, exists := c.Get("user")
 		if !exists {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authenticatie vereist"})
+			i18n.Abort(c, http.StatusUnauthorized, i18n.MsgAuthRequired)
 			return
 		}
 
 		userObj, ok := user.(*models.User)
 		if !ok {
 			log.Printf("[AuthMiddleware] Error casting user object")
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Serverfout"})
+			i18n.Abort(c, http.StatusInternalServerError, i18n.MsgServerError)
 			return
 		}
 
@@ -113,7 +118,7 @@ func (m *AuthMiddleware) RequireRole(roles ...models.UserRole) gin.HandlerFunc {
 		}
 
 		if !hasRole {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Onvoldoende rechten"})
+			i18n.Abort(c, http.StatusForbidden, i18n.MsgInsufficientPermissions)
 			return
 		}
 
@@ -121,6 +126,18 @@ func (m *AuthMiddleware) RequireRole(roles ...models.UserRole) gin.HandlerFunc {
 	}
 }
 
+// streamQueryToken geeft de access_token query parameter terug, maar alleen voor
+// Server-Sent Events en WebSocket requests. Voor gewone requests blijft de
+// Authorization header verplicht zodat tokens niet in logs van URLs belanden.
+func streamQueryToken(c *gin.Context) string {
+	isEventStream := strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+	isWebSocket := strings.EqualFold(c.GetHeader("Upgrade"), "websocket")
+	if !isEventStream && !isWebSocket {
+		return ""
+	}
+	return c.Query("access_token")
+}
+
 // GetUserFromContext haalt de gebruiker uit de context
 func GetUserFromContext(c *gin.Context) *models.User {
 	user, exists := c.Get("user")
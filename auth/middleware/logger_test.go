@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/events", "/api/events"},
+		{"/api/emails?limit=10", "/api/emails?limit=10"},
+		{"/api/events?access_token=geheim", "/api/events?access_token=REDACTED"},
+		{"/api/events?access_token=geheim&lang=en", "/api/events?access_token=REDACTED&lang=en"},
+		{"/api/events?access_token=geheim;x=%zz", "/api/events?REDACTED"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, redactPath(tt.path), tt.path)
+	}
+}
+
+func TestRequestLogger_RedactsAccessToken(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	original := gin.DefaultWriter
+	gin.DefaultWriter = &logs
+	defer func() { gin.DefaultWriter = original }()
+
+	router := gin.New()
+	router.Use(RequestLogger())
+	router.GET("/api/events", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// Test
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/events?access_token=geheim", nil))
+
+	// Controleer het resultaat
+	assert.Contains(t, logs.String(), "access_token=REDACTED")
+	assert.NotContains(t, logs.String(), "geheim")
+}
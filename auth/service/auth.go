@@ -21,8 +21,46 @@ var (
 	ErrTokenExpired         = errors.New("token is verlopen")
 	ErrPasswordResetExpired = errors.New("wachtwoord reset link is verlopen")
 	ErrPasswordTooWeak      = errors.New("wachtwoord voldoet niet aan de vereisten")
+	ErrEmailInUse           = errors.New("email is al in gebruik")
+	ErrAdminOnly            = errors.New("alleen beheerders kunnen deze actie uitvoeren")
 )
 
+// Eisen aan een wachtwoord in PasswordError
+const (
+	PasswordRuleLength    = "length"
+	PasswordRuleUppercase = "uppercase"
+	PasswordRuleLowercase = "lowercase"
+	PasswordRuleNumber    = "number"
+	PasswordRuleSpecial   = "special"
+)
+
+// PasswordError geeft aan aan welke eis een wachtwoord niet voldoet, zodat de
+// foutmelding vertaald kan worden. Het is ook een ErrPasswordTooWeak.
+type PasswordError struct {
+	Rule      string
+	MinLength int // Bij PasswordRuleLength
+}
+
+func (e *PasswordError) Error() string {
+	switch e.Rule {
+	case PasswordRuleLength:
+		return fmt.Sprintf("wachtwoord moet minimaal %d karakters bevatten", e.MinLength)
+	case PasswordRuleUppercase:
+		return "wachtwoord moet minimaal één hoofdletter bevatten"
+	case PasswordRuleLowercase:
+		return "wachtwoord moet minimaal één kleine letter bevatten"
+	case PasswordRuleNumber:
+		return "wachtwoord moet minimaal één cijfer bevatten"
+	case PasswordRuleSpecial:
+		return "wachtwoord moet minimaal één speciaal teken bevatten"
+	}
+	return ErrPasswordTooWeak.Error()
+}
+
+func (e *PasswordError) Unwrap() error {
+	return ErrPasswordTooWeak
+}
+
 // AuthService bevat de business logic voor authenticatie
 type AuthService struct {
 	userRepo     *repository.UserRepository
@@ -129,7 +167,7 @@ func (s *AuthService) CreateUser(email, password string, role models.UserRole) (
 		return nil, err
 	}
 	if existingUser != nil {
-		return nil, fmt.Errorf("email is al in gebruik")
+		return nil, ErrEmailInUse
 	}
 
 	// Valideer wachtwoord
@@ -183,7 +221,7 @@ func (s *AuthService) ApproveUser(userID, approverID uuid.UUID) error {
 
 	// Controleer of approver een beheerder is
 	if approver.Role != models.RoleBeheerder {
-		return fmt.Errorf("alleen beheerders kunnen gebruikers goedkeuren")
+		return ErrAdminOnly
 	}
 
 	// Keur gebruiker goed
@@ -212,7 +250,7 @@ func (s *AuthService) UpdateUser(userID uuid.UUID, updates *models.UpdateUserReq
 				return err
 			}
 			if existingUser != nil {
-				return fmt.Errorf("email is al in gebruik")
+				return ErrEmailInUse
 			}
 			user.Email = *updates.Email
 		}
@@ -372,7 +410,7 @@ func (s *AuthService) DeleteUser(userID uuid.UUID, deleterID uuid.UUID) error {
 
 	// Controleer of deleter een beheerder is
 	if deleter.Role != models.RoleBeheerder {
-		return fmt.Errorf("alleen beheerders kunnen gebruikers verwijderen")
+		return ErrAdminOnly
 	}
 
 	// Verwijder gebruiker
@@ -404,7 +442,7 @@ func (s *AuthService) AdminChangePassword(userID uuid.UUID, adminID uuid.UUID, n
 
 	// Controleer of admin een beheerder is
 	if admin.Role != models.RoleBeheerder {
-		return fmt.Errorf("alleen beheerders kunnen wachtwoorden wijzigen")
+		return ErrAdminOnly
 	}
 
 	// Valideer nieuw wachtwoord
@@ -471,27 +509,27 @@ func (s *AuthService) generateTokens(user *models.User) (*models.TokenResponse,
 func (s *AuthService) validatePassword(password string) error {
 	minLength := getPasswordMinLength()
 	if len(password) < minLength {
-		return fmt.Errorf("wachtwoord moet minimaal %d karakters bevatten", minLength)
+		return &PasswordError{Rule: PasswordRuleLength, MinLength: minLength}
 	}
 
 	// Controleer op hoofdletter
 	if getPasswordRequireUppercase() && !containsUppercase(password) {
-		return fmt.Errorf("wachtwoord moet minimaal één hoofdletter bevatten")
+		return &PasswordError{Rule: PasswordRuleUppercase}
 	}
 
 	// Controleer op kleine letter
 	if getPasswordRequireLowercase() && !containsLowercase(password) {
-		return fmt.Errorf("wachtwoord moet minimaal één kleine letter bevatten")
+		return &PasswordError{Rule: PasswordRuleLowercase}
 	}
 
 	// Controleer op cijfer
 	if getPasswordRequireNumber() && !containsNumber(password) {
-		return fmt.Errorf("wachtwoord moet minimaal één cijfer bevatten")
+		return &PasswordError{Rule: PasswordRuleNumber}
 	}
 
 	// Controleer op speciaal teken
 	if getPasswordRequireSpecial() && !containsSpecial(password) {
-		return fmt.Errorf("wachtwoord moet minimaal één speciaal teken bevatten")
+		return &PasswordError{Rule: PasswordRuleSpecial}
 	}
 
 	return nil
@@ -0,0 +1,46 @@
+// Command reencrypt versleutelt alle versleutelde kolommen opnieuw met de
+// actieve sleutel (SECRETS_ACTIVE_KEY). Gebruik het na het toevoegen van een
+// nieuwe sleutel; daarna kan de oude sleutel uit de configuratie verwijderd
+// worden. Waarden van voor de versleuteling worden ook versleuteld.
+//
+//	go run ./cmd/reencrypt [-dry-run]
+package main
+
+import (
+	"dklautomationgo/database"
+	"dklautomationgo/services/secrets"
+	"flag"
+	"log"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "alleen tellen welke rijen opnieuw versleuteld worden")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Warning: .env file not found")
+	}
+
+	keyring, err := secrets.LoadKeyring()
+	if err != nil {
+		log.Fatalf("Failed to load encryption keys: %v", err)
+	}
+	secrets.SetDefault(keyring)
+
+	db, err := database.NewConnection(database.NewConfig())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	log.Printf("[Reencrypt] Re-encrypting with key %s (dry run: %t)", keyring.ActiveKeyID(), *dryRun)
+	results, err := database.Reencrypt(db, keyring, *dryRun)
+	for _, result := range results {
+		log.Printf("[Reencrypt] %s %v: %d rows, %d updated", result.Table, result.Columns, result.Rows, result.Updated)
+	}
+	if err != nil {
+		log.Fatalf("Re-encryption failed: %v", err)
+	}
+	log.Printf("[Reencrypt] Done")
+}
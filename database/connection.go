@@ -133,6 +133,14 @@ func AutoMigrate(db *gorm.DB) error {
 		&models.Aanmelding{},
 		&models.User{},
 		&models.RefreshToken{},
+		&models.EmailLink{},
+		&models.EmailBounce{},
+		&models.FormIntakeResult{},
+		&models.InboxRule{},
+		&models.EmailMetadata{},
+		&models.EmailNote{},
+		&models.EmailAccount{},
+		&models.EmailTemplate{},
 	)
 
 	if err != nil {
@@ -0,0 +1,2 @@
+-- database/migrations/000003_add_email_links.down.sql
+DROP TABLE IF EXISTS email_links;
@@ -0,0 +1,22 @@
+-- database/migrations/000003_add_email_links.up.sql
+-- Koppelingen tussen inkomende emails en contactformulieren of aanmeldingen
+CREATE TABLE IF NOT EXISTS email_links (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    email_id VARCHAR(255) NOT NULL,
+    message_id VARCHAR(998),
+    account VARCHAR(50) NOT NULL,
+    sender VARCHAR(255) NOT NULL,
+    subject TEXT,
+    received_at VARCHAR(50),
+    record_type VARCHAR(20) NOT NULL CHECK (record_type IN ('contact', 'aanmelding')),
+    record_id UUID NOT NULL,
+    matched_by VARCHAR(20) NOT NULL CHECK (matched_by IN ('thread', 'sender'))
+);
+
+COMMENT ON TABLE email_links IS 'Koppelingen tussen emails in de mailbox en contactformulieren of aanmeldingen';
+
+-- Een email wordt maar één keer aan hetzelfde record gekoppeld
+CREATE UNIQUE INDEX idx_email_links_unique ON email_links(email_id, record_type, record_id);
+CREATE INDEX idx_email_links_record ON email_links(record_type, record_id);
+CREATE INDEX idx_email_links_message_id ON email_links(message_id);
@@ -0,0 +1,24 @@
+-- Resultaten van het automatisch verwerken van formuliermeldingen uit de mailbox
+CREATE TABLE IF NOT EXISTS form_intake_results (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    email_id VARCHAR(255) NOT NULL,
+    message_id VARCHAR(998),
+    account VARCHAR(50) NOT NULL,
+    sender VARCHAR(255),
+    subject TEXT,
+    received_at VARCHAR(50),
+    rule VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('processed', 'duplicate', 'unparsed')),
+    record_type VARCHAR(20) NOT NULL CHECK (record_type IN ('contact', 'aanmelding')),
+    record_id UUID,
+    fields JSONB,
+    missing TEXT
+);
+
+COMMENT ON TABLE form_intake_results IS 'Verwerking van formuliermeldingen uit de mailbox tot contactformulieren en aanmeldingen';
+
+CREATE UNIQUE INDEX idx_form_intake_results_email_id ON form_intake_results(email_id);
+CREATE INDEX idx_form_intake_results_message_id ON form_intake_results(message_id);
+CREATE INDEX idx_form_intake_results_status ON form_intake_results(status);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS email_metadata;
+DROP TABLE IF EXISTS inbox_rules;
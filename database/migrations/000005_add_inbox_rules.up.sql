@@ -0,0 +1,43 @@
+-- Regels die op nieuwe emails toegepast worden
+CREATE TABLE IF NOT EXISTS inbox_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    name VARCHAR(100) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    priority INTEGER NOT NULL DEFAULT 0,
+    stop_after BOOLEAN NOT NULL DEFAULT FALSE,
+    account VARCHAR(50),
+    sender_contains VARCHAR(255),
+    subject_contains VARCHAR(255),
+    body_keywords JSONB,
+    attachment_type VARCHAR(100),
+    label VARCHAR(100),
+    assign_to UUID REFERENCES users(id) ON DELETE SET NULL,
+    mark_read BOOLEAN NOT NULL DEFAULT FALSE,
+    move_to VARCHAR(255),
+    auto_reply_template VARCHAR(255),
+    auto_reply_subject VARCHAR(255)
+);
+
+COMMENT ON TABLE inbox_rules IS 'Regels voor het automatisch labelen, toewijzen, verplaatsen en beantwoorden van emails';
+
+CREATE INDEX idx_inbox_rules_priority ON inbox_rules(enabled, priority);
+
+-- Labels en toewijzing van emails, per Message-ID binnen een account
+CREATE TABLE IF NOT EXISTS email_metadata (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    account VARCHAR(50) NOT NULL,
+    message_key VARCHAR(998) NOT NULL,
+    email_id VARCHAR(255),
+    labels JSONB,
+    assigned_to UUID REFERENCES users(id) ON DELETE SET NULL,
+    rules_applied_at TIMESTAMP WITH TIME ZONE
+);
+
+COMMENT ON TABLE email_metadata IS 'Gegevens die het dashboard bij een email bijhoudt, zoals labels en toewijzing';
+
+CREATE UNIQUE INDEX idx_email_metadata_message ON email_metadata(account, message_key);
+CREATE INDEX idx_email_metadata_email_id ON email_metadata(email_id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS email_notes;
+DROP INDEX IF EXISTS idx_email_metadata_assigned_to;
+DROP INDEX IF EXISTS idx_email_metadata_status;
+ALTER TABLE email_metadata DROP COLUMN IF EXISTS status;
@@ -0,0 +1,21 @@
+-- Status van een email in een gedeelde inbox
+ALTER TABLE email_metadata ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'open';
+
+CREATE INDEX idx_email_metadata_status ON email_metadata(status);
+CREATE INDEX idx_email_metadata_assigned_to ON email_metadata(assigned_to);
+
+-- Interne notities bij emails, per Message-ID binnen een account
+CREATE TABLE IF NOT EXISTS email_notes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    account VARCHAR(50) NOT NULL,
+    message_key VARCHAR(998) NOT NULL,
+    email_id VARCHAR(255),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    author VARCHAR(255),
+    body TEXT NOT NULL
+);
+
+COMMENT ON TABLE email_notes IS 'Interne notities van beheerders bij emails';
+
+CREATE INDEX idx_email_notes_message ON email_notes(account, message_key);
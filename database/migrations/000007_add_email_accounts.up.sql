@@ -0,0 +1,23 @@
+-- Email accounts die via de admin API beheerd worden
+CREATE TABLE IF NOT EXISTS email_accounts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    name VARCHAR(50) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    display_name VARCHAR(255),
+    password_encrypted TEXT,
+    imap_host VARCHAR(255) NOT NULL,
+    imap_port INTEGER NOT NULL DEFAULT 993,
+    imap_security VARCHAR(20),
+    smtp_host VARCHAR(255) NOT NULL,
+    smtp_port INTEGER NOT NULL DEFAULT 587,
+    tls_skip_verify BOOLEAN NOT NULL DEFAULT FALSE,
+    tls_ca_file VARCHAR(255),
+    default_for JSONB,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+COMMENT ON TABLE email_accounts IS 'Email accounts met IMAP/SMTP instellingen; wachtwoorden zijn versleuteld met AES-256-GCM';
+
+CREATE UNIQUE INDEX idx_email_accounts_name ON email_accounts(name);
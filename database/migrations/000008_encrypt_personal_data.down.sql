@@ -0,0 +1,10 @@
+-- Versleutelde telefoonnummers passen niet in VARCHAR(20): ontsleutel de
+-- gegevens voordat deze migratie teruggedraaid wordt
+COMMENT ON COLUMN aanmeldingen.telefoon IS NULL;
+COMMENT ON COLUMN aanmeldingen.ondersteuning IS NULL;
+COMMENT ON COLUMN aanmeldingen.bijzonderheden IS NULL;
+COMMENT ON COLUMN contact_formulieren.bericht IS NULL;
+COMMENT ON COLUMN contact_formulieren.notities IS NULL;
+COMMENT ON COLUMN email_accounts.password_encrypted IS NULL;
+
+ALTER TABLE aanmeldingen ALTER COLUMN telefoon TYPE VARCHAR(20);
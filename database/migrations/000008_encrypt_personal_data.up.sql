@@ -0,0 +1,9 @@
+-- Versleutelde waarden zijn langer dan de oorspronkelijke kolommen toestaan
+ALTER TABLE aanmeldingen ALTER COLUMN telefoon TYPE TEXT;
+
+COMMENT ON COLUMN aanmeldingen.telefoon IS 'Versleuteld (envelope encryption, zie services/secrets)';
+COMMENT ON COLUMN aanmeldingen.ondersteuning IS 'Versleuteld (envelope encryption, zie services/secrets)';
+COMMENT ON COLUMN aanmeldingen.bijzonderheden IS 'Versleuteld (envelope encryption, zie services/secrets)';
+COMMENT ON COLUMN contact_formulieren.bericht IS 'Versleuteld (envelope encryption, zie services/secrets)';
+COMMENT ON COLUMN contact_formulieren.notities IS 'Versleuteld (envelope encryption, zie services/secrets)';
+COMMENT ON COLUMN email_accounts.password_encrypted IS 'Versleuteld (envelope encryption, zie services/secrets)';
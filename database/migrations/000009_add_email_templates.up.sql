@@ -0,0 +1,17 @@
+-- Versies van email templates die via de admin API beheerd worden
+CREATE TABLE IF NOT EXISTS email_templates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    name VARCHAR(100) NOT NULL,
+    version INTEGER NOT NULL,
+    subject TEXT,
+    body TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT FALSE,
+    comment TEXT,
+    created_by VARCHAR(255)
+);
+
+COMMENT ON TABLE email_templates IS 'Versies van email templates; de actieve versie gaat voor het bestand in templates/';
+
+CREATE UNIQUE INDEX idx_email_templates_name_version ON email_templates(name, version);
+CREATE UNIQUE INDEX idx_email_templates_active ON email_templates(name) WHERE active;
@@ -0,0 +1,2 @@
+ALTER TABLE aanmeldingen DROP COLUMN IF EXISTS locale;
+ALTER TABLE contact_formulieren DROP COLUMN IF EXISTS locale;
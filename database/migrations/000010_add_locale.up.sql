@@ -0,0 +1,6 @@
+-- Taal van de indiener, gebruikt voor de bevestigingsemail
+ALTER TABLE contact_formulieren ADD COLUMN IF NOT EXISTS locale VARCHAR(10) NOT NULL DEFAULT 'nl';
+ALTER TABLE aanmeldingen ADD COLUMN IF NOT EXISTS locale VARCHAR(10) NOT NULL DEFAULT 'nl';
+
+COMMENT ON COLUMN contact_formulieren.locale IS 'Taal van de indiener (nl of en)';
+COMMENT ON COLUMN aanmeldingen.locale IS 'Taal van de indiener (nl of en)';
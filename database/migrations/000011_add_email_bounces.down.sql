@@ -0,0 +1,9 @@
+-- database/migrations/000011_add_email_bounces.down.sql
+DROP TABLE IF EXISTS email_bounces;
+
+ALTER TABLE contact_formulieren DROP COLUMN IF EXISTS email_bounced;
+ALTER TABLE contact_formulieren DROP COLUMN IF EXISTS email_bounced_op;
+ALTER TABLE contact_formulieren DROP COLUMN IF EXISTS email_bounce_reden;
+ALTER TABLE aanmeldingen DROP COLUMN IF EXISTS email_bounced;
+ALTER TABLE aanmeldingen DROP COLUMN IF EXISTS email_bounced_op;
+ALTER TABLE aanmeldingen DROP COLUMN IF EXISTS email_bounce_reden;
@@ -0,0 +1,32 @@
+-- database/migrations/000011_add_email_bounces.up.sql
+-- Bevestigingsmails die als onbestelbaar teruggekomen zijn
+ALTER TABLE contact_formulieren ADD COLUMN IF NOT EXISTS email_bounced BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE contact_formulieren ADD COLUMN IF NOT EXISTS email_bounced_op TIMESTAMP WITH TIME ZONE;
+ALTER TABLE contact_formulieren ADD COLUMN IF NOT EXISTS email_bounce_reden TEXT;
+ALTER TABLE aanmeldingen ADD COLUMN IF NOT EXISTS email_bounced BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE aanmeldingen ADD COLUMN IF NOT EXISTS email_bounced_op TIMESTAMP WITH TIME ZONE;
+ALTER TABLE aanmeldingen ADD COLUMN IF NOT EXISTS email_bounce_reden TEXT;
+
+COMMENT ON COLUMN contact_formulieren.email_bounced IS 'Of de bevestigingsemail teruggekomen is als onbestelbaar';
+COMMENT ON COLUMN aanmeldingen.email_bounced IS 'Of de bevestigingsemail teruggekomen is als onbestelbaar';
+
+CREATE TABLE IF NOT EXISTS email_bounces (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    email_id VARCHAR(255) NOT NULL,
+    account VARCHAR(50) NOT NULL,
+    received_at VARCHAR(50),
+    recipient VARCHAR(255) NOT NULL,
+    status VARCHAR(20),
+    reason TEXT,
+    message_id VARCHAR(998),
+    record_type VARCHAR(20) CHECK (record_type IN ('', 'contact', 'aanmelding')),
+    record_id UUID,
+    matched_by VARCHAR(20)
+);
+
+COMMENT ON TABLE email_bounces IS 'Meldingen van mailservers dat een bevestigingsmail niet afgeleverd kon worden';
+
+-- Een bounce wordt per adres maar één keer opgeslagen
+CREATE UNIQUE INDEX idx_email_bounces_unique ON email_bounces(email_id, recipient);
+CREATE INDEX idx_email_bounces_record ON email_bounces(record_type, record_id);
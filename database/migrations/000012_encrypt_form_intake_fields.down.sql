@@ -0,0 +1,5 @@
+-- Versleutelde velden zijn geen geldige JSON: ontsleutel de gegevens voordat
+-- deze migratie teruggedraaid wordt
+COMMENT ON COLUMN form_intake_results.fields IS NULL;
+
+ALTER TABLE form_intake_results ALTER COLUMN fields TYPE JSONB USING fields::jsonb;
@@ -0,0 +1,5 @@
+-- De gevonden velden van een formuliermelding bevatten dezelfde persoonsgegevens
+-- als de aanmelding zelf en worden versleuteld als JSON opgeslagen
+ALTER TABLE form_intake_results ALTER COLUMN fields TYPE TEXT USING fields::text;
+
+COMMENT ON COLUMN form_intake_results.fields IS 'Versleuteld (envelope encryption, zie services/secrets)';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_aanmeldingen_bron_email;
+DROP INDEX IF EXISTS idx_contact_formulieren_bron_email;
+
+ALTER TABLE aanmeldingen DROP COLUMN IF EXISTS bron_email;
+ALTER TABLE contact_formulieren DROP COLUMN IF EXISTS bron_email;
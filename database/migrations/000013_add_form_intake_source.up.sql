@@ -0,0 +1,9 @@
+-- Records uit een formuliermelding krijgen de Message-ID van die melding, zodat
+-- dezelfde melding maar één keer een record oplevert, ook als de watcher en het
+-- ophalen van de INBOX haar tegelijk verwerken
+ALTER TABLE aanmeldingen ADD COLUMN IF NOT EXISTS bron_email VARCHAR(998);
+ALTER TABLE contact_formulieren ADD COLUMN IF NOT EXISTS bron_email VARCHAR(998);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_aanmeldingen_bron_email ON aanmeldingen(bron_email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_contact_formulieren_bron_email ON contact_formulieren(bron_email);
+
@@ -0,0 +1 @@
+ALTER TABLE form_intake_results DROP COLUMN IF EXISTS error;
@@ -0,0 +1,3 @@
+-- Een melding waarvan het record niet opgeslagen kon worden blijft onverwerkt,
+-- met de fout, zodat deze na het oplossen opnieuw verwerkt kan worden
+ALTER TABLE form_intake_results ADD COLUMN IF NOT EXISTS error TEXT;
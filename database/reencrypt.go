@@ -0,0 +1,126 @@
+package database
+
+import (
+	"dklautomationgo/models"
+	"dklautomationgo/services/secrets"
+	"fmt"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// EncryptedModels zijn de modellen met kolommen die de "encrypted" serializer gebruiken
+var EncryptedModels = []interface{}{
+	&models.Aanmelding{},
+	&models.ContactFormulier{},
+	&models.EmailAccount{},
+	&models.FormIntakeResult{},
+}
+
+// reencryptBatchSize is het aantal rijen dat per query gelezen wordt
+const reencryptBatchSize = 200
+
+// ReencryptResult is de uitkomst van Reencrypt voor één tabel
+type ReencryptResult struct {
+	Table   string
+	Columns []string
+	Rows    int // Aantal gelezen rijen
+	Updated int // Aantal rijen met een of meer opnieuw versleutelde waarden
+}
+
+// Reencrypt zorgt dat alle versleutelde kolommen met de actieve sleutel van de
+// keyring versleuteld zijn. Onversleutelde waarden (van voor de versleuteling)
+// worden versleuteld; waarden met een oude sleutel krijgen een met de actieve
+// sleutel versleutelde data key. Met dryRun wordt alleen geteld.
+func Reencrypt(db *gorm.DB, keyring *secrets.Keyring, dryRun bool) ([]ReencryptResult, error) {
+	if keyring == nil {
+		return nil, secrets.ErrNoKey
+	}
+
+	var results []ReencryptResult
+	for _, model := range EncryptedModels {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return results, fmt.Errorf("failed to parse model %T: %w", model, err)
+		}
+
+		result := ReencryptResult{Table: stmt.Schema.Table, Columns: EncryptedColumns(stmt.Schema.Fields)}
+		if len(result.Columns) > 0 {
+			if err := reencryptTable(db, keyring, &result, dryRun); err != nil {
+				return append(results, result), err
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// reencryptTable leest de rijen op volgorde van id, zonder de serializer, en
+// schrijft alleen de gewijzigde kolommen terug
+func reencryptTable(db *gorm.DB, keyring *secrets.Keyring, result *ReencryptResult, dryRun bool) error {
+	columns := append([]string{"id::text AS id"}, result.Columns...)
+	lastID := ""
+	for {
+		var rows []map[string]interface{}
+		query := db.Table(result.Table).Select(columns).Order(result.Table + ".id").Limit(reencryptBatchSize)
+		if lastID != "" {
+			query = query.Where(result.Table+".id > ?", lastID)
+		}
+		if err := query.Find(&rows).Error; err != nil {
+			return fmt.Errorf("failed to read %s: %w", result.Table, err)
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		for _, row := range rows {
+			lastID = fmt.Sprint(row["id"])
+			result.Rows++
+
+			updates := make(map[string]interface{})
+			for _, column := range result.Columns {
+				var value string
+				switch v := row[column].(type) {
+				case string:
+					value = v
+				case []byte:
+					value = string(v)
+				default:
+					continue // NULL
+				}
+				rotated, changed, err := keyring.Rotate(value)
+				if err != nil {
+					return fmt.Errorf("%s %s, column %s: %w", result.Table, lastID, column, err)
+				}
+				if changed {
+					updates[column] = rotated
+				}
+			}
+			if len(updates) == 0 {
+				continue
+			}
+
+			result.Updated++
+			if dryRun {
+				continue
+			}
+			if err := db.Table(result.Table).Where("id = ?", lastID).UpdateColumns(updates).Error; err != nil {
+				return fmt.Errorf("failed to update %s %s: %w", result.Table, lastID, err)
+			}
+		}
+		log.Printf("[Reencrypt] %s: %d rows checked, %d to update", result.Table, result.Rows, result.Updated)
+	}
+}
+
+// EncryptedColumns geeft de kolommen van de velden met de "encrypted" serializer
+func EncryptedColumns(fields []*schema.Field) []string {
+	var columns []string
+	for _, field := range fields {
+		if field.DBName != "" && strings.EqualFold(field.TagSettings["SERIALIZER"], secrets.SerializerName) {
+			columns = append(columns, field.DBName)
+		}
+	}
+	return columns
+}
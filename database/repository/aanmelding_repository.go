@@ -6,14 +6,18 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // IAanmeldingRepository definieert de interface voor aanmelding repositories
 type IAanmeldingRepository interface {
 	Create(aanmelding *models.Aanmelding) error
+	CreateFromSource(aanmelding *models.Aanmelding) (bool, error)
 	FindAll(limit, offset int) ([]*models.Aanmelding, error)
 	FindByID(id string) (*models.Aanmelding, error)
+	FindByEmail(email string) ([]*models.Aanmelding, error)
 	Update(aanmelding *models.Aanmelding) error
+	MarkBounced(id string, bouncedAt time.Time, reason string) error
 	Count() (int64, error)
 }
 
@@ -35,6 +39,27 @@ func (r *AanmeldingRepository) Create(aanmelding *models.Aanmelding) error {
 	return r.db.Create(aanmelding).Error
 }
 
+// CreateFromSource slaat een aanmelding uit een formuliermelding op, tenzij er al
+// een aanmelding uit dezelfde melding (BronEmail) bestaat. In dat geval wordt de
+// bestaande in aanmelding geladen en is het resultaat false. De unieke index maakt
+// dit veilig als dezelfde melding tegelijk door meerdere processen verwerkt wordt.
+func (r *AanmeldingRepository) CreateFromSource(aanmelding *models.Aanmelding) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(aanmelding)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	var existing models.Aanmelding
+	if err := r.db.Where("bron_email = ?", aanmelding.BronEmail).First(&existing).Error; err != nil {
+		return false, err
+	}
+	*aanmelding = existing
+	return false, nil
+}
+
 // FindByID zoekt een aanmelding op basis van ID
 func (r *AanmeldingRepository) FindByID(id string) (*models.Aanmelding, error) {
 	var aanmelding models.Aanmelding
@@ -42,6 +67,13 @@ func (r *AanmeldingRepository) FindByID(id string) (*models.Aanmelding, error) {
 	return &aanmelding, err
 }
 
+// FindByEmail haalt de aanmeldingen van een email adres op, ongeacht hoofdletters
+func (r *AanmeldingRepository) FindByEmail(email string) ([]*models.Aanmelding, error) {
+	var aanmeldingen []*models.Aanmelding
+	err := r.db.Where("LOWER(email) = LOWER(?)", email).Order("created_at DESC").Find(&aanmeldingen).Error
+	return aanmeldingen, err
+}
+
 // FindAll haalt alle aanmeldingen op
 func (r *AanmeldingRepository) FindAll(limit, offset int) ([]*models.Aanmelding, error) {
 	var aanmeldingen []*models.Aanmelding
@@ -69,6 +101,19 @@ func (r *AanmeldingRepository) Update(aanmelding *models.Aanmelding) error {
 	return r.db.Save(aanmelding).Error
 }
 
+// MarkBounced markeert dat de bevestigingsmail van een aanmelding teruggekomen is.
+// Alleen de bounce kolommen worden bijgewerkt, zodat gelijktijdige wijzigingen
+// door een beheerder niet overschreven worden.
+func (r *AanmeldingRepository) MarkBounced(id string, bouncedAt time.Time, reason string) error {
+	return r.db.Model(&models.Aanmelding{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"email_bounced":      true,
+			"email_bounced_op":   bouncedAt,
+			"email_bounce_reden": reason,
+		}).Error
+}
+
 // MarkEmailSent markeert een aanmelding als verzonden
 func (r *AanmeldingRepository) MarkEmailSent(id string) error {
 	now := time.Now()
@@ -73,6 +73,27 @@ func (s *AanmeldingRepositoryTestSuite) TestCreate() {
 	s.Assert().Equal(aanmelding.Afstand, result.Afstand)
 }
 
+func (s *AanmeldingRepositoryTestSuite) TestCreateFromSource() {
+	// Create test aanmelding uit een formuliermelding
+	source := "<melding-7@dekoninklijkeloop.nl>"
+	first := &models.Aanmelding{Naam: "Test User", Email: "test@example.com", Telefoon: "0612345678", Rol: "chauffeur", Afstand: "10 km", Terms: true, BronEmail: &source}
+	created, err := s.repository.CreateFromSource(first)
+	s.Require().NoError(err)
+	s.Require().True(created)
+	s.Require().NotEmpty(first.ID)
+
+	// Dezelfde melding nog een keer levert de bestaande aanmelding op
+	second := &models.Aanmelding{Naam: "Test User", Email: "test@example.com", Telefoon: "0612345678", Rol: "chauffeur", Afstand: "10 km", Terms: true, BronEmail: &source}
+	created, err = s.repository.CreateFromSource(second)
+	s.Require().NoError(err)
+	s.Assert().False(created)
+	s.Assert().Equal(first.ID, second.ID)
+
+	count, err := s.repository.Count()
+	s.Require().NoError(err)
+	s.Assert().Equal(int64(1), count)
+}
+
 func (s *AanmeldingRepositoryTestSuite) TestFindAll() {
 	// Create test aanmeldingen
 	aanmeldingen := []models.Aanmelding{
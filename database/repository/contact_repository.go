@@ -6,8 +6,24 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// IContactRepository definieert de interface voor contact repositories
+type IContactRepository interface {
+	Create(contact *models.ContactFormulier) error
+	CreateFromSource(contact *models.ContactFormulier) (bool, error)
+	FindByID(id string) (*models.ContactFormulier, error)
+	FindByEmail(email string) ([]*models.ContactFormulier, error)
+	FindAll(limit, offset int) ([]*models.ContactFormulier, error)
+	Update(contact *models.ContactFormulier) error
+	MarkBounced(id string, bouncedAt time.Time, reason string) error
+	Count() (int64, error)
+}
+
+// Controleer of ContactRepository de IContactRepository interface implementeert
+var _ IContactRepository = (*ContactRepository)(nil)
+
 // ContactRepository bevat methoden voor het werken met contactformulieren in de database
 type ContactRepository struct {
 	db *gorm.DB
@@ -23,6 +39,27 @@ func (r *ContactRepository) Create(contact *models.ContactFormulier) error {
 	return r.db.Create(contact).Error
 }
 
+// CreateFromSource slaat een contactformulier uit een formuliermelding op, tenzij er al
+// een contactformulier uit dezelfde melding (BronEmail) bestaat. In dat geval wordt de
+// bestaande in contact geladen en is het resultaat false. De unieke index maakt
+// dit veilig als dezelfde melding tegelijk door meerdere processen verwerkt wordt.
+func (r *ContactRepository) CreateFromSource(contact *models.ContactFormulier) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(contact)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	var existing models.ContactFormulier
+	if err := r.db.Where("bron_email = ?", contact.BronEmail).First(&existing).Error; err != nil {
+		return false, err
+	}
+	*contact = existing
+	return false, nil
+}
+
 // FindByID zoekt een contactformulier op basis van ID
 func (r *ContactRepository) FindByID(id string) (*models.ContactFormulier, error) {
 	var contact models.ContactFormulier
@@ -30,6 +67,13 @@ func (r *ContactRepository) FindByID(id string) (*models.ContactFormulier, error
 	return &contact, err
 }
 
+// FindByEmail haalt de contactformulieren van een email adres op, ongeacht hoofdletters
+func (r *ContactRepository) FindByEmail(email string) ([]*models.ContactFormulier, error) {
+	var contacts []*models.ContactFormulier
+	err := r.db.Where("LOWER(email) = LOWER(?)", email).Order("created_at DESC").Find(&contacts).Error
+	return contacts, err
+}
+
 // FindAll haalt alle contactformulieren op
 func (r *ContactRepository) FindAll(limit, offset int) ([]*models.ContactFormulier, error) {
 	var contacts []*models.ContactFormulier
@@ -50,6 +94,19 @@ func (r *ContactRepository) Update(contact *models.ContactFormulier) error {
 	return r.db.Save(contact).Error
 }
 
+// MarkBounced markeert dat de bevestigingsmail van een contactformulier teruggekomen is.
+// Alleen de bounce kolommen worden bijgewerkt, zodat gelijktijdige wijzigingen
+// door een beheerder niet overschreven worden.
+func (r *ContactRepository) MarkBounced(id string, bouncedAt time.Time, reason string) error {
+	return r.db.Model(&models.ContactFormulier{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"email_bounced":      true,
+			"email_bounced_op":   bouncedAt,
+			"email_bounce_reden": reason,
+		}).Error
+}
+
 // MarkEmailSent markeert een contactformulier als verzonden
 func (r *ContactRepository) MarkEmailSent(id string) error {
 	now := time.Now()
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"dklautomationgo/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IEmailAccountRepository definieert de interface voor email account repositories
+type IEmailAccountRepository interface {
+	Create(account *models.EmailAccount) error
+	FindByID(id string) (*models.EmailAccount, error)
+	FindAll() ([]*models.EmailAccount, error)
+	FindEnabled() ([]*models.EmailAccount, error)
+	Update(account *models.EmailAccount) error
+	Delete(id string) error
+}
+
+// Controleer of EmailAccountRepository de IEmailAccountRepository interface implementeert
+var _ IEmailAccountRepository = (*EmailAccountRepository)(nil)
+
+// EmailAccountRepository bevat methoden voor het werken met email accounts in de database
+type EmailAccountRepository struct {
+	db *gorm.DB
+}
+
+// NewEmailAccountRepository maakt een nieuwe EmailAccountRepository
+func NewEmailAccountRepository(db *gorm.DB) *EmailAccountRepository {
+	return &EmailAccountRepository{db: db}
+}
+
+// Create slaat een nieuw account op in de database
+func (r *EmailAccountRepository) Create(account *models.EmailAccount) error {
+	return r.db.Create(account).Error
+}
+
+// FindByID zoekt een account op basis van ID
+func (r *EmailAccountRepository) FindByID(id string) (*models.EmailAccount, error) {
+	var account models.EmailAccount
+	err := r.db.Where("id = ?", id).First(&account).Error
+	return &account, err
+}
+
+// FindAll haalt alle accounts op, gesorteerd op naam
+func (r *EmailAccountRepository) FindAll() ([]*models.EmailAccount, error) {
+	var accounts []*models.EmailAccount
+	err := r.db.Order("name").Find(&accounts).Error
+	return accounts, err
+}
+
+// FindEnabled haalt de actieve accounts op, gesorteerd op naam
+func (r *EmailAccountRepository) FindEnabled() ([]*models.EmailAccount, error) {
+	var accounts []*models.EmailAccount
+	err := r.db.Where("enabled = ?", true).Order("name").Find(&accounts).Error
+	return accounts, err
+}
+
+// Update werkt een bestaand account bij
+func (r *EmailAccountRepository) Update(account *models.EmailAccount) error {
+	account.UpdatedAt = time.Now()
+	return r.db.Save(account).Error
+}
+
+// Delete verwijdert een account
+func (r *EmailAccountRepository) Delete(id string) error {
+	result := r.db.Where("id = ?", id).Delete(&models.EmailAccount{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+// database/repository/email_bounce_repository.go
+package repository
+
+import (
+	"dklautomationgo/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IEmailBounceRepository definieert de interface voor email bounce repositories
+type IEmailBounceRepository interface {
+	Create(bounce *models.EmailBounce) error
+	FindAll(limit, offset int) ([]*models.EmailBounce, error)
+	Count() (int64, error)
+}
+
+// Controleer of EmailBounceRepository de IEmailBounceRepository interface implementeert
+var _ IEmailBounceRepository = (*EmailBounceRepository)(nil)
+
+// EmailBounceRepository bevat methoden voor het werken met onbestelbare bevestigingsmails
+type EmailBounceRepository struct {
+	db *gorm.DB
+}
+
+// NewEmailBounceRepository maakt een nieuwe EmailBounceRepository
+func NewEmailBounceRepository(db *gorm.DB) *EmailBounceRepository {
+	return &EmailBounceRepository{db: db}
+}
+
+// Create slaat een bounce op; dezelfde bounce wordt bij opnieuw verwerken niet gedupliceerd
+func (r *EmailBounceRepository) Create(bounce *models.EmailBounce) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(bounce).Error
+}
+
+// FindAll haalt de bounces op, nieuwste eerst
+func (r *EmailBounceRepository) FindAll(limit, offset int) ([]*models.EmailBounce, error) {
+	var bounces []*models.EmailBounce
+	err := r.db.Order("created_at DESC").Limit(limit).Offset(offset).Find(&bounces).Error
+	return bounces, err
+}
+
+// Count telt het aantal bounces
+func (r *EmailBounceRepository) Count() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.EmailBounce{}).Count(&count).Error
+	return count, err
+}
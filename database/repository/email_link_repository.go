@@ -0,0 +1,50 @@
+// database/repository/email_link_repository.go
+package repository
+
+import (
+	"dklautomationgo/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IEmailLinkRepository definieert de interface voor email link repositories
+type IEmailLinkRepository interface {
+	Create(link *models.EmailLink) error
+	FindByRecord(recordType, recordID string) ([]*models.EmailLink, error)
+	FindByEmailID(emailID string) ([]*models.EmailLink, error)
+}
+
+// Controleer of EmailLinkRepository de IEmailLinkRepository interface implementeert
+var _ IEmailLinkRepository = (*EmailLinkRepository)(nil)
+
+// EmailLinkRepository bevat methoden voor het werken met koppelingen tussen emails en records
+type EmailLinkRepository struct {
+	db *gorm.DB
+}
+
+// NewEmailLinkRepository maakt een nieuwe EmailLinkRepository
+func NewEmailLinkRepository(db *gorm.DB) *EmailLinkRepository {
+	return &EmailLinkRepository{db: db}
+}
+
+// Create slaat een koppeling op; een bestaande koppeling van dezelfde email aan hetzelfde record wordt niet gedupliceerd
+func (r *EmailLinkRepository) Create(link *models.EmailLink) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(link).Error
+}
+
+// FindByRecord haalt de emails op die aan een contactformulier of aanmelding gekoppeld zijn, nieuwste eerst
+func (r *EmailLinkRepository) FindByRecord(recordType, recordID string) ([]*models.EmailLink, error) {
+	var links []*models.EmailLink
+	err := r.db.Where("record_type = ? AND record_id = ?", recordType, recordID).
+		Order("received_at DESC").
+		Find(&links).Error
+	return links, err
+}
+
+// FindByEmailID haalt de records op waaraan een email gekoppeld is
+func (r *EmailLinkRepository) FindByEmailID(emailID string) ([]*models.EmailLink, error) {
+	var links []*models.EmailLink
+	err := r.db.Where("email_id = ?", emailID).Order("created_at").Find(&links).Error
+	return links, err
+}
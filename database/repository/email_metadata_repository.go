@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"dklautomationgo/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IEmailMetadataRepository definieert de interface voor email metadata repositories
+type IEmailMetadataRepository interface {
+	FindByMessage(account, messageKey string) (*models.EmailMetadata, error)
+	FindByEmailID(emailID string) (*models.EmailMetadata, error)
+	FindByMessageKeys(messageKeys []string) ([]*models.EmailMetadata, error)
+	SaveColumns(metadata *models.EmailMetadata, columns ...string) error
+	ClaimRules(account, messageKey, emailID string) (bool, error)
+}
+
+// Controleer of EmailMetadataRepository de IEmailMetadataRepository interface implementeert
+var _ IEmailMetadataRepository = (*EmailMetadataRepository)(nil)
+
+// EmailMetadataRepository bevat methoden voor het werken met de metadata van emails
+type EmailMetadataRepository struct {
+	db *gorm.DB
+}
+
+// NewEmailMetadataRepository maakt een nieuwe EmailMetadataRepository
+func NewEmailMetadataRepository(db *gorm.DB) *EmailMetadataRepository {
+	return &EmailMetadataRepository{db: db}
+}
+
+// FindByMessage zoekt de metadata van een email. Geeft nil zonder fout als er
+// nog geen metadata is.
+func (r *EmailMetadataRepository) FindByMessage(account, messageKey string) (*models.EmailMetadata, error) {
+	var metadata []*models.EmailMetadata
+	err := r.db.Where("account = ? AND message_key = ?", account, messageKey).Limit(1).Find(&metadata).Error
+	if err != nil || len(metadata) == 0 {
+		return nil, err
+	}
+	return metadata[0], nil
+}
+
+// FindByEmailID zoekt de metadata via het laatst bekende email ID. Geeft nil
+// zonder fout als er geen metadata met dit ID is.
+func (r *EmailMetadataRepository) FindByEmailID(emailID string) (*models.EmailMetadata, error) {
+	var metadata []*models.EmailMetadata
+	err := r.db.Where("email_id = ?", emailID).Limit(1).Find(&metadata).Error
+	if err != nil || len(metadata) == 0 {
+		return nil, err
+	}
+	return metadata[0], nil
+}
+
+// FindByMessageKeys haalt de metadata van een lijst emails in één query op
+func (r *EmailMetadataRepository) FindByMessageKeys(messageKeys []string) ([]*models.EmailMetadata, error) {
+	var metadata []*models.EmailMetadata
+	if len(messageKeys) == 0 {
+		return metadata, nil
+	}
+	err := r.db.Where("message_key IN ?", messageKeys).Find(&metadata).Error
+	return metadata, err
+}
+
+// SaveColumns slaat nieuwe metadata op, of werkt van bestaande metadata alleen
+// de gegeven kolommen (en email_id) bij, zodat gelijktijdige wijzigingen van
+// andere kolommen behouden blijven. Metadata die intussen door een ander proces
+// aangemaakt is wordt via account en Message-ID bijgewerkt.
+func (r *EmailMetadataRepository) SaveColumns(metadata *models.EmailMetadata, columns ...string) error {
+	now := time.Now()
+	metadata.UpdatedAt = now
+	columns = append(columns, "email_id", "updated_at")
+
+	if metadata.ID != "" {
+		return r.db.Model(metadata).
+			Where("account = ? AND message_key = ?", metadata.Account, metadata.MessageKey).
+			Select(columns).
+			Updates(metadata).Error
+	}
+
+	metadata.CreatedAt = now
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "account"}, {Name: "message_key"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Create(metadata).Error
+}
+
+// ClaimRules legt atomair vast dat de inbox regels op een email toegepast
+// worden, door rules_applied_at alleen te zetten als die nog leeg is. Alleen de
+// eerste aanroep per email krijgt true, ook als de watcher en het ophalen van de
+// INBOX dezelfde email tegelijk verwerken. Ontbrekende metadata wordt aangemaakt.
+func (r *EmailMetadataRepository) ClaimRules(account, messageKey, emailID string) (bool, error) {
+	now := time.Now()
+	metadata := models.NewEmailMetadata(account, messageKey, emailID)
+	metadata.CreatedAt = now
+	metadata.UpdatedAt = now
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(metadata).Error; err != nil {
+		return false, err
+	}
+
+	result := r.db.Model(&models.EmailMetadata{}).
+		Where("account = ? AND message_key = ? AND rules_applied_at IS NULL", account, messageKey).
+		Updates(map[string]interface{}{
+			"rules_applied_at": now,
+			"updated_at":       now,
+		})
+	return result.RowsAffected > 0, result.Error
+}
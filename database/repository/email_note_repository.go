@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"dklautomationgo/models"
+
+	"gorm.io/gorm"
+)
+
+// IEmailNoteRepository definieert de interface voor email notitie repositories
+type IEmailNoteRepository interface {
+	Create(note *models.EmailNote) error
+	FindByMessage(account, messageKey string) ([]*models.EmailNote, error)
+}
+
+// Controleer of EmailNoteRepository de IEmailNoteRepository interface implementeert
+var _ IEmailNoteRepository = (*EmailNoteRepository)(nil)
+
+// EmailNoteRepository bevat methoden voor het werken met interne notities bij emails
+type EmailNoteRepository struct {
+	db *gorm.DB
+}
+
+// NewEmailNoteRepository maakt een nieuwe EmailNoteRepository
+func NewEmailNoteRepository(db *gorm.DB) *EmailNoteRepository {
+	return &EmailNoteRepository{db: db}
+}
+
+// Create slaat een nieuwe notitie op
+func (r *EmailNoteRepository) Create(note *models.EmailNote) error {
+	return r.db.Create(note).Error
+}
+
+// FindByMessage haalt de notities bij een email op, oudste eerst
+func (r *EmailNoteRepository) FindByMessage(account, messageKey string) ([]*models.EmailNote, error) {
+	var notes []*models.EmailNote
+	err := r.db.Where("account = ? AND message_key = ?", account, messageKey).
+		Order("created_at").
+		Find(&notes).Error
+	return notes, err
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"dklautomationgo/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IEmailTemplateRepository definieert de interface voor email template repositories
+type IEmailTemplateRepository interface {
+	FindActive() ([]*models.EmailTemplate, error)
+	FindAll() ([]*models.EmailTemplate, error)
+	FindVersions(name string) ([]*models.EmailTemplate, error)
+	FindVersion(name string, version int) (*models.EmailTemplate, error)
+	CreateVersion(tmpl *models.EmailTemplate, activate bool) error
+	Activate(name string, version int) error
+	DeleteByName(name string) error
+}
+
+// Controleer of EmailTemplateRepository de IEmailTemplateRepository interface implementeert
+var _ IEmailTemplateRepository = (*EmailTemplateRepository)(nil)
+
+// EmailTemplateRepository bevat methoden voor het werken met email templates in de database
+type EmailTemplateRepository struct {
+	db *gorm.DB
+}
+
+// NewEmailTemplateRepository maakt een nieuwe EmailTemplateRepository
+func NewEmailTemplateRepository(db *gorm.DB) *EmailTemplateRepository {
+	return &EmailTemplateRepository{db: db}
+}
+
+// FindActive haalt de actieve versie van elk template op
+func (r *EmailTemplateRepository) FindActive() ([]*models.EmailTemplate, error) {
+	var templates []*models.EmailTemplate
+	err := r.db.Where("active = ?", true).Order("name").Find(&templates).Error
+	return templates, err
+}
+
+// FindAll haalt alle versies van alle templates op, nieuwste versie eerst
+func (r *EmailTemplateRepository) FindAll() ([]*models.EmailTemplate, error) {
+	var templates []*models.EmailTemplate
+	err := r.db.Order("name, version DESC").Find(&templates).Error
+	return templates, err
+}
+
+// FindVersions haalt alle versies van een template op, nieuwste versie eerst
+func (r *EmailTemplateRepository) FindVersions(name string) ([]*models.EmailTemplate, error) {
+	var templates []*models.EmailTemplate
+	err := r.db.Where("name = ?", name).Order("version DESC").Find(&templates).Error
+	return templates, err
+}
+
+// FindVersion zoekt een specifieke versie van een template
+func (r *EmailTemplateRepository) FindVersion(name string, version int) (*models.EmailTemplate, error) {
+	var tmpl models.EmailTemplate
+	err := r.db.Where("name = ? AND version = ?", name, version).First(&tmpl).Error
+	return &tmpl, err
+}
+
+// CreateVersion slaat een nieuwe versie van een template op, met het volgende
+// versienummer. Met activate wordt het de actieve versie.
+func (r *EmailTemplateRepository) CreateVersion(tmpl *models.EmailTemplate, activate bool) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&models.EmailTemplate{}).Where("name = ?", tmpl.Name).
+			Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+
+		if activate {
+			if err := tx.Model(&models.EmailTemplate{}).Where("name = ? AND active = ?", tmpl.Name, true).
+				Update("active", false).Error; err != nil {
+				return err
+			}
+		}
+
+		tmpl.ID = ""
+		tmpl.Version = latest + 1
+		tmpl.Active = activate
+		tmpl.CreatedAt = time.Now()
+		return tx.Create(tmpl).Error
+	})
+}
+
+// Activate maakt een versie van een template actief, bijvoorbeeld om terug te gaan naar een eerdere versie
+func (r *EmailTemplateRepository) Activate(name string, version int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.EmailTemplate{}).Where("name = ? AND active = ?", name, true).
+			Update("active", false).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.EmailTemplate{}).Where("name = ? AND version = ?", name, version).Update("active", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// DeleteByName verwijdert alle versies van een template, zodat het bestand weer gebruikt wordt
+func (r *EmailTemplateRepository) DeleteByName(name string) error {
+	result := r.db.Where("name = ?", name).Delete(&models.EmailTemplate{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
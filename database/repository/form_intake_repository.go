@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"dklautomationgo/models"
+
+	"gorm.io/gorm"
+)
+
+// IFormIntakeRepository definieert de interface voor form intake repositories
+type IFormIntakeRepository interface {
+	Save(result *models.FormIntakeResult) error
+	FindByEmail(emailID, messageID string) (*models.FormIntakeResult, error)
+	FindAll(status string, limit, offset int) ([]*models.FormIntakeResult, error)
+	Count(status string) (int64, error)
+}
+
+// Controleer of FormIntakeRepository de IFormIntakeRepository interface implementeert
+var _ IFormIntakeRepository = (*FormIntakeRepository)(nil)
+
+// FormIntakeRepository bevat methoden voor het werken met verwerkte formuliermeldingen
+type FormIntakeRepository struct {
+	db *gorm.DB
+}
+
+// NewFormIntakeRepository maakt een nieuwe FormIntakeRepository
+func NewFormIntakeRepository(db *gorm.DB) *FormIntakeRepository {
+	return &FormIntakeRepository{db: db}
+}
+
+// Save slaat een nieuw resultaat op of werkt een bestaand resultaat bij
+func (r *FormIntakeRepository) Save(result *models.FormIntakeResult) error {
+	return r.db.Save(result).Error
+}
+
+// FindByEmail zoekt het resultaat van een email op ID of, als de email
+// verplaatst is, op Message-ID. Geeft nil zonder fout als er geen resultaat is.
+func (r *FormIntakeRepository) FindByEmail(emailID, messageID string) (*models.FormIntakeResult, error) {
+	query := r.db.Where("email_id = ?", emailID)
+	if messageID != "" {
+		query = query.Or("message_id = ?", messageID)
+	}
+
+	var results []*models.FormIntakeResult
+	if err := query.Order("created_at").Limit(1).Find(&results).Error; err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	return results[0], nil
+}
+
+// FindAll haalt resultaten op, eventueel alleen met een bepaalde status, nieuwste eerst
+func (r *FormIntakeRepository) FindAll(status string, limit, offset int) ([]*models.FormIntakeResult, error) {
+	var results []*models.FormIntakeResult
+	query := r.db.Order("updated_at DESC").Limit(limit).Offset(offset)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&results).Error
+	return results, err
+}
+
+// Count telt de resultaten, eventueel alleen met een bepaalde status
+func (r *FormIntakeRepository) Count(status string) (int64, error) {
+	var count int64
+	query := r.db.Model(&models.FormIntakeResult{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Count(&count).Error
+	return count, err
+}
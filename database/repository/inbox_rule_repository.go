@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"dklautomationgo/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IInboxRuleRepository definieert de interface voor inbox rule repositories
+type IInboxRuleRepository interface {
+	Create(rule *models.InboxRule) error
+	FindByID(id string) (*models.InboxRule, error)
+	FindAll() ([]*models.InboxRule, error)
+	FindEnabled() ([]*models.InboxRule, error)
+	Update(rule *models.InboxRule) error
+	Delete(id string) error
+}
+
+// Controleer of InboxRuleRepository de IInboxRuleRepository interface implementeert
+var _ IInboxRuleRepository = (*InboxRuleRepository)(nil)
+
+// InboxRuleRepository bevat methoden voor het werken met inbox regels in de database
+type InboxRuleRepository struct {
+	db *gorm.DB
+}
+
+// NewInboxRuleRepository maakt een nieuwe InboxRuleRepository
+func NewInboxRuleRepository(db *gorm.DB) *InboxRuleRepository {
+	return &InboxRuleRepository{db: db}
+}
+
+// Create slaat een nieuwe regel op in de database
+func (r *InboxRuleRepository) Create(rule *models.InboxRule) error {
+	return r.db.Create(rule).Error
+}
+
+// FindByID zoekt een regel op basis van ID
+func (r *InboxRuleRepository) FindByID(id string) (*models.InboxRule, error) {
+	var rule models.InboxRule
+	err := r.db.Where("id = ?", id).First(&rule).Error
+	return &rule, err
+}
+
+// FindAll haalt alle regels op in de volgorde waarin ze toegepast worden
+func (r *InboxRuleRepository) FindAll() ([]*models.InboxRule, error) {
+	var rules []*models.InboxRule
+	err := r.db.Order("priority, created_at").Find(&rules).Error
+	return rules, err
+}
+
+// FindEnabled haalt de actieve regels op in de volgorde waarin ze toegepast worden
+func (r *InboxRuleRepository) FindEnabled() ([]*models.InboxRule, error) {
+	var rules []*models.InboxRule
+	err := r.db.Where("enabled = ?", true).Order("priority, created_at").Find(&rules).Error
+	return rules, err
+}
+
+// Update werkt een bestaande regel bij
+func (r *InboxRuleRepository) Update(rule *models.InboxRule) error {
+	rule.UpdatedAt = time.Now()
+	return r.db.Save(rule).Error
+}
+
+// Delete verwijdert een regel
+func (r *InboxRuleRepository) Delete(id string) error {
+	result := r.db.Where("id = ?", id).Delete(&models.InboxRule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"dklautomationgo/database/repository"
 	"dklautomationgo/models"
 	"dklautomationgo/services"
+	"dklautomationgo/services/i18n"
+	"log"
 	"net/http"
 	"strconv"
 
@@ -24,7 +26,8 @@ var _ IAanmeldingHandler = (*AanmeldingHandler)(nil)
 
 // AanmeldingHandler bevat handlers voor aanmeldingen
 type AanmeldingHandler struct {
-	service services.IAanmeldingService
+	service     services.IAanmeldingService
+	linkService services.IEmailLinkService
 }
 
 // NewAanmeldingHandler maakt een nieuwe AanmeldingHandler
@@ -34,21 +37,31 @@ func NewAanmeldingHandler(service services.IAanmeldingService) *AanmeldingHandle
 	}
 }
 
+// SetEmailLinkService stelt de service in waarmee gekoppelde emails opgehaald worden
+func (h *AanmeldingHandler) SetEmailLinkService(linkService services.IEmailLinkService) {
+	h.linkService = linkService
+}
+
 // CreateAanmelding handelt het aanmaken van een aanmelding af
 func (h *AanmeldingHandler) CreateAanmelding(c *gin.Context) {
 	var aanmelding models.Aanmelding
 	if err := c.ShouldBindJSON(&aanmelding); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Ongeldige invoer"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidRequest)
 		return
 	}
+	// Zonder (ondersteunde) taal in het formulier geldt de taal van het verzoek
+	if aanmelding.Locale = i18n.Normalize(aanmelding.Locale); aanmelding.Locale == "" {
+		aanmelding.Locale = i18n.Locale(c)
+	}
 
 	if err := h.service.CreateAanmelding(&aanmelding); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		log.Printf("[CreateAanmelding] Error creating aanmelding: %v", err)
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgAanmeldingSaveFailed)
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"message":    "Aanmelding succesvol aangemaakt",
+		"message":    i18n.Message(c, i18n.MsgAanmeldingCreated),
 		"aanmelding": aanmelding,
 	})
 }
@@ -80,14 +93,16 @@ func (h *AanmeldingHandler) GetAanmeldingen(c *gin.Context) {
 	// Haal aanmeldingen op
 	aanmeldingen, err := h.service.GetAanmeldingen(params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		log.Printf("[GetAanmeldingen] Error fetching aanmeldingen: %v", err)
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgAanmeldingenFetchFailed)
 		return
 	}
 
 	// Tel totaal aantal aanmeldingen
 	total, err := h.service.CountAanmeldingen(params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		log.Printf("[GetAanmeldingen] Error counting aanmeldingen: %v", err)
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgAanmeldingenFetchFailed)
 		return
 	}
 
@@ -103,48 +118,59 @@ func (h *AanmeldingHandler) GetAanmeldingen(c *gin.Context) {
 func (h *AanmeldingHandler) GetAanmeldingByID(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID is verplicht"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgIDRequired)
 		return
 	}
 
 	aanmelding, err := h.service.GetAanmeldingByID(id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Aanmelding niet gevonden"})
+		i18n.Error(c, http.StatusNotFound, i18n.MsgAanmeldingNotFound)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"aanmelding": aanmelding})
+	response := gin.H{"aanmelding": aanmelding}
+	if h.linkService != nil {
+		links, err := h.linkService.GetLinksForRecord(models.EmailLinkAanmelding, id)
+		if err != nil {
+			log.Printf("[GetAanmeldingByID] Error fetching related emails: %v", err)
+		} else {
+			response["related_emails"] = links
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 // UpdateAanmelding handelt het bijwerken van een aanmelding af
 func (h *AanmeldingHandler) UpdateAanmelding(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID is verplicht"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgIDRequired)
 		return
 	}
 
 	// Controleer of aanmelding bestaat
 	aanmelding, err := h.service.GetAanmeldingByID(id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Aanmelding niet gevonden"})
+		i18n.Error(c, http.StatusNotFound, i18n.MsgAanmeldingNotFound)
 		return
 	}
 
 	// Bind JSON naar aanmelding
 	if err := c.ShouldBindJSON(aanmelding); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Ongeldige invoer"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidRequest)
 		return
 	}
 
 	// Werk aanmelding bij
 	if err := h.service.UpdateAanmelding(aanmelding); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		log.Printf("[UpdateAanmelding] Error updating aanmelding %s: %v", id, err)
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgAanmeldingUpdateFailed)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":    "Aanmelding succesvol bijgewerkt",
+		"message":    i18n.Message(c, i18n.MsgAanmeldingUpdated),
 		"aanmelding": aanmelding,
 	})
 }
@@ -153,14 +179,14 @@ func (h *AanmeldingHandler) UpdateAanmelding(c *gin.Context) {
 func (h *AanmeldingHandler) DeleteAanmelding(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID is verplicht"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgIDRequired)
 		return
 	}
 
 	if err := h.service.DeleteAanmelding(id); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Aanmelding niet gevonden"})
+		i18n.Error(c, http.StatusNotFound, i18n.MsgAanmeldingNotFound)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Aanmelding succesvol verwijderd"})
+	c.JSON(http.StatusOK, gin.H{"message": i18n.Message(c, i18n.MsgAanmeldingDeleted)})
 }
@@ -3,7 +3,10 @@ package handlers
 import (
 	"dklautomationgo/database/repository"
 	"dklautomationgo/models"
+	"dklautomationgo/services"
 	"dklautomationgo/services/email"
+	"dklautomationgo/services/events"
+	"dklautomationgo/services/i18n"
 	"fmt"
 	"log"
 	"net/http"
@@ -17,15 +20,23 @@ import (
 type ContactHandler struct {
 	emailService *email.EmailService
 	contactRepo  *repository.ContactRepository
+	publisher    events.Publisher
+	linkService  services.IEmailLinkService
 }
 
-func NewContactHandler(emailService *email.EmailService, contactRepo *repository.ContactRepository) *ContactHandler {
+func NewContactHandler(emailService *email.EmailService, contactRepo *repository.ContactRepository, publisher events.Publisher) *ContactHandler {
 	return &ContactHandler{
 		emailService: emailService,
 		contactRepo:  contactRepo,
+		publisher:    publisher,
 	}
 }
 
+// SetEmailLinkService stelt de service in waarmee gekoppelde emails opgehaald worden
+func (h *ContactHandler) SetEmailLinkService(linkService services.IEmailLinkService) {
+	h.linkService = linkService
+}
+
 func (h *ContactHandler) HandleContactEmail(c *gin.Context) {
 	// Parse simplified contact form data
 	var formData struct {
@@ -33,11 +44,12 @@ func (h *ContactHandler) HandleContactEmail(c *gin.Context) {
 		Email          string `json:"email"`
 		Bericht        string `json:"bericht"`
 		PrivacyAkkoord bool   `json:"privacy_akkoord"`
+		Locale         string `json:"locale"`
 	}
 
 	if err := c.BindJSON(&formData); err != nil {
 		log.Printf("[HandleContactEmail] Error parsing contact form: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidRequest)
 		return
 	}
 
@@ -51,20 +63,30 @@ func (h *ContactHandler) HandleContactEmail(c *gin.Context) {
 		UpdatedAt:      time.Now(),
 		Status:         "nieuw",
 		EmailVerzonden: false,
+		Locale:         i18n.Normalize(formData.Locale),
+	}
+	// Zonder (ondersteunde) taal in het formulier geldt de taal van het verzoek
+	if contact.Locale == "" {
+		contact.Locale = i18n.Locale(c)
 	}
 
 	// Sla het contactformulier op in de database
 	if err := h.contactRepo.Create(&contact); err != nil {
 		log.Printf("[HandleContactEmail] Error saving contact form: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save contact form"})
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgContactSaveFailed)
 		return
 	}
 	log.Printf("[HandleContactEmail] Successfully saved contact form with ID: %s", contact.ID)
 
+	// Meld het nieuwe contactformulier aan het dashboard
+	if h.publisher != nil {
+		h.publisher.Publish(events.EventContactCreated, &contact)
+	}
+
 	adminEmail := os.Getenv("ADMIN_EMAIL")
 	if adminEmail == "" {
 		log.Printf("[HandleContactEmail] ADMIN_EMAIL environment variable not set")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Admin email not configured"})
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgAdminEmailNotConfigured)
 		return
 	}
 	log.Printf("[HandleContactEmail] Admin email configured: %s", adminEmail)
@@ -78,7 +100,7 @@ func (h *ContactHandler) HandleContactEmail(c *gin.Context) {
 	log.Printf("[HandleContactEmail] Sending admin email to: %s", adminEmail)
 	if err := h.emailService.SendContactEmail(adminEmailData); err != nil {
 		log.Printf("[HandleContactEmail] Error sending admin email: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send admin notification"})
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgAdminNotificationFailed)
 		return
 	}
 	log.Printf("[HandleContactEmail] Successfully sent admin email")
@@ -113,8 +135,8 @@ func (h *ContactHandler) HandleContactEmail(c *gin.Context) {
 			os.Getenv("DEV_MODE") == "1" {
 			log.Printf("[HandleContactEmail] Ignoring email error for test domain or in dev mode")
 			c.JSON(http.StatusOK, gin.H{
-				"message": "Contact form submitted successfully. Admin notification sent. User email simulated.",
-				"warning": "User email would normally be sent, but was simulated for test domain.",
+				"message": i18n.Message(c, i18n.MsgContactSubmittedSimulated),
+				"warning": i18n.Message(c, i18n.MsgConfirmationSimulated),
 				"id":      contact.ID,
 			})
 			return
@@ -122,8 +144,8 @@ func (h *ContactHandler) HandleContactEmail(c *gin.Context) {
 
 		// In productie geven we een foutmelding terug, maar het contactformulier is wel verwerkt
 		c.JSON(http.StatusOK, gin.H{
-			"message": "Contact form submitted successfully. Admin notification sent.",
-			"warning": "Could not send confirmation email to user.",
+			"message": i18n.Message(c, i18n.MsgContactSubmittedAdminOnly),
+			"warning": i18n.Message(c, i18n.MsgConfirmationFailed),
 			"id":      contact.ID,
 		})
 		return
@@ -131,7 +153,7 @@ func (h *ContactHandler) HandleContactEmail(c *gin.Context) {
 
 	// Alles is succesvol
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Contact form submitted successfully. Confirmation emails sent.",
+		"message": i18n.Message(c, i18n.MsgContactSubmitted),
 		"id":      contact.ID,
 	})
 }
@@ -145,14 +167,14 @@ func (h *ContactHandler) GetContacts(c *gin.Context) {
 
 	if limitParam := c.Query("limit"); limitParam != "" {
 		if _, err := fmt.Sscanf(limitParam, "%d", &limit); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+			i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidParameter, "limit")
 			return
 		}
 	}
 
 	if offsetParam := c.Query("offset"); offsetParam != "" {
 		if _, err := fmt.Sscanf(offsetParam, "%d", &offset); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset parameter"})
+			i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidParameter, "offset")
 			return
 		}
 	}
@@ -166,7 +188,7 @@ func (h *ContactHandler) GetContacts(c *gin.Context) {
 		contacts, err = h.contactRepo.FindByStatus(status, limit, offset)
 		if err != nil {
 			log.Printf("[GetContacts] Error fetching contacts by status: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contacts"})
+			i18n.Error(c, http.StatusInternalServerError, i18n.MsgContactsFetchFailed)
 			return
 		}
 		total, err = h.contactRepo.CountByStatus(status)
@@ -174,7 +196,7 @@ func (h *ContactHandler) GetContacts(c *gin.Context) {
 		contacts, err = h.contactRepo.FindAll(limit, offset)
 		if err != nil {
 			log.Printf("[GetContacts] Error fetching all contacts: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contacts"})
+			i18n.Error(c, http.StatusInternalServerError, i18n.MsgContactsFetchFailed)
 			return
 		}
 		total, err = h.contactRepo.Count()
@@ -182,7 +204,7 @@ func (h *ContactHandler) GetContacts(c *gin.Context) {
 
 	if err != nil {
 		log.Printf("[GetContacts] Error counting contacts: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count contacts"})
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgContactsCountFailed)
 		return
 	}
 
@@ -193,11 +215,39 @@ func (h *ContactHandler) GetContacts(c *gin.Context) {
 	})
 }
 
+// GetContact haalt een contactformulier op met de emails die eraan gekoppeld zijn (admin endpoint)
+func (h *ContactHandler) GetContact(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgIDRequired)
+		return
+	}
+
+	contact, err := h.contactRepo.FindByID(id)
+	if err != nil {
+		log.Printf("[GetContact] Error fetching contact: %v", err)
+		i18n.Error(c, http.StatusNotFound, i18n.MsgContactNotFound)
+		return
+	}
+
+	response := gin.H{"data": contact}
+	if h.linkService != nil {
+		links, err := h.linkService.GetLinksForRecord(models.EmailLinkContact, id)
+		if err != nil {
+			log.Printf("[GetContact] Error fetching related emails: %v", err)
+		} else {
+			response["related_emails"] = links
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 // UpdateContactStatus werkt de status van een contactformulier bij
 func (h *ContactHandler) UpdateContactStatus(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Contact ID is required"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgIDRequired)
 		return
 	}
 
@@ -207,13 +257,13 @@ func (h *ContactHandler) UpdateContactStatus(c *gin.Context) {
 	}
 
 	if err := c.BindJSON(&updateData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidRequest)
 		return
 	}
 
 	if err := h.contactRepo.UpdateStatus(id, updateData.Status, updateData.BehandeldDoor); err != nil {
 		log.Printf("[UpdateContactStatus] Error updating contact status: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update contact status"})
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgContactStatusUpdateFailed)
 		return
 	}
 
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"dklautomationgo/database/repository"
+	"dklautomationgo/models"
+	"dklautomationgo/services/email"
+	"dklautomationgo/services/i18n"
+	"dklautomationgo/services/secrets"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// EmailAccountHandler bevat handlers voor het beheren van de email accounts
+type EmailAccountHandler struct {
+	accountRepo  repository.IEmailAccountRepository
+	emailService *email.EmailService
+	readOnly     string // Bestand waarin de accounts beheerd worden, als ze niet via de API gewijzigd kunnen worden
+}
+
+// emailAccountRequest is een account met het (optionele) nieuwe wachtwoord.
+// Zonder password blijft het huidige wachtwoord behouden; "" verwijdert het.
+type emailAccountRequest struct {
+	models.EmailAccount
+	Password *string `json:"password"`
+}
+
+// NewEmailAccountHandler maakt een nieuwe EmailAccountHandler
+func NewEmailAccountHandler(accountRepo repository.IEmailAccountRepository, emailService *email.EmailService) *EmailAccountHandler {
+	return &EmailAccountHandler{
+		accountRepo:  accountRepo,
+		emailService: emailService,
+	}
+}
+
+// SetReadOnly maakt de accounts alleen-lezen, omdat ze uit het gegeven configuratiebestand komen
+func (h *EmailAccountHandler) SetReadOnly(file string) {
+	h.readOnly = file
+}
+
+// GetAccounts handles GET /api/email-accounts
+func (h *EmailAccountHandler) GetAccounts(c *gin.Context) {
+	accounts, err := h.accountRepo.FindAll()
+	if err != nil {
+		log.Printf("[GetAccounts] Error fetching email accounts: %v", err)
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgAccountsFetchFailed)
+		return
+	}
+	for _, account := range accounts {
+		account.HasPassword = account.Password != ""
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": accounts, "read_only": h.readOnly != ""})
+}
+
+// GetAccount handles GET /api/email-accounts/:id
+func (h *EmailAccountHandler) GetAccount(c *gin.Context) {
+	account, err := h.accountRepo.FindByID(c.Param("id"))
+	if err != nil {
+		i18n.Error(c, http.StatusNotFound, i18n.MsgAccountNotFound)
+		return
+	}
+	account.HasPassword = account.Password != ""
+
+	c.JSON(http.StatusOK, gin.H{"data": account})
+}
+
+// CreateAccount handles POST /api/email-accounts
+func (h *EmailAccountHandler) CreateAccount(c *gin.Context) {
+	if !h.writable(c) {
+		return
+	}
+
+	req := emailAccountRequest{EmailAccount: models.EmailAccount{Enabled: true, IMAPPort: 993, SMTPPort: 587}}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidRequest)
+		return
+	}
+	account := &req.EmailAccount
+	account.ID = ""
+	account.Password = ""
+	account.CreatedAt = time.Now()
+	account.UpdatedAt = time.Now()
+
+	if !h.prepare(c, account, req.Password) {
+		return
+	}
+
+	if err := h.accountRepo.Create(account); err != nil {
+		log.Printf("[CreateAccount] Error saving email account: %v", err)
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgAccountSaveFailed)
+		return
+	}
+
+	h.respond(c, http.StatusCreated, account)
+}
+
+// UpdateAccount handles PUT /api/email-accounts/:id
+func (h *EmailAccountHandler) UpdateAccount(c *gin.Context) {
+	if !h.writable(c) {
+		return
+	}
+
+	existing, err := h.accountRepo.FindByID(c.Param("id"))
+	if err != nil {
+		i18n.Error(c, http.StatusNotFound, i18n.MsgAccountNotFound)
+		return
+	}
+
+	req := emailAccountRequest{EmailAccount: *existing}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidRequest)
+		return
+	}
+	account := &req.EmailAccount
+	account.ID, account.CreatedAt, account.Password = existing.ID, existing.CreatedAt, existing.Password
+
+	if !h.prepare(c, account, req.Password) {
+		return
+	}
+
+	if err := h.accountRepo.Update(account); err != nil {
+		log.Printf("[UpdateAccount] Error updating email account: %v", err)
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgAccountUpdateFailed)
+		return
+	}
+
+	h.respond(c, http.StatusOK, account)
+}
+
+// DeleteAccount handles DELETE /api/email-accounts/:id
+func (h *EmailAccountHandler) DeleteAccount(c *gin.Context) {
+	if !h.writable(c) {
+		return
+	}
+
+	if err := h.accountRepo.Delete(c.Param("id")); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			i18n.Error(c, http.StatusNotFound, i18n.MsgAccountNotFound)
+			return
+		}
+		log.Printf("[DeleteAccount] Error deleting email account: %v", err)
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgAccountDeleteFailed)
+		return
+	}
+
+	response := gin.H{"status": "success"}
+	if err := h.emailService.ReloadAccounts(); err != nil {
+		log.Printf("[DeleteAccount] Reload of email accounts failed: %v", err)
+		response["warning"] = err.Error()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ReloadAccounts handles POST /api/email-accounts/reload
+func (h *EmailAccountHandler) ReloadAccounts(c *gin.Context) {
+	if err := h.emailService.ReloadAccounts(); err != nil {
+		log.Printf("[ReloadAccounts] Reload of email accounts failed: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Message(c, i18n.MsgAccountsReloadFailed), "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": h.emailService.AccountStatuses()})
+}
+
+// writable controleert of de accounts via de API gewijzigd mogen worden
+func (h *EmailAccountHandler) writable(c *gin.Context) bool {
+	if h.readOnly != "" {
+		i18n.Error(c, http.StatusConflict, i18n.MsgAccountsReadOnly, h.readOnly)
+		return false
+	}
+	return true
+}
+
+// prepare valideert een account en versleutelt een nieuw wachtwoord. Bij een
+// fout is er al een response gestuurd.
+func (h *EmailAccountHandler) prepare(c *gin.Context, account *models.EmailAccount, password *string) bool {
+	account.Name = strings.ToLower(strings.TrimSpace(account.Name))
+	account.Email = strings.TrimSpace(account.Email)
+	account.DisplayName = strings.TrimSpace(account.DisplayName)
+
+	if _, err := mail.ParseAddress(account.Email); err != nil {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidEmailAddress)
+		return false
+	}
+	if err := h.validateAccount(account); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Message(c, i18n.MsgInvalidAccount), "details": err.Error()})
+		return false
+	}
+
+	if password != nil {
+		if *password != "" && !secrets.Enabled() {
+			log.Printf("[EmailAccounts] Refusing to store a password without encryption keys")
+			i18n.Error(c, http.StatusServiceUnavailable, i18n.MsgEncryptionNotConfigured, secrets.KeysEnv)
+			return false
+		}
+		// Het wachtwoord wordt bij het opslaan versleuteld door de serializer
+		account.Password = *password
+	}
+	return true
+}
+
+// validateAccount controleert het account samen met de andere actieve accounts,
+// zodat een naam of afzenderdoel niet dubbel gebruikt wordt
+func (h *EmailAccountHandler) validateAccount(account *models.EmailAccount) error {
+	others, err := h.accountRepo.FindAll()
+	if err != nil {
+		return err
+	}
+
+	accounts := make(map[string]*email.EmailConfig)
+	for _, other := range others {
+		if other.ID == account.ID {
+			continue
+		}
+		if other.Name == account.Name {
+			return fmt.Errorf("an account named %s already exists", account.Name)
+		}
+		if other.Enabled {
+			accounts[other.Name] = email.AccountConfig(other)
+		}
+	}
+	if !account.Enabled {
+		return email.ValidateAccount(account.Name, email.AccountConfig(account))
+	}
+	accounts[account.Name] = email.AccountConfig(account)
+	return email.ValidateAccounts(accounts)
+}
+
+// respond herlaadt de accounts na een wijziging en stuurt het account terug.
+// Als herladen mislukt is het account wel opgeslagen, maar nog niet actief.
+func (h *EmailAccountHandler) respond(c *gin.Context, status int, account *models.EmailAccount) {
+	account.HasPassword = account.Password != ""
+	response := gin.H{"data": account}
+	if err := h.emailService.ReloadAccounts(); err != nil {
+		log.Printf("[EmailAccounts] Reload of email accounts failed: %v", err)
+		response["warning"] = err.Error()
+	}
+	c.JSON(status, response)
+}
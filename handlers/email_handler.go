@@ -1,19 +1,45 @@
 package handlers
 
 import (
+	"context"
+	"dklautomationgo/auth/middleware"
+	"dklautomationgo/database/repository"
 	"dklautomationgo/models"
+	"dklautomationgo/services"
 	"dklautomationgo/services/email"
-	"fmt"
+	"dklautomationgo/services/i18n"
+	"errors"
 	"log"
+	"mime"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// accountCheckTimeout begrenst GET /api/emails/accounts?check=true
+const accountCheckTimeout = 30 * time.Second
+
+// Limieten voor bijlagen bij versturen, beantwoorden en doorsturen. Het totaal
+// blijft ruim onder de SIZE limiet van gangbare SMTP servers (25 MB), ook na
+// de base64 codering in het bericht.
+const (
+	maxAttachmentSize  = 10 * 1024 * 1024
+	maxAttachmentsSize = 15 * 1024 * 1024
+	// maxSendRequestSize is de maximale request body: de bijlagen base64
+	// gecodeerd plus ruimte voor de tekst van het bericht
+	maxSendRequestSize = maxAttachmentsSize/3*4 + 5*1024*1024
 )
 
 type EmailHandler struct {
 	emailService *email.EmailService
+	linkService  services.IEmailLinkService
+	intake       services.IFormIntakeService
+	bounces      services.IBounceService
+	userRepo     *repository.UserRepository
 }
 
 func NewEmailHandler(emailService *email.EmailService) *EmailHandler {
@@ -22,25 +48,87 @@ func NewEmailHandler(emailService *email.EmailService) *EmailHandler {
 	}
 }
 
+// SetEmailLinkService stelt de service in waarmee gekoppelde records opgehaald worden
+func (h *EmailHandler) SetEmailLinkService(linkService services.IEmailLinkService) {
+	h.linkService = linkService
+}
+
+// SetFormIntakeService stelt de service in die formuliermeldingen naar records omzet
+func (h *EmailHandler) SetFormIntakeService(intake services.IFormIntakeService) {
+	h.intake = intake
+}
+
+// SetBounceService stelt de service in die onbestelbare bevestigingsmails bijhoudt
+func (h *EmailHandler) SetBounceService(bounces services.IBounceService) {
+	h.bounces = bounces
+}
+
+// SetUserRepository stelt de repository in waarmee toegewezen gebruikers gecontroleerd worden
+func (h *EmailHandler) SetUserRepository(userRepo *repository.UserRepository) {
+	h.userRepo = userRepo
+}
+
 // GetEmails handles GET /api/emails
 func (h *EmailHandler) GetEmails(c *gin.Context) {
 	// Parse query parameters
+	options, ok := fetchOptions(c)
+	if !ok {
+		return
+	}
+	options.Folder = c.Query("folder")
+	if !searchOptions(c, options) || !metadataFilters(c, options) {
+		return
+	}
+
+	// Fetch emails
+	emails, accounts, err := h.emailService.FetchEmailsWithStatus(options)
+	if err != nil {
+		log.Printf("[ERROR] Failed to fetch emails: %v", err)
+		status, response := mailboxErrorResponse(c, err, "fetch emails")
+		if accounts != nil {
+			response["accounts"] = accounts
+		}
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": h.emailService.SummarizeEmails(emails), "accounts": accounts})
+}
+
+// GetEmailAccounts handles GET /api/emails/accounts
+// Geeft de status van elk geconfigureerd account; met ?check=true wordt eerst
+// met elke IMAP server verbinding gemaakt
+func (h *EmailHandler) GetEmailAccounts(c *gin.Context) {
+	check, _ := strconv.ParseBool(c.Query("check"))
+	if check {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), accountCheckTimeout)
+		defer cancel()
+		c.JSON(http.StatusOK, gin.H{"data": h.emailService.CheckAccounts(ctx)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": h.emailService.AccountStatuses()})
+}
+
+// fetchOptions leest limit, offset, read en account uit de query parameters.
+// Bij een ongeldige waarde is de foutmelding al verstuurd en is ok false.
+func fetchOptions(c *gin.Context) (*models.EmailFetchOptions, bool) {
 	options := &models.EmailFetchOptions{}
 
 	if limitStr := c.Query("limit"); limitStr != "" {
 		limit, err := strconv.Atoi(limitStr)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
-			return
+		if err != nil || limit < 0 {
+			i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidParameter, "limit")
+			return nil, false
 		}
 		options.Limit = limit
 	}
 
 	if offsetStr := c.Query("offset"); offsetStr != "" {
 		offset, err := strconv.Atoi(offsetStr)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset parameter"})
-			return
+		if err != nil || offset < 0 {
+			i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidParameter, "offset")
+			return nil, false
 		}
 		options.Offset = offset
 	}
@@ -48,29 +136,336 @@ func (h *EmailHandler) GetEmails(c *gin.Context) {
 	if readStr := c.Query("read"); readStr != "" {
 		read, err := strconv.ParseBool(readStr)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid read parameter"})
-			return
+			i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidParameter, "read")
+			return nil, false
 		}
 		options.Read = &read
 	}
 
-	// Fetch emails
-	emails, err := h.emailService.FetchEmails(options)
+	options.Account = c.Query("account")
+	return options, true
+}
+
+// searchOptions leest de zoekvelden q, from, to, subject, body, since, before en
+// has_attachment uit de query parameters. Datums zijn YYYY-MM-DD of RFC3339.
+func searchOptions(c *gin.Context, options *models.EmailFetchOptions) bool {
+	options.Query = strings.TrimSpace(c.Query("q"))
+	options.From = strings.TrimSpace(c.Query("from"))
+	options.To = strings.TrimSpace(c.Query("to"))
+	options.Subject = strings.TrimSpace(c.Query("subject"))
+	options.Body = strings.TrimSpace(c.Query("body"))
+
+	for _, param := range []struct {
+		name   string
+		target **time.Time
+	}{{"since", &options.Since}, {"before", &options.Before}} {
+		value := c.Query(param.name)
+		if value == "" {
+			continue
+		}
+		date, err := parseDateParam(value)
+		if err != nil {
+			i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidDateParameter, param.name)
+			return false
+		}
+		*param.target = &date
+	}
+	if options.Since != nil && options.Before != nil && !options.Since.Before(*options.Before) {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgSinceAfterBefore)
+		return false
+	}
+
+	if value := c.Query("has_attachment"); value != "" {
+		hasAttachment, err := strconv.ParseBool(value)
+		if err != nil {
+			i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidParameter, "has_attachment")
+			return false
+		}
+		options.HasAttachment = &hasAttachment
+	}
+	return true
+}
+
+// parseDateParam leest een datum (in de lokale tijdzone) of een RFC3339 tijdstip
+func parseDateParam(value string) (time.Time, error) {
+	if date, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// metadataFilters leest de status, assigned_to en label filters uit de query
+// parameters. assigned_to=me filtert op de ingelogde gebruiker.
+func metadataFilters(c *gin.Context, options *models.EmailFetchOptions) bool {
+	options.Status = c.Query("status")
+	if options.Status != "" && !models.IsValidEmailStatus(options.Status) {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidParameter, "status")
+		return false
+	}
+
+	options.AssignedTo = c.Query("assigned_to")
+	switch options.AssignedTo {
+	case "", models.EmailUnassigned:
+	case "me":
+		user := middleware.GetUserFromContext(c)
+		if user == nil {
+			i18n.Error(c, http.StatusUnauthorized, i18n.MsgAuthRequired)
+			return false
+		}
+		options.AssignedTo = user.ID.String()
+	default:
+		if _, err := uuid.Parse(options.AssignedTo); err != nil {
+			i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidParameter, "assigned_to")
+			return false
+		}
+	}
+
+	options.Label = strings.TrimSpace(c.Query("label"))
+	return true
+}
+
+// GetEmailThreads handles GET /api/emails/threads
+func (h *EmailHandler) GetEmailThreads(c *gin.Context) {
+	options, ok := fetchOptions(c)
+	if !ok || !searchOptions(c, options) {
+		return
+	}
+
+	threads, err := h.emailService.FetchThreads(options)
 	if err != nil {
-		log.Printf("[ERROR] Failed to fetch emails: %v", err)
-		if strings.Contains(err.Error(), "authentication failed") {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Email authentication failed"})
-			return
+		log.Printf("[ERROR] Failed to fetch threads: %v", err)
+		h.mailboxError(c, err, "fetch threads")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": threads})
+}
+
+// GetEmailThread handles GET /api/emails/threads/:threadId
+func (h *EmailHandler) GetEmailThread(c *gin.Context) {
+	thread, err := h.emailService.GetThread(c.Param("threadId"))
+	if err != nil {
+		log.Printf("[ERROR] Failed to get thread: %v", err)
+		h.mailboxError(c, err, "get thread")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": thread})
+}
+
+// GetEmail handles GET /api/emails/:id
+// De HTML wordt gesaniteerd; externe afbeeldingen worden standaard geblokkeerd
+// en alleen met ?remote_content=proxy via de image proxy geladen.
+func (h *EmailHandler) GetEmail(c *gin.Context) {
+	found, err := h.emailService.GetEmail(c.Param("id"))
+	if err != nil {
+		log.Printf("[ERROR] Failed to get email: %v", err)
+		h.mailboxError(c, err, "get email")
+		return
+	}
+
+	policy := email.RemoteContentBlock
+	if c.Query("remote_content") == "proxy" {
+		policy = email.RemoteContentProxy
+	}
+
+	response := gin.H{"data": h.emailService.SanitizeEmail(found, policy)}
+	if metadata, err := h.emailService.MetadataFor(found); err == nil {
+		response["metadata"] = metadata
+	} else if !errors.Is(err, email.ErrMetadataUnavailable) {
+		log.Printf("[ERROR] Failed to get email metadata: %v", err)
+	}
+	if h.linkService != nil {
+		links, err := h.linkService.GetLinksForEmail(found.ID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to get related records: %v", err)
+		} else {
+			response["related_records"] = links
 		}
-		if strings.Contains(err.Error(), "connection refused") || strings.Contains(err.Error(), "dial tcp") {
-			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Could not connect to email server"})
-			return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetFormIntakeResults handles GET /api/emails/intake
+// Geeft de verwerkte formuliermeldingen, met ?status=unparsed alleen de meldingen
+// die niet gelezen konden worden
+func (h *EmailHandler) GetFormIntakeResults(c *gin.Context) {
+	if h.intake == nil {
+		i18n.Error(c, http.StatusServiceUnavailable, i18n.MsgFormIntakeNotConfigured)
+		return
+	}
+
+	options, ok := fetchOptions(c)
+	if !ok {
+		return
+	}
+	if options.Limit <= 0 {
+		options.Limit = 50
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", models.FormIntakeProcessed, models.FormIntakeDuplicate, models.FormIntakeUnparsed:
+	default:
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidParameter, "status")
+		return
+	}
+
+	results, total, err := h.intake.GetResults(status, options.Limit, options.Offset)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get form intake results: %v", err)
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgFormIntakeFetchFailed)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":     results,
+		"total":    total,
+		"has_more": int64(options.Offset+len(results)) < total,
+	})
+}
+
+// ProcessFormIntake handles POST /api/emails/:id/intake
+// Verwerkt een formuliermelding (opnieuw), bijvoorbeeld na het aanpassen van de regels
+func (h *EmailHandler) ProcessFormIntake(c *gin.Context) {
+	if h.intake == nil {
+		i18n.Error(c, http.StatusServiceUnavailable, i18n.MsgFormIntakeNotConfigured)
+		return
+	}
+
+	found, err := h.emailService.GetEmail(c.Param("id"))
+	if err != nil {
+		log.Printf("[ERROR] Failed to get email: %v", err)
+		h.mailboxError(c, err, "get email")
+		return
+	}
+
+	result, err := h.intake.ProcessEmail(found)
+	if err != nil {
+		log.Printf("[ERROR] Failed to process form intake: %v", err)
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgFormIntakeFailed)
+		return
+	}
+	if result == nil {
+		i18n.Error(c, http.StatusUnprocessableEntity, i18n.MsgNoMatchingFormRule)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// GetBounces handles GET /api/emails/bounces
+// Geeft de adressen waarop een bevestigingsmail niet afgeleverd kon worden,
+// met de reden en het contactformulier of de aanmelding waar ze bij horen
+func (h *EmailHandler) GetBounces(c *gin.Context) {
+	if h.bounces == nil {
+		i18n.Error(c, http.StatusServiceUnavailable, i18n.MsgBouncesNotConfigured)
+		return
+	}
+
+	options, ok := fetchOptions(c)
+	if !ok {
+		return
+	}
+	if options.Limit <= 0 {
+		options.Limit = 50
+	}
+
+	bounces, total, err := h.bounces.GetBounces(options.Limit, options.Offset)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get bounces: %v", err)
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgBouncesFetchFailed)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":     bounces,
+		"total":    total,
+		"has_more": int64(options.Offset+len(bounces)) < total,
+	})
+}
+
+// ProcessBounce handles POST /api/emails/:id/bounce
+// Verwerkt een bounce (opnieuw), bijvoorbeeld een die binnenkwam voordat bounces herkend werden
+func (h *EmailHandler) ProcessBounce(c *gin.Context) {
+	if h.bounces == nil {
+		i18n.Error(c, http.StatusServiceUnavailable, i18n.MsgBouncesNotConfigured)
+		return
+	}
+
+	found, err := h.emailService.GetEmail(c.Param("id"))
+	if err != nil {
+		log.Printf("[ERROR] Failed to get email: %v", err)
+		h.mailboxError(c, err, "get email")
+		return
+	}
+
+	bounce, err := h.bounces.ProcessEmail(found)
+	if err != nil {
+		log.Printf("[ERROR] Failed to process bounce: %v", err)
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgBounceFailed)
+		return
+	}
+	if bounce == nil {
+		i18n.Error(c, http.StatusUnprocessableEntity, i18n.MsgNotABounce)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": bounce})
+}
+
+// ProxyEmailImage handles GET /api/emails/image-proxy
+// Deze route zit buiten de auth middleware omdat <img> tags geen Authorization
+// header meesturen; alleen URLs met een geldige handtekening worden opgehaald.
+func (h *EmailHandler) ProxyEmailImage(c *gin.Context) {
+	contentType, data, err := h.emailService.ProxyImage(c.Request.Context(), c.Query("url"), c.Query("sig"))
+	if err != nil {
+		log.Printf("[ERROR] Failed to proxy image: %v", err)
+		switch {
+		case errors.Is(err, email.ErrInvalidProxySignature):
+			i18n.Error(c, http.StatusForbidden, i18n.MsgInvalidSignature)
+		case errors.Is(err, email.ErrBlockedProxyTarget):
+			i18n.Error(c, http.StatusUnprocessableEntity, i18n.MsgImageNotAllowed)
+		default:
+			i18n.Error(c, http.StatusBadGateway, i18n.MsgImageFetchFailed)
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to fetch emails: %v", err)})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": emails})
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Security-Policy", "default-src 'none'")
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// GetEmailAttachment handles GET /api/emails/:id/attachments/:index
+func (h *EmailHandler) GetEmailAttachment(c *gin.Context) {
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil || index < 0 {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidAttachmentIndex)
+		return
+	}
+
+	attachment, err := h.emailService.GetAttachment(c.Param("id"), index)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get attachment: %v", err)
+		h.mailboxError(c, err, "get attachment")
+		return
+	}
+
+	contentType := attachment.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	disposition := "attachment"
+	if c.Query("inline") == "true" {
+		disposition = "inline"
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, contentType, attachment.Content)
 }
 
 // GetEmailStats handles GET /api/emails/stats
@@ -79,15 +474,7 @@ func (h *EmailHandler) GetEmailStats(c *gin.Context) {
 	emails, err := h.emailService.FetchEmails(nil)
 	if err != nil {
 		log.Printf("[ERROR] Failed to fetch email stats: %v", err)
-		if strings.Contains(err.Error(), "authentication failed") {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Email authentication failed"})
-			return
-		}
-		if strings.Contains(err.Error(), "connection refused") || strings.Contains(err.Error(), "dial tcp") {
-			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Could not connect to email server"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to fetch email stats: %v", err)})
+		h.mailboxError(c, err, "fetch email stats")
 		return
 	}
 
@@ -110,34 +497,443 @@ func (h *EmailHandler) GetEmailStats(c *gin.Context) {
 func (h *EmailHandler) MarkEmailAsRead(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Email ID is required"})
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgIDRequired)
 		return
 	}
 
-	err := h.emailService.MarkEmailAsRead(id)
-	if err != nil {
+	if err := h.emailService.MarkEmailAsRead(id); err != nil {
 		log.Printf("[ERROR] Failed to mark email as read: %v", err)
+		h.mailboxError(c, err, "mark email as read")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// MarkEmailAsUnread handles PUT /api/emails/:id/unread
+func (h *EmailHandler) MarkEmailAsUnread(c *gin.Context) {
+	if err := h.emailService.MarkEmailAsUnread(c.Param("id")); err != nil {
+		log.Printf("[ERROR] Failed to mark email as unread: %v", err)
+		h.mailboxError(c, err, "mark email as unread")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// SetEmailFlagged handles PUT /api/emails/:id/flag
+func (h *EmailHandler) SetEmailFlagged(c *gin.Context) {
+	var req struct {
+		Flagged *bool `json:"flagged" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgFlaggedRequired)
+		return
+	}
+
+	if err := h.emailService.SetEmailFlagged(c.Param("id"), *req.Flagged); err != nil {
+		log.Printf("[ERROR] Failed to flag email: %v", err)
+		h.mailboxError(c, err, "flag email")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// ReplyToEmail handles POST /api/emails/:id/reply
+func (h *EmailHandler) ReplyToEmail(c *gin.Context) {
+	h.reply(c, email.ReplyModeReply)
+}
 
-		if strings.Contains(err.Error(), "invalid email ID") {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email ID format"})
+// ReplyAllToEmail handles POST /api/emails/:id/reply-all
+func (h *EmailHandler) ReplyAllToEmail(c *gin.Context) {
+	h.reply(c, email.ReplyModeReplyAll)
+}
+
+// ForwardEmail handles POST /api/emails/:id/forward
+func (h *EmailHandler) ForwardEmail(c *gin.Context) {
+	h.reply(c, email.ReplyModeForward)
+}
+
+// reply verwerkt een antwoord of doorgestuurd bericht voor de gegeven modus
+func (h *EmailHandler) reply(c *gin.Context, mode email.ReplyMode) {
+	id := c.Param("id")
+	if id == "" {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgIDRequired)
+		return
+	}
+
+	var req models.EmailReplyRequest
+	if !bindSendRequest(c, &req) || !checkAttachmentSizes(c, req.Attachments) {
+		return
+	}
+	if strings.TrimSpace(req.Body) == "" && strings.TrimSpace(req.HTML) == "" && mode != email.ReplyModeForward {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgMessageBodyRequired)
+		return
+	}
+
+	sent, err := h.emailService.ReplyToEmail(id, mode, &req)
+	if err != nil {
+		log.Printf("[ERROR] Failed to %s email %s: %v", mode, id, err)
+
+		if errors.Is(err, email.ErrInvalidEmailID) {
+			i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidEmailID)
 			return
 		}
-		if strings.Contains(err.Error(), "unknown account") {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown email account"})
+		if errors.Is(err, email.ErrNoRecipients) {
+			i18n.Error(c, http.StatusBadRequest, i18n.MsgRecipientRequired)
 			return
 		}
-		if strings.Contains(err.Error(), "authentication failed") {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Email authentication failed"})
+		if errors.Is(err, email.ErrEmailNotFound) {
+			i18n.Error(c, http.StatusNotFound, i18n.MsgEmailNotFound)
 			return
 		}
-		if strings.Contains(err.Error(), "connection refused") || strings.Contains(err.Error(), "dial tcp") {
-			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Could not connect to email server"})
+		if errors.Is(err, email.ErrStaleEmailID) {
+			i18n.Error(c, http.StatusGone, i18n.MsgEmailIDStale)
 			return
 		}
+		if errors.Is(err, email.ErrUnknownAccount) {
+			i18n.Error(c, http.StatusBadRequest, i18n.MsgUnknownAccount)
+			return
+		}
+		if errors.Is(err, email.ErrAuthFailed) || errors.Is(err, email.ErrServerUnreachable) || errors.Is(err, email.ErrTimeout) {
+			h.mailboxError(c, err, "send email")
+			return
+		}
+
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgSendFailed, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": sent})
+}
+
+// SendEmail handles POST /api/emails/send
+func (h *EmailHandler) SendEmail(c *gin.Context) {
+	var req models.EmailSendRequest
+	if !bindSendRequest(c, &req) || !checkAttachmentSizes(c, req.Attachments) {
+		return
+	}
+	if strings.TrimSpace(req.Subject) == "" {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgSubjectRequired)
+		return
+	}
+	if strings.TrimSpace(req.Body) == "" && strings.TrimSpace(req.HTML) == "" && req.Template == "" {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgBodyOrTemplateRequired)
+		return
+	}
+
+	sent, err := h.emailService.SendNewEmail(&req)
+	if err != nil {
+		log.Printf("[ERROR] Failed to send email: %v", err)
+
+		switch {
+		case errors.Is(err, email.ErrNoRecipients):
+			i18n.Error(c, http.StatusBadRequest, i18n.MsgRecipientRequired)
+		case errors.Is(err, email.ErrInvalidRecipient):
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Message(c, i18n.MsgInvalidRecipient), "details": err.Error()})
+		case errors.Is(err, email.ErrUnknownAccount):
+			i18n.Error(c, http.StatusBadRequest, i18n.MsgUnknownAccount)
+		case errors.Is(err, email.ErrTemplateNotFound):
+			i18n.Error(c, http.StatusBadRequest, i18n.MsgUnknownTemplate)
+		default:
+			h.mailboxError(c, err, "send email")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success", "data": sent})
+}
+
+// bindSendRequest leest de JSON body van een te versturen bericht, begrensd op
+// maxSendRequestSize. Bij een fout is de response al geschreven.
+func bindSendRequest(c *gin.Context, req interface{}) bool {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSendRequestSize)
+	if err := c.ShouldBindJSON(req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			i18n.Error(c, http.StatusRequestEntityTooLarge, i18n.MsgAttachmentsTooLarge, maxAttachmentsSize/(1024*1024))
+			return false
+		}
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidRequest)
+		return false
+	}
+	return true
+}
+
+// checkAttachmentSizes controleert de gedecodeerde grootte van de bijlagen,
+// per bijlage en samen. Bij te grote bijlagen is de response al geschreven.
+func checkAttachmentSizes(c *gin.Context, attachments []models.EmailAttachment) bool {
+	total := 0
+	for _, attachment := range attachments {
+		if len(attachment.Content) > maxAttachmentSize {
+			i18n.Error(c, http.StatusRequestEntityTooLarge, i18n.MsgAttachmentTooLarge, attachment.Filename, maxAttachmentSize/(1024*1024))
+			return false
+		}
+		total += len(attachment.Content)
+	}
+	if total > maxAttachmentsSize {
+		i18n.Error(c, http.StatusRequestEntityTooLarge, i18n.MsgAttachmentsTooLarge, maxAttachmentsSize/(1024*1024))
+		return false
+	}
+	return true
+}
+
+// GetEmailFolders handles GET /api/emails/folders
+func (h *EmailHandler) GetEmailFolders(c *gin.Context) {
+	folders, err := h.emailService.ListFolders(c.Query("account"))
+	if err != nil {
+		log.Printf("[ERROR] Failed to list folders: %v", err)
+		h.mailboxError(c, err, "list folders")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": folders})
+}
+
+// MoveEmail handles POST /api/emails/:id/move
+func (h *EmailHandler) MoveEmail(c *gin.Context) {
+	var req struct {
+		Folder string `json:"folder" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgTargetFolderRequired)
+		return
+	}
+
+	if err := h.emailService.MoveEmail(c.Param("id"), req.Folder); err != nil {
+		log.Printf("[ERROR] Failed to move email: %v", err)
+		h.mailboxError(c, err, "move email")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
 
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to mark email as read: %v", err)})
+// ArchiveEmail handles POST /api/emails/:id/archive
+func (h *EmailHandler) ArchiveEmail(c *gin.Context) {
+	if err := h.emailService.ArchiveEmail(c.Param("id")); err != nil {
+		log.Printf("[ERROR] Failed to archive email: %v", err)
+		h.mailboxError(c, err, "archive email")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"status": "success"})
 }
+
+// DeleteEmail handles DELETE /api/emails/:id
+func (h *EmailHandler) DeleteEmail(c *gin.Context) {
+	if err := h.emailService.DeleteEmail(c.Param("id")); err != nil {
+		log.Printf("[ERROR] Failed to delete email: %v", err)
+		h.mailboxError(c, err, "delete email")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// AssignEmail handles PUT /api/emails/:id/assign
+// Wijst een email toe aan een gebruiker ("me" voor de ingelogde gebruiker);
+// met een lege user_id wordt de toewijzing verwijderd
+func (h *EmailHandler) AssignEmail(c *gin.Context) {
+	var req struct {
+		UserID *string `json:"user_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidRequest)
+		return
+	}
+
+	var assignee *string
+	if req.UserID != nil && strings.TrimSpace(*req.UserID) != "" {
+		userID, ok := h.resolveAssignee(c, strings.TrimSpace(*req.UserID))
+		if !ok {
+			return
+		}
+		assignee = &userID
+	}
+
+	metadata, err := h.emailService.UpdateEmailMetadata(c.Param("id"), func(metadata *models.EmailMetadata) {
+		metadata.AssignedTo = assignee
+	})
+	if err != nil {
+		log.Printf("[ERROR] Failed to assign email: %v", err)
+		h.mailboxError(c, err, "assign email")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": metadata})
+}
+
+// resolveAssignee zet "me" om naar de ingelogde gebruiker en controleert of de
+// gebruiker bestaat. Bij een ongeldige gebruiker is de foutmelding al verstuurd.
+func (h *EmailHandler) resolveAssignee(c *gin.Context, userID string) (string, bool) {
+	if userID == "me" {
+		user := middleware.GetUserFromContext(c)
+		if user == nil {
+			i18n.Error(c, http.StatusUnauthorized, i18n.MsgAuthRequired)
+			return "", false
+		}
+		return user.ID.String(), true
+	}
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidUserID)
+		return "", false
+	}
+	if h.userRepo != nil {
+		user, err := h.userRepo.FindByID(id)
+		if err != nil {
+			log.Printf("[ERROR] Failed to find user: %v", err)
+			i18n.Error(c, http.StatusInternalServerError, i18n.MsgUserFetchFailed)
+			return "", false
+		}
+		if user == nil {
+			i18n.Error(c, http.StatusBadRequest, i18n.MsgUnknownUser)
+			return "", false
+		}
+	}
+	return id.String(), true
+}
+
+// SetEmailStatus handles PUT /api/emails/:id/status
+func (h *EmailHandler) SetEmailStatus(c *gin.Context) {
+	var req struct {
+		Status string `json:"status" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || !models.IsValidEmailStatus(req.Status) {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidEmailStatus)
+		return
+	}
+
+	metadata, err := h.emailService.UpdateEmailMetadata(c.Param("id"), func(metadata *models.EmailMetadata) {
+		metadata.Status = req.Status
+	})
+	if err != nil {
+		log.Printf("[ERROR] Failed to set email status: %v", err)
+		h.mailboxError(c, err, "set email status")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": metadata})
+}
+
+// SetEmailLabels handles PUT /api/emails/:id/labels
+// Vervangt de labels van een email; lege en dubbele labels worden genegeerd
+func (h *EmailHandler) SetEmailLabels(c *gin.Context) {
+	var req struct {
+		Labels []string `json:"labels"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidRequest)
+		return
+	}
+
+	labels := make([]string, 0, len(req.Labels))
+	for _, label := range req.Labels {
+		label = strings.TrimSpace(label)
+		if label == "" || containsLabel(labels, label) {
+			continue
+		}
+		labels = append(labels, label)
+	}
+
+	metadata, err := h.emailService.UpdateEmailMetadata(c.Param("id"), func(metadata *models.EmailMetadata) {
+		metadata.Labels = labels
+	})
+	if err != nil {
+		log.Printf("[ERROR] Failed to set email labels: %v", err)
+		h.mailboxError(c, err, "set email labels")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": metadata})
+}
+
+func containsLabel(labels []string, label string) bool {
+	for _, existing := range labels {
+		if existing == label {
+			return true
+		}
+	}
+	return false
+}
+
+// GetEmailNotes handles GET /api/emails/:id/notes
+func (h *EmailHandler) GetEmailNotes(c *gin.Context) {
+	notes, err := h.emailService.GetEmailNotes(c.Param("id"))
+	if err != nil {
+		log.Printf("[ERROR] Failed to get email notes: %v", err)
+		h.mailboxError(c, err, "get email notes")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": notes})
+}
+
+// AddEmailNote handles POST /api/emails/:id/notes
+func (h *EmailHandler) AddEmailNote(c *gin.Context) {
+	var req struct {
+		Body string `json:"body" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Body) == "" {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgNoteBodyRequired)
+		return
+	}
+
+	user := middleware.GetUserFromContext(c)
+	if user == nil {
+		i18n.Error(c, http.StatusUnauthorized, i18n.MsgAuthRequired)
+		return
+	}
+
+	note, err := h.emailService.AddEmailNote(c.Param("id"), user, req.Body)
+	if err != nil {
+		log.Printf("[ERROR] Failed to add email note: %v", err)
+		h.mailboxError(c, err, "add email note")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": note})
+}
+
+// mailboxError vertaalt een fout van een mailbox operatie naar een HTTP response
+func (h *EmailHandler) mailboxError(c *gin.Context, err error, action string) {
+	status, response := mailboxErrorResponse(c, err, action)
+	c.JSON(status, response)
+}
+
+// mailboxErrorResponse geeft de HTTP status en response voor een fout van een
+// mailbox operatie, in de taal van het verzoek
+func mailboxErrorResponse(c *gin.Context, err error, action string) (int, gin.H) {
+	switch {
+	case errors.Is(err, email.ErrInvalidEmailID):
+		return http.StatusBadRequest, gin.H{"error": i18n.Message(c, i18n.MsgInvalidEmailID)}
+	case errors.Is(err, email.ErrUnknownAccount):
+		return http.StatusBadRequest, gin.H{"error": i18n.Message(c, i18n.MsgUnknownAccount)}
+	case errors.Is(err, email.ErrEmailNotFound):
+		return http.StatusNotFound, gin.H{"error": i18n.Message(c, i18n.MsgEmailNotFound)}
+	case errors.Is(err, email.ErrAttachmentNotFound):
+		return http.StatusNotFound, gin.H{"error": i18n.Message(c, i18n.MsgAttachmentNotFound)}
+	case errors.Is(err, email.ErrThreadNotFound):
+		return http.StatusNotFound, gin.H{"error": i18n.Message(c, i18n.MsgThreadNotFound)}
+	case errors.Is(err, email.ErrStaleEmailID):
+		return http.StatusGone, gin.H{"error": i18n.Message(c, i18n.MsgEmailIDStale)}
+	case errors.Is(err, email.ErrMetadataUnavailable):
+		return http.StatusServiceUnavailable, gin.H{"error": i18n.Message(c, i18n.MsgMetadataNotConfigured)}
+	case errors.Is(err, email.ErrAuthFailed):
+		// De mailserver weigert de credentials van het account, niet die van de
+		// gebruiker; een 401 zou de frontend laten denken dat de sessie verlopen is
+		return http.StatusBadGateway, gin.H{"error": i18n.Message(c, i18n.MsgMailAuthFailed), "code": email.ErrorCodeAuthFailed}
+	case errors.Is(err, email.ErrTimeout):
+		return http.StatusGatewayTimeout, gin.H{"error": i18n.Message(c, i18n.MsgMailTimeout), "code": email.ErrorCodeTimeout}
+	case errors.Is(err, email.ErrCertificate):
+		return http.StatusBadGateway, gin.H{"error": i18n.Message(c, i18n.MsgMailCertificate), "code": email.ErrorCodeCertificate}
+	case errors.Is(err, email.ErrServerUnreachable):
+		return http.StatusServiceUnavailable, gin.H{"error": i18n.Message(c, i18n.MsgMailUnreachable), "code": email.ErrorCodeUnreachable}
+	default:
+		log.Printf("[EmailHandler] Failed to %s: %v", action, err)
+		return http.StatusInternalServerError, gin.H{"error": i18n.Message(c, i18n.MsgMailboxFailed, err)}
+	}
+}
@@ -0,0 +1,76 @@
+package handlers_test
+
+import (
+	"bytes"
+	"dklautomationgo/handlers"
+	"dklautomationgo/models"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetEmailThreads_RejectsNegativePaging(t *testing.T) {
+	// Setup: de parameters worden gecontroleerd voordat de mailbox gebruikt wordt
+	gin.SetMode(gin.TestMode)
+	handler := handlers.NewEmailHandler(nil)
+	router := gin.New()
+	router.GET("/api/emails/threads", handler.GetEmailThreads)
+
+	for _, query := range []string{"offset=-1", "limit=-5"} {
+		// Test
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/emails/threads?"+query, nil))
+
+		// Controleer het resultaat
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestSendEmail_RejectsOversizedAttachments(t *testing.T) {
+	// Setup: de bijlagen worden gecontroleerd voordat er iets verstuurd wordt
+	gin.SetMode(gin.TestMode)
+	handler := handlers.NewEmailHandler(nil)
+	router := gin.New()
+	router.POST("/api/emails/send", handler.SendEmail)
+	router.POST("/api/emails/:id/reply", handler.ReplyToEmail)
+
+	attachment := func(name string, size int) models.EmailAttachment {
+		return models.EmailAttachment{Filename: name, ContentType: "application/pdf", Content: make([]byte, size)}
+	}
+	tests := []struct {
+		name        string
+		path        string
+		attachments []models.EmailAttachment
+		message     string
+	}{
+		{"te grote bijlage", "/api/emails/send", []models.EmailAttachment{attachment("groot.pdf", 11*1024*1024)}, "groot.pdf"},
+		{"samen te groot", "/api/emails/send", []models.EmailAttachment{attachment("a.pdf", 8*1024*1024), attachment("b.pdf", 8*1024*1024)}, "15 MB"},
+		{"te grote bijlage bij antwoord", "/api/emails/info:INBOX:1:9/reply", []models.EmailAttachment{attachment("groot.pdf", 11*1024*1024)}, "groot.pdf"},
+		{"te grote request", "/api/emails/send", []models.EmailAttachment{attachment("a.pdf", 9*1024*1024), attachment("b.pdf", 9*1024*1024), attachment("c.pdf", 9*1024*1024)}, "15 MB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(map[string]interface{}{
+				"to":          []string{"jan@example.org"},
+				"subject":     "Bijlagen",
+				"body":        "Zie bijlagen",
+				"attachments": tt.attachments,
+			})
+			require.NoError(t, err)
+
+			// Test
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, bytes.NewReader(body)))
+
+			// Controleer het resultaat
+			assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+			assert.Contains(t, w.Body.String(), tt.message)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"dklautomationgo/auth/middleware"
+	"dklautomationgo/database/repository"
+	"dklautomationgo/models"
+	"dklautomationgo/services/email"
+	"dklautomationgo/services/i18n"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// EmailTemplateHandler bevat handlers voor het beheren van de email templates
+type EmailTemplateHandler struct {
+	templateRepo repository.IEmailTemplateRepository
+	emailService *email.EmailService
+}
+
+// emailTemplateRequest is een nieuwe versie van een template
+type emailTemplateRequest struct {
+	Subject  string `json:"subject"`
+	Body     string `json:"body"`
+	Comment  string `json:"comment"`
+	Activate *bool  `json:"activate"` // Standaard wordt de nieuwe versie direct actief
+}
+
+// templatePreviewRequest is een template om te renderen. Zonder body wordt de
+// opgegeven versie gebruikt, of anders het template dat nu actief is.
+type templatePreviewRequest struct {
+	Name    string `json:"name" binding:"required"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	Version int    `json:"version"`
+}
+
+// NewEmailTemplateHandler maakt een nieuwe EmailTemplateHandler
+func NewEmailTemplateHandler(templateRepo repository.IEmailTemplateRepository, emailService *email.EmailService) *EmailTemplateHandler {
+	return &EmailTemplateHandler{
+		templateRepo: templateRepo,
+		emailService: emailService,
+	}
+}
+
+// GetTemplates handles GET /api/email-templates
+func (h *EmailTemplateHandler) GetTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"data": h.emailService.Templates()})
+}
+
+// GetTemplate handles GET /api/email-templates/:name
+func (h *EmailTemplateHandler) GetTemplate(c *gin.Context) {
+	name := c.Param("name")
+	versions, err := h.templateRepo.FindVersions(name)
+	if err != nil {
+		log.Printf("[GetTemplate] Error fetching versions of %s: %v", name, err)
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgTemplateFetchFailed)
+		return
+	}
+	if len(versions) == 0 && !h.emailService.HasTemplate(name) {
+		i18n.Error(c, http.StatusNotFound, i18n.MsgTemplateNotFound)
+		return
+	}
+
+	var info *email.TemplateInfo
+	for _, t := range h.emailService.Templates() {
+		if t.Name == name {
+			info = &t
+			break
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": info, "versions": versions})
+}
+
+// CreateVersion handles POST /api/email-templates/:name/versions
+func (h *EmailTemplateHandler) CreateVersion(c *gin.Context) {
+	name := c.Param("name")
+	var req emailTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidRequest)
+		return
+	}
+
+	if err := h.emailService.ValidateTemplate(name, req.Subject, req.Body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Message(c, i18n.MsgInvalidTemplate), "details": err.Error()})
+		return
+	}
+
+	tmpl := &models.EmailTemplate{
+		Name:    name,
+		Subject: req.Subject,
+		Body:    req.Body,
+		Comment: req.Comment,
+	}
+	if user := middleware.GetUserFromContext(c); user != nil {
+		tmpl.CreatedBy = user.Email
+	}
+	activate := req.Activate == nil || *req.Activate
+	if err := h.templateRepo.CreateVersion(tmpl, activate); err != nil {
+		log.Printf("[CreateVersion] Error saving template %s: %v", name, err)
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgTemplateSaveFailed)
+		return
+	}
+
+	h.respond(c, http.StatusCreated, gin.H{"data": tmpl})
+}
+
+// ActivateVersion handles POST /api/email-templates/:name/versions/:version/activate
+func (h *EmailTemplateHandler) ActivateVersion(c *gin.Context) {
+	name := c.Param("name")
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidVersion)
+		return
+	}
+
+	if err := h.templateRepo.Activate(name, version); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			i18n.Error(c, http.StatusNotFound, i18n.MsgTemplateVersionNotFound)
+			return
+		}
+		log.Printf("[ActivateVersion] Error activating %s version %d: %v", name, version, err)
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgTemplateActivateFailed)
+		return
+	}
+
+	h.respond(c, http.StatusOK, gin.H{"status": "success"})
+}
+
+// DeleteTemplate handles DELETE /api/email-templates/:name. Alle versies worden
+// verwijderd, zodat weer het bestand met dezelfde naam gebruikt wordt.
+func (h *EmailTemplateHandler) DeleteTemplate(c *gin.Context) {
+	name := c.Param("name")
+	if err := h.templateRepo.DeleteByName(name); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			i18n.Error(c, http.StatusNotFound, i18n.MsgTemplateNotFound)
+			return
+		}
+		log.Printf("[DeleteTemplate] Error deleting template %s: %v", name, err)
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgTemplateDeleteFailed)
+		return
+	}
+
+	h.respond(c, http.StatusOK, gin.H{"status": "success"})
+}
+
+// PreviewTemplate handles POST /api/email-templates/preview
+func (h *EmailTemplateHandler) PreviewTemplate(c *gin.Context) {
+	var req templatePreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidRequest)
+		return
+	}
+
+	if req.Body == "" && req.Version > 0 {
+		version, err := h.templateRepo.FindVersion(req.Name, req.Version)
+		if err != nil {
+			i18n.Error(c, http.StatusNotFound, i18n.MsgTemplateVersionNotFound)
+			return
+		}
+		req.Subject, req.Body = version.Subject, version.Body
+	}
+
+	preview, err := h.emailService.PreviewTemplate(req.Name, req.Subject, req.Body)
+	if err != nil {
+		if errors.Is(err, email.ErrTemplateNotFound) {
+			i18n.Error(c, http.StatusNotFound, i18n.MsgTemplateNotFound)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Message(c, i18n.MsgInvalidTemplate), "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": preview})
+}
+
+// respond herlaadt de templates na een wijziging. Als herladen mislukt is de
+// wijziging wel opgeslagen, maar nog niet actief.
+func (h *EmailTemplateHandler) respond(c *gin.Context, status int, response gin.H) {
+	if err := h.emailService.ReloadTemplates(); err != nil {
+		log.Printf("[EmailTemplates] Reload of email templates failed: %v", err)
+		response["warning"] = err.Error()
+	}
+	c.JSON(status, response)
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"dklautomationgo/auth/middleware"
+	"dklautomationgo/services/events"
+	"dklautomationgo/services/i18n"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// heartbeatInterval houdt proxies (nginx, Render) ervan om idle streams te sluiten
+const heartbeatInterval = 25 * time.Second
+
+// EventHandler bevat handlers voor de realtime dashboard notificaties
+type EventHandler struct {
+	bus *events.Bus
+}
+
+// NewEventHandler maakt een nieuwe EventHandler
+func NewEventHandler(bus *events.Bus) *EventHandler {
+	return &EventHandler{
+		bus: bus,
+	}
+}
+
+// StreamEvents handles GET /api/events als Server-Sent Events stream
+func (h *EventHandler) StreamEvents(c *gin.Context) {
+	sub, ok := h.subscribe(c)
+	if !ok {
+		return
+	}
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			c.SSEvent(string(event.Type), event)
+			return true
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return false
+			}
+			return true
+		}
+	})
+}
+
+// StreamEventsWebSocket handles GET /api/events/ws als WebSocket alternatief voor SSE
+func (h *EventHandler) StreamEventsWebSocket(c *gin.Context) {
+	sub, ok := h.subscribe(c)
+	if !ok {
+		return
+	}
+	defer sub.Close()
+
+	websocket.Handler(func(ws *websocket.Conn) {
+		defer ws.Close()
+
+		// Lees (en negeer) berichten van de client om een gesloten verbinding te detecteren
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			var msg string
+			for {
+				if err := websocket.Message.Receive(ws, &msg); err != nil {
+					return
+				}
+			}
+		}()
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-closed:
+				return
+			case event, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				if err := websocket.JSON.Send(ws, event); err != nil {
+					log.Printf("[StreamEventsWebSocket] Error sending event: %v", err)
+					return
+				}
+			case <-heartbeat.C:
+				if err := websocket.JSON.Send(ws, gin.H{"type": "ping"}); err != nil {
+					return
+				}
+			}
+		}
+	}).ServeHTTP(c.Writer, c.Request)
+}
+
+// subscribe registreert de ingelogde gebruiker op de bus, gefilterd op diens rol
+// en optioneel op de event types uit de types query parameter
+func (h *EventHandler) subscribe(c *gin.Context) (*events.Subscription, bool) {
+	user := middleware.GetUserFromContext(c)
+	if user == nil {
+		i18n.Error(c, http.StatusUnauthorized, i18n.MsgAuthRequired)
+		return nil, false
+	}
+
+	var types []events.EventType
+	if typesParam := c.Query("types"); typesParam != "" {
+		for _, t := range strings.Split(typesParam, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				types = append(types, events.EventType(t))
+			}
+		}
+	}
+
+	return h.bus.Subscribe(events.RoleFilter(user.Role, types...)), true
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"dklautomationgo/database/repository"
+	"dklautomationgo/models"
+	"dklautomationgo/services/email"
+	"dklautomationgo/services/i18n"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InboxRuleHandler bevat handlers voor het beheren van inbox regels
+type InboxRuleHandler struct {
+	ruleRepo     repository.IInboxRuleRepository
+	userRepo     *repository.UserRepository
+	emailService *email.EmailService
+}
+
+// NewInboxRuleHandler maakt een nieuwe InboxRuleHandler
+func NewInboxRuleHandler(ruleRepo repository.IInboxRuleRepository, userRepo *repository.UserRepository, emailService *email.EmailService) *InboxRuleHandler {
+	return &InboxRuleHandler{
+		ruleRepo:     ruleRepo,
+		userRepo:     userRepo,
+		emailService: emailService,
+	}
+}
+
+// GetRules handles GET /api/inbox-rules
+func (h *InboxRuleHandler) GetRules(c *gin.Context) {
+	rules, err := h.ruleRepo.FindAll()
+	if err != nil {
+		log.Printf("[GetRules] Error fetching inbox rules: %v", err)
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgRulesFetchFailed)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": rules})
+}
+
+// GetRule handles GET /api/inbox-rules/:id
+func (h *InboxRuleHandler) GetRule(c *gin.Context) {
+	rule, err := h.ruleRepo.FindByID(c.Param("id"))
+	if err != nil {
+		i18n.Error(c, http.StatusNotFound, i18n.MsgRuleNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": rule})
+}
+
+// CreateRule handles POST /api/inbox-rules
+func (h *InboxRuleHandler) CreateRule(c *gin.Context) {
+	rule := models.InboxRule{Enabled: true}
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidRequest)
+		return
+	}
+	rule.ID = ""
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = time.Now()
+
+	if key := h.validateRule(&rule); key != "" {
+		i18n.Error(c, http.StatusBadRequest, key)
+		return
+	}
+
+	if err := h.ruleRepo.Create(&rule); err != nil {
+		log.Printf("[CreateRule] Error saving inbox rule: %v", err)
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgRuleSaveFailed)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": rule})
+}
+
+// UpdateRule handles PUT /api/inbox-rules/:id
+func (h *InboxRuleHandler) UpdateRule(c *gin.Context) {
+	rule, err := h.ruleRepo.FindByID(c.Param("id"))
+	if err != nil {
+		i18n.Error(c, http.StatusNotFound, i18n.MsgRuleNotFound)
+		return
+	}
+
+	id, createdAt := rule.ID, rule.CreatedAt
+	if err := c.ShouldBindJSON(rule); err != nil {
+		i18n.Error(c, http.StatusBadRequest, i18n.MsgInvalidRequest)
+		return
+	}
+	rule.ID, rule.CreatedAt = id, createdAt
+
+	if key := h.validateRule(rule); key != "" {
+		i18n.Error(c, http.StatusBadRequest, key)
+		return
+	}
+
+	if err := h.ruleRepo.Update(rule); err != nil {
+		log.Printf("[UpdateRule] Error updating inbox rule: %v", err)
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgRuleUpdateFailed)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": rule})
+}
+
+// DeleteRule handles DELETE /api/inbox-rules/:id
+func (h *InboxRuleHandler) DeleteRule(c *gin.Context) {
+	if err := h.ruleRepo.Delete(c.Param("id")); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			i18n.Error(c, http.StatusNotFound, i18n.MsgRuleNotFound)
+			return
+		}
+		log.Printf("[DeleteRule] Error deleting inbox rule: %v", err)
+		i18n.Error(c, http.StatusInternalServerError, i18n.MsgRuleDeleteFailed)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// validateRule controleert een regel en geeft het bericht van de fout terug als deze ongeldig is
+func (h *InboxRuleHandler) validateRule(rule *models.InboxRule) i18n.Key {
+	rule.Name = strings.TrimSpace(rule.Name)
+	rule.Label = strings.TrimSpace(rule.Label)
+	if rule.AssignTo != nil && strings.TrimSpace(*rule.AssignTo) == "" {
+		rule.AssignTo = nil
+	}
+
+	switch {
+	case rule.Name == "":
+		return i18n.MsgRuleNameRequired
+	case !rule.HasActions():
+		return i18n.MsgRuleActionRequired
+	case rule.Account != "" && !h.emailService.HasAccount(rule.Account):
+		return i18n.MsgUnknownAccount
+	case rule.AutoReplyTemplate != "" && !h.emailService.HasTemplate(rule.AutoReplyTemplate):
+		return i18n.MsgUnknownAutoReply
+	}
+
+	if rule.AssignTo != nil {
+		userID, err := uuid.Parse(*rule.AssignTo)
+		if err != nil {
+			return i18n.MsgInvalidAssignTo
+		}
+		if user, err := h.userRepo.FindByID(userID); err != nil || user == nil {
+			return i18n.MsgUnknownAssignTo
+		}
+	}
+	return ""
+}
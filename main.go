@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	authHandlers "dklautomationgo/auth/handlers"
 	"dklautomationgo/auth/middleware"
 	"dklautomationgo/auth/service"
@@ -10,11 +11,13 @@ import (
 	"dklautomationgo/models"
 	"dklautomationgo/services"
 	"dklautomationgo/services/email"
-	"html/template"
+	"dklautomationgo/services/events"
+	"dklautomationgo/services/i18n"
+	"dklautomationgo/services/secrets"
+	"errors"
 	"log"
 	"net/http"
 	"os"
-	"path/filepath"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -27,6 +30,20 @@ func main() {
 		log.Printf("Warning: .env file not found")
 	}
 
+	// Sleutels voor de versleutelde kolommen, nodig voordat de database gebruikt wordt
+	keyring, err := secrets.LoadKeyring()
+	switch {
+	case errors.Is(err, secrets.ErrNoKey) && !email.DevMode():
+		log.Fatalf("No encryption keys configured (%s): personal data cannot be stored without encryption outside DEV_MODE", secrets.KeysEnv)
+	case errors.Is(err, secrets.ErrNoKey):
+		log.Printf("Warning: no encryption keys configured (%s), personal data is stored unencrypted (DEV_MODE) and email account passwords cannot be stored in the database", secrets.KeysEnv)
+	case err != nil:
+		log.Fatalf("Failed to load encryption keys: %v", err)
+	default:
+		secrets.SetDefault(keyring)
+		log.Printf("Encryption keys loaded, active key: %s", keyring.ActiveKeyID())
+	}
+
 	// Initialize database connection
 	dbConfig := database.NewConfig()
 	db, err := database.NewConnection(dbConfig)
@@ -43,42 +60,94 @@ func main() {
 	contactRepo := repository.NewContactRepository(db)
 	aanmeldingRepo := repository.NewAanmeldingRepository(db)
 	userRepo := repository.NewUserRepository(db)
+	emailLinkRepo := repository.NewEmailLinkRepository(db)
+	emailBounceRepo := repository.NewEmailBounceRepository(db)
+	formIntakeRepo := repository.NewFormIntakeRepository(db)
+	inboxRuleRepo := repository.NewInboxRuleRepository(db)
+	emailMetadataRepo := repository.NewEmailMetadataRepository(db)
+	emailNoteRepo := repository.NewEmailNoteRepository(db)
+	emailAccountRepo := repository.NewEmailAccountRepository(db)
+	emailTemplateRepo := repository.NewEmailTemplateRepository(db)
 
-	// Load email templates
-	templatesDir := "templates"
-	templates := make(map[string]*template.Template)
-	templateFiles, err := filepath.Glob(filepath.Join(templatesDir, "*.html"))
-	if err != nil {
-		log.Fatalf("Failed to find template files: %v", err)
-	}
-	for _, file := range templateFiles {
-		tmpl, err := template.ParseFiles(file)
-		if err != nil {
-			log.Fatalf("Failed to parse template %s: %v", file, err)
-		}
-		templates[filepath.Base(file)] = tmpl
-	}
+	// Initialize event bus voor realtime dashboard notificaties
+	eventBus := events.NewBus()
 
 	// Initialize services
 	emailService, err := email.NewEmailService()
 	if err != nil {
 		log.Fatalf("Failed to initialize email service: %v", err)
 	}
+	defer emailService.Close()
+	// Accounts uit EMAIL_ACCOUNTS_FILE, of anders uit de database (met de
+	// omgevingsvariabelen als fallback zolang daar geen accounts staan)
+	accountsFile := os.Getenv("EMAIL_ACCOUNTS_FILE")
+	if accountsFile != "" {
+		if err := emailService.SetAccountSource(email.NewFileAccountSource(accountsFile)); err != nil {
+			log.Fatalf("Failed to load email accounts: %v", err)
+		}
+	} else if err := emailService.SetAccountSource(email.NewDatabaseAccountSource(emailAccountRepo)); err != nil {
+		log.Printf("Warning: failed to load email accounts from database, using current accounts: %v", err)
+	}
+	// Actieve templates uit de database gaan voor de bestanden in templates/
+	if err := emailService.SetTemplateStore(emailTemplateRepo); err != nil {
+		log.Printf("Warning: failed to load email templates from database, using template files: %v", err)
+	}
+	emailService.SetEventPublisher(eventBus)
+	// Status, toewijzing, labels en notities van emails in de gedeelde inboxen
+	emailService.SetMetadataStores(emailMetadataRepo, emailNoteRepo)
+	// Koppel inkomende emails aan contactformulieren en aanmeldingen
+	emailLinkService := services.NewEmailLinkService(emailLinkRepo, contactRepo, aanmeldingRepo)
+	emailService.AddIncomingHandler(emailLinkService)
+	// Markeer records waarvan de bevestigingsmail als onbestelbaar teruggekomen is
+	bounceService := services.NewBounceService(emailBounceRepo, contactRepo, aanmeldingRepo)
+	emailService.AddIncomingHandler(bounceService)
+	// Zet formuliermeldingen uit de mailbox om naar aanmeldingen en contactformulieren
+	formRules := email.DefaultFormRules()
+	if rulesFile := os.Getenv("FORM_INTAKE_RULES_FILE"); rulesFile != "" {
+		if formRules, err = email.LoadFormRules(rulesFile); err != nil {
+			log.Fatalf("Failed to load form intake rules: %v", err)
+		}
+	}
+	formIntakeService := services.NewFormIntakeService(formRules, formIntakeRepo, contactRepo, aanmeldingRepo, emailService, eventBus)
+	emailService.AddIncomingHandler(formIntakeService)
+	// Inbox regels als laatste, omdat een regel de email naar een andere map kan verplaatsen
+	emailService.AddIncomingHandler(email.NewRuleEngine(emailService, inboxRuleRepo, emailMetadataRepo))
+	// Start IMAP IDLE watchers zodat nieuwe mail direct in de cache verschijnt
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	emailService.StartWatching(watchCtx)
+	// Wijzigingen in de accounts worden zonder herstart actief
+	emailService.WatchAccounts(watchCtx, email.AccountReloadInterval())
+
 	tokenService := service.NewTokenService()
 	authService := service.NewAuthService(userRepo, tokenService)
-	aanmeldingService := services.NewAanmeldingService(aanmeldingRepo, emailService)
+	aanmeldingService := services.NewAanmeldingService(aanmeldingRepo, emailService, eventBus)
 
 	// Initialize middleware
 	authMiddleware := middleware.NewAuthMiddleware(tokenService, userRepo)
 
 	// Initialize handlers
 	emailHandler := handlers.NewEmailHandler(emailService)
-	contactHandler := handlers.NewContactHandler(emailService, contactRepo)
+	contactHandler := handlers.NewContactHandler(emailService, contactRepo, eventBus)
 	aanmeldingHandler := handlers.NewAanmeldingHandler(aanmeldingService)
 	authHandler := authHandlers.NewAuthHandler(authService, authMiddleware)
+	eventHandler := handlers.NewEventHandler(eventBus)
+	inboxRuleHandler := handlers.NewInboxRuleHandler(inboxRuleRepo, userRepo, emailService)
+	emailAccountHandler := handlers.NewEmailAccountHandler(emailAccountRepo, emailService)
+	emailTemplateHandler := handlers.NewEmailTemplateHandler(emailTemplateRepo, emailService)
+	if accountsFile != "" {
+		emailAccountHandler.SetReadOnly(accountsFile)
+	}
+	emailHandler.SetEmailLinkService(emailLinkService)
+	emailHandler.SetFormIntakeService(formIntakeService)
+	emailHandler.SetBounceService(bounceService)
+	emailHandler.SetUserRepository(userRepo)
+	contactHandler.SetEmailLinkService(emailLinkService)
+	aanmeldingHandler.SetEmailLinkService(emailLinkService)
 
-	// Setup Gin
-	r := gin.Default()
+	// Setup Gin; de request log laat access_token tokens van streams weg
+	r := gin.New()
+	r.Use(middleware.RequestLogger(), gin.Recovery())
 
 	// Configure CORS
 	config := cors.DefaultConfig()
@@ -97,12 +166,16 @@ func main() {
 		"Accept",
 		"Authorization",
 		"X-Requested-With",
+		"Accept-Language",
 	}
 	config.AllowCredentials = true
-	config.ExposeHeaders = []string{"Content-Length"}
+	config.ExposeHeaders = []string{"Content-Length", "Content-Language"}
 	config.MaxAge = 12 * 60 * 60 // 12 hours
 	r.Use(cors.New(config))
 
+	// Taal van de API berichten op basis van Accept-Language of ?lang=
+	r.Use(i18n.Middleware())
+
 	// Health check endpoint
 	r.GET("/health", func(c *gin.Context) {
 		// Check database connection
@@ -126,6 +199,17 @@ func main() {
 	// API routes
 	api := r.Group("/api")
 	{
+		// Realtime notificaties voor het dashboard, gefilterd op rol
+		eventsGroup := api.Group("/events")
+		eventsGroup.Use(authMiddleware.RequireAuth())
+		{
+			eventsGroup.GET("", eventHandler.StreamEvents)
+			eventsGroup.GET("/ws", eventHandler.StreamEventsWebSocket)
+		}
+
+		// Image proxy voor externe afbeeldingen in emails, beveiligd met ondertekende URLs
+		api.GET("/emails/image-proxy", emailHandler.ProxyEmailImage)
+
 		// Email routes - beschermd met auth
 		emails := api.Group("/emails")
 		emails.Use(authMiddleware.RequireAuth())
@@ -133,7 +217,69 @@ func main() {
 		{
 			emails.GET("", emailHandler.GetEmails)
 			emails.GET("/stats", emailHandler.GetEmailStats)
+			emails.GET("/folders", emailHandler.GetEmailFolders)
+			emails.GET("/accounts", emailHandler.GetEmailAccounts)
+			emails.GET("/threads", emailHandler.GetEmailThreads)
+			emails.GET("/threads/:threadId", emailHandler.GetEmailThread)
+			emails.GET("/intake", emailHandler.GetFormIntakeResults)
+			emails.GET("/bounces", emailHandler.GetBounces)
+			emails.POST("/send", emailHandler.SendEmail)
+			emails.GET("/:id", emailHandler.GetEmail)
+			emails.GET("/:id/attachments/:index", emailHandler.GetEmailAttachment)
 			emails.PUT("/:id/read", emailHandler.MarkEmailAsRead)
+			emails.PUT("/:id/unread", emailHandler.MarkEmailAsUnread)
+			emails.PUT("/:id/flag", emailHandler.SetEmailFlagged)
+			emails.PUT("/:id/assign", emailHandler.AssignEmail)
+			emails.PUT("/:id/status", emailHandler.SetEmailStatus)
+			emails.PUT("/:id/labels", emailHandler.SetEmailLabels)
+			emails.GET("/:id/notes", emailHandler.GetEmailNotes)
+			emails.POST("/:id/notes", emailHandler.AddEmailNote)
+			emails.POST("/:id/reply", emailHandler.ReplyToEmail)
+			emails.POST("/:id/reply-all", emailHandler.ReplyAllToEmail)
+			emails.POST("/:id/forward", emailHandler.ForwardEmail)
+			emails.POST("/:id/intake", emailHandler.ProcessFormIntake)
+			emails.POST("/:id/bounce", emailHandler.ProcessBounce)
+			emails.POST("/:id/move", emailHandler.MoveEmail)
+			emails.POST("/:id/archive", emailHandler.ArchiveEmail)
+			emails.DELETE("/:id", emailHandler.DeleteEmail)
+		}
+
+		// Inbox regels - alleen voor beheerders
+		inboxRules := api.Group("/inbox-rules")
+		inboxRules.Use(authMiddleware.RequireAuth())
+		inboxRules.Use(authMiddleware.RequireRole(models.RoleBeheerder, models.RoleAdmin))
+		{
+			inboxRules.GET("", inboxRuleHandler.GetRules)
+			inboxRules.POST("", inboxRuleHandler.CreateRule)
+			inboxRules.GET("/:id", inboxRuleHandler.GetRule)
+			inboxRules.PUT("/:id", inboxRuleHandler.UpdateRule)
+			inboxRules.DELETE("/:id", inboxRuleHandler.DeleteRule)
+		}
+
+		// Email accounts - alleen voor admins
+		emailAccounts := api.Group("/email-accounts")
+		emailAccounts.Use(authMiddleware.RequireAuth())
+		emailAccounts.Use(authMiddleware.RequireRole(models.RoleAdmin))
+		{
+			emailAccounts.GET("", emailAccountHandler.GetAccounts)
+			emailAccounts.POST("", emailAccountHandler.CreateAccount)
+			emailAccounts.POST("/reload", emailAccountHandler.ReloadAccounts)
+			emailAccounts.GET("/:id", emailAccountHandler.GetAccount)
+			emailAccounts.PUT("/:id", emailAccountHandler.UpdateAccount)
+			emailAccounts.DELETE("/:id", emailAccountHandler.DeleteAccount)
+		}
+
+		// Email templates - alleen voor admins
+		emailTemplates := api.Group("/email-templates")
+		emailTemplates.Use(authMiddleware.RequireAuth())
+		emailTemplates.Use(authMiddleware.RequireRole(models.RoleAdmin))
+		{
+			emailTemplates.GET("", emailTemplateHandler.GetTemplates)
+			emailTemplates.POST("/preview", emailTemplateHandler.PreviewTemplate)
+			emailTemplates.GET("/:name", emailTemplateHandler.GetTemplate)
+			emailTemplates.DELETE("/:name", emailTemplateHandler.DeleteTemplate)
+			emailTemplates.POST("/:name/versions", emailTemplateHandler.CreateVersion)
+			emailTemplates.POST("/:name/versions/:version/activate", emailTemplateHandler.ActivateVersion)
 		}
 
 		// Contact form routes - gedeeltelijk beschermd
@@ -147,6 +293,7 @@ func main() {
 			contactsAdmin.Use(authMiddleware.RequireRole(models.RoleBeheerder, models.RoleAdmin))
 			{
 				contactsAdmin.GET("", contactHandler.GetContacts)
+				contactsAdmin.GET("/:id", contactHandler.GetContact)
 				contactsAdmin.PUT("/:id/status", contactHandler.UpdateContactStatus)
 			}
 		}
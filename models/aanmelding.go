@@ -1,6 +1,7 @@
 package models
 
 import (
+	_ "dklautomationgo/services/secrets" // Registreert de "encrypted" serializer
 	"time"
 
 	"gorm.io/gorm"
@@ -8,21 +9,32 @@ import (
 
 // Aanmelding representeert een vrijwilliger aanmelding in de database
 type Aanmelding struct {
-	ID             string     `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"` // Unieke identifier
-	CreatedAt      time.Time  `json:"created_at" gorm:"not null"`                                // Tijdstip van aanmaken
-	UpdatedAt      time.Time  `json:"updated_at" gorm:"not null"`                                // Tijdstip van laatste update
-	Naam           string     `json:"naam" gorm:"not null" validate:"required,min=2,max=100"`    // Naam van de vrijwilliger
-	Email          string     `json:"email" gorm:"not null" validate:"required,email"`           // Email adres voor communicatie
-	Telefoon       string     `json:"telefoon" gorm:"not null" validate:"required"`              // Telefoonnummer
-	Rol            string     `json:"rol" gorm:"not null" validate:"required"`                   // Gewenste rol (bijv. chauffeur, bijrijder)
-	Afstand        string     `json:"afstand" gorm:"not null" validate:"required"`               // Maximale reisafstand
-	Ondersteuning  string     `json:"ondersteuning"`                                             // Benodigde ondersteuning
-	Bijzonderheden string     `json:"bijzonderheden"`                                            // Eventuele bijzonderheden
-	Terms          bool       `json:"terms" gorm:"not null" validate:"required"`                 // Akkoord met voorwaarden
-	EmailVerzonden bool       `json:"email_verzonden" gorm:"default:false"`                      // Of de bevestigingsemail is verzonden
-	EmailVerzondOp *time.Time `json:"email_verzonden_op"`                                        // Wanneer de email is verzonden
+	ID               string     `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`                   // Unieke identifier
+	CreatedAt        time.Time  `json:"created_at" gorm:"not null"`                                                  // Tijdstip van aanmaken
+	UpdatedAt        time.Time  `json:"updated_at" gorm:"not null"`                                                  // Tijdstip van laatste update
+	Naam             string     `json:"naam" gorm:"not null" validate:"required,min=2,max=100"`                      // Naam van de vrijwilliger
+	Email            string     `json:"email" gorm:"not null" validate:"required,email"`                             // Email adres voor communicatie
+	Telefoon         string     `json:"telefoon" gorm:"type:text;not null;serializer:encrypted" validate:"required"` // Telefoonnummer, versleuteld opgeslagen
+	Rol              string     `json:"rol" gorm:"not null" validate:"required"`                                     // Gewenste rol (bijv. chauffeur, bijrijder)
+	Afstand          string     `json:"afstand" gorm:"not null" validate:"required"`                                 // Maximale reisafstand
+	Ondersteuning    string     `json:"ondersteuning" gorm:"type:text;serializer:encrypted"`                         // Benodigde ondersteuning, versleuteld opgeslagen
+	Bijzonderheden   string     `json:"bijzonderheden" gorm:"type:text;serializer:encrypted"`                        // Eventuele bijzonderheden (mogelijk medisch), versleuteld opgeslagen
+	Terms            bool       `json:"terms" gorm:"not null" validate:"required"`                                   // Akkoord met voorwaarden
+	Locale           string     `json:"locale" gorm:"size:10;not null;default:'nl'"`                                 // Taal van de aanmelder, bepaalt de taal van de bevestigingsemail
+	EmailVerzonden   bool       `json:"email_verzonden" gorm:"default:false"`                                        // Of de bevestigingsemail is verzonden
+	EmailVerzondOp   *time.Time `json:"email_verzonden_op"`                                                          // Wanneer de email is verzonden
+	EmailBounced     bool       `json:"email_bounced" gorm:"default:false"`                                          // Of de bevestigingsemail teruggekomen is als onbestelbaar
+	EmailBouncedOp   *time.Time `json:"email_bounced_op"`                                                            // Wanneer de bounce binnenkwam
+	EmailBounceReden string     `json:"email_bounce_reden"`                                                          // Reden van de bounce volgens de ontvangende mailserver
+	BronEmail        *string    `json:"bron_email,omitempty" gorm:"uniqueIndex"`                                     // Message-ID van de formuliermelding waaruit de aanmelding aangemaakt is
 }
 
+// AanmeldingRollen zijn de rollen die een aanmelding kan hebben, zoals de database ze toestaat
+var AanmeldingRollen = []string{"Deelnemer", "Vrijwilliger", "Chauffeur", "Bijrijder", "Verzorging"}
+
+// AanmeldingAfstanden zijn de afstanden die een aanmelding kan hebben, zoals de database ze toestaat
+var AanmeldingAfstanden = []string{"2.5 KM", "5 KM", "10 KM", "15 KM", "Halve marathon"}
+
 // AanmeldingFormulier representeert het aanmeldingsformulier zoals ontvangen van de frontend
 type AanmeldingFormulier struct {
 	Naam           string `json:"naam" validate:"required,min=2,max=100"` // Naam van de vrijwilliger
@@ -33,6 +45,7 @@ type AanmeldingFormulier struct {
 	Ondersteuning  string `json:"ondersteuning"`                          // Benodigde ondersteuning
 	Bijzonderheden string `json:"bijzonderheden"`                         // Eventuele bijzonderheden
 	Terms          bool   `json:"terms" validate:"required"`              // Akkoord met voorwaarden
+	Locale         string `json:"locale"`                                 // Taal van het formulier, bijv. "nl" of "en"
 }
 
 // TableName override voor GORM
@@ -59,6 +72,7 @@ func (f *AanmeldingFormulier) ToDatabase() *Aanmelding {
 		Ondersteuning:  f.Ondersteuning,
 		Bijzonderheden: f.Bijzonderheden,
 		Terms:          f.Terms,
+		Locale:         f.Locale,
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 	}
@@ -8,19 +8,24 @@ import (
 
 // ContactFormulier representeert een contact formulier inzending met tracking informatie
 type ContactFormulier struct {
-	ID               string     `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"` // Unieke identifier
-	CreatedAt        time.Time  `json:"created_at" gorm:"not null"`                                // Tijdstip van aanmaken
-	UpdatedAt        time.Time  `json:"updated_at" gorm:"not null"`                                // Tijdstip van laatste update
-	Naam             string     `json:"naam" gorm:"not null" validate:"required,min=2,max=100"`    // Naam van de contactpersoon
-	Email            string     `json:"email" gorm:"not null" validate:"required,email"`           // Email adres voor communicatie
-	Bericht          string     `json:"bericht" gorm:"type:text;not null" validate:"required"`     // Het bericht van de gebruiker
-	EmailVerzonden   bool       `json:"email_verzonden" gorm:"default:false"`                      // Of de bevestigingsemail is verzonden
-	EmailVerzondenOp *time.Time `json:"email_verzonden_op"`                                        // Wanneer de email is verzonden
-	PrivacyAkkoord   bool       `json:"privacy_akkoord" gorm:"not null" validate:"required"`       // Of gebruiker akkoord is met privacy voorwaarden
-	Status           string     `json:"status" gorm:"not null;default:'nieuw'"`                    // Status van de aanvraag (nieuw/in behandeling/afgerond)
-	BehandeldDoor    *string    `json:"behandeld_door"`                                            // Wie de aanvraag heeft behandeld
-	BehandeldOp      *time.Time `json:"behandeld_op"`                                              // Wanneer de aanvraag is behandeld
-	Notities         *string    `json:"notities" gorm:"type:text"`                                 // Interne notities over de aanvraag
+	ID               string     `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`                  // Unieke identifier
+	CreatedAt        time.Time  `json:"created_at" gorm:"not null"`                                                 // Tijdstip van aanmaken
+	UpdatedAt        time.Time  `json:"updated_at" gorm:"not null"`                                                 // Tijdstip van laatste update
+	Naam             string     `json:"naam" gorm:"not null" validate:"required,min=2,max=100"`                     // Naam van de contactpersoon
+	Email            string     `json:"email" gorm:"not null" validate:"required,email"`                            // Email adres voor communicatie
+	Bericht          string     `json:"bericht" gorm:"type:text;not null;serializer:encrypted" validate:"required"` // Het bericht van de gebruiker, versleuteld opgeslagen
+	EmailVerzonden   bool       `json:"email_verzonden" gorm:"default:false"`                                       // Of de bevestigingsemail is verzonden
+	EmailVerzondenOp *time.Time `json:"email_verzonden_op"`                                                         // Wanneer de email is verzonden
+	PrivacyAkkoord   bool       `json:"privacy_akkoord" gorm:"not null" validate:"required"`                        // Of gebruiker akkoord is met privacy voorwaarden
+	Status           string     `json:"status" gorm:"not null;default:'nieuw'"`                                     // Status van de aanvraag (nieuw/in behandeling/afgerond)
+	BehandeldDoor    *string    `json:"behandeld_door"`                                                             // Wie de aanvraag heeft behandeld
+	BehandeldOp      *time.Time `json:"behandeld_op"`                                                               // Wanneer de aanvraag is behandeld
+	Notities         *string    `json:"notities" gorm:"type:text;serializer:encrypted"`                             // Interne notities over de aanvraag, versleuteld opgeslagen
+	Locale           string     `json:"locale" gorm:"size:10;not null;default:'nl'"`                                // Taal van de contactpersoon, bepaalt de taal van de bevestigingsemail
+	EmailBounced     bool       `json:"email_bounced" gorm:"default:false"`                                         // Of de bevestigingsemail teruggekomen is als onbestelbaar
+	EmailBouncedOp   *time.Time `json:"email_bounced_op"`                                                           // Wanneer de bounce binnenkwam
+	EmailBounceReden string     `json:"email_bounce_reden"`                                                         // Reden van de bounce volgens de ontvangende mailserver
+	BronEmail        *string    `json:"bron_email,omitempty" gorm:"uniqueIndex"`                                    // Message-ID van de formuliermelding waaruit het formulier aangemaakt is
 }
 
 // TableName override voor GORM
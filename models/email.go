@@ -1,5 +1,11 @@
 package models
 
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
 // ContactEmailData bevat de data nodig voor het versturen van contact formulier emails
 type ContactEmailData struct {
 	ToAdmin    bool              `json:"to_admin"`
@@ -9,9 +15,10 @@ type ContactEmailData struct {
 
 // AanmeldingEmailData bevat de data nodig voor het versturen van aanmelding emails
 type AanmeldingEmailData struct {
-	ToAdmin    bool                 `json:"to_admin"`
-	Aanmelding *AanmeldingFormulier `json:"aanmelding"`
-	AdminEmail string               `json:"admin_email,omitempty"`
+	ToAdmin      bool                 `json:"to_admin"`
+	Aanmelding   *AanmeldingFormulier `json:"aanmelding"`
+	AdminEmail   string               `json:"admin_email,omitempty"`
+	AanmeldingID string               `json:"aanmelding_id,omitempty"` // ID van de opgeslagen aanmelding, om antwoorden te kunnen koppelen
 }
 
 // EmailAttachment represents an email attachment or inline image
@@ -21,6 +28,8 @@ type EmailAttachment struct {
 	Size        int64  `json:"size"`         // Grootte in bytes
 	Content     []byte `json:"content"`      // De binary content
 	ContentID   string `json:"content_id"`   // Voor inline afbeeldingen
+	Section     string `json:"section"`      // IMAP MIME part nummer, bijv. 2 of 1.2
+	Inline      bool   `json:"inline"`       // Of de bijlage inline in de HTML getoond wordt
 }
 
 // Email represents a processed email message with all its metadata and content
@@ -31,9 +40,11 @@ type Email struct {
 	Body        string            `json:"body"`        // Platte tekst versie
 	HTML        string            `json:"html"`        // HTML versie (indien beschikbaar)
 	Account     string            `json:"account"`     // Email account waar dit bericht bij hoort
+	Folder      string            `json:"folder"`      // IMAP map waarin het bericht staat
 	MessageID   string            `json:"message_id"`  // Originele Message-ID header
 	CreatedAt   string            `json:"created_at"`  // Timestamp in RFC3339 formaat
 	Read        bool              `json:"read"`        // Of de email als gelezen is gemarkeerd
+	Flagged     bool              `json:"flagged"`     // Of de email met een ster (\Flagged) is gemarkeerd
 	Metadata    map[string]string `json:"metadata"`    // Extra metadata velden
 	To          []string          `json:"to"`          // Lijst van ontvangers
 	Cc          []string          `json:"cc"`          // Carbon copy ontvangers
@@ -43,13 +54,150 @@ type Email struct {
 	References  []string          `json:"references"`  // Gerelateerde Message-IDs
 	Attachments []EmailAttachment `json:"attachments"` // Lijst van bijlagen
 	Headers     map[string]string `json:"headers"`     // Alle email headers
+
+	HasRemoteContent bool `json:"has_remote_content"` // Of de HTML externe afbeeldingen of stijlen bevat
+}
+
+// previewLength is het maximaal aantal tekens van de preview in een EmailSummary
+const previewLength = 160
+
+// EmailSummary is de lichte weergave van een email voor lijsten, zonder bodies en bijlagen
+type EmailSummary struct {
+	ID              string   `json:"id"`               // Email ID, zie Email.ID
+	Account         string   `json:"account"`          // Email account waar dit bericht bij hoort
+	Folder          string   `json:"folder"`           // IMAP map waarin het bericht staat
+	Sender          string   `json:"sender"`           // Email adres van de verzender
+	Subject         string   `json:"subject"`          // Onderwerp van de email
+	Preview         string   `json:"preview"`          // Begin van de platte tekst
+	MessageID       string   `json:"message_id"`       // Originele Message-ID header
+	CreatedAt       string   `json:"created_at"`       // Timestamp in RFC3339 formaat
+	Read            bool     `json:"read"`             // Of de email als gelezen is gemarkeerd
+	Flagged         bool     `json:"flagged"`          // Of de email met een ster is gemarkeerd
+	To              []string `json:"to"`               // Lijst van ontvangers
+	AttachmentCount int      `json:"attachment_count"` // Aantal bijlagen
+
+	Status     string   `json:"status,omitempty"`      // Status uit de metadata (open, waiting, closed)
+	AssignedTo *string  `json:"assigned_to,omitempty"` // Gebruiker aan wie de email toegewezen is
+	Labels     []string `json:"labels,omitempty"`      // Labels uit de metadata
+}
+
+// ToSummary zet een email om naar de lichte weergave voor lijsten
+func (e *Email) ToSummary() *EmailSummary {
+	preview := strings.Join(strings.Fields(e.Body), " ")
+	if utf8.RuneCountInString(preview) > previewLength {
+		preview = string([]rune(preview)[:previewLength]) + "…"
+	}
+
+	return &EmailSummary{
+		ID:              e.ID,
+		Account:         e.Account,
+		Folder:          e.Folder,
+		Sender:          e.Sender,
+		Subject:         e.Subject,
+		Preview:         preview,
+		MessageID:       e.MessageID,
+		CreatedAt:       e.CreatedAt,
+		Read:            e.Read,
+		Flagged:         e.Flagged,
+		To:              e.To,
+		AttachmentCount: len(e.Attachments),
+	}
+}
+
+// WithoutAttachmentContent geeft een kopie van de email terug waarin van de
+// bijlagen alleen de metadata staat; de inhoud is via de download endpoint op te halen
+func (e *Email) WithoutAttachmentContent() *Email {
+	copied := *e
+	copied.Attachments = make([]EmailAttachment, len(e.Attachments))
+	for i, attachment := range e.Attachments {
+		copied.Attachments[i] = attachment
+		copied.Attachments[i].Content = nil
+	}
+	return &copied
 }
 
 // EmailFetchOptions bevat de parameters voor het ophalen van emails
 type EmailFetchOptions struct {
-	Limit  int   `json:"limit"`  // Maximum aantal emails om op te halen
-	Offset int   `json:"offset"` // Aantal emails om over te slaan (voor paginatie)
-	Read   *bool `json:"read"`   // Filter op gelezen/ongelezen status
+	Limit   int    `json:"limit"`   // Maximum aantal emails om op te halen
+	Offset  int    `json:"offset"`  // Aantal emails om over te slaan (voor paginatie)
+	Read    *bool  `json:"read"`    // Filter op gelezen/ongelezen status
+	Account string `json:"account"` // Alleen emails van dit account ophalen
+	Folder  string `json:"folder"`  // IMAP map om uit op te halen (standaard INBOX)
+
+	// Zoeken, hoofdletterongevoelig; lege velden tellen niet mee
+	Query         string     `json:"query"`          // Vrije tekst in afzender, ontvangers, onderwerp of tekst
+	From          string     `json:"from"`           // Deel van het adres van de afzender
+	To            string     `json:"to"`             // Deel van het adres van een ontvanger (To of Cc)
+	Subject       string     `json:"subject"`        // Deel van het onderwerp
+	Body          string     `json:"body"`           // Deel van de tekst
+	Since         *time.Time `json:"since"`          // Alleen emails vanaf dit tijdstip
+	Before        *time.Time `json:"before"`         // Alleen emails van voor dit tijdstip
+	HasAttachment *bool      `json:"has_attachment"` // Filter op emails met of zonder (niet-inline) bijlagen
+
+	// Filters op de metadata uit de database
+	Status     string `json:"status"`      // Alleen emails met deze status (open, waiting, closed)
+	AssignedTo string `json:"assigned_to"` // Alleen emails toegewezen aan deze gebruiker, of "none" voor niet toegewezen
+	Label      string `json:"label"`       // Alleen emails met dit label
+}
+
+// EmailUnassigned is de waarde van EmailFetchOptions.AssignedTo voor emails die aan niemand toegewezen zijn
+const EmailUnassigned = "none"
+
+// HasSearch geeft aan of er op inhoud, datum of bijlagen gezocht wordt
+func (o *EmailFetchOptions) HasSearch() bool {
+	return o != nil && (o.Query != "" || o.From != "" || o.To != "" || o.Subject != "" || o.Body != "" ||
+		o.Since != nil || o.Before != nil || o.HasAttachment != nil)
+}
+
+// HasMetadataFilter geeft aan of er gefilterd wordt op metadata uit de database
+func (o *EmailFetchOptions) HasMetadataFilter() bool {
+	return o != nil && (o.Status != "" || o.AssignedTo != "" || o.Label != "")
+}
+
+// Statussen van een email account in EmailAccountStatus
+const (
+	AccountStatusOK      = "ok"      // Laatste synchronisatie geslaagd
+	AccountStatusError   = "error"   // Laatste synchronisatie mislukt
+	AccountStatusUnknown = "unknown" // Nog niet gesynchroniseerd sinds het starten van de server
+)
+
+// EmailAccountStatus beschrijft de gezondheid van een email account
+type EmailAccountStatus struct {
+	Account     string     `json:"account"`                // Naam van het account
+	Email       string     `json:"email"`                  // Email adres van het account
+	Status      string     `json:"status"`                 // ok, error of unknown
+	ErrorCode   string     `json:"error_code,omitempty"`   // auth_failed, unreachable, timeout of error
+	Error       string     `json:"error,omitempty"`        // Foutmelding van de laatste poging
+	LastSync    *time.Time `json:"last_sync,omitempty"`    // Tijdstip van de laatste geslaagde synchronisatie
+	LastAttempt *time.Time `json:"last_attempt,omitempty"` // Tijdstip van de laatste poging
+	Messages    uint32     `json:"messages"`               // Aantal berichten in de INBOX
+	Unread      uint32     `json:"unread"`                 // Aantal ongelezen berichten in de INBOX
+	Watching    bool       `json:"watching"`               // Of een IDLE watcher de INBOX bijhoudt
+}
+
+// EmailFolder beschrijft een IMAP map van een account
+type EmailFolder struct {
+	Account    string   `json:"account"`               // Account waar de map bij hoort
+	Name       string   `json:"name"`                  // Volledige naam van de map op de server
+	Delimiter  string   `json:"delimiter"`             // Scheidingsteken voor submappen
+	Attributes []string `json:"attributes"`            // IMAP attributen, waaronder SPECIAL-USE
+	SpecialUse string   `json:"special_use,omitempty"` // sent, archive, trash, junk of drafts
+	Messages   uint32   `json:"messages"`              // Aantal berichten in de map
+	Unseen     uint32   `json:"unseen"`                // Aantal ongelezen berichten
+}
+
+// EmailThread is een gesprek: een email met alle antwoorden en doorgestuurde
+// berichten, uit alle accounts en inclusief onze eigen verzonden antwoorden
+type EmailThread struct {
+	ID            string          `json:"id"`                 // Stabiel ID, afgeleid van het eerste bericht
+	Subject       string          `json:"subject"`            // Onderwerp zonder Re:/Fwd: prefixen
+	Participants  []string        `json:"participants"`       // Email adressen van afzenders en ontvangers
+	Accounts      []string        `json:"accounts"`           // Accounts waarin berichten van het gesprek staan
+	MessageCount  int             `json:"message_count"`      // Aantal berichten in het gesprek
+	UnreadCount   int             `json:"unread_count"`       // Aantal ongelezen berichten
+	LastMessageAt string          `json:"last_message_at"`    // Tijdstip van het laatste bericht in RFC3339 formaat
+	Latest        *EmailSummary   `json:"latest"`             // Het laatste bericht
+	Messages      []*EmailSummary `json:"messages,omitempty"` // Alle berichten op datum, alleen bij een enkel gesprek
 }
 
 // EmailResponse is de gestandaardiseerde response voor email requests
@@ -59,3 +207,27 @@ type EmailResponse struct {
 	Total   int      `json:"total"`           // Totaal aantal beschikbare emails
 	HasMore bool     `json:"has_more"`        // Of er meer emails beschikbaar zijn
 }
+
+// EmailReplyRequest bevat de gegevens voor het beantwoorden of doorsturen van een email
+type EmailReplyRequest struct {
+	Body        string            `json:"body"`        // Platte tekst van het bericht
+	HTML        string            `json:"html"`        // HTML versie van het bericht (optioneel)
+	To          []string          `json:"to"`          // Extra of (bij doorsturen) verplichte ontvangers
+	Cc          []string          `json:"cc"`          // Extra carbon copy ontvangers
+	Bcc         []string          `json:"bcc"`         // Blind carbon copy ontvangers
+	Attachments []EmailAttachment `json:"attachments"` // Bijlagen, content base64 gecodeerd
+}
+
+// EmailSendRequest bevat de gegevens voor het versturen van een nieuwe email
+type EmailSendRequest struct {
+	Account      string                 `json:"account"`       // Account waarvandaan verstuurd wordt (standaard info)
+	To           []string               `json:"to"`            // Ontvangers
+	Cc           []string               `json:"cc"`            // Carbon copy ontvangers
+	Bcc          []string               `json:"bcc"`           // Blind carbon copy ontvangers
+	Subject      string                 `json:"subject"`       // Onderwerp van de email
+	Body         string                 `json:"body"`          // Platte tekst van het bericht
+	HTML         string                 `json:"html"`          // HTML versie van het bericht
+	Template     string                 `json:"template"`      // Optionele template naam, bijv. contact_email.html
+	TemplateData map[string]interface{} `json:"template_data"` // Data voor het renderen van de template
+	Attachments  []EmailAttachment      `json:"attachments"`   // Bijlagen, content base64 gecodeerd
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Doelen waarvoor een account standaard de afzender is
+const (
+	EmailPurposeConfirmation = "confirmation" // Bevestigingen aan inzenders van formulieren
+	EmailPurposeNotification = "notification" // Meldingen aan beheerders
+	EmailPurposeReply        = "reply"        // Nieuwe berichten vanuit het dashboard zonder gekozen account
+)
+
+// IsValidEmailPurpose controleert of het doel een geldig afzenderdoel is
+func IsValidEmailPurpose(purpose string) bool {
+	switch purpose {
+	case EmailPurposeConfirmation, EmailPurposeNotification, EmailPurposeReply:
+		return true
+	}
+	return false
+}
+
+// EmailAccount is een email account dat via de admin API beheerd wordt. Het
+// wachtwoord wordt versleuteld opgeslagen en nooit teruggegeven.
+type EmailAccount struct {
+	ID            string    `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"` // Unieke identifier
+	CreatedAt     time.Time `json:"created_at" gorm:"not null"`                                // Tijdstip van aanmaken
+	UpdatedAt     time.Time `json:"updated_at" gorm:"not null"`                                // Tijdstip van laatste update
+	Name          string    `json:"name" gorm:"not null;uniqueIndex"`                          // Naam van het account in de API, bijv. "info"
+	Email         string    `json:"email" gorm:"not null"`                                     // Email adres, ook de gebruikersnaam voor IMAP en SMTP
+	DisplayName   string    `json:"display_name"`                                              // Naam van de afzender in de From header
+	Password      string    `json:"-" gorm:"column:password_encrypted;serializer:encrypted"`   // Wachtwoord, versleuteld opgeslagen
+	HasPassword   bool      `json:"has_password" gorm:"-"`                                     // Of er een wachtwoord ingesteld is
+	IMAPHost      string    `json:"imap_host" gorm:"column:imap_host;not null"`                // IMAP server
+	IMAPPort      int       `json:"imap_port" gorm:"column:imap_port;not null;default:993"`    // IMAP poort
+	IMAPSecurity  string    `json:"imap_security" gorm:"column:imap_security"`                 // tls, starttls of none; leeg kiest op basis van de poort
+	SMTPHost      string    `json:"smtp_host" gorm:"column:smtp_host;not null"`                // SMTP server
+	SMTPPort      int       `json:"smtp_port" gorm:"column:smtp_port;not null;default:587"`    // SMTP poort
+	TLSSkipVerify bool      `json:"tls_skip_verify" gorm:"not null;default:false"`             // Certificaat niet controleren, alleen voor testen
+	TLSCAFile     string    `json:"tls_ca_file" gorm:"column:tls_ca_file"`                     // PEM bundel met extra vertrouwde CA certificaten
+	DefaultFor    []string  `json:"default_for" gorm:"serializer:json;type:jsonb"`             // Doelen waarvoor dit account de afzender is
+	Enabled       bool      `json:"enabled" gorm:"not null"`                                   // Of het account gebruikt wordt
+}
+
+// TableName override voor GORM
+func (EmailAccount) TableName() string {
+	return "email_accounts"
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Manieren waarop een bounce aan een record gekoppeld is
+const (
+	EmailBounceMatchedByThread    = "thread"    // Message-ID van de bevestigingsmail in de bounce
+	EmailBounceMatchedByRecipient = "recipient" // Laatste record met het onbereikbare adres waarnaar een bevestiging verstuurd is
+)
+
+// EmailBounce is een bevestigingsmail die niet afgeleverd kon worden
+type EmailBounce struct {
+	ID         string    `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`      // Unieke identifier
+	CreatedAt  time.Time `json:"created_at" gorm:"not null"`                                     // Tijdstip van verwerken
+	EmailID    string    `json:"email_id" gorm:"not null;uniqueIndex:idx_email_bounces_unique"`  // ID van de bounce in de inbox
+	Account    string    `json:"account" gorm:"not null"`                                        // Email account waarin de bounce binnenkwam
+	ReceivedAt string    `json:"received_at"`                                                    // Tijdstip van de bounce in RFC3339 formaat
+	Recipient  string    `json:"recipient" gorm:"not null;uniqueIndex:idx_email_bounces_unique"` // Adres dat niet bereikt kon worden
+	Status     string    `json:"status"`                                                         // Enhanced status code, bijv. 5.1.1
+	Reason     string    `json:"reason" gorm:"type:text"`                                        // Reden volgens de ontvangende mailserver
+	MessageID  string    `json:"message_id"`                                                     // Message-ID van de mail die teruggekomen is
+	RecordType string    `json:"record_type" gorm:"index:idx_email_bounces_record"`              // contact of aanmelding, leeg als er geen record gevonden is
+	RecordID   *string   `json:"record_id" gorm:"type:uuid;index:idx_email_bounces_record"`      // ID van het contactformulier of de aanmelding
+	MatchedBy  string    `json:"matched_by"`                                                     // thread of recipient
+}
+
+// TableName override voor GORM
+func (EmailBounce) TableName() string {
+	return "email_bounces"
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Soorten records waaraan een email gekoppeld kan worden
+const (
+	EmailLinkContact    = "contact"
+	EmailLinkAanmelding = "aanmelding"
+)
+
+// Manieren waarop een email aan een record gekoppeld is
+const (
+	EmailLinkMatchedByThread = "thread" // Antwoord op een bevestigingsmail, via In-Reply-To/References
+	EmailLinkMatchedBySender = "sender" // Afzender is het email adres van het record
+)
+
+// EmailLink koppelt een inkomende email aan een contactformulier of aanmelding
+type EmailLink struct {
+	ID         string    `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`                                           // Unieke identifier
+	CreatedAt  time.Time `json:"created_at" gorm:"not null"`                                                                          // Tijdstip van koppelen
+	EmailID    string    `json:"email_id" gorm:"not null;uniqueIndex:idx_email_links_unique"`                                         // Email ID (account:map:uidvalidity:uid)
+	MessageID  string    `json:"message_id" gorm:"index"`                                                                             // Message-ID header, blijft gelijk als de email verplaatst wordt
+	Account    string    `json:"account" gorm:"not null"`                                                                             // Email account waarin de email binnenkwam
+	Sender     string    `json:"sender" gorm:"not null"`                                                                              // Email adres van de verzender
+	Subject    string    `json:"subject"`                                                                                             // Onderwerp van de email
+	ReceivedAt string    `json:"received_at"`                                                                                         // Tijdstip van de email in RFC3339 formaat
+	RecordType string    `json:"record_type" gorm:"not null;uniqueIndex:idx_email_links_unique;index:idx_email_links_record"`         // contact of aanmelding
+	RecordID   string    `json:"record_id" gorm:"type:uuid;not null;uniqueIndex:idx_email_links_unique;index:idx_email_links_record"` // ID van het contactformulier of de aanmelding
+	MatchedBy  string    `json:"matched_by" gorm:"not null"`                                                                          // thread of sender
+}
+
+// TableName override voor GORM
+func (EmailLink) TableName() string {
+	return "email_links"
+}
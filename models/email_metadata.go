@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// Statussen van een email in een gedeelde inbox
+const (
+	EmailStatusOpen    = "open"    // Moet nog opgepakt worden
+	EmailStatusWaiting = "waiting" // Wacht op een reactie van de afzender
+	EmailStatusClosed  = "closed"  // Afgehandeld
+)
+
+// IsValidEmailStatus controleert of de status een geldige email status is
+func IsValidEmailStatus(status string) bool {
+	switch status {
+	case EmailStatusOpen, EmailStatusWaiting, EmailStatusClosed:
+		return true
+	}
+	return false
+}
+
+// EmailMetadata bevat gegevens die het dashboard bij een email bijhoudt en die
+// niet op de IMAP server staan, zoals status, labels en toewijzing. De metadata hoort bij de Message-ID binnen een
+// account, zodat deze behouden blijft als de email verplaatst wordt.
+type EmailMetadata struct {
+	ID             string     `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`          // Unieke identifier
+	CreatedAt      time.Time  `json:"created_at" gorm:"not null"`                                         // Tijdstip van aanmaken
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"not null"`                                         // Tijdstip van laatste update
+	Account        string     `json:"account" gorm:"not null;uniqueIndex:idx_email_metadata_message"`     // Email account
+	MessageKey     string     `json:"message_key" gorm:"not null;uniqueIndex:idx_email_metadata_message"` // Message-ID zonder punthaken, of "email:<id>" zonder Message-ID
+	EmailID        string     `json:"email_id" gorm:"index"`                                              // Laatst bekende email ID
+	Labels         []string   `json:"labels" gorm:"serializer:json;type:jsonb"`                           // Labels van de email
+	AssignedTo     *string    `json:"assigned_to" gorm:"type:uuid"`                                       // Gebruiker aan wie de email toegewezen is
+	Status         string     `json:"status" gorm:"not null;default:open"`                                // open, waiting of closed
+	RulesAppliedAt *time.Time `json:"rules_applied_at"`                                                   // Wanneer de inbox regels op de email toegepast zijn
+}
+
+// NewEmailMetadata maakt lege metadata voor een email die nog geen metadata heeft
+func NewEmailMetadata(account, messageKey, emailID string) *EmailMetadata {
+	return &EmailMetadata{Account: account, MessageKey: messageKey, EmailID: emailID, Status: EmailStatusOpen}
+}
+
+// HasLabel geeft aan of de email het label al heeft
+func (m *EmailMetadata) HasLabel(label string) bool {
+	for _, existing := range m.Labels {
+		if existing == label {
+			return true
+		}
+	}
+	return false
+}
+
+// TableName override voor GORM
+func (EmailMetadata) TableName() string {
+	return "email_metadata"
+}
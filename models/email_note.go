@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// EmailNote is een interne notitie bij een email, alleen zichtbaar voor
+// beheerders. Net als EmailMetadata hoort een notitie bij de Message-ID binnen
+// een account, zodat deze bij de email blijft als die verplaatst wordt.
+type EmailNote struct {
+	ID         string    `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"` // Unieke identifier
+	CreatedAt  time.Time `json:"created_at" gorm:"not null"`                                // Tijdstip van aanmaken
+	Account    string    `json:"account" gorm:"not null;index:idx_email_notes_message"`     // Email account
+	MessageKey string    `json:"message_key" gorm:"not null;index:idx_email_notes_message"` // Zie EmailMetadata.MessageKey
+	EmailID    string    `json:"email_id"`                                                  // Email ID op het moment van schrijven
+	UserID     string    `json:"user_id" gorm:"type:uuid;not null"`                         // Schrijver van de notitie
+	Author     string    `json:"author"`                                                    // Email adres van de schrijver
+	Body       string    `json:"body" gorm:"type:text;not null"`                            // Inhoud van de notitie
+}
+
+// TableName override voor GORM
+func (EmailNote) TableName() string {
+	return "email_notes"
+}
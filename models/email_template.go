@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// EmailTemplate is een versie van een email template in de database. Per naam
+// is hooguit één versie actief; zonder actieve versie wordt het bestand uit
+// templates/ met dezelfde naam gebruikt.
+type EmailTemplate struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"`            // Unieke identifier
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`                                           // Tijdstip van aanmaken
+	Name      string    `json:"name" gorm:"not null;uniqueIndex:idx_email_templates_name_version"`    // Naam van het template, bijv. "aanmelding_email.html"
+	Version   int       `json:"version" gorm:"not null;uniqueIndex:idx_email_templates_name_version"` // Versienummer, oplopend per naam
+	Subject   string    `json:"subject"`                                                              // Onderwerp (text/template); leeg gebruikt het standaard onderwerp
+	Body      string    `json:"body" gorm:"type:text;not null"`                                       // HTML body (html/template)
+	Active    bool      `json:"active" gorm:"not null;default:false"`                                 // Of deze versie gebruikt wordt
+	Comment   string    `json:"comment"`                                                              // Toelichting bij de wijziging
+	CreatedBy string    `json:"created_by"`                                                           // Email adres van de beheerder die de versie gemaakt heeft
+}
+
+// TableName override voor GORM
+func (EmailTemplate) TableName() string {
+	return "email_templates"
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Resultaten van het verwerken van een formuliermelding
+const (
+	FormIntakeProcessed = "processed" // Record aangemaakt
+	FormIntakeDuplicate = "duplicate" // Record bestond al, niets aangemaakt
+	FormIntakeUnparsed  = "unparsed"  // Melding herkend, maar verplichte velden ontbreken
+)
+
+// FormIntakeResult legt vast wat er met een formuliermelding uit de mailbox gebeurd is
+type FormIntakeResult struct {
+	ID         string            `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"` // Unieke identifier
+	CreatedAt  time.Time         `json:"created_at" gorm:"not null"`                                // Tijdstip van eerste verwerking
+	UpdatedAt  time.Time         `json:"updated_at" gorm:"not null"`                                // Tijdstip van laatste verwerking
+	EmailID    string            `json:"email_id" gorm:"not null;uniqueIndex"`                      // Email ID (account:map:uidvalidity:uid)
+	MessageID  string            `json:"message_id" gorm:"index"`                                   // Message-ID header, om dubbele verwerking te voorkomen
+	Account    string            `json:"account" gorm:"not null"`                                   // Email account waarin de melding binnenkwam
+	Sender     string            `json:"sender"`                                                    // Email adres van de verzender
+	Subject    string            `json:"subject"`                                                   // Onderwerp van de melding
+	ReceivedAt string            `json:"received_at"`                                               // Tijdstip van de melding in RFC3339 formaat
+	Rule       string            `json:"rule" gorm:"not null"`                                      // Naam van de regel die de melding herkende
+	Status     string            `json:"status" gorm:"not null;index"`                              // processed, duplicate of unparsed
+	RecordType string            `json:"record_type" gorm:"not null"`                               // aanmelding of contact
+	RecordID   *string           `json:"record_id" gorm:"type:uuid"`                                // Aangemaakt of bestaand record
+	Fields     map[string]string `json:"fields" gorm:"type:text;serializer:encrypted"`              // Gevonden labels en waarden, om regels te kunnen verbeteren, versleuteld opgeslagen
+	Missing    string            `json:"missing,omitempty"`                                         // Ontbrekende verplichte velden, komma gescheiden
+	Error      string            `json:"error,omitempty"`                                           // Fout bij het opslaan van het record
+}
+
+// TableName override voor GORM
+func (FormIntakeResult) TableName() string {
+	return "form_intake_results"
+}
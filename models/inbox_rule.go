@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// InboxRule is een regel die op elke nieuwe email in een INBOX toegepast wordt.
+// Alle opgegeven condities moeten gelden; lege condities tellen niet mee.
+type InboxRule struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"` // Unieke identifier
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`                                // Tijdstip van aanmaken
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`                                // Tijdstip van laatste update
+	Name      string    `json:"name" gorm:"not null" binding:"required"`                   // Naam van de regel
+	Enabled   bool      `json:"enabled" gorm:"not null;default:true"`                      // Of de regel actief is
+	Priority  int       `json:"priority" gorm:"not null;default:0"`                        // Volgorde van toepassen, laagste eerst
+	StopAfter bool      `json:"stop_after" gorm:"not null;default:false"`                  // Geen volgende regels toepassen als deze regel voldoet
+
+	// Condities
+	Account         string   `json:"account"`                                         // Account (leeg = alle accounts)
+	SenderContains  string   `json:"sender_contains"`                                 // Deel van het adres van de afzender
+	SubjectContains string   `json:"subject_contains"`                                // Deel van het onderwerp
+	BodyKeywords    []string `json:"body_keywords" gorm:"serializer:json;type:jsonb"` // Minstens één van deze woorden in de tekst
+	AttachmentType  string   `json:"attachment_type"`                                 // MIME type ("application/pdf", "image/") of extensie (".pdf") van een bijlage
+
+	// Acties
+	Label             string  `json:"label"`                                   // Label dat aan de email toegevoegd wordt
+	AssignTo          *string `json:"assign_to" gorm:"type:uuid"`              // Gebruiker aan wie de email toegewezen wordt
+	MarkRead          bool    `json:"mark_read" gorm:"not null;default:false"` // Markeer de email als gelezen
+	MoveTo            string  `json:"move_to"`                                 // Map (of alias als "archive") waarheen de email verplaatst wordt
+	AutoReplyTemplate string  `json:"auto_reply_template"`                     // Template voor een automatisch antwoord
+	AutoReplySubject  string  `json:"auto_reply_subject"`                      // Onderwerp van het automatische antwoord (standaard "Re: <onderwerp>")
+}
+
+// HasActions geeft aan of de regel iets doet
+func (r *InboxRule) HasActions() bool {
+	return r.Label != "" || r.AssignTo != nil || r.MarkRead || r.MoveTo != "" || r.AutoReplyTemplate != ""
+}
+
+// TableName override voor GORM
+func (InboxRule) TableName() string {
+	return "inbox_rules"
+}
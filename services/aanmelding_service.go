@@ -4,6 +4,7 @@ import (
 	"dklautomationgo/database/repository"
 	"dklautomationgo/models"
 	"dklautomationgo/services/email"
+	"dklautomationgo/services/events"
 	"fmt"
 	"time"
 )
@@ -27,13 +28,15 @@ var _ IAanmeldingService = (*AanmeldingService)(nil)
 type AanmeldingService struct {
 	repo         repository.IAanmeldingRepository
 	emailService email.IEmailService
+	publisher    events.Publisher
 }
 
 // NewAanmeldingService maakt een nieuwe AanmeldingService
-func NewAanmeldingService(repo repository.IAanmeldingRepository, emailService email.IEmailService) *AanmeldingService {
+func NewAanmeldingService(repo repository.IAanmeldingRepository, emailService email.IEmailService, publisher events.Publisher) *AanmeldingService {
 	return &AanmeldingService{
 		repo:         repo,
 		emailService: emailService,
+		publisher:    publisher,
 	}
 }
 
@@ -44,6 +47,11 @@ func (s *AanmeldingService) CreateAanmelding(aanmelding *models.Aanmelding) erro
 		return fmt.Errorf("fout bij opslaan aanmelding: %w", err)
 	}
 
+	// Meld de nieuwe aanmelding aan het dashboard
+	if s.publisher != nil {
+		s.publisher.Publish(events.EventAanmeldingCreated, aanmelding)
+	}
+
 	// Stuur bevestigingsmail
 	if err := s.SendBevestigingsEmail(aanmelding); err != nil {
 		return fmt.Errorf("fout bij versturen bevestigingsmail: %w", err)
@@ -145,12 +153,14 @@ func (s *AanmeldingService) SendBevestigingsEmail(aanmelding *models.Aanmelding)
 		Ondersteuning:  aanmelding.Ondersteuning,
 		Bijzonderheden: aanmelding.Bijzonderheden,
 		Terms:          aanmelding.Terms,
+		Locale:         aanmelding.Locale,
 	}
 
 	// Maak email data
 	emailData := &models.AanmeldingEmailData{
-		Aanmelding: formulier,
-		ToAdmin:    false,
+		Aanmelding:   formulier,
+		ToAdmin:      false,
+		AanmeldingID: aanmelding.ID,
 	}
 
 	// Stuur email
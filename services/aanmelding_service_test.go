@@ -4,9 +4,11 @@ import (
 	"dklautomationgo/database/repository"
 	"dklautomationgo/models"
 	"dklautomationgo/services"
+	"dklautomationgo/services/events"
 	"dklautomationgo/tests/fixtures"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -23,6 +25,12 @@ func (m *MockAanmeldingRepository) Create(aanmelding *models.Aanmelding) error {
 	return args.Error(0)
 }
 
+// CreateFromSource is een mock implementatie van de CreateFromSource methode
+func (m *MockAanmeldingRepository) CreateFromSource(aanmelding *models.Aanmelding) (bool, error) {
+	args := m.Called(aanmelding)
+	return args.Bool(0), args.Error(1)
+}
+
 // FindAll is een mock implementatie van de FindAll methode
 func (m *MockAanmeldingRepository) FindAll(limit, offset int) ([]*models.Aanmelding, error) {
 	args := m.Called(limit, offset)
@@ -41,12 +49,27 @@ func (m *MockAanmeldingRepository) FindByID(id string) (*models.Aanmelding, erro
 	return args.Get(0).(*models.Aanmelding), args.Error(1)
 }
 
+// FindByEmail is een mock implementatie van de FindByEmail methode
+func (m *MockAanmeldingRepository) FindByEmail(email string) ([]*models.Aanmelding, error) {
+	args := m.Called(email)
+	if args.Get(0) == nil {
+		return []*models.Aanmelding{}, args.Error(1)
+	}
+	return args.Get(0).([]*models.Aanmelding), args.Error(1)
+}
+
 // Update is een mock implementatie van de Update methode
 func (m *MockAanmeldingRepository) Update(aanmelding *models.Aanmelding) error {
 	args := m.Called(aanmelding)
 	return args.Error(0)
 }
 
+// MarkBounced is een mock implementatie van de MarkBounced methode
+func (m *MockAanmeldingRepository) MarkBounced(id string, bouncedAt time.Time, reason string) error {
+	args := m.Called(id, bouncedAt, reason)
+	return args.Error(0)
+}
+
 // Count is een mock implementatie van de Count methode
 func (m *MockAanmeldingRepository) Count() (int64, error) {
 	args := m.Called()
@@ -70,12 +93,22 @@ func (m *MockEmailService) SendContactEmail(data *models.ContactEmailData) error
 	return args.Error(0)
 }
 
+// MockPublisher is een mock implementatie van de events.Publisher interface
+type MockPublisher struct {
+	mock.Mock
+}
+
+// Publish is een mock implementatie van de Publish methode
+func (m *MockPublisher) Publish(eventType events.EventType, data interface{}) {
+	m.Called(eventType, data)
+}
+
 func setupAanmeldingServiceTest() (*services.AanmeldingService, *MockAanmeldingRepository, *MockEmailService) {
 	mockRepo := new(MockAanmeldingRepository)
 	mockEmailService := new(MockEmailService)
 
 	// Gebruik de interfaces in plaats van concrete types
-	service := services.NewAanmeldingService(mockRepo, mockEmailService)
+	service := services.NewAanmeldingService(mockRepo, mockEmailService, nil)
 
 	return service, mockRepo, mockEmailService
 }
@@ -99,6 +132,28 @@ func TestCreateAanmelding_Success(t *testing.T) {
 	mockEmailService.AssertExpectations(t)
 }
 
+func TestCreateAanmelding_PublishesEvent(t *testing.T) {
+	// Setup
+	mockRepo := new(MockAanmeldingRepository)
+	mockEmailService := new(MockEmailService)
+	mockPublisher := new(MockPublisher)
+	service := services.NewAanmeldingService(mockRepo, mockEmailService, mockPublisher)
+	testAanmelding := fixtures.GetTestAanmelding()
+
+	// Mock verwachtingen
+	mockRepo.On("Create", testAanmelding).Return(nil)
+	mockPublisher.On("Publish", events.EventAanmeldingCreated, testAanmelding).Return()
+	mockEmailService.On("SendAanmeldingEmail", mock.AnythingOfType("*models.AanmeldingEmailData")).Return(nil)
+	mockRepo.On("Update", testAanmelding).Return(nil)
+
+	// Voer de test uit
+	err := service.CreateAanmelding(testAanmelding)
+
+	// Controleer het resultaat
+	assert.NoError(t, err)
+	mockPublisher.AssertExpectations(t)
+}
+
 func TestCreateAanmelding_RepositoryError(t *testing.T) {
 	// Setup
 	service, mockRepo, _ := setupAanmeldingServiceTest()
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"dklautomationgo/database/repository"
+	"dklautomationgo/models"
+	"dklautomationgo/services/email"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// IBounceService definieert de interface voor het verwerken van onbestelbare bevestigingsmails
+type IBounceService interface {
+	ProcessEmail(msg *models.Email) (*models.EmailBounce, error)
+	GetBounces(limit, offset int) ([]*models.EmailBounce, int64, error)
+}
+
+// Controleer of BounceService de IBounceService en IncomingEmailHandler interfaces implementeert
+var (
+	_ IBounceService             = (*BounceService)(nil)
+	_ email.IncomingEmailHandler = (*BounceService)(nil)
+)
+
+// BounceService herkent bounces in de inbox en markeert de contactformulieren
+// en aanmeldingen waarvan de bevestigingsmail niet afgeleverd kon worden
+type BounceService struct {
+	bounces      repository.IEmailBounceRepository
+	contacts     repository.IContactRepository
+	aanmeldingen repository.IAanmeldingRepository
+}
+
+// NewBounceService maakt een nieuwe BounceService
+func NewBounceService(bounces repository.IEmailBounceRepository, contacts repository.IContactRepository, aanmeldingen repository.IAanmeldingRepository) *BounceService {
+	return &BounceService{
+		bounces:      bounces,
+		contacts:     contacts,
+		aanmeldingen: aanmeldingen,
+	}
+}
+
+// HandleIncomingEmail verwerkt een nieuwe email uit de INBOX als het een bounce is
+func (s *BounceService) HandleIncomingEmail(msg *models.Email) {
+	bounce, err := s.ProcessEmail(msg)
+	if err != nil {
+		log.Printf("[BounceService] Failed to process email %s: %v", msg.ID, err)
+		return
+	}
+	if bounce != nil {
+		log.Printf("[BounceService] Email to %s bounced (%s), record %s %v", bounce.Recipient, bounce.Reason, bounce.RecordType, bounce.RecordID)
+	}
+}
+
+// ProcessEmail controleert of een email een bounce is, zoekt het contactformulier
+// of de aanmelding waarvan de bevestigingsmail teruggekomen is en markeert dat
+// record. De Message-ID van de bevestigingsmail wijst het record precies aan;
+// ontbreekt die, dan wordt het laatste record met het onbereikbare adres
+// genomen waarnaar een bevestiging verstuurd is. Geeft nil terug als de email
+// geen bounce is.
+func (s *BounceService) ProcessEmail(msg *models.Email) (*models.EmailBounce, error) {
+	parsed, ok := email.ParseBounce(msg)
+	if !ok {
+		return nil, nil
+	}
+
+	bouncedAt := time.Now()
+	if receivedAt, err := time.Parse(time.RFC3339, msg.CreatedAt); err == nil {
+		bouncedAt = receivedAt
+	}
+	bounce := &models.EmailBounce{
+		CreatedAt:  time.Now(),
+		EmailID:    msg.ID,
+		Account:    msg.Account,
+		ReceivedAt: msg.CreatedAt,
+		Recipient:  strings.ToLower(strings.TrimSpace(parsed.Recipient)),
+		Status:     parsed.Status,
+		Reason:     parsed.Reason(),
+		MessageID:  parsed.OriginalMessageID,
+	}
+
+	if recordType, recordID, ok := email.ParseRecordMessageID(parsed.OriginalMessageID); ok {
+		recipient, err := s.markBounced(recordType, recordID, bouncedAt, bounce.Reason)
+		if err != nil {
+			return nil, err
+		}
+		if bounce.Recipient == "" {
+			bounce.Recipient = strings.ToLower(recipient)
+		}
+		bounce.RecordType, bounce.RecordID, bounce.MatchedBy = recordType, &recordID, models.EmailBounceMatchedByThread
+	} else if bounce.Recipient != "" {
+		recordType, recordID, err := s.findByRecipient(bounce.Recipient)
+		if err != nil {
+			return nil, err
+		}
+		if recordID != "" {
+			if _, err := s.markBounced(recordType, recordID, bouncedAt, bounce.Reason); err != nil {
+				return nil, err
+			}
+			bounce.RecordType, bounce.RecordID, bounce.MatchedBy = recordType, &recordID, models.EmailBounceMatchedByRecipient
+		}
+	}
+
+	if bounce.Recipient == "" {
+		// Zonder adres en zonder eigen record is er niets om aan beheerders te tonen
+		return nil, nil
+	}
+	if err := s.bounces.Create(bounce); err != nil {
+		return nil, fmt.Errorf("fout bij opslaan bounce: %w", err)
+	}
+	return bounce, nil
+}
+
+// markBounced markeert een contactformulier of aanmelding als onbestelbaar en
+// geeft het email adres van het record terug
+func (s *BounceService) markBounced(recordType, recordID string, bouncedAt time.Time, reason string) (string, error) {
+	switch recordType {
+	case models.EmailLinkContact:
+		contact, err := s.contacts.FindByID(recordID)
+		if err != nil {
+			return "", fmt.Errorf("fout bij ophalen contactformulier %s: %w", recordID, err)
+		}
+		if err := s.contacts.MarkBounced(recordID, bouncedAt, reason); err != nil {
+			return "", fmt.Errorf("fout bij bijwerken contactformulier %s: %w", recordID, err)
+		}
+		return contact.Email, nil
+	case models.EmailLinkAanmelding:
+		aanmelding, err := s.aanmeldingen.FindByID(recordID)
+		if err != nil {
+			return "", fmt.Errorf("fout bij ophalen aanmelding %s: %w", recordID, err)
+		}
+		if err := s.aanmeldingen.MarkBounced(recordID, bouncedAt, reason); err != nil {
+			return "", fmt.Errorf("fout bij bijwerken aanmelding %s: %w", recordID, err)
+		}
+		return aanmelding.Email, nil
+	default:
+		return "", fmt.Errorf("onbekend record type %q", recordType)
+	}
+}
+
+// findByRecipient zoekt het contactformulier of de aanmelding waarnaar het
+// laatst een bevestiging verstuurd is op het onbereikbare adres
+func (s *BounceService) findByRecipient(recipient string) (string, string, error) {
+	var recordType, recordID string
+	var latest time.Time
+	consider := func(kind, id string, sentAt *time.Time, createdAt time.Time) {
+		at := createdAt
+		if sentAt != nil {
+			at = *sentAt
+		}
+		if recordID == "" || at.After(latest) {
+			recordType, recordID, latest = kind, id, at
+		}
+	}
+
+	contacts, err := s.contacts.FindByEmail(recipient)
+	if err != nil {
+		return "", "", fmt.Errorf("fout bij zoeken contactformulieren: %w", err)
+	}
+	for _, contact := range contacts {
+		if contact.EmailVerzonden {
+			consider(models.EmailLinkContact, contact.ID, contact.EmailVerzondenOp, contact.CreatedAt)
+		}
+	}
+
+	aanmeldingen, err := s.aanmeldingen.FindByEmail(recipient)
+	if err != nil {
+		return "", "", fmt.Errorf("fout bij zoeken aanmeldingen: %w", err)
+	}
+	for _, aanmelding := range aanmeldingen {
+		if aanmelding.EmailVerzonden {
+			consider(models.EmailLinkAanmelding, aanmelding.ID, aanmelding.EmailVerzondOp, aanmelding.CreatedAt)
+		}
+	}
+	return recordType, recordID, nil
+}
+
+// GetBounces haalt de onbestelbare bevestigingsmails op, nieuwste eerst
+func (s *BounceService) GetBounces(limit, offset int) ([]*models.EmailBounce, int64, error) {
+	bounces, err := s.bounces.FindAll(limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("fout bij ophalen bounces: %w", err)
+	}
+	total, err := s.bounces.Count()
+	if err != nil {
+		return nil, 0, fmt.Errorf("fout bij tellen bounces: %w", err)
+	}
+	return bounces, total, nil
+}
@@ -0,0 +1,180 @@
+package services_test
+
+import (
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockEmailBounceRepository is een mock implementatie van de IEmailBounceRepository interface
+type MockEmailBounceRepository struct {
+	mock.Mock
+}
+
+// Create is een mock implementatie van de Create methode
+func (m *MockEmailBounceRepository) Create(bounce *models.EmailBounce) error {
+	args := m.Called(bounce)
+	return args.Error(0)
+}
+
+// FindAll is een mock implementatie van de FindAll methode
+func (m *MockEmailBounceRepository) FindAll(limit, offset int) ([]*models.EmailBounce, error) {
+	args := m.Called(limit, offset)
+	if args.Get(0) == nil {
+		return []*models.EmailBounce{}, args.Error(1)
+	}
+	return args.Get(0).([]*models.EmailBounce), args.Error(1)
+}
+
+// Count is een mock implementatie van de Count methode
+func (m *MockEmailBounceRepository) Count() (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// setupBounceServiceTest maakt een BounceService met mock repositories
+func setupBounceServiceTest() (*services.BounceService, *MockEmailBounceRepository, *MockContactRepository, *MockAanmeldingRepository) {
+	bounceRepo := new(MockEmailBounceRepository)
+	contactRepo := new(MockContactRepository)
+	aanmeldingRepo := new(MockAanmeldingRepository)
+	service := services.NewBounceService(bounceRepo, contactRepo, aanmeldingRepo)
+	return service, bounceRepo, contactRepo, aanmeldingRepo
+}
+
+// newBounceEmail maakt een bounce van Postfix voor het opgegeven adres
+func newBounceEmail(recipient, originalMessageID string) *models.Email {
+	headers := "From: noreply@dekoninklijkeloop.nl\n"
+	if originalMessageID != "" {
+		headers += "Message-ID: " + originalMessageID + "\n"
+	}
+	return &models.Email{
+		ID:        "info:INBOX:1:50",
+		Account:   "info",
+		Sender:    "MAILER-DAEMON@mail.example.org",
+		Subject:   "Undelivered Mail Returned to Sender",
+		CreatedAt: "2026-10-19T10:00:00Z",
+		Attachments: []models.EmailAttachment{
+			{
+				ContentType: "message/delivery-status",
+				Content:     []byte("Reporting-MTA: dns; mail.example.org\n\nFinal-Recipient: rfc822; " + recipient + "\nAction: failed\nStatus: 5.1.1\nDiagnostic-Code: smtp; 550 User unknown\n"),
+			},
+			{ContentType: "text/rfc822-headers", Content: []byte(headers)},
+		},
+	}
+}
+
+func TestProcessBounce_ThreadMatch(t *testing.T) {
+	// Setup
+	service, bounceRepo, _, aanmeldingRepo := setupBounceServiceTest()
+	incoming := newBounceEmail("jan@exmaple.org", "<dkl.aanmelding.22222222-3333-4444-5555-666666666666.1700000000@dekoninklijkeloop.nl>")
+	aanmelding := &models.Aanmelding{ID: "22222222-3333-4444-5555-666666666666", Email: "jan@exmaple.org", EmailVerzonden: true}
+
+	// Mock verwachtingen
+	aanmeldingRepo.On("FindByID", "22222222-3333-4444-5555-666666666666").Return(aanmelding, nil)
+	aanmeldingRepo.On("MarkBounced", "22222222-3333-4444-5555-666666666666", time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC), "5.1.1: 550 User unknown").Return(nil).Once()
+	bounceRepo.On("Create", mock.AnythingOfType("*models.EmailBounce")).Return(nil).Once()
+
+	// Voer de test uit
+	bounce, err := service.ProcessEmail(incoming)
+
+	// Controleer het resultaat
+	assert.NoError(t, err)
+	if assert.NotNil(t, bounce) {
+		assert.Equal(t, "jan@exmaple.org", bounce.Recipient)
+		assert.Equal(t, models.EmailLinkAanmelding, bounce.RecordType)
+		assert.Equal(t, "22222222-3333-4444-5555-666666666666", *bounce.RecordID)
+		assert.Equal(t, models.EmailBounceMatchedByThread, bounce.MatchedBy)
+		assert.Equal(t, "5.1.1: 550 User unknown", bounce.Reason)
+	}
+	aanmeldingRepo.AssertNotCalled(t, "Update", mock.Anything)
+	aanmeldingRepo.AssertExpectations(t)
+	bounceRepo.AssertExpectations(t)
+}
+
+func TestProcessBounce_RecipientMatch(t *testing.T) {
+	// Setup
+	service, bounceRepo, contactRepo, aanmeldingRepo := setupBounceServiceTest()
+	incoming := newBounceEmail("Jan@Exmaple.org", "")
+	earlier := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	later := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	contact := &models.ContactFormulier{ID: "contact-1", Email: "jan@exmaple.org", EmailVerzonden: true, EmailVerzondenOp: &later}
+
+	// Mock verwachtingen: de oudere aanmelding en het record zonder bevestiging worden overgeslagen
+	contactRepo.On("FindByEmail", "jan@exmaple.org").Return([]*models.ContactFormulier{contact, {ID: "contact-2"}}, nil)
+	aanmeldingRepo.On("FindByEmail", "jan@exmaple.org").Return([]*models.Aanmelding{{ID: "22222222-3333-4444-5555-666666666666", EmailVerzonden: true, EmailVerzondOp: &earlier}}, nil)
+	contactRepo.On("FindByID", "contact-1").Return(contact, nil)
+	contactRepo.On("MarkBounced", "contact-1", mock.AnythingOfType("time.Time"), "5.1.1: 550 User unknown").Return(nil).Once()
+	bounceRepo.On("Create", mock.AnythingOfType("*models.EmailBounce")).Return(nil).Once()
+
+	// Voer de test uit
+	bounce, err := service.ProcessEmail(incoming)
+
+	// Controleer het resultaat
+	assert.NoError(t, err)
+	if assert.NotNil(t, bounce) {
+		assert.Equal(t, models.EmailLinkContact, bounce.RecordType)
+		assert.Equal(t, "contact-1", *bounce.RecordID)
+		assert.Equal(t, models.EmailBounceMatchedByRecipient, bounce.MatchedBy)
+	}
+	aanmeldingRepo.AssertNotCalled(t, "MarkBounced", mock.Anything, mock.Anything, mock.Anything)
+	contactRepo.AssertExpectations(t)
+}
+
+func TestProcessBounce_UnknownRecipient(t *testing.T) {
+	// Setup
+	service, bounceRepo, contactRepo, aanmeldingRepo := setupBounceServiceTest()
+	incoming := newBounceEmail("onbekend@example.org", "")
+
+	// Mock verwachtingen: de bounce wordt zonder record opgeslagen
+	contactRepo.On("FindByEmail", "onbekend@example.org").Return(nil, nil)
+	aanmeldingRepo.On("FindByEmail", "onbekend@example.org").Return(nil, nil)
+	bounceRepo.On("Create", mock.AnythingOfType("*models.EmailBounce")).Return(nil).Once()
+
+	// Voer de test uit
+	bounce, err := service.ProcessEmail(incoming)
+
+	// Controleer het resultaat
+	assert.NoError(t, err)
+	if assert.NotNil(t, bounce) {
+		assert.Empty(t, bounce.RecordType)
+		assert.Nil(t, bounce.RecordID)
+	}
+	bounceRepo.AssertExpectations(t)
+}
+
+func TestProcessBounce_NotABounce(t *testing.T) {
+	// Setup
+	service, bounceRepo, contactRepo, _ := setupBounceServiceTest()
+	incoming := &models.Email{ID: "info:INBOX:1:51", Sender: "jan@example.org", Subject: "Vraag"}
+
+	// Voer de test uit
+	bounce, err := service.ProcessEmail(incoming)
+
+	// Controleer het resultaat
+	assert.NoError(t, err)
+	assert.Nil(t, bounce)
+	contactRepo.AssertNotCalled(t, "FindByEmail", mock.Anything)
+	bounceRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestProcessBounce_RepositoryError(t *testing.T) {
+	// Setup
+	service, bounceRepo, _, aanmeldingRepo := setupBounceServiceTest()
+	incoming := newBounceEmail("jan@exmaple.org", "<dkl.aanmelding.22222222-3333-4444-5555-666666666666.1700000000@dekoninklijkeloop.nl>")
+
+	// Mock verwachtingen
+	aanmeldingRepo.On("FindByID", "22222222-3333-4444-5555-666666666666").Return(nil, errors.New("database error"))
+
+	// Voer de test uit
+	bounce, err := service.ProcessEmail(incoming)
+
+	// Controleer het resultaat
+	assert.Error(t, err)
+	assert.Nil(t, bounce)
+	bounceRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
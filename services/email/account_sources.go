@@ -0,0 +1,203 @@
+package email
+
+import (
+	"bytes"
+	"dklautomationgo/models"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AccountSource levert de configuratie van de email accounts, op naam
+type AccountSource interface {
+	LoadAccounts() (map[string]*EmailConfig, error)
+}
+
+// EnvAccountSource levert de vaste accounts uit de omgevingsvariabelen, zie EnvAccounts
+type EnvAccountSource struct{}
+
+var _ AccountSource = EnvAccountSource{}
+
+// LoadAccounts implementeert AccountSource
+func (EnvAccountSource) LoadAccounts() (map[string]*EmailConfig, error) {
+	return EnvAccounts(), nil
+}
+
+// AccountFile is het formaat van een YAML of JSON bestand met accounts.
+// Instellingen in Defaults gelden voor elk account dat ze zelf niet opgeeft.
+type AccountFile struct {
+	Defaults AccountFileEntry            `yaml:"defaults" json:"defaults"`
+	Accounts map[string]AccountFileEntry `yaml:"accounts" json:"accounts"`
+}
+
+// AccountFileEntry is één account in een AccountFile. Het wachtwoord staat bij
+// voorkeur in een omgevingsvariabele (password_env) en niet in het bestand zelf.
+type AccountFileEntry struct {
+	Email         string   `yaml:"email" json:"email"`
+	DisplayName   string   `yaml:"display_name" json:"display_name"`
+	Password      string   `yaml:"password" json:"password"`
+	PasswordEnv   string   `yaml:"password_env" json:"password_env"`
+	IMAPHost      string   `yaml:"imap_host" json:"imap_host"`
+	IMAPPort      int      `yaml:"imap_port" json:"imap_port"`
+	IMAPSecurity  string   `yaml:"imap_security" json:"imap_security"`
+	SMTPHost      string   `yaml:"smtp_host" json:"smtp_host"`
+	SMTPPort      int      `yaml:"smtp_port" json:"smtp_port"`
+	TLSSkipVerify bool     `yaml:"tls_skip_verify" json:"tls_skip_verify"`
+	TLSCAFile     string   `yaml:"tls_ca_file" json:"tls_ca_file"`
+	DefaultFor    []string `yaml:"default_for" json:"default_for"`
+}
+
+// FileAccountSource leest de accounts uit een YAML (.yml, .yaml) of JSON bestand.
+// Het bestand wordt bij elke LoadAccounts opnieuw gelezen.
+type FileAccountSource struct {
+	path string
+}
+
+var _ AccountSource = (*FileAccountSource)(nil)
+
+// NewFileAccountSource maakt een FileAccountSource voor het gegeven bestand
+func NewFileAccountSource(path string) *FileAccountSource {
+	return &FileAccountSource{path: path}
+}
+
+// LoadAccounts implementeert AccountSource
+func (f *FileAccountSource) LoadAccounts() (map[string]*EmailConfig, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read accounts file: %w", err)
+	}
+
+	file, err := ParseAccountFile(data, filepath.Ext(f.path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", f.path, err)
+	}
+	return file.Configs()
+}
+
+// ParseAccountFile leest een AccountFile; ext bepaalt het formaat (".json" of YAML).
+// Onbekende velden geven een fout, zodat een typefout niet stilletjes genegeerd wordt.
+func ParseAccountFile(data []byte, ext string) (*AccountFile, error) {
+	var file AccountFile
+	if strings.EqualFold(ext, ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&file); err != nil {
+			return nil, err
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&file); err != nil {
+			return nil, err
+		}
+	}
+	return &file, nil
+}
+
+// Configs zet de accounts uit het bestand om naar EmailConfigs, aangevuld met de defaults
+func (f *AccountFile) Configs() (map[string]*EmailConfig, error) {
+	accounts := make(map[string]*EmailConfig, len(f.Accounts))
+	for name, entry := range f.Accounts {
+		password := entry.Password
+		if entry.PasswordEnv != "" {
+			password = os.Getenv(entry.PasswordEnv)
+			if password == "" {
+				return nil, fmt.Errorf("account %s: environment variable %s is not set", name, entry.PasswordEnv)
+			}
+		}
+
+		accounts[name] = &EmailConfig{
+			Email:         entry.Email,
+			Password:      password,
+			IMAPHost:      firstNonEmpty(entry.IMAPHost, f.Defaults.IMAPHost),
+			IMAPPort:      firstNonZero(entry.IMAPPort, f.Defaults.IMAPPort),
+			IMAPSecurity:  firstNonEmpty(entry.IMAPSecurity, f.Defaults.IMAPSecurity),
+			SMTPHost:      firstNonEmpty(entry.SMTPHost, f.Defaults.SMTPHost),
+			SMTPPort:      firstNonZero(entry.SMTPPort, f.Defaults.SMTPPort),
+			TLSSkipVerify: entry.TLSSkipVerify || f.Defaults.TLSSkipVerify,
+			TLSCAFile:     firstNonEmpty(entry.TLSCAFile, f.Defaults.TLSCAFile),
+			DisplayName:   firstNonEmpty(entry.DisplayName, f.Defaults.DisplayName),
+			DefaultFor:    entry.DefaultFor,
+		}
+	}
+	return accounts, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func firstNonZero(values ...int) int {
+	for _, value := range values {
+		if value != 0 {
+			return value
+		}
+	}
+	return 0
+}
+
+// EmailAccountStore is de opslag van de accounts die via de admin API beheerd worden
+type EmailAccountStore interface {
+	FindEnabled() ([]*models.EmailAccount, error)
+}
+
+// DatabaseAccountSource leest de actieve accounts uit de database. Zolang daar
+// geen accounts staan worden de accounts uit de omgevingsvariabelen gebruikt.
+// De wachtwoorden worden bij het lezen ontsleuteld door de "encrypted" serializer.
+type DatabaseAccountSource struct {
+	store    EmailAccountStore
+	fallback AccountSource
+}
+
+var _ AccountSource = (*DatabaseAccountSource)(nil)
+
+// NewDatabaseAccountSource maakt een DatabaseAccountSource
+func NewDatabaseAccountSource(store EmailAccountStore) *DatabaseAccountSource {
+	return &DatabaseAccountSource{
+		store:    store,
+		fallback: EnvAccountSource{},
+	}
+}
+
+// LoadAccounts implementeert AccountSource
+func (d *DatabaseAccountSource) LoadAccounts() (map[string]*EmailConfig, error) {
+	rows, err := d.store.FindEnabled()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return d.fallback.LoadAccounts()
+	}
+
+	accounts := make(map[string]*EmailConfig, len(rows))
+	for _, row := range rows {
+		accounts[row.Name] = AccountConfig(row)
+	}
+	return accounts, nil
+}
+
+// AccountConfig zet een account uit de database om naar een EmailConfig
+func AccountConfig(account *models.EmailAccount) *EmailConfig {
+	return &EmailConfig{
+		Email:         account.Email,
+		Password:      account.Password,
+		IMAPHost:      account.IMAPHost,
+		IMAPPort:      account.IMAPPort,
+		IMAPSecurity:  account.IMAPSecurity,
+		SMTPHost:      account.SMTPHost,
+		SMTPPort:      account.SMTPPort,
+		TLSSkipVerify: account.TLSSkipVerify,
+		TLSCAFile:     account.TLSCAFile,
+		DisplayName:   account.DisplayName,
+		DefaultFor:    account.DefaultFor,
+	}
+}
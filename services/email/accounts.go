@@ -0,0 +1,259 @@
+package email
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// ErrNoSender betekent dat er geen account is om voor een doel vanaf te versturen
+var ErrNoSender = errors.New("no sender account configured")
+
+// accountNamePattern beperkt namen van accounts, omdat ze in email IDs
+// ("account:folder:uidvalidity:uid") en URLs gebruikt worden
+var accountNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
+// ValidateAccounts controleert een set accounts voordat deze in gebruik genomen
+// wordt. Elk doel mag bij hooguit één account horen.
+func ValidateAccounts(accounts map[string]*EmailConfig) error {
+	if len(accounts) == 0 {
+		return errors.New("no email accounts configured")
+	}
+
+	senders := make(map[string]string)
+	for _, name := range sortedAccountNames(accounts) {
+		config := accounts[name]
+		if err := ValidateAccount(name, config); err != nil {
+			return err
+		}
+		for _, purpose := range config.DefaultFor {
+			if other, ok := senders[purpose]; ok {
+				return fmt.Errorf("accounts %s and %s are both the default for %s", other, name, purpose)
+			}
+			senders[purpose] = name
+		}
+	}
+	return nil
+}
+
+// ValidateAccount controleert de configuratie van één account
+func ValidateAccount(name string, config *EmailConfig) error {
+	switch {
+	case !accountNamePattern.MatchString(name):
+		return fmt.Errorf("invalid account name %q: use lowercase letters, digits, - and _", name)
+	case config == nil:
+		return fmt.Errorf("account %s: missing configuration", name)
+	case config.Email == "":
+		return fmt.Errorf("account %s: email is required", name)
+	case config.IMAPHost == "" || config.IMAPPort <= 0:
+		return fmt.Errorf("account %s: IMAP host and port are required", name)
+	case config.SMTPHost == "" || config.SMTPPort <= 0:
+		return fmt.Errorf("account %s: SMTP host and port are required", name)
+	}
+
+	switch config.IMAPSecurity {
+	case "", IMAPSecurityTLS, IMAPSecurityStartTLS, IMAPSecurityNone:
+	default:
+		return fmt.Errorf("account %s: unknown IMAP security %q", name, config.IMAPSecurity)
+	}
+
+	for _, purpose := range config.DefaultFor {
+		if !models.IsValidEmailPurpose(purpose) {
+			return fmt.Errorf("account %s: unknown purpose %q", name, purpose)
+		}
+	}
+	return nil
+}
+
+func sortedAccountNames(accounts map[string]*EmailConfig) []string {
+	names := make([]string, 0, len(accounts))
+	for name := range accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// account geeft de configuratie van een account
+func (s *EmailService) account(name string) (*EmailConfig, bool) {
+	s.accountsMu.RLock()
+	defer s.accountsMu.RUnlock()
+
+	config, ok := s.config.Accounts[name]
+	return config, ok
+}
+
+// accountConfigs geeft een kopie van de geconfigureerde accounts, zodat de
+// aanroeper erover kan itereren terwijl de accounts herladen worden
+func (s *EmailService) accountConfigs() map[string]*EmailConfig {
+	s.accountsMu.RLock()
+	defer s.accountsMu.RUnlock()
+
+	accounts := make(map[string]*EmailConfig, len(s.config.Accounts))
+	for name, config := range s.config.Accounts {
+		accounts[name] = config
+	}
+	return accounts
+}
+
+// cache geeft de INBOX cache van een account. Voor een account dat (nog) geen
+// cache heeft, bijvoorbeeld net na het herladen, wordt er een aangemaakt.
+func (s *EmailService) cache(name string) *AccountCache {
+	s.accountsMu.RLock()
+	cache := s.accountCaches[name]
+	s.accountsMu.RUnlock()
+	if cache != nil {
+		return cache
+	}
+
+	s.accountsMu.Lock()
+	defer s.accountsMu.Unlock()
+	if s.accountCaches == nil {
+		s.accountCaches = make(map[string]*AccountCache)
+	}
+	if s.accountCaches[name] == nil {
+		s.accountCaches[name] = NewAccountCache()
+	}
+	return s.accountCaches[name]
+}
+
+// senderFor geeft het account waarvandaan voor een doel verstuurd wordt: het
+// account met dat doel in DefaultFor, anders het info account
+func (s *EmailService) senderFor(purpose string) (string, *EmailConfig, error) {
+	accounts := s.accountConfigs()
+	for _, name := range sortedAccountNames(accounts) {
+		for _, p := range accounts[name].DefaultFor {
+			if p == purpose {
+				return name, accounts[name], nil
+			}
+		}
+	}
+
+	if config, ok := accounts["info"]; ok {
+		return "info", config, nil
+	}
+	return "", nil, fmt.Errorf("%w for %s", ErrNoSender, purpose)
+}
+
+// SetAccountSource stelt de bron van de accounts in en laadt ze direct
+func (s *EmailService) SetAccountSource(source AccountSource) error {
+	s.accountsMu.Lock()
+	s.accountSource = source
+	s.accountsMu.Unlock()
+
+	return s.ReloadAccounts()
+}
+
+// ReloadAccounts laadt de accounts opnieuw uit de bron. Gewijzigde accounts
+// krijgen een lege cache, nieuwe verbindingen en een nieuwe watcher; bij een
+// fout blijven de huidige accounts in gebruik.
+func (s *EmailService) ReloadAccounts() error {
+	s.accountsMu.RLock()
+	source := s.accountSource
+	s.accountsMu.RUnlock()
+	if source == nil {
+		return nil
+	}
+
+	accounts, err := source.LoadAccounts()
+	if err != nil {
+		return fmt.Errorf("failed to load email accounts: %w", err)
+	}
+	if err := ValidateAccounts(accounts); err != nil {
+		return fmt.Errorf("invalid email accounts: %w", err)
+	}
+
+	s.applyAccounts(accounts)
+	return nil
+}
+
+// WatchAccounts herlaadt de accounts periodiek, zodat wijzigingen in het
+// configuratiebestand of de database zonder herstart actief worden
+func (s *EmailService) WatchAccounts(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.ReloadAccounts(); err != nil {
+					log.Printf("[Accounts] Reload failed, keeping current accounts: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// applyAccounts neemt een nieuwe set accounts in gebruik
+func (s *EmailService) applyAccounts(accounts map[string]*EmailConfig) {
+	s.accountsMu.Lock()
+	current := s.config.Accounts
+	var added, changed, removed []string
+	for name, config := range accounts {
+		old, ok := current[name]
+		switch {
+		case !ok:
+			added = append(added, name)
+		case !reflect.DeepEqual(old, config):
+			changed = append(changed, name)
+		default:
+			// Ongewijzigd: de huidige configuratie houden, zodat verbindingen in de pool bruikbaar blijven
+			accounts[name] = old
+		}
+	}
+	for name := range current {
+		if _, ok := accounts[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	if len(added)+len(changed)+len(removed) == 0 {
+		s.accountsMu.Unlock()
+		return
+	}
+
+	s.config.Accounts = accounts
+	if s.accountCaches == nil {
+		s.accountCaches = make(map[string]*AccountCache)
+	}
+	for _, name := range append(added, changed...) {
+		// Een andere server of ander adres heeft andere UIDs, dus begin met een lege cache
+		s.accountCaches[name] = NewAccountCache()
+	}
+	for _, name := range removed {
+		delete(s.accountCaches, name)
+	}
+	s.accountsMu.Unlock()
+
+	for _, name := range append(changed, removed...) {
+		s.stopWatcher(name)
+		s.pool.closeAccount(name)
+		s.statuses.remove(name)
+	}
+	for _, name := range append(added, changed...) {
+		s.startWatcher(name, accounts[name])
+	}
+
+	sort.Strings(added)
+	sort.Strings(changed)
+	sort.Strings(removed)
+	log.Printf("[Accounts] Loaded %d accounts (added: %v, changed: %v, removed: %v)", len(accounts), added, changed, removed)
+}
+
+// AccountReloadInterval geeft hoe vaak de accounts herladen worden, instelbaar
+// met EMAIL_ACCOUNTS_RELOAD_INTERVAL (standaard 1 minuut)
+func AccountReloadInterval() time.Duration {
+	if interval, err := time.ParseDuration(os.Getenv("EMAIL_ACCOUNTS_RELOAD_INTERVAL")); err == nil && interval > 0 {
+		return interval
+	}
+	return time.Minute
+}
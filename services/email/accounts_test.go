@@ -0,0 +1,251 @@
+package email
+
+import (
+	"dklautomationgo/models"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticAccountSource levert steeds de accounts die de test erin zet
+type staticAccountSource struct {
+	accounts map[string]*EmailConfig
+	err      error
+}
+
+func (s *staticAccountSource) LoadAccounts() (map[string]*EmailConfig, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	accounts := make(map[string]*EmailConfig, len(s.accounts))
+	for name, config := range s.accounts {
+		copied := *config
+		accounts[name] = &copied
+	}
+	return accounts, nil
+}
+
+// memoryAccountStore is een EmailAccountStore in het geheugen
+type memoryAccountStore struct {
+	accounts []*models.EmailAccount
+}
+
+func (m *memoryAccountStore) FindEnabled() ([]*models.EmailAccount, error) {
+	return m.accounts, nil
+}
+
+func testAccount(email string, purposes ...string) *EmailConfig {
+	return &EmailConfig{
+		Email:      email,
+		IMAPHost:   "imap.example.org",
+		IMAPPort:   993,
+		SMTPHost:   "smtp.example.org",
+		SMTPPort:   587,
+		DefaultFor: purposes,
+	}
+}
+
+func TestParseAccountFile_YAMLWithDefaults(t *testing.T) {
+	// Setup
+	t.Setenv("TEST_INFO_PASSWORD", "geheim")
+	data := []byte(`
+defaults:
+  imap_host: imap.example.org
+  imap_port: 993
+  smtp_host: smtp.example.org
+  smtp_port: 465
+  display_name: De Koninklijke Loop
+accounts:
+  info:
+    email: info@example.org
+    password_env: TEST_INFO_PASSWORD
+    default_for: [notification, reply]
+  inschrijving:
+    email: inschrijving@example.org
+    password: direct
+    smtp_port: 587
+    display_name: Inschrijving DKL
+    default_for: [confirmation]
+`)
+
+	// Test
+	file, err := ParseAccountFile(data, ".yaml")
+	require.NoError(t, err)
+	accounts, err := file.Configs()
+	require.NoError(t, err)
+
+	// Controleer het resultaat
+	require.Len(t, accounts, 2)
+	info := accounts["info"]
+	assert.Equal(t, "geheim", info.Password)
+	assert.Equal(t, "imap.example.org", info.IMAPHost)
+	assert.Equal(t, 465, info.SMTPPort)
+	assert.Equal(t, "De Koninklijke Loop", info.DisplayName)
+	assert.Equal(t, []string{models.EmailPurposeNotification, models.EmailPurposeReply}, info.DefaultFor)
+
+	inschrijving := accounts["inschrijving"]
+	assert.Equal(t, "direct", inschrijving.Password)
+	assert.Equal(t, 587, inschrijving.SMTPPort)
+	assert.Equal(t, "Inschrijving DKL", inschrijving.DisplayName)
+	assert.NoError(t, ValidateAccounts(accounts))
+}
+
+func TestParseAccountFile_JSONAndErrors(t *testing.T) {
+	// Test: JSON
+	file, err := ParseAccountFile([]byte(`{"accounts": {"info": {"email": "info@example.org", "imap_host": "imap", "imap_port": 993}}}`), ".json")
+	require.NoError(t, err)
+	assert.Equal(t, "info@example.org", file.Accounts["info"].Email)
+
+	// Test: een typefout in een veldnaam
+	_, err = ParseAccountFile([]byte("accounts:\n  info:\n    emial: info@example.org\n"), ".yml")
+	assert.Error(t, err)
+	_, err = ParseAccountFile([]byte(`{"accounts": {"info": {"emial": "info@example.org"}}}`), ".json")
+	assert.Error(t, err)
+
+	// Test: een ontbrekende wachtwoord variabele
+	file, err = ParseAccountFile([]byte("accounts:\n  info:\n    email: info@example.org\n    password_env: TEST_UNSET_PASSWORD\n"), ".yaml")
+	require.NoError(t, err)
+	_, err = file.Configs()
+	assert.ErrorContains(t, err, "TEST_UNSET_PASSWORD")
+}
+
+func TestValidateAccounts(t *testing.T) {
+	tests := []struct {
+		name     string
+		accounts map[string]*EmailConfig
+		wantErr  string
+	}{
+		{"geldig", map[string]*EmailConfig{"info": testAccount("info@example.org", models.EmailPurposeReply)}, ""},
+		{"geen accounts", map[string]*EmailConfig{}, "no email accounts"},
+		{"ongeldige naam", map[string]*EmailConfig{"Info:1": testAccount("info@example.org")}, "invalid account name"},
+		{"geen email", map[string]*EmailConfig{"info": testAccount("")}, "email is required"},
+		{"onbekend doel", map[string]*EmailConfig{"info": testAccount("info@example.org", "marketing")}, "unknown purpose"},
+		{"dubbel doel", map[string]*EmailConfig{
+			"info":         testAccount("info@example.org", models.EmailPurposeConfirmation),
+			"inschrijving": testAccount("inschrijving@example.org", models.EmailPurposeConfirmation),
+		}, "both the default for confirmation"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAccounts(tt.accounts)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSenderFor_UsesPurposeAndFallsBackToInfo(t *testing.T) {
+	// Setup
+	service := &EmailService{config: &ServiceConfig{Accounts: map[string]*EmailConfig{
+		"info":         testAccount("info@example.org"),
+		"inschrijving": testAccount("inschrijving@example.org", models.EmailPurposeConfirmation),
+	}}}
+
+	// Test
+	name, config, err := service.senderFor(models.EmailPurposeConfirmation)
+	require.NoError(t, err)
+	assert.Equal(t, "inschrijving", name)
+	assert.Equal(t, "inschrijving@example.org", config.Email)
+
+	name, _, err = service.senderFor(models.EmailPurposeReply)
+	require.NoError(t, err)
+	assert.Equal(t, "info", name)
+
+	// Zonder info account is er geen afzender
+	service.config.Accounts = map[string]*EmailConfig{"inschrijving": testAccount("inschrijving@example.org")}
+	_, _, err = service.senderFor(models.EmailPurposeReply)
+	assert.True(t, errors.Is(err, ErrNoSender))
+}
+
+func TestReloadAccounts_AppliesChanges(t *testing.T) {
+	// Setup
+	source := &staticAccountSource{accounts: map[string]*EmailConfig{
+		"info":         testAccount("info@example.org"),
+		"inschrijving": testAccount("inschrijving@example.org"),
+	}}
+	service := &EmailService{config: &ServiceConfig{}}
+	require.NoError(t, service.SetAccountSource(source))
+	info, _ := service.account("info")
+	inschrijvingCache := service.cache("inschrijving")
+	inschrijvingCache.store([]*models.Email{{ID: "inschrijving:INBOX:1:1"}})
+
+	// Test: info ongewijzigd, inschrijving gewijzigd, nieuwsbrief toegevoegd
+	source.accounts = map[string]*EmailConfig{
+		"info":         testAccount("info@example.org"),
+		"inschrijving": testAccount("aanmelden@example.org", models.EmailPurposeConfirmation),
+		"nieuwsbrief":  testAccount("nieuwsbrief@example.org"),
+	}
+	require.NoError(t, service.ReloadAccounts())
+
+	// Controleer het resultaat
+	assert.ElementsMatch(t, []string{"info", "inschrijving", "nieuwsbrief"}, sortedAccountNames(service.accountConfigs()))
+	unchanged, _ := service.account("info")
+	assert.Same(t, info, unchanged, "an unchanged account keeps its configuration")
+	assert.NotSame(t, inschrijvingCache, service.cache("inschrijving"), "a changed account starts with an empty cache")
+	name, _, err := service.senderFor(models.EmailPurposeConfirmation)
+	require.NoError(t, err)
+	assert.Equal(t, "inschrijving", name)
+
+	// Test: een verwijderd account
+	delete(source.accounts, "nieuwsbrief")
+	require.NoError(t, service.ReloadAccounts())
+	assert.False(t, service.HasAccount("nieuwsbrief"))
+}
+
+func TestReloadAccounts_KeepsAccountsOnError(t *testing.T) {
+	// Setup
+	source := &staticAccountSource{accounts: map[string]*EmailConfig{"info": testAccount("info@example.org")}}
+	service := &EmailService{config: &ServiceConfig{}}
+	require.NoError(t, service.SetAccountSource(source))
+
+	// Test
+	source.accounts = map[string]*EmailConfig{
+		"info":  testAccount("info@example.org", models.EmailPurposeReply),
+		"extra": testAccount("extra@example.org", models.EmailPurposeReply),
+	}
+	assert.Error(t, service.ReloadAccounts())
+	source.err = errors.New("file not found")
+	assert.Error(t, service.ReloadAccounts())
+
+	// Controleer het resultaat
+	assert.True(t, service.HasAccount("info"))
+	assert.False(t, service.HasAccount("extra"))
+}
+
+func TestDatabaseAccountSource(t *testing.T) {
+	// Setup
+	store := &memoryAccountStore{accounts: []*models.EmailAccount{{
+		Name:        "info",
+		Email:       "info@example.org",
+		DisplayName: "DKL",
+		Password:    "geheim",
+		IMAPHost:    "imap.example.org",
+		IMAPPort:    993,
+		SMTPHost:    "smtp.example.org",
+		SMTPPort:    587,
+		DefaultFor:  []string{models.EmailPurposeReply},
+		Enabled:     true,
+	}}}
+
+	// Test
+	accounts, err := NewDatabaseAccountSource(store).LoadAccounts()
+
+	// Controleer het resultaat
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	assert.Equal(t, "geheim", accounts["info"].Password)
+	assert.Equal(t, "DKL", accounts["info"].DisplayName)
+	assert.Equal(t, []string{models.EmailPurposeReply}, accounts["info"].DefaultFor)
+
+	// Zonder accounts in de database worden de omgevingsvariabelen gebruikt
+	t.Setenv("SMTP_USER", "env@example.org")
+	accounts, err = NewDatabaseAccountSource(&memoryAccountStore{}).LoadAccounts()
+	require.NoError(t, err)
+	assert.Equal(t, "env@example.org", accounts["info"].Email)
+}
@@ -0,0 +1,200 @@
+package email
+
+import (
+	"bytes"
+	"dklautomationgo/models"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-message"
+)
+
+var ErrAttachmentNotFound = errors.New("attachment not found")
+
+// attachmentPart beschrijft een bijlage in de BODYSTRUCTURE van een bericht
+type attachmentPart struct {
+	path        []int
+	filename    string
+	contentType string
+	contentID   string
+}
+
+// GetAttachment geeft de bijlage met de gegeven index van een email terug. Als
+// het bericht met inhoud in de cache staat wordt die gebruikt, anders wordt
+// alleen de betreffende MIME part via BODY[section] van de server opgehaald.
+func (s *EmailService) GetAttachment(emailID string, index int) (*models.EmailAttachment, error) {
+	ref, err := parseEmailID(emailID)
+	if err != nil {
+		return nil, err
+	}
+
+	config, ok := s.account(ref.account)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAccount, ref.account)
+	}
+
+	if s.config.Cache.Enabled && ref.isInbox() {
+		cache := s.cache(ref.account)
+		lookup := ref
+		if lookup.uidValidity == 0 {
+			lookup.uidValidity = cache.currentUIDValidity()
+		}
+		if email := cache.find(lookup.id()); email != nil {
+			if index < 0 || index >= len(email.Attachments) {
+				return nil, ErrAttachmentNotFound
+			}
+			if attachment := email.Attachments[index]; attachment.Content != nil {
+				return &attachment, nil
+			}
+		}
+	}
+
+	var attachment *models.EmailAttachment
+	err = s.withIMAP(ref.account, config, func(c *client.Client) error {
+		var err error
+		attachment, err = s.fetchAttachment(c, ref, index)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// fetchAttachment haalt alleen de MIME part van de bijlage van de server
+func (s *EmailService) fetchAttachment(c *client.Client, ref emailRef, index int) (*models.EmailAttachment, error) {
+	_, seqSet, err := s.selectMessage(c, ref, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bepaal eerst via de BODYSTRUCTURE welke part de bijlage is
+	var structure *imap.BodyStructure
+	if err := uidFetchOne(c, seqSet, []imap.FetchItem{imap.FetchBodyStructure}, func(msg *imap.Message) {
+		structure = msg.BodyStructure
+	}); err != nil {
+		return nil, err
+	}
+	if structure == nil {
+		return nil, ErrEmailNotFound
+	}
+
+	parts := attachmentParts(structure)
+	if index < 0 || index >= len(parts) {
+		return nil, ErrAttachmentNotFound
+	}
+	part := parts[index]
+
+	// Haal alleen de headers en inhoud van die part op
+	mimeSection := &imap.BodySectionName{
+		BodyPartName: imap.BodyPartName{Specifier: imap.MIMESpecifier, Path: part.path},
+		Peek:         true,
+	}
+	bodySection := &imap.BodySectionName{
+		BodyPartName: imap.BodyPartName{Path: part.path},
+		Peek:         true,
+	}
+
+	var raw bytes.Buffer
+	if err := uidFetchOne(c, seqSet, []imap.FetchItem{mimeSection.FetchItem(), bodySection.FetchItem()}, func(msg *imap.Message) {
+		if header := msg.GetBody(mimeSection); header != nil {
+			io.Copy(&raw, header)
+		}
+		if body := msg.GetBody(bodySection); body != nil {
+			io.Copy(&raw, body)
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	content, err := decodePart(&raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.EmailAttachment{
+		Filename:    part.filename,
+		ContentType: part.contentType,
+		Size:        int64(len(content)),
+		Content:     content,
+		ContentID:   part.contentID,
+		Section:     sectionName(part.path),
+	}, nil
+}
+
+// attachmentParts geeft de bijlagen uit een BODYSTRUCTURE terug, in dezelfde
+// volgorde als processMessage ze vindt
+func attachmentParts(structure *imap.BodyStructure) []attachmentPart {
+	var parts []attachmentPart
+	structure.Walk(func(path []int, part *imap.BodyStructure) bool {
+		mimeType := strings.ToLower(part.MIMEType + "/" + part.MIMESubType)
+		filename, _ := part.Filename()
+		if classifyPart(mimeType, part.Disposition, filename) != partAttachment {
+			return true
+		}
+
+		if filename == "" {
+			filename = defaultFilename(mimeType)
+		}
+		parts = append(parts, attachmentPart{
+			path:        append([]int(nil), path...),
+			filename:    filename,
+			contentType: mimeType,
+			contentID:   strings.Trim(part.Id, "<> "),
+		})
+		return true
+	})
+	return parts
+}
+
+// decodePart decodeert een MIME part (headers gevolgd door de inhoud) en geeft
+// de inhoud zonder transfer encoding terug
+func decodePart(r io.Reader) ([]byte, error) {
+	entity, err := message.Read(r)
+	if err != nil && !message.IsUnknownCharset(err) && !message.IsUnknownEncoding(err) {
+		return nil, fmt.Errorf("failed to parse attachment: %w", err)
+	}
+
+	content, err := io.ReadAll(entity.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode attachment: %w", err)
+	}
+	return content, nil
+}
+
+// uidFetchOne voert een UID FETCH uit en roept fn aan voor het opgehaalde bericht
+func uidFetchOne(c *client.Client, seqSet *imap.SeqSet, items []imap.FetchItem, fn func(msg *imap.Message)) error {
+	messages := make(chan *imap.Message, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.UidFetch(seqSet, items, messages)
+	}()
+
+	found := false
+	for msg := range messages {
+		found = true
+		fn(msg)
+	}
+
+	if err := <-done; err != nil {
+		return fmt.Errorf("IMAP fetch failed: %w", err)
+	}
+	if !found {
+		return ErrEmailNotFound
+	}
+	return nil
+}
+
+// sectionName zet een part pad om naar de IMAP notatie, bijv. 1.2
+func sectionName(path []int) string {
+	names := make([]string, len(path))
+	for i, n := range path {
+		names[i] = strconv.Itoa(n)
+	}
+	return strings.Join(names, ".")
+}
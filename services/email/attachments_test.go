@@ -0,0 +1,65 @@
+package email
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttachmentParts(t *testing.T) {
+	// Setup: multipart/mixed met een alternative deel en twee bijlagen, waarvan één genest
+	structure := &imap.BodyStructure{
+		MIMEType:    "multipart",
+		MIMESubType: "mixed",
+		Parts: []*imap.BodyStructure{
+			{
+				MIMEType:    "multipart",
+				MIMESubType: "alternative",
+				Parts: []*imap.BodyStructure{
+					{MIMEType: "text", MIMESubType: "plain"},
+					{MIMEType: "text", MIMESubType: "html"},
+				},
+			},
+			{
+				MIMEType:          "application",
+				MIMESubType:       "PDF",
+				Disposition:       "attachment",
+				DispositionParams: map[string]string{"filename": "inschrijving.pdf"},
+			},
+			{
+				MIMEType:    "multipart",
+				MIMESubType: "mixed",
+				Parts: []*imap.BodyStructure{
+					{MIMEType: "image", MIMESubType: "png", Disposition: "ATTACHMENT", Id: "<logo@dkl>"},
+				},
+			},
+		},
+	}
+
+	// Test
+	parts := attachmentParts(structure)
+
+	assert.Len(t, parts, 2)
+	assert.Equal(t, "2", sectionName(parts[0].path))
+	assert.Equal(t, "inschrijving.pdf", parts[0].filename)
+	assert.Equal(t, "application/pdf", parts[0].contentType)
+	assert.Equal(t, "3.1", sectionName(parts[1].path))
+	assert.Equal(t, "unnamed-attachment", parts[1].filename)
+	assert.Equal(t, "logo@dkl", parts[1].contentID)
+}
+
+func TestDecodePart_Base64(t *testing.T) {
+	// Setup
+	raw := "Content-Type: text/plain; name=groet.txt\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		"SGFsbG8gd2VyZWxkIQ==\r\n"
+
+	// Test
+	content, err := decodePart(strings.NewReader(raw))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Hallo wereld!", string(content))
+}
@@ -0,0 +1,238 @@
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"dklautomationgo/models"
+	"net/textproto"
+	"regexp"
+	"strings"
+)
+
+// Bounce is een melding van een mailserver dat een bericht niet afgeleverd kon worden
+type Bounce struct {
+	Recipient         string // Adres dat niet bereikt kon worden
+	Status            string // Enhanced status code, bijv. 5.1.1
+	Diagnostic        string // Foutmelding van de ontvangende mailserver
+	OriginalMessageID string // Message-ID van het oorspronkelijke bericht, als die gevonden is
+}
+
+// Reason geeft de reden van de bounce zoals die aan beheerders getoond wordt
+func (b *Bounce) Reason() string {
+	switch {
+	case b.Status != "" && b.Diagnostic != "":
+		return b.Status + ": " + b.Diagnostic
+	case b.Diagnostic != "":
+		return b.Diagnostic
+	case b.Status != "":
+		return b.Status
+	default:
+		return "Onbekende reden"
+	}
+}
+
+// dsnRecipient is een per-recipient blok uit een message/delivery-status part
+type dsnRecipient struct {
+	recipient  string
+	action     string
+	status     string
+	diagnostic string
+}
+
+var (
+	// bounceSubject herkent de onderwerpen van bounces van gangbare mailservers
+	// (Postfix, Exim, qmail, Sendmail, Exchange, Gmail) zonder multipart/report
+	bounceSubject = regexp.MustCompile(`(?i)(undeliver(ed|able)|delivery (status notification|failure|has failed)|returned mail|mail delivery (failed|failure|system)|failure notice|non[- ]?delivery|could not be delivered|onbestelbaar|niet (afgeleverd|bezorgd))`)
+	// delaySubject herkent waarschuwingen over vertraagde aflevering, die geen bounce zijn
+	delaySubject   = regexp.MustCompile(`(?i)(delay(ed)?|warning|vertraagd)`)
+	statusCode     = regexp.MustCompile(`\b([245]\.\d{1,3}\.\d{1,3})\b`)
+	smtpReply      = regexp.MustCompile(`\b[45]\d\d[ -][^\r\n]+`)
+	bodyAddress    = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	bodyMessageID  = regexp.MustCompile(`(?im)^\s*Message-ID:\s*(<[^>\s]+>)`)
+	bounceSenders  = []string{"mailer-daemon", "postmaster", "mail-daemon", "mailerdaemon"}
+	originalHeader = map[string]bool{
+		"message/rfc822": true, "text/rfc822-headers": true,
+		"message/global": true, "message/global-headers": true,
+	}
+)
+
+// ParseBounce herkent een bounce en haalt het onbereikbare adres, de reden en
+// de Message-ID van het oorspronkelijke bericht eruit. Delivery status
+// notifications (multipart/report, RFC 3464) worden volledig gelezen; voor
+// bounces zonder DSN wordt op afzender en onderwerp herkend en worden adres en
+// status uit de tekst gehaald. Meldingen over vertraagde of geslaagde
+// aflevering zijn geen bounce.
+func ParseBounce(email *models.Email) (*Bounce, bool) {
+	bounce := &Bounce{}
+	var report []dsnRecipient
+	for _, attachment := range email.Attachments {
+		switch attachment.ContentType {
+		case "message/delivery-status", "message/global-delivery-status":
+			report = append(report, parseDeliveryStatus(attachment.Content)...)
+		default:
+			if originalHeader[attachment.ContentType] && bounce.OriginalMessageID == "" {
+				bounce.OriginalMessageID = headerMessageID(attachment.Content)
+			}
+		}
+	}
+
+	if len(report) > 0 {
+		failed := firstFailed(report)
+		if failed == nil {
+			return nil, false
+		}
+		bounce.Recipient = failed.recipient
+		bounce.Status = failed.status
+		bounce.Diagnostic = failed.diagnostic
+	} else {
+		if !looksLikeBounce(email) {
+			return nil, false
+		}
+		text := email.Body
+		if strings.TrimSpace(text) == "" {
+			text = HTMLToText(email.HTML)
+		}
+		bounce.Recipient = failedAddress(text, email)
+		if match := statusCode.FindStringSubmatch(text); match != nil {
+			bounce.Status = match[1]
+		}
+		if match := smtpReply.FindString(text); match != "" {
+			bounce.Diagnostic = strings.TrimSpace(match)
+		}
+		if bounce.OriginalMessageID == "" {
+			if match := bodyMessageID.FindStringSubmatch(text); match != nil {
+				bounce.OriginalMessageID = match[1]
+			}
+		}
+	}
+
+	// Sommige mailservers verwijzen met In-Reply-To of References naar het origineel
+	if bounce.OriginalMessageID == "" {
+		if email.InReplyTo != "" {
+			bounce.OriginalMessageID = email.InReplyTo
+		} else if len(email.References) > 0 {
+			bounce.OriginalMessageID = email.References[len(email.References)-1]
+		}
+	}
+
+	if bounce.Recipient == "" && bounce.OriginalMessageID == "" {
+		return nil, false
+	}
+	return bounce, true
+}
+
+// looksLikeBounce herkent een bounce zonder DSN aan de afzender en het onderwerp
+func looksLikeBounce(email *models.Email) bool {
+	if strings.Contains(strings.ToLower(email.Headers["Content-Type"]), "report-type=delivery-status") {
+		return true
+	}
+	if !bounceSubject.MatchString(email.Subject) {
+		return false
+	}
+	if delaySubject.MatchString(email.Subject) {
+		return false
+	}
+	local := strings.ToLower(email.Sender)
+	if at := strings.Index(local, "@"); at != -1 {
+		local = local[:at]
+	}
+	for _, sender := range bounceSenders {
+		if local == sender {
+			return true
+		}
+	}
+	// Exchange en Gmail sturen bounces soms vanaf een gewoon adres, maar altijd
+	// automatisch; een mens die "Undeliverable" in het onderwerp zet niet
+	autoSubmitted := strings.ToLower(email.Headers["Auto-Submitted"])
+	return autoSubmitted != "" && autoSubmitted != "no"
+}
+
+// parseDeliveryStatus leest de per-recipient blokken uit een message/delivery-status
+// part. Het eerste blok bevat velden over het bericht en wordt overgeslagen.
+func parseDeliveryStatus(content []byte) []dsnRecipient {
+	reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(normalizeNewlines(content))))
+	var recipients []dsnRecipient
+	for {
+		header, err := reader.ReadMIMEHeader()
+		recipient := headerAddress(header.Get("Final-Recipient"))
+		if recipient == "" {
+			recipient = headerAddress(header.Get("Original-Recipient"))
+		}
+		if recipient != "" {
+			recipients = append(recipients, dsnRecipient{
+				recipient:  recipient,
+				action:     strings.ToLower(strings.TrimSpace(header.Get("Action"))),
+				status:     strings.TrimSpace(header.Get("Status")),
+				diagnostic: headerValue(header.Get("Diagnostic-Code")),
+			})
+		}
+		if err != nil {
+			return recipients
+		}
+	}
+}
+
+// firstFailed geeft het eerste adres waarvan de aflevering definitief mislukt is
+func firstFailed(report []dsnRecipient) *dsnRecipient {
+	for i, recipient := range report {
+		if recipient.action == "failed" || (recipient.action == "" && strings.HasPrefix(recipient.status, "5")) {
+			return &report[i]
+		}
+	}
+	return nil
+}
+
+// headerAddress haalt het adres uit een veld als "rfc822; jan@example.org"
+func headerAddress(value string) string {
+	value = headerValue(value)
+	return strings.Trim(strings.TrimSpace(value), "<>")
+}
+
+// headerValue haalt het type voor de puntkomma weg, bijv. "smtp; 550 5.1.1 ..."
+func headerValue(value string) string {
+	if semi := strings.Index(value, ";"); semi != -1 {
+		value = value[semi+1:]
+	}
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// headerMessageID leest de Message-ID uit de headers van het oorspronkelijke bericht
+func headerMessageID(content []byte) string {
+	reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(normalizeNewlines(content))))
+	header, _ := reader.ReadMIMEHeader()
+	return strings.TrimSpace(header.Get("Message-Id"))
+}
+
+// failedAddress zoekt het onbereikbare adres in de tekst van een bounce: het
+// eerste adres dat niet van de mailserver zelf of van ons afkomstig is
+func failedAddress(text string, email *models.Email) string {
+	exclude := append(append([]string{email.Sender}, email.To...), email.Cc...)
+	for _, address := range bodyAddress.FindAllString(text, -1) {
+		address = strings.Trim(address, ".")
+		skip := false
+		for _, e := range exclude {
+			if strings.EqualFold(address, e) {
+				skip = true
+				break
+			}
+		}
+		local := strings.ToLower(address[:strings.Index(address, "@")])
+		for _, sender := range bounceSenders {
+			if local == sender {
+				skip = true
+			}
+		}
+		if !skip {
+			return address
+		}
+	}
+	return ""
+}
+
+// normalizeNewlines zet regeleinden om naar CRLF, zoals textproto verwacht, en
+// zorgt voor een lege regel aan het einde zodat het laatste blok gelezen wordt
+func normalizeNewlines(content []byte) []byte {
+	content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	content = bytes.TrimRight(content, "\n")
+	return append(bytes.ReplaceAll(content, []byte("\n"), []byte("\r\n")), "\r\n\r\n"...)
+}
@@ -0,0 +1,158 @@
+package email
+
+import (
+	"testing"
+
+	"dklautomationgo/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// postfixBounce is een DSN zoals Postfix die terugstuurt voor een onbekend adres
+const postfixBounce = "From: MAILER-DAEMON@mail.example.org (Mail Delivery System)\r\n" +
+	"To: noreply@dekoninklijkeloop.nl\r\n" +
+	"Subject: Undelivered Mail Returned to Sender\r\n" +
+	"Auto-Submitted: auto-replied\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/report; report-type=delivery-status; boundary=\"dsn\"\r\n" +
+	"\r\n" +
+	"--dsn\r\n" +
+	"Content-Type: text/plain; charset=us-ascii\r\n" +
+	"\r\n" +
+	"I'm sorry to have to inform you that your message could not\r\n" +
+	"be delivered to one or more recipients.\r\n" +
+	"\r\n" +
+	"<jan@exmaple.org>: host mx.exmaple.org said: 550 5.1.1 User unknown\r\n" +
+	"--dsn\r\n" +
+	"Content-Type: message/delivery-status\r\n" +
+	"\r\n" +
+	"Reporting-MTA: dns; mail.example.org\r\n" +
+	"Arrival-Date: Mon, 19 Oct 2026 10:00:00 +0200\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; jan@exmaple.org\r\n" +
+	"Original-Recipient: rfc822;jan@exmaple.org\r\n" +
+	"Action: failed\r\n" +
+	"Status: 5.1.1\r\n" +
+	"Diagnostic-Code: smtp; 550 5.1.1 <jan@exmaple.org>: Recipient address\r\n" +
+	"    rejected: User unknown\r\n" +
+	"--dsn\r\n" +
+	"Content-Type: text/rfc822-headers\r\n" +
+	"\r\n" +
+	"From: De Koninklijke Loop <noreply@dekoninklijkeloop.nl>\r\n" +
+	"To: jan@exmaple.org\r\n" +
+	"Subject: Bedankt voor je aanmelding\r\n" +
+	"Message-ID: <dkl.aanmelding.aanmelding-1.1700000000@dekoninklijkeloop.nl>\r\n" +
+	"--dsn--\r\n"
+
+// eximBounce is een bounce van Exim zonder DSN
+const eximBounce = "From: Mail Delivery System <Mailer-Daemon@mx.example.net>\r\n" +
+	"To: info@dekoninklijkeloop.nl\r\n" +
+	"Subject: Mail delivery failed: returning message to sender\r\n" +
+	"Content-Type: text/plain; charset=us-ascii\r\n" +
+	"\r\n" +
+	"This message was created automatically by mail delivery software.\r\n" +
+	"\r\n" +
+	"A message that you sent could not be delivered to one or more of its\r\n" +
+	"recipients. This is a permanent error. The following address(es) failed:\r\n" +
+	"\r\n" +
+	"  piet@example.net\r\n" +
+	"    host mx.example.net [192.0.2.1]\r\n" +
+	"    SMTP error from remote mail server after RCPT TO:<piet@example.net>:\r\n" +
+	"    550 5.2.1 Mailbox disabled\r\n" +
+	"\r\n" +
+	"------ This is a copy of the message, including all the headers. ------\r\n" +
+	"\r\n" +
+	"From: noreply@dekoninklijkeloop.nl\r\n" +
+	"Message-ID: <dkl.contact.contact-1.1700000000@dekoninklijkeloop.nl>\r\n" +
+	"Subject: Bedankt voor je bericht\r\n"
+
+func TestParseBounce(t *testing.T) {
+	service := &EmailService{}
+	mailbox := mailboxRef{account: "info", folder: inboxFolder, uidValidity: 7}
+
+	tests := []struct {
+		name       string
+		raw        string
+		recipient  string
+		status     string
+		diagnostic string
+		messageID  string
+	}{
+		{
+			name:       "postfix dsn",
+			raw:        postfixBounce,
+			recipient:  "jan@exmaple.org",
+			status:     "5.1.1",
+			diagnostic: "550 5.1.1 <jan@exmaple.org>: Recipient address rejected: User unknown",
+			messageID:  "<dkl.aanmelding.aanmelding-1.1700000000@dekoninklijkeloop.nl>",
+		},
+		{
+			name:       "exim zonder dsn",
+			raw:        eximBounce,
+			recipient:  "piet@example.net",
+			status:     "5.2.1",
+			diagnostic: "550 5.2.1 Mailbox disabled",
+			messageID:  "<dkl.contact.contact-1.1700000000@dekoninklijkeloop.nl>",
+		},
+		{
+			name: "qmail",
+			raw: "From: MAILER-DAEMON@qmail.example.com\r\n" +
+				"To: noreply@dekoninklijkeloop.nl\r\n" +
+				"Subject: failure notice\r\n" +
+				"\r\n" +
+				"Hi. This is the qmail-send program at qmail.example.com.\r\n" +
+				"I'm afraid I wasn't able to deliver your message to the following addresses.\r\n" +
+				"This is a permanent error; I've given up. Sorry it didn't work out.\r\n" +
+				"\r\n" +
+				"<klaas@example.com>:\r\n" +
+				"Sorry, no mailbox here by that name. (#5.1.1)\r\n",
+			recipient: "klaas@example.com",
+			status:    "5.1.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			msg, err := service.processMessage(newRawMessage(tt.raw), mailbox)
+			require.NoError(t, err)
+
+			// Test
+			bounce, ok := ParseBounce(msg)
+
+			// Controleer het resultaat
+			require.True(t, ok)
+			assert.Equal(t, tt.recipient, bounce.Recipient)
+			assert.Equal(t, tt.status, bounce.Status)
+			assert.Equal(t, tt.diagnostic, bounce.Diagnostic)
+			assert.Equal(t, tt.messageID, bounce.OriginalMessageID)
+		})
+	}
+}
+
+func TestParseBounce_NotABounce(t *testing.T) {
+	tests := []struct {
+		name  string
+		email *models.Email
+	}{
+		{"gewone email", &models.Email{Sender: "jan@example.org", Subject: "Vraag over de route", Body: "Mail me op piet@example.org"}},
+		{"onderwerp van een mens", &models.Email{Sender: "jan@example.org", Subject: "Undeliverable: mijn vorige mail", Body: "piet@example.org"}},
+		{"vertraging", &models.Email{Sender: "MAILER-DAEMON@example.org", Subject: "Delayed Mail (still being retried)", Body: "piet@example.org"}},
+		{"dsn zonder mislukte aflevering", &models.Email{
+			Sender:  "postmaster@example.org",
+			Subject: "Delivery Status Notification (Delay)",
+			Attachments: []models.EmailAttachment{{
+				ContentType: "message/delivery-status",
+				Content:     []byte("Reporting-MTA: dns; example.org\n\nFinal-Recipient: rfc822; piet@example.org\nAction: delayed\nStatus: 4.4.1\n"),
+			}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := ParseBounce(tt.email)
+			assert.False(t, ok)
+		})
+	}
+}
@@ -8,9 +8,13 @@ import (
 
 // AccountCache holds the cache for one account
 type AccountCache struct {
-	emails     []*models.Email
-	lastFetch  time.Time
-	cacheMutex sync.RWMutex
+	emails      []*models.Email
+	lastFetch   time.Time
+	watched     bool            // Of een IDLE watcher de cache actueel houdt
+	uidValidity uint32          // UIDVALIDITY van de INBOX waar de gecachte IDs bij horen
+	sent        []*models.Email // Recente berichten uit de Sent map, voor gesprekken
+	sentFetch   time.Time
+	cacheMutex  sync.RWMutex
 }
 
 func NewAccountCache() *AccountCache {
@@ -20,3 +24,192 @@ func NewAccountCache() *AccountCache {
 		cacheMutex: sync.RWMutex{},
 	}
 }
+
+// isFresh geeft aan of de cache gebruikt kan worden. Een cache die door een
+// watcher bijgehouden wordt blijft geldig zolang de watcher verbonden is.
+func (c *AccountCache) isFresh(maxAge time.Duration) bool {
+	c.cacheMutex.RLock()
+	defer c.cacheMutex.RUnlock()
+
+	if c.lastFetch.IsZero() {
+		return false
+	}
+	return c.watched || time.Since(c.lastFetch) < maxAge
+}
+
+// snapshot geeft een kopie van de gecachte emails terug
+func (c *AccountCache) snapshot() []*models.Email {
+	c.cacheMutex.RLock()
+	defer c.cacheMutex.RUnlock()
+
+	emails := make([]*models.Email, len(c.emails))
+	copy(emails, c.emails)
+	return emails
+}
+
+// store vervangt de inhoud van de cache en geeft de emails terug die nog niet in de cache stonden
+func (c *AccountCache) store(emails []*models.Email) []*models.Email {
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+
+	known := make(map[string]bool, len(c.emails))
+	for _, email := range c.emails {
+		known[email.ID] = true
+	}
+	var added []*models.Email
+	for _, email := range emails {
+		if !known[email.ID] {
+			added = append(added, email)
+		}
+	}
+
+	c.emails = emails
+	c.lastFetch = time.Now()
+	return added
+}
+
+// add voegt nieuw ontvangen emails toe aan een reeds gevulde cache
+func (c *AccountCache) add(emails []*models.Email) {
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+
+	// Een lege cache wordt bij de volgende FetchEmails volledig gevuld
+	if c.lastFetch.IsZero() {
+		return
+	}
+
+	for _, email := range emails {
+		exists := false
+		for _, cached := range c.emails {
+			if cached.ID == email.ID {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			c.emails = append(c.emails, email)
+		}
+	}
+}
+
+// update past fn toe op een gecachte email en geeft aan of deze gevonden is
+func (c *AccountCache) update(emailID string, fn func(email *models.Email)) bool {
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+
+	for _, email := range c.emails {
+		if email.ID == emailID {
+			fn(email)
+			return true
+		}
+	}
+	return false
+}
+
+// find zoekt een gecachte email op basis van ID
+func (c *AccountCache) find(emailID string) *models.Email {
+	c.cacheMutex.RLock()
+	defer c.cacheMutex.RUnlock()
+
+	for _, email := range c.emails {
+		if email.ID == emailID {
+			return email
+		}
+	}
+	return nil
+}
+
+// remove verwijdert een email uit de cache
+func (c *AccountCache) remove(emailID string) {
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+
+	for i, email := range c.emails {
+		if email.ID == emailID {
+			c.emails = append(c.emails[:i:i], c.emails[i+1:]...)
+			return
+		}
+	}
+}
+
+// invalidate zorgt dat de cache bij de volgende fetch opnieuw gevuld wordt
+func (c *AccountCache) invalidate() {
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+
+	c.emails = make([]*models.Email, 0)
+	c.lastFetch = time.Time{}
+}
+
+// sentSnapshot geeft de gecachte berichten uit de Sent map, zolang die niet
+// ouder zijn dan maxAge
+func (c *AccountCache) sentSnapshot(maxAge time.Duration) ([]*models.Email, bool) {
+	c.cacheMutex.RLock()
+	defer c.cacheMutex.RUnlock()
+
+	if c.sentFetch.IsZero() || time.Since(c.sentFetch) >= maxAge {
+		return nil, false
+	}
+	sent := make([]*models.Email, len(c.sent))
+	copy(sent, c.sent)
+	return sent, true
+}
+
+// storeSent vervangt de gecachte berichten uit de Sent map
+func (c *AccountCache) storeSent(emails []*models.Email) {
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+
+	c.sent = emails
+	c.sentFetch = time.Now()
+}
+
+// invalidateSent leegt de gecachte berichten uit de Sent map, bijvoorbeeld
+// nadat er een bericht aan toegevoegd is
+func (c *AccountCache) invalidateSent() {
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+
+	c.sent = nil
+	c.sentFetch = time.Time{}
+}
+
+// setWatched markeert of een watcher de cache actueel houdt
+func (c *AccountCache) setWatched(watched bool) {
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+
+	c.watched = watched
+}
+
+// isWatched geeft aan of een watcher de cache actueel houdt
+func (c *AccountCache) isWatched() bool {
+	c.cacheMutex.RLock()
+	defer c.cacheMutex.RUnlock()
+
+	return c.watched
+}
+
+// checkUIDValidity legt de UIDVALIDITY van de INBOX vast. Wijkt deze af van de
+// eerder geziene waarde, dan zijn alle gecachte IDs ongeldig en wordt de cache
+// geleegd; de return waarde geeft aan of dat gebeurd is.
+func (c *AccountCache) checkUIDValidity(uidValidity uint32) bool {
+	c.cacheMutex.Lock()
+	defer c.cacheMutex.Unlock()
+
+	reset := c.uidValidity != 0 && c.uidValidity != uidValidity
+	if reset {
+		c.emails = make([]*models.Email, 0)
+		c.lastFetch = time.Time{}
+	}
+	c.uidValidity = uidValidity
+	return reset
+}
+
+// currentUIDValidity geeft de laatst geziene UIDVALIDITY van de INBOX terug
+func (c *AccountCache) currentUIDValidity() uint32 {
+	c.cacheMutex.RLock()
+	defer c.cacheMutex.RUnlock()
+
+	return c.uidValidity
+}
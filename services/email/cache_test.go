@@ -0,0 +1,42 @@
+package email
+
+import (
+	"dklautomationgo/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountCache_UIDValidityResetInvalidates(t *testing.T) {
+	// Setup
+	cache := NewAccountCache()
+	assert.False(t, cache.checkUIDValidity(100))
+	cache.store([]*models.Email{{ID: "info:INBOX:100:1"}})
+
+	// Test: dezelfde UIDVALIDITY behoudt de cache
+	assert.False(t, cache.checkUIDValidity(100))
+	assert.NotNil(t, cache.find("info:INBOX:100:1"))
+
+	// Test: een nieuwe UIDVALIDITY leegt de cache
+	assert.True(t, cache.checkUIDValidity(200))
+	assert.Nil(t, cache.find("info:INBOX:100:1"))
+	assert.False(t, cache.isFresh(0))
+	assert.Equal(t, uint32(200), cache.currentUIDValidity())
+}
+
+func TestAccountCache_UpdateAndRemove(t *testing.T) {
+	// Setup
+	cache := NewAccountCache()
+	cache.store([]*models.Email{{ID: "a"}, {ID: "b"}})
+	snapshot := cache.snapshot()
+
+	// Test
+	assert.True(t, cache.update("a", func(email *models.Email) { email.Flagged = true }))
+	assert.False(t, cache.update("onbekend", func(email *models.Email) {}))
+	assert.True(t, cache.find("a").Flagged)
+
+	cache.remove("a")
+	assert.Nil(t, cache.find("a"))
+	assert.Len(t, snapshot, 2, "eerdere snapshots blijven ongewijzigd")
+	assert.Equal(t, "a", snapshot[0].ID)
+}
@@ -0,0 +1,413 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"dklautomationgo/models"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+	"gopkg.in/gomail.v2"
+)
+
+// ReplyMode bepaalt hoe een antwoord op een email wordt opgebouwd
+type ReplyMode string
+
+const (
+	ReplyModeReply    ReplyMode = "reply"
+	ReplyModeReplyAll ReplyMode = "reply_all"
+	ReplyModeForward  ReplyMode = "forward"
+)
+
+var ErrNoRecipients = errors.New("no recipients specified")
+
+// outgoingMessage is een op te stellen bericht vanuit een van de accounts
+type outgoingMessage struct {
+	account     string
+	to          []string
+	cc          []string
+	bcc         []string
+	subject     string
+	text        string
+	html        string
+	inReplyTo   string
+	references  []string
+	attachments []models.EmailAttachment
+	answers     string            // ID van de email die beantwoord wordt, voor de \Answered flag
+	headers     map[string]string // Extra headers, zoals Auto-Submitted
+}
+
+// ReplyToEmail beantwoordt of stuurt een email door vanuit het account waarop
+// deze ontvangen is. Threading headers worden gezet op basis van het origineel
+// en een kopie wordt in de verzonden map van het account bewaard.
+func (s *EmailService) ReplyToEmail(emailID string, mode ReplyMode, req *models.EmailReplyRequest) (*models.Email, error) {
+	original, err := s.GetEmail(emailID)
+	if err != nil {
+		return nil, err
+	}
+
+	config, ok := s.account(original.Account)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAccount, original.Account)
+	}
+
+	msg := &outgoingMessage{
+		account:     original.Account,
+		cc:          req.Cc,
+		bcc:         req.Bcc,
+		attachments: req.Attachments,
+		references:  threadReferences(original),
+		inReplyTo:   original.MessageID,
+	}
+
+	switch mode {
+	case ReplyModeReply, ReplyModeReplyAll:
+		msg.to = appendUnique(replyRecipients(original), req.To...)
+		if mode == ReplyModeReplyAll {
+			exclude := append([]string{config.Email}, msg.to...)
+			msg.cc = appendUnique(msg.cc, excludeAddresses(originalRecipients(original), exclude)...)
+		}
+		msg.subject = prefixSubject("Re:", original.Subject)
+		msg.text = req.Body + quoteText(original)
+		msg.html = bodyHTML(req) + quoteHTML(original)
+		msg.answers = original.ID
+	case ReplyModeForward:
+		msg.to = req.To
+		msg.subject = prefixSubject("Fwd:", original.Subject)
+		msg.text = req.Body + forwardText(original)
+		msg.html = bodyHTML(req) + forwardHTML(original)
+		msg.attachments = append(append([]models.EmailAttachment{}, original.Attachments...), req.Attachments...)
+	default:
+		return nil, fmt.Errorf("unknown reply mode: %s", mode)
+	}
+
+	if len(msg.to) == 0 {
+		return nil, ErrNoRecipients
+	}
+
+	return s.sendOutgoing(config, msg)
+}
+
+// sendOutgoing bouwt een bericht op, verstuurt het via de SMTP server van het
+// account en bewaart een kopie in de verzonden map
+func (s *EmailService) sendOutgoing(config *EmailConfig, msg *outgoingMessage) (*models.Email, error) {
+	messageID := generateMessageID(config.Email)
+	now := time.Now()
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", config.fromHeader(m))
+	m.SetHeader("To", msg.to...)
+	if len(msg.cc) > 0 {
+		m.SetHeader("Cc", msg.cc...)
+	}
+	if len(msg.bcc) > 0 {
+		m.SetHeader("Bcc", msg.bcc...)
+	}
+	m.SetHeader("Subject", msg.subject)
+	m.SetHeader("Message-ID", messageID)
+	m.SetDateHeader("Date", now)
+	if msg.inReplyTo != "" {
+		m.SetHeader("In-Reply-To", msg.inReplyTo)
+	}
+	if len(msg.references) > 0 {
+		m.SetHeader("References", strings.Join(msg.references, " "))
+	}
+	for name, value := range msg.headers {
+		m.SetHeader(name, value)
+	}
+
+	m.SetBody("text/plain", msg.text)
+	if msg.html != "" {
+		m.AddAlternative("text/html", msg.html)
+	}
+
+	for _, attachment := range msg.attachments {
+		content := attachment.Content
+		settings := []gomail.FileSetting{
+			gomail.SetCopyFunc(func(w io.Writer) error {
+				_, err := w.Write(content)
+				return err
+			}),
+		}
+		if attachment.ContentType != "" {
+			settings = append(settings, gomail.SetHeader(map[string][]string{
+				"Content-Type": {attachment.ContentType},
+			}))
+		}
+		m.Attach(attachment.Filename, settings...)
+	}
+
+	recipients := append(append(append([]string{}, msg.to...), msg.cc...), msg.bcc...)
+	simulated, err := s.deliver(config, m, recipients)
+	if err != nil {
+		return nil, err
+	}
+
+	if !simulated {
+		s.saveToSent(msg.account, config, m, msg.answers)
+	}
+
+	attachments := make([]models.EmailAttachment, len(msg.attachments))
+	for i, attachment := range msg.attachments {
+		attachments[i] = attachment
+		attachments[i].Size = int64(len(attachment.Content))
+	}
+
+	// Geef alleen metadata van de bijlagen terug
+	sent := &models.Email{
+		Sender:      config.Email,
+		Subject:     msg.subject,
+		Body:        msg.text,
+		HTML:        msg.html,
+		Account:     msg.account,
+		MessageID:   messageID,
+		CreatedAt:   now.Format(time.RFC3339),
+		Read:        true,
+		To:          msg.to,
+		Cc:          msg.cc,
+		Bcc:         msg.bcc,
+		InReplyTo:   msg.inReplyTo,
+		References:  msg.references,
+		Attachments: attachments,
+	}
+	return sent.WithoutAttachmentContent(), nil
+}
+
+// saveToSent bewaart een kopie van een verzonden bericht in de verzonden map van
+// het account en markeert een beantwoorde email met \Answered. Fouten worden
+// gelogd maar niet teruggegeven, het bericht is immers al verstuurd.
+func (s *EmailService) saveToSent(accountName string, config *EmailConfig, m *gomail.Message, answers string) {
+	var raw bytes.Buffer
+	if _, err := m.WriteTo(&raw); err != nil {
+		log.Printf("[saveToSent] %s: failed to render message: %v", accountName, err)
+		return
+	}
+
+	err := s.withIMAP(accountName, config, func(c *client.Client) error {
+		folder, err := findSpecialFolder(c, imap.SentAttr, sentFolderNames)
+		if err != nil || folder == "" {
+			log.Printf("[saveToSent] %s: no sent folder found (%v), not storing a copy", accountName, err)
+		} else if err := c.Append(folder, []string{imap.SeenFlag}, time.Now(), &raw); err != nil {
+			log.Printf("[saveToSent] %s: failed to append to %s: %v", accountName, folder, err)
+		} else {
+			// Het antwoord hoort direct in het gesprek te staan
+			s.cache(accountName).invalidateSent()
+		}
+
+		if answers == "" {
+			return nil
+		}
+		ref, err := parseEmailID(answers)
+		if err != nil {
+			return nil
+		}
+		_, seqSet, err := s.selectMessage(c, ref, false)
+		if err != nil {
+			return err
+		}
+		item := imap.FormatFlagsOp(imap.AddFlags, true)
+		if err := c.UidStore(seqSet, item, []interface{}{imap.AnsweredFlag}, nil); err != nil {
+			return fmt.Errorf("failed to flag %s as answered: %w", answers, err)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[saveToSent] %s: %v", accountName, err)
+	}
+}
+
+// generateMessageID maakt een unieke Message-ID binnen het domein van de afzender
+func generateMessageID(from string) string {
+	domain := "dekoninklijkeloop.nl"
+	if at := strings.LastIndex(from, "@"); at != -1 {
+		domain = from[at+1:]
+	}
+
+	random := make([]byte, 8)
+	rand.Read(random)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(random), domain)
+}
+
+// threadReferences bouwt de References header voor een antwoord: de references
+// van het origineel gevolgd door diens Message-ID
+func threadReferences(original *models.Email) []string {
+	references := append([]string{}, original.References...)
+	if len(references) == 0 && original.Headers != nil {
+		references = strings.Fields(original.Headers["References"])
+	}
+	if original.MessageID != "" {
+		references = appendUnique(references, original.MessageID)
+	}
+	return references
+}
+
+// replyRecipients bepaalt aan wie een antwoord gericht is: Reply-To indien
+// aanwezig, anders de afzender
+func replyRecipients(original *models.Email) []string {
+	if len(original.ReplyTo) > 0 {
+		return append([]string{}, original.ReplyTo...)
+	}
+	if original.Sender == "" {
+		return nil
+	}
+	return []string{original.Sender}
+}
+
+// originalRecipients geeft de To en Cc ontvangers van het origineel terug
+func originalRecipients(original *models.Email) []string {
+	recipients := append(append([]string{}, original.To...), original.Cc...)
+	if len(recipients) > 0 || original.Headers == nil {
+		return recipients
+	}
+
+	// Val terug op de ruwe headers voor berichten zonder geparste ontvangers
+	for _, field := range []string{"To", "Cc"} {
+		if addresses, err := mail.ParseAddressList(original.Headers[field]); err == nil {
+			for _, address := range addresses {
+				recipients = append(recipients, address.Address)
+			}
+		}
+	}
+	return recipients
+}
+
+// excludeAddresses filtert adressen uit een lijst (hoofdletterongevoelig)
+func excludeAddresses(addresses []string, exclude []string) []string {
+	var result []string
+	for _, address := range addresses {
+		skip := false
+		for _, e := range exclude {
+			if strings.EqualFold(address, e) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			result = append(result, address)
+		}
+	}
+	return result
+}
+
+// appendUnique voegt waarden toe die nog niet (hoofdletterongevoelig) in de lijst staan
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		exists := false
+		for _, existing := range list {
+			if strings.EqualFold(existing, value) {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// prefixSubject zet een prefix als Re: of Fwd: voor het onderwerp als die er nog niet staat
+func prefixSubject(prefix, subject string) string {
+	if strings.HasPrefix(strings.ToLower(subject), strings.ToLower(prefix)) {
+		return subject
+	}
+	return prefix + " " + subject
+}
+
+// originalText geeft de platte tekst van het origineel, afgeleid van HTML indien nodig
+func originalText(original *models.Email) string {
+	if strings.TrimSpace(original.Body) != "" {
+		return original.Body
+	}
+	return HTMLToText(original.HTML)
+}
+
+// quoteText citeert het origineel met > voor elke regel
+func quoteText(original *models.Email) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n\nOp %s schreef %s:\n", formatQuoteDate(original.CreatedAt), original.Sender)
+	for _, line := range strings.Split(strings.ReplaceAll(originalText(original), "\r\n", "\n"), "\n") {
+		b.WriteString("> ")
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// quoteHTML citeert het origineel in een blockquote
+func quoteHTML(original *models.Email) string {
+	return fmt.Sprintf(
+		`<br><div>Op %s schreef %s:</div><blockquote style="margin:0 0 0 .8ex;border-left:1px solid #ccc;padding-left:1ex">%s</blockquote>`,
+		html.EscapeString(formatQuoteDate(original.CreatedAt)),
+		html.EscapeString(original.Sender),
+		originalHTML(original),
+	)
+}
+
+// forwardText geeft de kop en inhoud van een doorgestuurd bericht als platte tekst
+func forwardText(original *models.Email) string {
+	return fmt.Sprintf(
+		"\n\n---------- Doorgestuurd bericht ----------\nVan: %s\nDatum: %s\nOnderwerp: %s\nAan: %s\n\n%s",
+		original.Sender,
+		formatQuoteDate(original.CreatedAt),
+		original.Subject,
+		strings.Join(originalRecipients(original), ", "),
+		originalText(original),
+	)
+}
+
+// forwardHTML geeft de kop en inhoud van een doorgestuurd bericht als HTML
+func forwardHTML(original *models.Email) string {
+	return fmt.Sprintf(
+		`<br><div>---------- Doorgestuurd bericht ----------<br>Van: %s<br>Datum: %s<br>Onderwerp: %s<br>Aan: %s</div><br>%s`,
+		html.EscapeString(original.Sender),
+		html.EscapeString(formatQuoteDate(original.CreatedAt)),
+		html.EscapeString(original.Subject),
+		html.EscapeString(strings.Join(originalRecipients(original), ", ")),
+		originalHTML(original),
+	)
+}
+
+// originalHTML geeft de HTML van het origineel, of de ge-escapete platte tekst
+func originalHTML(original *models.Email) string {
+	if strings.TrimSpace(original.HTML) != "" {
+		sanitized, _ := SanitizeHTML(original.HTML, RemoteContentAllow, nil)
+		return sanitized
+	}
+	return textToHTML(original.Body)
+}
+
+// bodyHTML geeft de HTML van het nieuwe bericht, afgeleid van de platte tekst indien nodig
+func bodyHTML(req *models.EmailReplyRequest) string {
+	if req.HTML != "" {
+		return req.HTML
+	}
+	return textToHTML(req.Body)
+}
+
+// textToHTML escapet platte tekst en behoudt de regelovergangen
+func textToHTML(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+}
+
+// formatQuoteDate zet een RFC3339 tijdstip om naar een leesbare datum
+func formatQuoteDate(createdAt string) string {
+	t, err := time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		return createdAt
+	}
+	return t.Format("02-01-2006 15:04")
+}
@@ -0,0 +1,62 @@
+package email
+
+import (
+	"dklautomationgo/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThreadReferences(t *testing.T) {
+	// Setup
+	original := &models.Email{
+		MessageID:  "<b@example.org>",
+		References: []string{"<a@example.org>"},
+	}
+
+	// Test
+	assert.Equal(t, []string{"<a@example.org>", "<b@example.org>"}, threadReferences(original))
+}
+
+func TestThreadReferences_FallsBackToHeaders(t *testing.T) {
+	// Setup
+	original := &models.Email{
+		MessageID: "<c@example.org>",
+		Headers:   map[string]string{"References": "<a@example.org> <b@example.org>"},
+	}
+
+	// Test
+	assert.Equal(t, []string{"<a@example.org>", "<b@example.org>", "<c@example.org>"}, threadReferences(original))
+}
+
+func TestReplyRecipients_PrefersReplyTo(t *testing.T) {
+	// Setup
+	original := &models.Email{
+		Sender:  "sender@example.org",
+		ReplyTo: []string{"reply@example.org"},
+	}
+
+	// Test
+	assert.Equal(t, []string{"reply@example.org"}, replyRecipients(original))
+	assert.Equal(t, []string{"sender@example.org"}, replyRecipients(&models.Email{Sender: "sender@example.org"}))
+}
+
+func TestOriginalRecipients_ExcludesOwnAddress(t *testing.T) {
+	// Setup
+	original := &models.Email{
+		Headers: map[string]string{
+			"To": "Info <info@dekoninklijkeloop.nl>, other@example.org",
+			"Cc": "third@example.org",
+		},
+	}
+
+	// Test
+	recipients := excludeAddresses(originalRecipients(original), []string{"INFO@dekoninklijkeloop.nl"})
+	assert.Equal(t, []string{"other@example.org", "third@example.org"}, recipients)
+}
+
+func TestPrefixSubject(t *testing.T) {
+	assert.Equal(t, "Re: Vraag", prefixSubject("Re:", "Vraag"))
+	assert.Equal(t, "RE: Vraag", prefixSubject("Re:", "RE: Vraag"))
+	assert.Equal(t, "Fwd: Re: Vraag", prefixSubject("Fwd:", "Re: Vraag"))
+}
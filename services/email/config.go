@@ -1,10 +1,23 @@
 package email
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"dklautomationgo/models"
+	"fmt"
 	"log"
 	"os"
 	"strconv"
 	"time"
+
+	"gopkg.in/gomail.v2"
+)
+
+// Beveiliging van de IMAP verbinding
+const (
+	IMAPSecurityTLS      = "tls"      // Impliciete TLS, standaard op poort 993
+	IMAPSecurityStartTLS = "starttls" // STARTTLS na het verbinden, standaard op poort 143
+	IMAPSecurityNone     = "none"     // Onversleuteld, alleen voor lokale testservers
 )
 
 // EmailConfig bevat de configuratie voor een email account
@@ -15,6 +28,65 @@ type EmailConfig struct {
 	IMAPPort int
 	SMTPHost string
 	SMTPPort int
+
+	// IMAPSecurity is tls, starttls of none; leeg kiest op basis van de poort
+	IMAPSecurity string
+	// TLSSkipVerify schakelt de controle van het servercertificaat uit (IMAP en SMTP).
+	// Alleen bedoeld voor testen, standaard wordt het certificaat gecontroleerd.
+	TLSSkipVerify bool
+	// TLSCAFile is een PEM bundel met CA certificaten die naast de systeem CAs vertrouwd worden
+	TLSCAFile string
+
+	// DisplayName is de naam van de afzender in de From header
+	DisplayName string
+	// DefaultFor bevat de doelen (models.EmailPurpose*) waarvoor dit account de afzender is
+	DefaultFor []string
+}
+
+// fromHeader geeft de From header van het account, met de naam van de afzender als die ingesteld is
+func (c *EmailConfig) fromHeader(m *gomail.Message) string {
+	if c.DisplayName == "" {
+		return c.Email
+	}
+	return m.FormatAddress(c.Email, c.DisplayName)
+}
+
+// imapSecurity geeft de beveiliging van de IMAP verbinding, met poort 143 als STARTTLS
+func (c *EmailConfig) imapSecurity() string {
+	if c.IMAPSecurity != "" {
+		return c.IMAPSecurity
+	}
+	if c.IMAPPort == 143 {
+		return IMAPSecurityStartTLS
+	}
+	return IMAPSecurityTLS
+}
+
+// tlsConfig bouwt de TLS configuratie voor een verbinding met host. Het
+// certificaat wordt gecontroleerd tegen de systeem CAs en de optionele CA bundel.
+func (c *EmailConfig) tlsConfig(host string) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: c.TLSSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if c.TLSCAFile != "" {
+		pem, err := os.ReadFile(c.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", c.TLSCAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	return config, nil
 }
 
 // CacheConfig bevat de configuratie voor email caching
@@ -24,15 +96,85 @@ type CacheConfig struct {
 	MaxEntries int
 }
 
+// WatchConfig bevat de configuratie voor de IMAP IDLE watchers
+type WatchConfig struct {
+	Enabled           bool
+	PollInterval      time.Duration // NOOP interval voor servers zonder IDLE ondersteuning
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+}
+
 // ServiceConfig bevat alle configuratie voor de email service
 type ServiceConfig struct {
 	Accounts     map[string]*EmailConfig
 	Cache        CacheConfig
+	Watch        WatchConfig
 	FetchTimeout time.Duration
 	DevMode      bool // Ontwikkelingsmodus voor testen
+
+	// ImageProxySecret ondertekent URLs van de image proxy voor externe afbeeldingen
+	ImageProxySecret string
+	// UnsubscribeURL wordt naast het mailto adres in de List-Unsubscribe header
+	// gezet en moet een POST voor one-click afmelden accepteren (RFC 8058)
+	UnsubscribeURL string
+	// DKIM ondertekent uitgaande mail als er een key ingesteld is
+	DKIM DKIMConfig
+}
+
+// DevMode geeft aan of de applicatie in ontwikkelingsmodus draait (DEV_MODE)
+func DevMode() bool {
+	devModeStr := os.Getenv("DEV_MODE")
+	return devModeStr == "true" || devModeStr == "1"
 }
 
 func GetDefaultConfig() *ServiceConfig {
+	// Check development mode
+	devMode := DevMode()
+	if devMode {
+		log.Printf("[GetDefaultConfig] Running in DEVELOPMENT mode - emails to external domains will be simulated")
+	}
+
+	// IDLE watchers staan standaard aan en kunnen uitgezet worden
+	watchEnabled := true
+	if watchStr := os.Getenv("EMAIL_WATCH_ENABLED"); watchStr == "false" || watchStr == "0" {
+		watchEnabled = false
+	}
+
+	dkimDomain := os.Getenv("DKIM_DOMAIN")
+	if dkimDomain == "" {
+		dkimDomain = "dekoninklijkeloop.nl"
+	}
+
+	return &ServiceConfig{
+		Accounts: EnvAccounts(),
+		Cache: CacheConfig{
+			Enabled:    true,
+			Duration:   5 * time.Minute,
+			MaxEntries: 1000,
+		},
+		Watch: WatchConfig{
+			Enabled:           watchEnabled,
+			PollInterval:      time.Minute,
+			ReconnectDelay:    5 * time.Second,
+			MaxReconnectDelay: 5 * time.Minute,
+		},
+		FetchTimeout:     2 * time.Minute,
+		DevMode:          devMode,
+		ImageProxySecret: os.Getenv("EMAIL_IMAGE_PROXY_SECRET"),
+		UnsubscribeURL:   os.Getenv("EMAIL_UNSUBSCRIBE_URL"),
+		DKIM: DKIMConfig{
+			KeyFile:  os.Getenv("DKIM_KEY_FILE"),
+			Selector: os.Getenv("DKIM_SELECTOR"),
+			Domain:   dkimDomain,
+			Headers:  dkimHeaders(os.Getenv("DKIM_HEADERS")),
+		},
+	}
+}
+
+// EnvAccounts geeft de vaste accounts info, inschrijving en noreply met de
+// instellingen uit de omgevingsvariabelen. Ze worden gebruikt zolang er geen
+// accounts in een configuratiebestand of de database staan.
+func EnvAccounts() map[string]*EmailConfig {
 	smtpHost := os.Getenv("SMTP_HOST")
 	if smtpHost == "" {
 		smtpHost = "smtp.hostnet.nl"
@@ -46,57 +188,62 @@ func GetDefaultConfig() *ServiceConfig {
 		}
 	}
 
-	// Check development mode
-	devMode := false
-	devModeStr := os.Getenv("DEV_MODE")
-	if devModeStr == "true" || devModeStr == "1" {
-		devMode = true
-		log.Printf("[GetDefaultConfig] Running in DEVELOPMENT mode - emails to external domains will be simulated")
+	imapHost := os.Getenv("IMAP_HOST")
+	if imapHost == "" {
+		imapHost = "imap.hostnet.nl"
 	}
 
+	imapPort := 993 // Default port for implicit TLS
+	if port, err := strconv.Atoi(os.Getenv("IMAP_PORT")); err == nil {
+		imapPort = port
+	}
+
+	imapSecurity := os.Getenv("IMAP_SECURITY")
+	switch imapSecurity {
+	case "", IMAPSecurityTLS, IMAPSecurityStartTLS, IMAPSecurityNone:
+	default:
+		log.Printf("[EnvAccounts] Warning: Unknown IMAP_SECURITY %q, choosing based on port", imapSecurity)
+		imapSecurity = ""
+	}
+
+	// Certificaten worden standaard gecontroleerd; uitzetten alleen voor testen
+	tlsSkipVerify := false
+	if skipStr := os.Getenv("EMAIL_TLS_SKIP_VERIFY"); skipStr == "true" || skipStr == "1" {
+		tlsSkipVerify = true
+		log.Printf("[EnvAccounts] Warning: TLS certificate verification is DISABLED for IMAP and SMTP")
+	}
+	tlsCAFile := os.Getenv("EMAIL_TLS_CA_FILE")
+
 	// Log the SMTP configuration
-	log.Printf("[GetDefaultConfig] Using SMTP configuration - Host: %s, Port: %d", smtpHost, smtpPort)
+	log.Printf("[EnvAccounts] Using SMTP configuration - Host: %s, Port: %d", smtpHost, smtpPort)
 	if smtpPort == 465 {
-		log.Printf("[GetDefaultConfig] Using implicit SSL/TLS for SMTP")
+		log.Printf("[EnvAccounts] Using implicit SSL/TLS for SMTP")
 	} else if smtpPort == 587 {
-		log.Printf("[GetDefaultConfig] Using STARTTLS for SMTP")
+		log.Printf("[EnvAccounts] Using STARTTLS for SMTP")
 	} else {
-		log.Printf("[GetDefaultConfig] Warning: Unusual SMTP port %d, please verify configuration", smtpPort)
+		log.Printf("[EnvAccounts] Warning: Unusual SMTP port %d, please verify configuration", smtpPort)
 	}
 
-	return &ServiceConfig{
-		Accounts: map[string]*EmailConfig{
-			"info": {
-				Email:    os.Getenv("SMTP_USER"),
-				Password: os.Getenv("SMTP_PASSWORD"),
-				IMAPHost: "imap.hostnet.nl",
-				IMAPPort: 993,
-				SMTPHost: smtpHost,
-				SMTPPort: smtpPort,
-			},
-			"inschrijving": {
-				Email:    "inschrijving@dekoninklijkeloop.nl",
-				Password: os.Getenv("INSCHRIJVING_EMAIL_PASSWORD"),
-				IMAPHost: "imap.hostnet.nl",
-				IMAPPort: 993,
-				SMTPHost: smtpHost,
-				SMTPPort: smtpPort,
-			},
-			"noreply": {
-				Email:    "noreply@dekoninklijkeloop.nl",
-				Password: os.Getenv("NOREPLY_EMAIL_PASSWORD"),
-				IMAPHost: "imap.hostnet.nl",
-				IMAPPort: 993,
-				SMTPHost: smtpHost,
-				SMTPPort: smtpPort,
-			},
-		},
-		Cache: CacheConfig{
-			Enabled:    true,
-			Duration:   5 * time.Minute,
-			MaxEntries: 1000,
-		},
-		FetchTimeout: 2 * time.Minute,
-		DevMode:      devMode,
+	account := func(address, password string, defaultFor ...string) *EmailConfig {
+		return &EmailConfig{
+			Email:         address,
+			Password:      password,
+			IMAPHost:      imapHost,
+			IMAPPort:      imapPort,
+			SMTPHost:      smtpHost,
+			SMTPPort:      smtpPort,
+			IMAPSecurity:  imapSecurity,
+			TLSSkipVerify: tlsSkipVerify,
+			TLSCAFile:     tlsCAFile,
+			DefaultFor:    defaultFor,
+		}
+	}
+
+	// Alle automatische mails gaan vanaf info
+	return map[string]*EmailConfig{
+		"info": account(os.Getenv("SMTP_USER"), os.Getenv("SMTP_PASSWORD"),
+			models.EmailPurposeConfirmation, models.EmailPurposeNotification, models.EmailPurposeReply),
+		"inschrijving": account("inschrijving@dekoninklijkeloop.nl", os.Getenv("INSCHRIJVING_EMAIL_PASSWORD")),
+		"noreply":      account("noreply@dekoninklijkeloop.nl", os.Getenv("NOREPLY_EMAIL_PASSWORD")),
 	}
 }
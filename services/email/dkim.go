@@ -0,0 +1,374 @@
+package email
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/gomail.v2"
+)
+
+// DefaultDKIMHeaders zijn de headers die standaard ondertekend worden, voor
+// zover ze in het bericht staan. From wordt altijd ondertekend.
+var DefaultDKIMHeaders = []string{
+	"From", "Reply-To", "To", "Cc", "Subject", "Date", "Message-ID",
+	"In-Reply-To", "References", "MIME-Version", "Content-Type",
+	"List-Unsubscribe", "List-Unsubscribe-Post",
+}
+
+// DKIMConfig bevat de instellingen voor het ondertekenen van uitgaande mail
+type DKIMConfig struct {
+	KeyFile  string   // PEM bestand met een RSA of Ed25519 private key
+	Selector string   // Selector van het DNS record <selector>._domainkey.<domain>
+	Domain   string   // Domein in de handtekening (d=)
+	Headers  []string // Te ondertekenen headers; leeg gebruikt DefaultDKIMHeaders
+}
+
+// DKIMSigner ondertekent berichten volgens RFC 6376 met relaxed/relaxed
+// canonicalisatie, met rsa-sha256 of ed25519-sha256 (RFC 8463)
+type DKIMSigner struct {
+	domain    string
+	selector  string
+	headers   []string
+	key       crypto.Signer
+	algorithm string
+	now       func() time.Time
+}
+
+// NewDKIMSigner leest de private key en controleert de instellingen
+func NewDKIMSigner(config DKIMConfig) (*DKIMSigner, error) {
+	if config.Selector == "" || config.Domain == "" {
+		return nil, errors.New("DKIM selector and domain are required")
+	}
+
+	data, err := os.ReadFile(config.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DKIM key: %w", err)
+	}
+	key, err := parseDKIMKey(data)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := config.Headers
+	if len(headers) == 0 {
+		headers = DefaultDKIMHeaders
+	}
+	hasFrom := false
+	for _, header := range headers {
+		if strings.EqualFold(header, "From") {
+			hasFrom = true
+		}
+	}
+	if !hasFrom {
+		headers = append([]string{"From"}, headers...)
+	}
+
+	signer := &DKIMSigner{
+		domain:   strings.ToLower(config.Domain),
+		selector: config.Selector,
+		headers:  headers,
+		key:      key,
+		now:      time.Now,
+	}
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		signer.algorithm = "rsa-sha256"
+	case ed25519.PrivateKey:
+		signer.algorithm = "ed25519-sha256"
+	}
+	return signer, nil
+}
+
+// parseDKIMKey leest een PKCS#1 RSA key of een PKCS#8 RSA of Ed25519 key
+func parseDKIMKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found in DKIM key")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse DKIM key: %w", err)
+		}
+		return key, nil
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse DKIM key: %w", err)
+		}
+		switch key := key.(type) {
+		case *rsa.PrivateKey:
+			return key, nil
+		case ed25519.PrivateKey:
+			return key, nil
+		default:
+			return nil, fmt.Errorf("unsupported DKIM key type %T", key)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q in DKIM key", block.Type)
+	}
+}
+
+// SignsFor geeft aan of berichten van dit adres ondertekend worden: het domein
+// van het adres moet het DKIM domein of een subdomein daarvan zijn
+func (d *DKIMSigner) SignsFor(address string) bool {
+	at := strings.LastIndex(address, "@")
+	if at == -1 {
+		return false
+	}
+	domain := strings.ToLower(strings.TrimSuffix(address[at+1:], ">"))
+	return domain == d.domain || strings.HasSuffix(domain, "."+d.domain)
+}
+
+// DNSRecord geeft de inhoud van het TXT record voor <selector>._domainkey.<domain>
+func (d *DKIMSigner) DNSRecord() string {
+	var keyType string
+	var public []byte
+	switch key := d.key.(type) {
+	case *rsa.PrivateKey:
+		keyType = "rsa"
+		public, _ = x509.MarshalPKIXPublicKey(&key.PublicKey)
+	case ed25519.PrivateKey:
+		keyType = "ed25519"
+		public = key.Public().(ed25519.PublicKey)
+	}
+	return fmt.Sprintf("v=DKIM1; k=%s; p=%s", keyType, base64.StdEncoding.EncodeToString(public))
+}
+
+// Sign geeft het bericht terug met een DKIM-Signature header ervoor
+func (d *DKIMSigner) Sign(message []byte) ([]byte, error) {
+	message = toCRLF(message)
+	header, body := splitMessage(message)
+	fields := parseHeaderFields(header)
+
+	bodyHash := sha256.Sum256(relaxedBody(body))
+
+	// Meerdere exemplaren van een header worden van onder naar boven ondertekend
+	var names []string
+	var signed bytes.Buffer
+	seen := make(map[string]bool)
+	for _, name := range d.headers {
+		key := strings.ToLower(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		matches := fieldsNamed(fields, key)
+		for i := len(matches) - 1; i >= 0; i-- {
+			signed.WriteString(relaxedHeader(matches[i]))
+			names = append(names, key)
+		}
+	}
+
+	value := fmt.Sprintf("v=1; a=%s; c=relaxed/relaxed; d=%s; s=%s;\r\n\tt=%d; h=%s;\r\n\tbh=%s;\r\n\tb=",
+		d.algorithm, d.domain, d.selector, d.now().Unix(), strings.Join(names, ":"),
+		base64.StdEncoding.EncodeToString(bodyHash[:]))
+	signatureField := "DKIM-Signature: " + value
+
+	// De DKIM-Signature header zelf telt mee zonder b= waarde en zonder CRLF
+	signed.WriteString(strings.TrimSuffix(relaxedHeader(signatureField), "\r\n"))
+	digest := sha256.Sum256(signed.Bytes())
+
+	var signature []byte
+	var err error
+	switch d.key.(type) {
+	case ed25519.PrivateKey:
+		signature, err = d.key.Sign(rand.Reader, digest[:], crypto.Hash(0))
+	default:
+		signature, err = d.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign message: %w", err)
+	}
+
+	var result bytes.Buffer
+	result.WriteString(signatureField)
+	result.WriteString(foldBase64(base64.StdEncoding.EncodeToString(signature)))
+	result.WriteString("\r\n")
+	result.Write(message)
+	return result.Bytes(), nil
+}
+
+// dkimSender ondertekent berichten voordat ze aan de SMTP verbinding gegeven worden
+type dkimSender struct {
+	gomail.Sender
+	signer *DKIMSigner
+}
+
+func (s dkimSender) Send(from string, to []string, msg io.WriterTo) error {
+	var raw bytes.Buffer
+	if _, err := msg.WriteTo(&raw); err != nil {
+		return fmt.Errorf("failed to render message: %w", err)
+	}
+	signed, err := s.signer.Sign(raw.Bytes())
+	if err != nil {
+		return err
+	}
+	return s.Sender.Send(from, to, bytes.NewReader(signed))
+}
+
+// dialAndSend verstuurt een bericht, met een DKIM handtekening als die voor de afzender ingesteld is
+func (s *EmailService) dialAndSend(d *gomail.Dialer, emailConfig *EmailConfig, m *gomail.Message) error {
+	if s.dkim == nil || !s.dkim.SignsFor(emailConfig.Email) {
+		return d.DialAndSend(m)
+	}
+
+	sc, err := d.Dial()
+	if err != nil {
+		return err
+	}
+	if err := gomail.Send(dkimSender{Sender: sc, signer: s.dkim}, m); err != nil {
+		sc.Close()
+		return err
+	}
+	return sc.Close()
+}
+
+// headerField is een header zoals die in het bericht staat, inclusief vervolgregels
+type headerField struct {
+	name string // in kleine letters
+	raw  string // de volledige header zonder afsluitende CRLF
+}
+
+// toCRLF zet losse LF regeleinden om naar CRLF
+func toCRLF(message []byte) []byte {
+	if !bytes.Contains(message, []byte("\n")) {
+		return message
+	}
+	normalized := bytes.ReplaceAll(message, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(normalized, []byte("\n"), []byte("\r\n"))
+}
+
+// splitMessage splitst een bericht in de headers en de body
+func splitMessage(message []byte) (string, []byte) {
+	if i := bytes.Index(message, []byte("\r\n\r\n")); i != -1 {
+		return string(message[:i+2]), message[i+4:]
+	}
+	return string(message), nil
+}
+
+// parseHeaderFields leest de headers, met vervolgregels bij de header waar ze bij horen
+func parseHeaderFields(header string) []headerField {
+	var fields []headerField
+	for _, line := range strings.SplitAfter(header, "\r\n") {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1].raw += line
+			continue
+		}
+		name := line
+		if colon := strings.Index(line, ":"); colon != -1 {
+			name = line[:colon]
+		}
+		fields = append(fields, headerField{name: strings.ToLower(strings.TrimSpace(name)), raw: line})
+	}
+	for i := range fields {
+		fields[i].raw = strings.TrimSuffix(fields[i].raw, "\r\n")
+	}
+	return fields
+}
+
+// fieldsNamed geeft de headers met de gegeven naam in volgorde van het bericht
+func fieldsNamed(fields []headerField, name string) []string {
+	var matches []string
+	for _, field := range fields {
+		if field.name == name {
+			matches = append(matches, field.raw)
+		}
+	}
+	return matches
+}
+
+// relaxedHeader canonicaliseert een header volgens de relaxed methode: naam
+// in kleine letters, vervolgregels samengevoegd en witruimte tot één spatie
+func relaxedHeader(raw string) string {
+	colon := strings.Index(raw, ":")
+	if colon == -1 {
+		return ""
+	}
+	name := strings.ToLower(strings.TrimSpace(raw[:colon]))
+	value := strings.NewReplacer("\r\n", "").Replace(raw[colon+1:])
+	value = strings.Join(strings.FieldsFunc(value, isWSP), " ")
+	return name + ":" + value + "\r\n"
+}
+
+// relaxedBody canonicaliseert de body volgens de relaxed methode: witruimte
+// per regel tot één spatie, geen witruimte aan het einde van een regel en geen
+// lege regels aan het einde van de body
+func relaxedBody(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	for i, line := range lines {
+		trimmed := strings.TrimRight(line, " \t")
+		if trimmed == "" {
+			lines[i] = ""
+			continue
+		}
+		// Witruimte binnen de regel wordt één spatie, ook aan het begin
+		var b strings.Builder
+		inWSP := false
+		for _, r := range trimmed {
+			if r == ' ' || r == '\t' {
+				inWSP = true
+				continue
+			}
+			if inWSP {
+				b.WriteByte(' ')
+				inWSP = false
+			}
+			b.WriteRune(r)
+		}
+		lines[i] = b.String()
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+func isWSP(r rune) bool {
+	return r == ' ' || r == '\t'
+}
+
+// foldBase64 breekt een lange base64 waarde af over regels van 72 tekens
+func foldBase64(value string) string {
+	const width = 72
+	var b strings.Builder
+	for len(value) > width {
+		b.WriteString(value[:width])
+		b.WriteString("\r\n\t ")
+		value = value[width:]
+	}
+	b.WriteString(value)
+	return b.String()
+}
+
+// dkimHeaders leest de lijst met te ondertekenen headers uit een komma-gescheiden waarde
+func dkimHeaders(value string) []string {
+	var headers []string
+	for _, header := range strings.Split(value, ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			headers = append(headers, header)
+		}
+	}
+	return headers
+}
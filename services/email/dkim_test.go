@@ -0,0 +1,200 @@
+package email
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dklautomationgo/models"
+
+	"github.com/emersion/go-msgauth/dkim"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/gomail.v2"
+)
+
+// newTestDKIMSigner schrijft de key als PEM bestand en maakt er een signer mee
+func newTestDKIMSigner(t *testing.T, key crypto.Signer, headers ...string) *DKIMSigner {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	file := filepath.Join(t.TempDir(), "dkim.pem")
+	require.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+
+	signer, err := NewDKIMSigner(DKIMConfig{KeyFile: file, Selector: "mail2026", Domain: "dekoninklijkeloop.nl", Headers: headers})
+	require.NoError(t, err)
+	return signer
+}
+
+// newTestDKIMMessage bouwt een bevestiging zoals sendEmail die verstuurt
+func newTestDKIMMessage(t *testing.T) []byte {
+	t.Helper()
+	service := newTestSendService()
+	service.config.UnsubscribeURL = "https://dekoninklijkeloop.nl/afmelden"
+	config := &EmailConfig{Email: "noreply@dekoninklijkeloop.nl", DisplayName: "De Koninklijke Loop"}
+	body := "<p>Beste Jan,</p>\n<p>Bedankt voor je aanmelding   voor de  loop.</p>\n\n\n"
+	m := service.buildEmail(config, "jan@example.org", "info@dekoninklijkeloop.nl", "Bedankt voor je aanmelding", body, "")
+	m.SetHeader("List-Unsubscribe", service.listUnsubscribe(models.EmailPurposeConfirmation))
+
+	var raw bytes.Buffer
+	_, err := m.WriteTo(&raw)
+	require.NoError(t, err)
+	return raw.Bytes()
+}
+
+// verifyDKIM controleert de DKIM-Signature van een bericht zoals een ontvangende
+// mailserver dat doet: met de verificatie van go-msgauth, een implementatie los
+// van de signer, en de publieke key uit het DNS record
+func verifyDKIM(message []byte, dnsRecord string) error {
+	verifications, err := dkim.VerifyWithOptions(bytes.NewReader(message), &dkim.VerifyOptions{
+		LookupTXT: func(domain string) ([]string, error) {
+			if domain != "mail2026._domainkey.dekoninklijkeloop.nl" {
+				return nil, fmt.Errorf("unexpected DNS lookup for %s", domain)
+			}
+			return []string{dnsRecord}, nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if len(verifications) != 1 {
+		return fmt.Errorf("expected one DKIM signature, found %d", len(verifications))
+	}
+	return verifications[0].Err
+}
+
+// parseTags leest tag=waarde paren, zonder witruimte in de waarden
+func parseTags(value string) map[string]string {
+	tags := make(map[string]string)
+	for _, part := range strings.Split(value, ";") {
+		name, tagValue, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		tags[strings.TrimSpace(name)] = strings.Join(strings.Fields(tagValue), "")
+	}
+	return tags
+}
+
+func TestDKIMSigner_SignedMessageVerifies(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		key       crypto.Signer
+		algorithm string
+	}{
+		{"rsa", rsaKey, "rsa-sha256"},
+		{"ed25519", edKey, "ed25519-sha256"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup
+			signer := newTestDKIMSigner(t, tt.key)
+			message := newTestDKIMMessage(t)
+
+			// Test
+			signed, err := signer.Sign(message)
+			require.NoError(t, err)
+
+			// Controleer het resultaat
+			require.NoError(t, verifyDKIM(signed, signer.DNSRecord()))
+			tags := parseTags(strings.SplitN(string(signed), "\r\n\r\n", 2)[0][len("DKIM-Signature:"):])
+			assert.Equal(t, tt.algorithm, tags["a"])
+			assert.Equal(t, "dekoninklijkeloop.nl", tags["d"])
+			assert.Equal(t, "mail2026", tags["s"])
+			assert.Equal(t, "from:reply-to:to:subject:date:message-id:mime-version:content-type:list-unsubscribe", tags["h"])
+			assert.True(t, bytes.HasSuffix(signed, message))
+
+			// Een gewijzigd onderwerp of een gewijzigde body maakt de handtekening ongeldig
+			tampered := bytes.Replace(signed, []byte("Subject: Bedankt"), []byte("Subject: Gratis"), 1)
+			assert.Error(t, verifyDKIM(tampered, signer.DNSRecord()))
+			tampered = bytes.Replace(signed, []byte("Beste Jan"), []byte("Beste Piet"), 1)
+			assert.ErrorContains(t, verifyDKIM(tampered, signer.DNSRecord()), "body hash did not verify")
+		})
+	}
+}
+
+func TestDKIMSigner_ConfiguredHeaders(t *testing.T) {
+	// Setup
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer := newTestDKIMSigner(t, key, "Subject", "To", "X-Bestaat-Niet")
+
+	// Test
+	signed, err := signer.Sign(newTestDKIMMessage(t))
+	require.NoError(t, err)
+
+	// Controleer het resultaat: From wordt altijd ondertekend, ontbrekende headers niet
+	require.NoError(t, verifyDKIM(signed, signer.DNSRecord()))
+	tags := parseTags(strings.SplitN(string(signed), "\r\n\r\n", 2)[0][len("DKIM-Signature:"):])
+	assert.Equal(t, "from:subject:to", tags["h"])
+}
+
+func TestDKIMSender_SignsInSendPath(t *testing.T) {
+	// Setup
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer := newTestDKIMSigner(t, key)
+	m := gomail.NewMessage()
+	m.SetHeader("From", "info@dekoninklijkeloop.nl")
+	m.SetHeader("To", "jan@example.org")
+	m.SetHeader("Bcc", "archief@dekoninklijkeloop.nl")
+	m.SetHeader("Subject", "Test")
+	m.SetBody("text/plain", "Hallo")
+
+	var sent []byte
+	var recipients []string
+	capture := gomail.SendFunc(func(from string, to []string, msg io.WriterTo) error {
+		var raw bytes.Buffer
+		_, err := msg.WriteTo(&raw)
+		sent, recipients = raw.Bytes(), to
+		return err
+	})
+
+	// Test
+	require.NoError(t, gomail.Send(dkimSender{Sender: capture, signer: signer}, m))
+
+	// Controleer het resultaat
+	assert.True(t, bytes.HasPrefix(sent, []byte("DKIM-Signature: v=1; a=ed25519-sha256;")))
+	assert.NoError(t, verifyDKIM(sent, signer.DNSRecord()))
+	assert.ElementsMatch(t, []string{"jan@example.org", "archief@dekoninklijkeloop.nl"}, recipients)
+	assert.NotContains(t, string(sent), "archief@")
+}
+
+func TestDKIMSigner_SignsFor(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer := newTestDKIMSigner(t, key)
+
+	assert.True(t, signer.SignsFor("noreply@dekoninklijkeloop.nl"))
+	assert.True(t, signer.SignsFor("info@Mail.DeKoninklijkeLoop.nl"))
+	assert.False(t, signer.SignsFor("info@koninklijkeloop.nl"))
+	assert.False(t, signer.SignsFor("geen adres"))
+}
+
+func TestNewDKIMSigner_InvalidConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "dkim.pem")
+	require.NoError(t, os.WriteFile(file, []byte("geen key"), 0600))
+
+	_, err := NewDKIMSigner(DKIMConfig{KeyFile: file, Selector: "mail", Domain: "dekoninklijkeloop.nl"})
+	assert.Error(t, err)
+	_, err = NewDKIMSigner(DKIMConfig{KeyFile: file, Domain: "dekoninklijkeloop.nl"})
+	assert.Error(t, err)
+	_, err = NewDKIMSigner(DKIMConfig{KeyFile: filepath.Join(t.TempDir(), "ontbreekt.pem"), Selector: "mail", Domain: "dekoninklijkeloop.nl"})
+	assert.Error(t, err)
+}
@@ -1,144 +1,435 @@
 package email
 
 import (
-	"bytes"
-	"regexp"
+	"log"
+	"strconv"
 	"strings"
-)
+	"unicode"
 
-var (
-	// Voorgecompileerde regex patterns voor betere performance
-	scriptStyleRegex = regexp.MustCompile(`(?i)<(script|style)[^>]*>[\s\S]*?</($1)>`)
-	commentRegex     = regexp.MustCompile(`<!--[\s\S]*?-->`)
-	tagRegex         = regexp.MustCompile(`<[^>]*>`)
-	entityRegex      = regexp.MustCompile(`&[#a-zA-Z0-9]+;`)
-	whitespaceRegex  = regexp.MustCompile(`[ \t]+`)
-
-	// Common HTML entities map
-	htmlEntities = map[string]string{
-		"&nbsp;":  " ",
-		"&amp;":   "&",
-		"&lt;":    "<",
-		"&gt;":    ">",
-		"&quot;":  "\"",
-		"&apos;":  "'",
-		"&cent;":  "¢",
-		"&pound;": "£",
-		"&euro;":  "€",
-		"&copy;":  "©",
-		"&reg;":   "®",
-		"&trade;": "™",
-		"&#8216;": "'",
-		"&#8217;": "'",
-		"&#8220;": `"`,
-		"&#8221;": `"`,
-		"&#8230;": "...",
-		"&bull;":  "•",
-		"&ndash;": "–",
-		"&mdash;": "—",
-		"&lsquo;": "'",
-		"&rsquo;": "'",
-		"&ldquo;": `"`,
-		"&rdquo;": `"`,
-	}
-
-	// Semantic replacements voor HTML elementen
-	blockElements = map[string]string{
-		"</p>":       "\n\n",
-		"</div>":     "\n",
-		"</tr>":      "\n",
-		"</table>":   "\n\n",
-		"</h1>":      "\n\n",
-		"</h2>":      "\n\n",
-		"</h3>":      "\n\n",
-		"</h4>":      "\n\n",
-		"</h5>":      "\n\n",
-		"</h6>":      "\n\n",
-		"</pre>":     "\n\n",
-		"</form>":    "\n\n",
-		"</ul>":      "\n\n",
-		"</ol>":      "\n\n",
-		"</li>":      "\n",
-		"<br>":       "\n",
-		"<br/>":      "\n",
-		"<br />":     "\n",
-		"</article>": "\n\n",
-		"</section>": "\n\n",
-	}
-
-	// Inline element replacements voor opmaak behoud
-	inlineElements = map[string]string{
-		"</em>":     "_", // Markering voor emphasis
-		"</i>":      "_",
-		"</b>":      "*", // Markering voor bold
-		"</strong>": "*",
-	}
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
 )
 
+// maxHTMLSize is de maximale hoeveelheid HTML die naar tekst omgezet wordt
+const maxHTMLSize = 10 * 1024 * 1024
+
 // ProcessHTML verwerkt HTML content naar leesbare platte tekst met behoud van basis opmaak
 func (s *EmailService) ProcessHTML(html string) string {
-	if strings.TrimSpace(html) == "" {
+	return HTMLToText(html)
+}
+
+// HTMLToText zet HTML om naar platte tekst. De HTML wordt met de parser van
+// golang.org/x/net/html gelezen, zodat alle entities, hoofdletter-tags en niet
+// afgesloten elementen net als in een browser behandeld worden. Paragrafen en
+// regels blijven behouden, links worden "tekst (url)", lijsten krijgen "- " of
+// "1. ", tabelrijen komen op één regel en blockquotes worden met "> " geciteerd.
+func HTMLToText(input string) string {
+	if strings.TrimSpace(input) == "" {
 		return ""
 	}
 
-	// Maximum grootte check om oneindige loops te voorkomen
-	if len(html) > 10*1024*1024 { // 10MB limit
-		html = html[:10*1024*1024]
+	// Maximum grootte check om extreem grote berichten te begrenzen
+	if len(input) > maxHTMLSize {
+		input = input[:maxHTMLSize]
+	}
+
+	doc, err := html.Parse(strings.NewReader(input))
+	if err != nil {
+		log.Printf("[HTMLToText] Failed to parse HTML: %v", err)
+		return ""
 	}
 
-	var buf bytes.Buffer
-	buf.Grow(len(html)) // Pre-allocate buffer
+	t := &textConverter{}
+	t.render(doc)
+	return strings.Join(t.result(), "\n")
+}
+
+// textConverter bouwt de tekst regel voor regel op
+type textConverter struct {
+	lines        []string        // afgeronde regels, inclusief prefix
+	line         strings.Builder // de regel die nu opgebouwd wordt
+	pendingSpace bool            // of er witruimte staat tussen de vorige en volgende tekst
+	prefixes     []string        // "> " voor blockquotes en inspringing voor lijsten
+	marker       string          // lijstmarkering voor de eerste regel van een <li>
+	markerDepth  int             // niveau in prefixes waar de marker de inspringing vervangt
+	lists        []*listState    // geneste <ul> en <ol> elementen
+	pre          int             // > 0 binnen <pre>, waar witruimte behouden blijft
+}
+
+// listState houdt de nummering van een lijst bij
+type listState struct {
+	ordered bool
+	next    int
+}
+
+// skippedElements worden inclusief inhoud weggelaten
+var skippedElements = map[atom.Atom]bool{
+	atom.Head: true, atom.Script: true, atom.Style: true, atom.Title: true,
+	atom.Template: true, atom.Noscript: true, atom.Iframe: true, atom.Object: true,
+	atom.Svg: true, atom.Math: true, atom.Select: true, atom.Button: true,
+}
+
+// paragraphElements worden door een lege regel van de omliggende tekst gescheiden
+var paragraphElements = map[atom.Atom]bool{
+	atom.P: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true,
+	atom.H5: true, atom.H6: true, atom.Table: true, atom.Pre: true, atom.Dl: true,
+}
 
-	// 1. Verwijder scripts, styles en comments
-	html = scriptStyleRegex.ReplaceAllString(html, "")
-	html = commentRegex.ReplaceAllString(html, "")
+// blockElements beginnen en eindigen op een nieuwe regel
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Center: true,
+	atom.Dd: true, atom.Details: true, atom.Div: true, atom.Dt: true,
+	atom.Fieldset: true, atom.Figcaption: true, atom.Figure: true, atom.Footer: true,
+	atom.Form: true, atom.Header: true, atom.Legend: true, atom.Main: true,
+	atom.Nav: true, atom.Section: true, atom.Summary: true, atom.Caption: true,
+	atom.Tr: true,
+}
 
-	// 2. Vervang block elements met newlines
-	for tag, replacement := range blockElements {
-		html = strings.ReplaceAll(html, strings.ToLower(tag), replacement)
-		html = strings.ReplaceAll(html, strings.ToUpper(tag), replacement)
+func (t *textConverter) render(n *html.Node) {
+	switch n.Type {
+	case html.DocumentNode:
+		t.renderChildren(n)
+		return
+	case html.TextNode:
+		t.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		// Comments en doctypes leveren geen tekst op
+		return
 	}
 
-	// 3. Vervang inline elements met hun markeringen
-	for tag, replacement := range inlineElements {
-		html = strings.ReplaceAll(html, strings.ToLower(tag), replacement)
-		html = strings.ReplaceAll(html, strings.ToUpper(tag), replacement)
+	if skippedElements[n.DataAtom] {
+		return
 	}
 
-	// 4. Verwijder alle overige HTML tags, maar behoud hun inhoud
-	html = tagRegex.ReplaceAllString(html, "")
+	switch n.DataAtom {
+	case atom.Br:
+		t.lineBreak()
 
-	// 5. Decode HTML entities
-	html = entityRegex.ReplaceAllStringFunc(html, func(entity string) string {
-		if replacement, ok := htmlEntities[entity]; ok {
-			return replacement
+	case atom.Hr:
+		t.blankLine()
+		t.write("---")
+		t.blankLine()
+
+	case atom.Img:
+		if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+			t.write("[" + alt + "]")
 		}
-		return entity
-	})
 
-	// 6. Clean up whitespace met behoud van opmaak
-	lines := strings.Split(html, "\n")
-	var lastLineWasEmpty bool
+	case atom.A:
+		t.renderLink(n)
+
+	case atom.B, atom.Strong:
+		t.renderEmphasis(n, "*")
+
+	case atom.I, atom.Em:
+		t.renderEmphasis(n, "_")
+
+	case atom.Blockquote:
+		t.blankLine()
+		t.prefixes = append(t.prefixes, "> ")
+		t.renderChildren(n)
+		t.flush()
+		t.prefixes = t.prefixes[:len(t.prefixes)-1]
+		t.blankLine()
+
+	case atom.Ul, atom.Ol:
+		t.renderList(n)
+
+	case atom.Li:
+		t.renderListItem(n)
+
+	case atom.Pre:
+		t.blankLine()
+		t.pre++
+		t.renderChildren(n)
+		t.pre--
+		t.blankLine()
+
+	case atom.Tr:
+		t.renderRow(n)
+
+	default:
+		switch {
+		case paragraphElements[n.DataAtom]:
+			t.blankLine()
+			t.renderChildren(n)
+			t.blankLine()
+		case blockElements[n.DataAtom]:
+			t.flush()
+			t.renderChildren(n)
+			t.flush()
+		default:
+			t.renderChildren(n)
+		}
+	}
+}
+
+func (t *textConverter) renderChildren(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		t.render(child)
+	}
+}
+
+// renderLink schrijft een link als "tekst (url)". De url wordt weggelaten als
+// die gelijk is aan de tekst, en anchors en javascript: links tonen alleen tekst.
+func (t *textConverter) renderLink(n *html.Node) {
+	text := inlineText(n)
+	href := strings.TrimSpace(attr(n, "href"))
+
+	lower := strings.ToLower(href)
+	if href == "" || strings.HasPrefix(href, "#") ||
+		!(strings.HasPrefix(lower, "http:") || strings.HasPrefix(lower, "https:") || strings.HasPrefix(lower, "mailto:") || strings.HasPrefix(lower, "tel:")) {
+		t.write(text)
+		return
+	}
+
+	target := href
+	if strings.HasPrefix(lower, "mailto:") || strings.HasPrefix(lower, "tel:") {
+		target = href[strings.Index(href, ":")+1:]
+	}
+
+	switch {
+	case text == "":
+		t.write(target)
+	case sameLink(text, target):
+		t.write(text)
+	default:
+		t.write(text + " (" + target + ")")
+	}
+}
+
+// sameLink geeft aan of de tekst van een link de url zelf is, eventueel zonder schema of slash
+func sameLink(text, target string) bool {
+	normalize := func(s string) string {
+		s = strings.ToLower(strings.TrimSpace(s))
+		s = strings.TrimPrefix(s, "https://")
+		s = strings.TrimPrefix(s, "http://")
+		return strings.TrimSuffix(s, "/")
+	}
+	return normalize(text) == normalize(target)
+}
+
+// renderEmphasis markeert vetgedrukte en cursieve tekst zoals gebruikelijk in platte tekst
+func (t *textConverter) renderEmphasis(n *html.Node, mark string) {
+	if t.pre > 0 {
+		t.renderChildren(n)
+		return
+	}
+	if text := inlineText(n); text != "" {
+		t.write(mark + text + mark)
+	}
+}
+
+func (t *textConverter) renderList(n *html.Node) {
+	nested := len(t.lists) > 0
+	if nested {
+		t.flush()
+	} else {
+		t.blankLine()
+	}
+
+	list := &listState{ordered: n.DataAtom == atom.Ol, next: 1}
+	if start, err := strconv.Atoi(attr(n, "start")); err == nil {
+		list.next = start
+	}
+	t.lists = append(t.lists, list)
+	t.renderChildren(n)
+	t.flush()
+	t.lists = t.lists[:len(t.lists)-1]
+
+	if !nested {
+		t.blankLine()
+	}
+}
 
-	for i, line := range lines {
-		line = whitespaceRegex.ReplaceAllString(strings.TrimSpace(line), " ")
+func (t *textConverter) renderListItem(n *html.Node) {
+	t.flush()
 
-		if line == "" {
-			if !lastLineWasEmpty {
-				if i > 0 {
-					buf.WriteString("\n")
+	marker := "- "
+	if len(t.lists) > 0 {
+		list := t.lists[len(t.lists)-1]
+		if list.ordered {
+			marker = strconv.Itoa(list.next) + ". "
+			list.next++
+		}
+	}
+
+	// Vervolgregels springen in tot onder de tekst van de eerste regel
+	t.prefixes = append(t.prefixes, strings.Repeat(" ", len(marker)))
+	t.marker = marker
+	t.markerDepth = len(t.prefixes)
+	t.renderChildren(n)
+	t.flush()
+	t.marker = ""
+	t.prefixes = t.prefixes[:len(t.prefixes)-1]
+}
+
+// renderRow zet een tabelrij op één regel als alle cellen uit één regel
+// bestaan. Cellen worden gescheiden door " | ", behalve na een label dat op
+// ":" eindigt, zodat "Naam: | Jan" als "Naam: Jan" leesbaar blijft. Cellen met
+// meerdere regels (layout tabellen) worden als losse blokken onder elkaar gezet.
+func (t *textConverter) renderRow(n *html.Node) {
+	var cells [][]string
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode || (child.DataAtom != atom.Td && child.DataAtom != atom.Th) {
+			continue
+		}
+		cell := &textConverter{pre: t.pre}
+		cell.renderChildren(child)
+		if lines := cell.result(); len(lines) > 0 {
+			cells = append(cells, lines)
+		}
+	}
+
+	t.flush()
+	singleLine := true
+	for _, cell := range cells {
+		singleLine = singleLine && len(cell) == 1
+	}
+
+	if singleLine {
+		var row strings.Builder
+		for i, cell := range cells {
+			if i > 0 {
+				if strings.HasSuffix(strings.TrimRight(row.String(), "*_"), ":") {
+					row.WriteString(" ")
+				} else {
+					row.WriteString(" | ")
 				}
-				lastLineWasEmpty = true
 			}
+			row.WriteString(cell[0])
+		}
+		t.appendLine(row.String())
+		return
+	}
+
+	t.blankLine()
+	for _, cell := range cells {
+		for _, line := range cell {
+			t.appendLine(line)
+		}
+		t.blankLine()
+	}
+}
+
+// text schrijft een tekst node. Buiten <pre> wordt witruimte samengevoegd tot één spatie.
+func (t *textConverter) text(data string) {
+	if t.pre > 0 {
+		for i, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+			if i > 0 {
+				t.lineBreak()
+			}
+			t.line.WriteString(line)
+		}
+		return
+	}
+
+	for _, r := range data {
+		if unicode.IsSpace(r) {
+			t.pendingSpace = true
 			continue
 		}
+		if t.pendingSpace && t.line.Len() > 0 {
+			t.line.WriteByte(' ')
+		}
+		t.pendingSpace = false
+		t.line.WriteRune(r)
+	}
+}
+
+// write schrijft tekst die al opgemaakt is, met de witruimte die ervoor stond
+func (t *textConverter) write(s string) {
+	if s == "" {
+		return
+	}
+	if t.pendingSpace && t.line.Len() > 0 {
+		t.line.WriteByte(' ')
+	}
+	t.pendingSpace = false
+	t.line.WriteString(s)
+}
+
+// flush sluit de huidige regel af als die tekst bevat
+func (t *textConverter) flush() {
+	t.pendingSpace = false
+	if strings.TrimSpace(t.line.String()) == "" {
+		t.line.Reset()
+		return
+	}
+	t.lineBreak()
+}
+
+// lineBreak sluit de huidige regel af, ook als die leeg is (<br>)
+func (t *textConverter) lineBreak() {
+	line := strings.TrimRightFunc(t.line.String(), unicode.IsSpace)
+	t.line.Reset()
+	t.pendingSpace = false
+	t.appendLine(line)
+}
+
+// blankLine zorgt voor een lege regel tussen blokken, zonder lege regels te stapelen
+func (t *textConverter) blankLine() {
+	t.flush()
+	if len(t.lines) > 0 && !t.lastLineBlank() {
+		t.appendLine("")
+	}
+}
 
-		if i > 0 && !lastLineWasEmpty {
-			buf.WriteString(" ")
+// appendLine voegt een regel toe met de prefix van het huidige niveau
+func (t *textConverter) appendLine(line string) {
+	if line == "" && t.lastLineBlank() {
+		return
+	}
+
+	var prefix strings.Builder
+	for i, p := range t.prefixes {
+		if t.marker != "" && line != "" && i == t.markerDepth-1 {
+			p = t.marker
 		}
-		buf.WriteString(line)
-		lastLineWasEmpty = false
+		prefix.WriteString(p)
+	}
+	if line != "" {
+		t.marker = ""
 	}
 
-	return buf.String()
+	t.lines = append(t.lines, strings.TrimRightFunc(prefix.String()+line, unicode.IsSpace))
+}
+
+// lastLineBlank geeft aan of de laatste regel leeg is, afgezien van citaattekens en inspringing
+func (t *textConverter) lastLineBlank() bool {
+	if len(t.lines) == 0 {
+		return true
+	}
+	return strings.Trim(t.lines[len(t.lines)-1], "> ") == ""
+}
+
+// result geeft de regels terug zonder lege regels aan het begin en eind
+func (t *textConverter) result() []string {
+	t.flush()
+	lines := t.lines
+	for len(lines) > 0 && strings.Trim(lines[0], "> ") == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.Trim(lines[len(lines)-1], "> ") == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// inlineText geeft de tekst van een element als één regel
+func inlineText(n *html.Node) string {
+	t := &textConverter{}
+	t.renderChildren(n)
+	return strings.Join(t.result(), " ")
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
 }
@@ -0,0 +1,72 @@
+package email
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "werk de golden files in testdata bij")
+
+// TestHTMLToTextGolden zet de HTML uit testdata/html2text om en vergelijkt het
+// resultaat met het bijbehorende .txt bestand. Draai met -update om de
+// golden files na een bewuste wijziging opnieuw te schrijven.
+func TestHTMLToTextGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "html2text", "*.html"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".html")
+		t.Run(name, func(t *testing.T) {
+			// Setup
+			input, err := os.ReadFile(file)
+			require.NoError(t, err)
+			golden := strings.TrimSuffix(file, ".html") + ".txt"
+
+			// Test
+			output := HTMLToText(string(input)) + "\n"
+
+			// Controleer het resultaat
+			if *updateGolden {
+				require.NoError(t, os.WriteFile(golden, []byte(output), 0o644))
+			}
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), output)
+		})
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"leeg", "  ", ""},
+		{"platte tekst", "Hallo wereld", "Hallo wereld"},
+		{"entities", "&lt;b&gt; &amp; &#233;&#x20AC;&hellip; &copy;", "<b> & é€… ©"},
+		{"hoofdletters", "<P>een</P><P>twee<BR>drie</P>", "een\n\ntwee\ndrie"},
+		{"script en style", "<style>p{}</style><p>tekst<script>x()</script></p>", "tekst"},
+		{"niet afgesloten tags", "<p>een<p>twee<div>drie</div>vier", "een\n\ntwee\n\ndrie\nvier"},
+		{"link", `<a href="https://example.com/a">hier</a>`, "hier (https://example.com/a)"},
+		{"link met url als tekst", `<a href="https://example.com/">example.com</a>`, "example.com"},
+		{"javascript link", `<a href="javascript:void(0)">klik</a>`, "klik"},
+		{"lijst", "<ul><li>a</li><li>b</li></ul>", "- a\n- b"},
+		{"genummerde lijst", `<ol start="3"><li>a</li><li>b</li></ol>`, "3. a\n4. b"},
+		{"geneste blockquote", "<blockquote>a<blockquote>b</blockquote>c</blockquote>", "> a\n>\n> > b\n>\n> c"},
+		{"tabel", "<table><tr><td>a</td><td>b</td></tr><tr><td>Naam:</td><td>Jan</td></tr></table>", "a | b\nNaam: Jan"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, HTMLToText(tt.input))
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package email
+
+import (
+	"dklautomationgo/models"
+	"dklautomationgo/services/events"
+	"time"
+)
+
+// MailboxEventType beschrijft het soort wijziging in een mailbox
+type MailboxEventType string
+
+const (
+	MailboxEventNewMessage      MailboxEventType = "new_message"
+	MailboxEventFlagsChanged    MailboxEventType = "flags_changed"
+	MailboxEventExpunged        MailboxEventType = "expunged"
+	MailboxEventMetadataChanged MailboxEventType = "metadata_changed" // Status, toewijzing of labels gewijzigd
+)
+
+// MailboxEvent wordt verstuurd wanneer een watcher een wijziging in een mailbox
+// detecteert of de metadata van een email gewijzigd wordt
+type MailboxEvent struct {
+	Type      MailboxEventType      `json:"type"`
+	Account   string                `json:"account"`
+	EmailID   string                `json:"email_id,omitempty"`
+	Email     *models.EmailSummary  `json:"email,omitempty"`
+	Read      bool                  `json:"read,omitempty"`
+	Flagged   bool                  `json:"flagged,omitempty"`
+	Metadata  *models.EmailMetadata `json:"metadata,omitempty"`
+	Timestamp time.Time             `json:"timestamp"`
+}
+
+// mailboxEventTypes koppelt mailbox events aan de event types op de event bus
+var mailboxEventTypes = map[MailboxEventType]events.EventType{
+	MailboxEventNewMessage:      events.EventEmailReceived,
+	MailboxEventFlagsChanged:    events.EventEmailUpdated,
+	MailboxEventExpunged:        events.EventEmailRemoved,
+	MailboxEventMetadataChanged: events.EventEmailUpdated,
+}
+
+// SetEventPublisher stelt de publisher in waar mailbox events naartoe gaan
+func (s *EmailService) SetEventPublisher(publisher events.Publisher) {
+	s.eventPublisher = publisher
+}
+
+// publish verstuurt een mailbox event naar de event bus, indien ingesteld
+func (s *EmailService) publish(event MailboxEvent) {
+	if s.eventPublisher == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	s.eventPublisher.Publish(mailboxEventTypes[event.Type], event)
+}
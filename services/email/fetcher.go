@@ -2,10 +2,13 @@ package email
 
 import (
 	"context"
-	"crypto/tls"
 	"dklautomationgo/models"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,27 +16,72 @@ import (
 	"github.com/emersion/go-imap/client"
 )
 
+// FetchEmails haalt de emails van alle (of het gegeven) account(s) op. Zolang
+// minstens één account lukt worden de emails van de geslaagde accounts
+// teruggegeven; zie FetchEmailsWithStatus voor de status per account.
 func (s *EmailService) FetchEmails(options *models.EmailFetchOptions) ([]*models.Email, error) {
+	emails, _, err := s.FetchEmailsWithStatus(options)
+	return emails, err
+}
+
+// FetchEmailsWithStatus haalt emails op zoals FetchEmails en geeft daarnaast de
+// status van elk betrokken account terug. Als alle accounts falen is de fout een
+// samenvoeging van AccountErrors, zodat errors.Is werkt op bijvoorbeeld ErrAuthFailed.
+func (s *EmailService) FetchEmailsWithStatus(options *models.EmailFetchOptions) ([]*models.Email, []*models.EmailAccountStatus, error) {
 	var allEmails []*models.Email
 	var mu sync.Mutex
 	var wg sync.WaitGroup
-	errChan := make(chan error, len(s.config.Accounts))
-
-	// Check cache voor elk account
-	if s.config.Cache.Enabled {
-		for _, cache := range s.accountCaches {
-			cache.cacheMutex.RLock()
-			if time.Since(cache.lastFetch) < s.config.Cache.Duration && len(cache.emails) > 0 {
-				allEmails = append(allEmails, cache.emails...)
-				cache.cacheMutex.RUnlock()
-				continue
-			}
-			cache.cacheMutex.RUnlock()
+	accounts := s.accountConfigs()
+	errChan := make(chan error, len(accounts))
+
+	folder := inboxFolder
+	accountFilter := ""
+	if options != nil {
+		if options.Folder != "" {
+			folder = options.Folder
 		}
+		accountFilter = options.Account
+	}
+	if accountFilter != "" && accounts[accountFilter] == nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnknownAccount, accountFilter)
+	}
+	if options.HasMetadataFilter() && s.metadata == nil {
+		return nil, nil, ErrMetadataUnavailable
+	}
+
+	// Alleen de INBOX wordt gecachet en door de watchers bijgehouden. Zoekopdrachten
+	// en metadata filters gaan altijd naar de server: de cache kan maar een deel van
+	// de map bevatten, en hun resultaten mogen de cache op hun beurt niet vervangen.
+	useCache := s.config.Cache.Enabled && strings.EqualFold(folder, inboxFolder) && !options.HasSearch() && !options.HasMetadataFilter()
+
+	// Check cache voor elk account; alleen verlopen accounts worden opnieuw opgehaald
+	var accountNames []string
+	staleAccounts := make(map[string]*EmailConfig)
+	for accountName, config := range accounts {
+		if accountFilter != "" && accountName != accountFilter {
+			continue
+		}
+		accountNames = append(accountNames, accountName)
+		cache := s.cache(accountName)
+		if useCache && cache.isFresh(s.config.Cache.Duration) {
+			allEmails = append(allEmails, cache.snapshot()...)
+			continue
+		}
+		staleAccounts[accountName] = config
+	}
+	sort.Strings(accountNames)
 
-		if len(allEmails) == len(s.config.Accounts) {
-			return s.filterEmails(allEmails, options), nil
+	statuses := func() []*models.EmailAccountStatus {
+		result := make([]*models.EmailAccountStatus, len(accountNames))
+		for i, accountName := range accountNames {
+			result[i] = s.accountStatus(accountName)
 		}
+		return result
+	}
+
+	if len(staleAccounts) == 0 {
+		emails, err := s.filterEmails(allEmails, options)
+		return emails, statuses(), err
 	}
 
 	// Context met timeout voor alle operaties
@@ -41,25 +89,26 @@ func (s *EmailService) FetchEmails(options *models.EmailFetchOptions) ([]*models
 	defer cancel()
 
 	// Parallel ophalen van emails voor elk account
-	for accountName, config := range s.config.Accounts {
+	for accountName, config := range staleAccounts {
 		wg.Add(1)
 		go func(accName string, cfg *EmailConfig) {
 			defer wg.Done()
 
-			emails, err := s.fetchEmailsFromAccount(ctx, accName, cfg, options)
+			emails, err := s.fetchEmailsFromAccount(ctx, accName, cfg, folder, options)
 			if err != nil {
 				log.Printf("[ERROR] %s: %v", accName, err)
-				errChan <- fmt.Errorf("account %s: %w", accName, err)
+				if errors.Is(err, context.DeadlineExceeded) {
+					err = connectionError(err)
+				}
+				s.statuses.recordFailure(accName, err)
+				errChan <- &AccountError{Account: accName, Err: err}
 				return
 			}
+			s.statuses.recordSuccess(accName)
 
 			// Update cache en voeg emails toe aan resultaat
-			if s.config.Cache.Enabled {
-				cache := s.accountCaches[accName]
-				cache.cacheMutex.Lock()
-				cache.emails = emails
-				cache.lastFetch = time.Now()
-				cache.cacheMutex.Unlock()
+			if useCache {
+				s.notifyIncoming(s.cache(accName).store(emails))
 			}
 
 			mu.Lock()
@@ -72,31 +121,34 @@ func (s *EmailService) FetchEmails(options *models.EmailFetchOptions) ([]*models
 	close(errChan)
 
 	// Controleer op fouten
-	var errors []error
+	var accountErrors []error
 	for err := range errChan {
-		errors = append(errors, err)
+		accountErrors = append(accountErrors, err)
 	}
 
 	// Als alle accounts faalden, geef een fout terug
-	if len(errors) == len(s.config.Accounts) {
-		return nil, fmt.Errorf("all accounts failed: %v", errors)
+	if len(accountErrors) == len(staleAccounts) && len(allEmails) == 0 {
+		return nil, statuses(), fmt.Errorf("all accounts failed: %w", errors.Join(accountErrors...))
 	}
 
 	// Als er geen emails zijn gevonden, geef een lege lijst terug
 	if len(allEmails) == 0 {
-		return []*models.Email{}, nil
+		return []*models.Email{}, statuses(), nil
 	}
 
-	return s.filterEmails(allEmails, options), nil
+	emails, err := s.filterEmails(allEmails, options)
+	return emails, statuses(), err
 }
 
-func (s *EmailService) filterEmails(emails []*models.Email, options *models.EmailFetchOptions) []*models.Email {
+func (s *EmailService) filterEmails(emails []*models.Email, options *models.EmailFetchOptions) ([]*models.Email, error) {
 	if options == nil {
-		return emails
+		return emails, nil
 	}
 
+	// Zoek op afzender, ontvangers, onderwerp, tekst, datum en bijlagen
+	filtered := searchEmails(emails, options)
+
 	// Filter by read status if specified
-	filtered := emails
 	if options.Read != nil {
 		temp := make([]*models.Email, 0)
 		for _, email := range filtered {
@@ -107,10 +159,21 @@ func (s *EmailService) filterEmails(emails []*models.Email, options *models.Emai
 		filtered = temp
 	}
 
+	// Filter op status, toewijzing en labels uit de database
+	filtered, err := s.filterByMetadata(filtered, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nieuwste eerst, zodat offset en limit over alle accounts samen gelden
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return parseEmailTime(filtered[i].CreatedAt).After(parseEmailTime(filtered[j].CreatedAt))
+	})
+
 	// Apply offset and limit
-	start := options.Offset
+	start := max(options.Offset, 0)
 	if start >= len(filtered) {
-		return []*models.Email{}
+		return []*models.Email{}, nil
 	}
 
 	end := len(filtered)
@@ -121,25 +184,83 @@ func (s *EmailService) filterEmails(emails []*models.Email, options *models.Emai
 		}
 	}
 
-	return filtered[start:end]
+	return filtered[start:end], nil
 }
 
-func (s *EmailService) fetchEmailsFromAccount(ctx context.Context, accountName string, config *EmailConfig, options *models.EmailFetchOptions) ([]*models.Email, error) {
-	// Connect to IMAP server
-	c, err := client.DialTLS(fmt.Sprintf("%s:%d", config.IMAPHost, config.IMAPPort), &tls.Config{
-		ServerName:         config.IMAPHost,
-		InsecureSkipVerify: true,
-		MinVersion:         tls.VersionTLS12,
-	})
+// messageFetchItems zijn de IMAP items die nodig zijn om een volledig bericht te
+// verwerken. BODY.PEEK[] zorgt dat het ophalen het bericht niet als gelezen markeert.
+var messageFetchItems = []imap.FetchItem{
+	imap.FetchUid,
+	imap.FetchEnvelope,
+	imap.FetchFlags,
+	imap.FetchBody,
+	imap.FetchBodyStructure,
+	"BODY.PEEK[]",
+}
+
+// imapDialTimeout begrenst het opzetten van een verbinding, zodat een
+// onbereikbare server als time-out gemeld wordt in plaats van te blijven hangen
+const imapDialTimeout = 30 * time.Second
+
+// dialIMAP maakt een verbinding met de IMAP server van een account en logt in.
+// Afhankelijk van de configuratie met impliciete TLS, STARTTLS of onversleuteld.
+// Fouten zijn getypeerd als ErrServerUnreachable, ErrTimeout of ErrAuthFailed.
+func dialIMAP(config *EmailConfig) (*client.Client, error) {
+	tlsConfig, err := config.tlsConfig(config.IMAPHost)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: imapDialTimeout}
+	addr := fmt.Sprintf("%s:%d", config.IMAPHost, config.IMAPPort)
+
+	var c *client.Client
+	switch security := config.imapSecurity(); security {
+	case IMAPSecurityTLS:
+		c, err = client.DialWithDialerTLS(dialer, addr, tlsConfig)
+	case IMAPSecurityStartTLS, IMAPSecurityNone:
+		c, err = client.DialWithDialer(dialer, addr)
+	default:
+		return nil, fmt.Errorf("unknown IMAP security %q", security)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("IMAP connection failed: %w", err)
+		return nil, fmt.Errorf("IMAP connection failed: %w", connectionError(err))
 	}
-	defer func() {
-		if err := c.Logout(); err != nil {
-			log.Printf("[ERROR] %s: Logout failed: %v", accountName, err)
+
+	if config.imapSecurity() == IMAPSecurityStartTLS {
+		if ok, _ := c.SupportStartTLS(); !ok {
+			c.Terminate()
+			return nil, fmt.Errorf("IMAP server %s does not support STARTTLS", config.IMAPHost)
 		}
-	}()
+		if err := c.StartTLS(tlsConfig); err != nil {
+			c.Terminate()
+			return nil, fmt.Errorf("IMAP STARTTLS failed: %w", connectionError(err))
+		}
+	}
+
+	if err := c.Login(config.Email, config.Password); err != nil {
+		c.Logout()
+		return nil, fmt.Errorf("IMAP login failed: %w", loginError(err))
+	}
+
+	return c, nil
+}
+
+func (s *EmailService) fetchEmailsFromAccount(ctx context.Context, accountName string, config *EmailConfig, folder string, options *models.EmailFetchOptions) ([]*models.Email, error) {
+	var emails []*models.Email
+	err := s.withIMAP(accountName, config, func(c *client.Client) error {
+		var err error
+		emails, err = s.fetchFromClient(ctx, c, accountName, folder, options)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return emails, nil
+}
 
+// fetchFromClient haalt de emails van een map op via een ingelogde verbinding
+func (s *EmailService) fetchFromClient(ctx context.Context, c *client.Client, accountName string, folder string, options *models.EmailFetchOptions) ([]*models.Email, error) {
 	// Check context before proceeding
 	select {
 	case <-ctx.Done():
@@ -147,52 +268,73 @@ func (s *EmailService) fetchEmailsFromAccount(ctx context.Context, accountName s
 	default:
 	}
 
-	// Login
-	if err := c.Login(config.Email, config.Password); err != nil {
-		return nil, fmt.Errorf("IMAP login failed: %w", err)
+	// Resolve aliassen als "sent" of "archive" naar de map op deze server
+	folder, err := resolveFolder(c, folder)
+	if err != nil {
+		return nil, err
 	}
 
-	// Select INBOX
-	mbox, err := c.Select("INBOX", false)
+	// Select folder read-only; ophalen mag nooit flags wijzigen
+	mbox, err := c.Select(folder, true)
 	if err != nil {
-		return nil, fmt.Errorf("IMAP select inbox failed: %w", err)
+		return nil, fmt.Errorf("IMAP select %s failed: %w", folder, err)
+	}
+
+	mailbox := mailboxRef{account: accountName, folder: folder, uidValidity: mbox.UidValidity}
+	if mailbox.isInbox() {
+		if s.cache(accountName).checkUIDValidity(mbox.UidValidity) {
+			log.Printf("[WARN] %s: UIDVALIDITY of INBOX changed, cached IDs are no longer valid", accountName)
+		}
+		s.statuses.recordCounts(accountName, mbox.Messages, nil)
 	}
 
 	if mbox.Messages == 0 {
 		return []*models.Email{}, nil
 	}
 
-	// Calculate message range
-	from := uint32(1)
-	to := mbox.Messages
-
-	if options != nil && options.Limit > 0 {
-		if uint32(options.Offset) >= to {
+	seqSet := new(imap.SeqSet)
+	fetch := c.Fetch
+
+	if options.HasSearch() {
+		// Laat de server zoeken en haal alleen de treffers op. Offset en limit
+		// worden na het filteren toegepast, omdat IMAP niet op bijlagen kan zoeken.
+		uids, err := c.UidSearch(searchCriteria(options))
+		if err != nil {
+			return nil, fmt.Errorf("IMAP search failed: %w", err)
+		}
+		if len(uids) == 0 {
 			return []*models.Email{}, nil
 		}
-		from = to - uint32(options.Limit+options.Offset)
-		if from < 1 {
-			from = 1
+		uids = newestUIDs(uids, maxSearchResults)
+		seqSet.AddNum(uids...)
+		fetch = c.UidFetch
+		log.Printf("[INFO] %s: Fetching %d search results", accountName, len(uids))
+	} else {
+		// Haal de nieuwste berichten op; offset en limit worden pas na het filteren
+		// toegepast. Bij een metadata filter is vooraf niet bekend hoeveel berichten
+		// afvallen, dus dan net als bij zoeken de nieuwste maxSearchResults.
+		from := uint32(1)
+		to := mbox.Messages
+
+		window := 0
+		if options.HasMetadataFilter() {
+			window = maxSearchResults
+		} else if options != nil && options.Limit > 0 {
+			window = options.Limit + options.Offset
+		}
+		if window > 0 && uint32(window) < to {
+			from = to - uint32(window) + 1
 		}
-		to = to - uint32(options.Offset)
-	}
-
-	log.Printf("[INFO] %s: Fetching %d messages", accountName, to-from+1)
 
-	seqSet := new(imap.SeqSet)
-	seqSet.AddRange(from, to)
+		log.Printf("[INFO] %s: Fetching %d messages", accountName, to-from+1)
+		seqSet.AddRange(from, to)
+	}
 
 	messages := make(chan *imap.Message, 100)
 	done := make(chan error, 1)
 
 	go func() {
-		done <- c.Fetch(seqSet, []imap.FetchItem{
-			imap.FetchEnvelope,
-			imap.FetchFlags,
-			imap.FetchBody,
-			imap.FetchBodyStructure,
-			"BODY[]",
-		}, messages)
+		done <- fetch(seqSet, messageFetchItems, messages)
 	}()
 
 	var emails []*models.Email
@@ -204,7 +346,7 @@ func (s *EmailService) fetchEmailsFromAccount(ctx context.Context, accountName s
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		default:
-			email, err := s.processMessage(msg, accountName)
+			email, err := s.processMessage(msg, mailbox)
 			if err != nil {
 				errorCount++
 				continue
@@ -224,3 +366,59 @@ func (s *EmailService) fetchEmailsFromAccount(ctx context.Context, accountName s
 
 	return emails, nil
 }
+
+// GetEmail haalt één email op, voor de INBOX eerst uit de cache en anders via de IMAP server
+func (s *EmailService) GetEmail(emailID string) (*models.Email, error) {
+	ref, err := parseEmailID(emailID)
+	if err != nil {
+		return nil, err
+	}
+
+	config, ok := s.account(ref.account)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAccount, ref.account)
+	}
+
+	if s.config.Cache.Enabled && ref.isInbox() {
+		cache := s.cache(ref.account)
+		lookup := ref
+		if lookup.uidValidity == 0 {
+			lookup.uidValidity = cache.currentUIDValidity()
+		}
+		if email := cache.find(lookup.id()); email != nil {
+			return email, nil
+		}
+	}
+
+	var email *models.Email
+	err = s.withIMAP(ref.account, config, func(c *client.Client) error {
+		ref, seqSet, err := s.selectMessage(c, ref, true)
+		if err != nil {
+			return err
+		}
+
+		messages := make(chan *imap.Message, 1)
+		done := make(chan error, 1)
+		go func() {
+			done <- c.UidFetch(seqSet, messageFetchItems, messages)
+		}()
+
+		var processErr error
+		for msg := range messages {
+			email, processErr = s.processMessage(msg, ref.mailboxRef)
+		}
+
+		if err := <-done; err != nil {
+			return fmt.Errorf("IMAP fetch failed: %w", err)
+		}
+		return processErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	if email == nil {
+		return nil, ErrEmailNotFound
+	}
+
+	return email, nil
+}
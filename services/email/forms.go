@@ -1,71 +1,360 @@
 package email
 
 import (
+	"dklautomationgo/models"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
 	"strings"
+	"unicode"
 )
 
+// FormProcessedKeyword is het IMAP keyword waarmee verwerkte formuliermeldingen gemarkeerd worden
+const FormProcessedKeyword = "$Verwerkt"
+
+// Velden van de records waarop formuliervelden gemapt kunnen worden
+const (
+	FormFieldNaam           = "naam"
+	FormFieldEmail          = "email"
+	FormFieldTelefoon       = "telefoon"
+	FormFieldRol            = "rol"
+	FormFieldAfstand        = "afstand"
+	FormFieldOndersteuning  = "ondersteuning"
+	FormFieldBijzonderheden = "bijzonderheden"
+	FormFieldTerms          = "terms"
+	FormFieldBericht        = "bericht"
+	FormFieldPrivacy        = "privacy_akkoord"
+)
+
+// formFieldChoices geeft per veld de waarden die de database toestaat; een
+// waarde uit een melding wordt op een van deze waarden gemapt
+var formFieldChoices = map[string][]string{
+	FormFieldRol:     models.AanmeldingRollen,
+	FormFieldAfstand: models.AanmeldingAfstanden,
+}
+
+// maxFormKeyLength is de maximale lengte van een label; langere "Key: value"
+// regels zijn gewone zinnen met een dubbele punt
+const maxFormKeyLength = 40
+
+// listMarkerRegex vindt lijst markeringen ("- ", "3. ") die HTMLToText voor een regel zet
+var listMarkerRegex = regexp.MustCompile(`^(-|\d+\.)\s+`)
+
+// linkSuffixRegex vindt de URL die HTMLToText achter de tekst van een mailto: of tel: link zet
+var linkSuffixRegex = regexp.MustCompile(`\s*\((mailto|tel):[^)]*\)$`)
+
+// addressRegex vindt een email adres in een waarde als "Jan <jan@example.com>"
+var addressRegex = regexp.MustCompile(`[^\s<>()"',;:]+@[^\s<>()"',;:]+\.[^\s<>()"',;:]+`)
+
+// replyPrefixRegex vindt onderwerpen van antwoorden; doorgestuurde meldingen ("Fwd:") tellen wel mee
+var replyPrefixRegex = regexp.MustCompile(`(?i)^\s*(re|antw|aw|sv|vs)\s*(\[\d+\])?\s*:`)
+
+// ExtractFormData haalt de velden van een formuliermelding op, zie ParseFormFields
+func (s *EmailService) ExtractFormData(content string) (map[string]string, error) {
+	return ParseFormFields(s.ProcessHTML(content)), nil
+}
+
+// ProcessContactForm haalt de velden van een formuliermelding op.
+//
+// Deprecated: gebruik ExtractFormData.
 func (s *EmailService) ProcessContactForm(content string) (map[string]string, error) {
-	// Process HTML content first
-	content = s.ProcessHTML(content)
+	return s.ExtractFormData(content)
+}
 
-	// Extract form fields
+// ParseFormFields zet de "Label: waarde" regels van een formuliermelding (als
+// platte tekst) om naar een map van label naar waarde. Opmaak van HTMLToText
+// (lijst markeringen, *vet*, _cursief_) en citaat tekens van doorgestuurde
+// berichten worden genegeerd. Een label zonder waarde op dezelfde regel krijgt
+// de regels eronder, tot het volgende label; zo blijft een bericht van meerdere
+// regels heel. Bij dubbele labels telt het eerste voorkomen.
+func ParseFormFields(text string) map[string]string {
 	fields := make(map[string]string)
-	lines := strings.Split(content, "\n")
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
+	var currentKey string
+	var current []string
+	multiline := false
+	flush := func() {
+		if currentKey != "" {
+			if value := strings.TrimSpace(strings.Join(current, "\n")); value != "" {
+				if _, exists := fields[currentKey]; !exists {
+					fields[currentKey] = value
+				}
+			}
 		}
+		currentKey, current, multiline = "", nil, false
+	}
 
-		// Try different separators
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			parts = strings.SplitN(line, "=", 2)
-		}
-		if len(parts) != 2 {
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line = strings.TrimRight(stripQuote(line), " \t")
+		trimmed := strings.TrimSpace(line)
+
+		if key, value, ok := splitFormLine(trimmed); ok {
+			flush()
+			currentKey, current, multiline = key, []string{value}, value == ""
 			continue
 		}
 
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-		if key != "" && value != "" {
-			fields[key] = value
+		switch {
+		case currentKey == "":
+			// Tekst buiten een veld, zoals een inleiding of tabelkop
+		case trimmed == "--" || trimmed == "---":
+			// Handtekening of scheidingslijn sluit het veld af
+			flush()
+		case multiline:
+			current = append(current, line)
+		case trimmed == "":
+			flush()
+		default:
+			// Vervolgregel van een lange waarde
+			current = append(current, trimmed)
 		}
 	}
+	flush()
 
-	return fields, nil
+	return fields
 }
 
-func (s *EmailService) ExtractFormData(content string) (map[string]string, error) {
-	// Clean HTML first
-	content = s.ProcessHTML(content)
+// stripQuote verwijdert citaat tekens ("> ") aan het begin van een regel
+func stripQuote(line string) string {
+	for {
+		trimmed := strings.TrimLeft(line, " ")
+		if !strings.HasPrefix(trimmed, ">") {
+			return line
+		}
+		line = strings.TrimPrefix(trimmed[1:], " ")
+	}
+}
 
-	// Extract form fields
-	fields := make(map[string]string)
-	lines := strings.Split(content, "\n")
+// splitFormLine splitst een "Label: waarde" (of "Label = waarde") regel. Een
+// tabelrij zonder dubbele punt achter het label ("Label | waarde") telt ook.
+func splitFormLine(line string) (string, string, bool) {
+	line = listMarkerRegex.ReplaceAllString(line, "")
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
+	var parts []string
+	if cell := strings.Index(line, " | "); cell != -1 && strings.Count(line, " | ") == 1 && !strings.Contains(line[:cell], ":") {
+		parts = strings.SplitN(line, " | ", 2)
+	} else if parts = strings.SplitN(line, ":", 2); len(parts) != 2 {
+		parts = strings.SplitN(line, "=", 2)
+	}
+	if len(parts) != 2 {
+		return "", "", false
+	}
+
+	key := strings.TrimSpace(strings.Trim(strings.TrimSpace(parts[0]), "*_"))
+	value := strings.TrimSpace(strings.TrimLeft(parts[1], "*_"))
+	if key == "" || len(key) > maxFormKeyLength || strings.HasPrefix(value, "//") {
+		// Geen label, of de dubbele punt hoort bij een URL
+		return "", "", false
+	}
+	return key, value, true
+}
+
+// normalizeFormKey maakt een label vergelijkbaar: kleine letters, zonder spaties en leestekens
+func normalizeFormKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
 		}
+		return -1
+	}, key)
+}
+
+// FormRule beschrijft een soort formuliermelding: aan welke emails deze
+// herkend wordt en welke labels op welke velden van het record gemapt worden
+type FormRule struct {
+	Name            string              `json:"name"`             // Naam van de regel, voor logging en het rapport
+	Account         string              `json:"account"`          // Account waarin de melding binnenkomt (leeg = alle)
+	SenderContains  string              `json:"sender_contains"`  // Deel van het adres van de afzender (optioneel)
+	SubjectContains string              `json:"subject_contains"` // Deel van het onderwerp (optioneel)
+	RecordType      string              `json:"record_type"`      // aanmelding of contact
+	Fields          map[string][]string `json:"fields"`           // Veld van het record naar de mogelijke labels in de melding
+	Required        []string            `json:"required"`         // Velden die gevonden moeten worden
+}
 
-		// Try different separators
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			parts = strings.SplitN(line, "=", 2)
+// Matches geeft aan of een email een melding van deze regel is. Antwoorden op
+// een melding tellen niet mee, doorgestuurde meldingen wel.
+func (r *FormRule) Matches(email *models.Email) bool {
+	if r.Account != "" && !strings.EqualFold(r.Account, email.Account) {
+		return false
+	}
+	if r.SenderContains != "" && !strings.Contains(strings.ToLower(email.Sender), strings.ToLower(r.SenderContains)) {
+		return false
+	}
+	if r.SubjectContains != "" && !strings.Contains(strings.ToLower(email.Subject), strings.ToLower(r.SubjectContains)) {
+		return false
+	}
+	return !replyPrefixRegex.MatchString(email.Subject)
+}
+
+// Extract zoekt de velden van het record op in de velden van een melding. Het
+// tweede resultaat bevat de verplichte velden die niet gevonden zijn.
+func (r *FormRule) Extract(fields map[string]string) (map[string]string, []string) {
+	normalized := make(map[string]string, len(fields))
+	for key, value := range fields {
+		normalized[normalizeFormKey(key)] = value
+	}
+
+	values := make(map[string]string)
+	for field, labels := range r.Fields {
+		for _, label := range labels {
+			if value, ok := normalized[normalizeFormKey(label)]; ok {
+				values[field] = linkSuffixRegex.ReplaceAllString(value, "")
+				break
+			}
 		}
-		if len(parts) != 2 {
-			continue
+	}
+
+	if value, ok := values[FormFieldEmail]; ok {
+		values[FormFieldEmail] = strings.ToLower(addressRegex.FindString(value))
+	}
+
+	invalid := make(map[string]bool)
+	for field, choices := range formFieldChoices {
+		if value := values[field]; value != "" {
+			if values[field] = normalizeFormChoice(value, choices); values[field] == "" {
+				// Een waarde die de database niet toestaat telt als ontbrekend
+				invalid[field] = true
+			}
+		}
+	}
+
+	var missing []string
+	for _, field := range r.Required {
+		if values[field] == "" {
+			missing = append(missing, field)
+			delete(invalid, field)
+		}
+	}
+	for _, field := range slices.Sorted(maps.Keys(invalid)) {
+		missing = append(missing, field)
+	}
+	return values, missing
+}
+
+// normalizeFormChoice mapt een waarde op een van de toegestane waarden,
+// ongeacht hoofdletters, spaties en een komma als decimaalteken ("2,5 km" is
+// "2.5 KM"). Geeft een lege string als de waarde niet gemapt kan worden.
+func normalizeFormChoice(value string, choices []string) string {
+	key := func(s string) string {
+		return strings.ToLower(strings.Join(strings.Fields(strings.ReplaceAll(s, ",", ".")), ""))
+	}
+	for _, choice := range choices {
+		if key(choice) == key(value) {
+			return choice
+		}
+	}
+	return ""
+}
+
+// MatchFormRule geeft de eerste regel waaraan de email voldoet, of nil
+func MatchFormRule(rules []FormRule, email *models.Email) *FormRule {
+	for i := range rules {
+		if rules[i].Matches(email) {
+			return &rules[i]
 		}
+	}
+	return nil
+}
+
+// FormText geeft de tekst van een melding waaruit de velden gelezen worden.
+// De HTML versie gaat voor, omdat de tekstversie van formulierplugins vaak
+// labels en waarden op aparte regels zet.
+func FormText(email *models.Email) string {
+	if strings.TrimSpace(email.HTML) != "" {
+		return HTMLToText(email.HTML)
+	}
+	return email.Body
+}
+
+// DefaultFormRules geeft de regels voor de meldingen van de website, zoals
+// die in de inschrijving mailbox binnenkomen of ernaar doorgestuurd worden
+func DefaultFormRules() []FormRule {
+	return []FormRule{
+		{
+			Name:            "aanmelding",
+			Account:         "inschrijving",
+			SubjectContains: "aanmelding",
+			RecordType:      models.EmailLinkAanmelding,
+			Fields: map[string][]string{
+				FormFieldNaam:           {"Naam", "Volledige naam", "Name"},
+				FormFieldEmail:          {"E-mail", "Email", "E-mailadres", "Emailadres"},
+				FormFieldTelefoon:       {"Telefoonnummer", "Telefoon", "Tel", "Mobiel"},
+				FormFieldRol:            {"Rol", "Functie"},
+				FormFieldAfstand:        {"Gekozen Afstand", "Afstand"},
+				FormFieldOndersteuning:  {"Ondersteuning nodig", "Ondersteuning"},
+				FormFieldBijzonderheden: {"Bijzonderheden", "Opmerkingen"},
+				FormFieldTerms:          {"Algemene voorwaarden", "Voorwaarden", "Terms"},
+			},
+			Required: []string{FormFieldNaam, FormFieldEmail, FormFieldTelefoon, FormFieldRol, FormFieldAfstand},
+		},
+		{
+			Name:            "contact",
+			Account:         "inschrijving",
+			SubjectContains: "contactformulier",
+			RecordType:      models.EmailLinkContact,
+			Fields: map[string][]string{
+				FormFieldNaam:    {"Naam", "Volledige naam", "Name"},
+				FormFieldEmail:   {"E-mail", "Email", "E-mailadres", "Emailadres"},
+				FormFieldBericht: {"Bericht", "Vraag", "Message"},
+				FormFieldPrivacy: {"Privacy akkoord", "Privacy"},
+			},
+			Required: []string{FormFieldNaam, FormFieldEmail, FormFieldBericht},
+		},
+	}
+}
+
+// LoadFormRules leest regels uit een JSON bestand met een lijst van FormRule objecten
+func LoadFormRules(path string) ([]FormRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read form rules: %w", err)
+	}
 
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-		if key != "" && value != "" {
-			fields[key] = value
+	var rules []FormRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse form rules: %w", err)
+	}
+	for _, rule := range rules {
+		if rule.RecordType != models.EmailLinkAanmelding && rule.RecordType != models.EmailLinkContact {
+			return nil, fmt.Errorf("form rule %q: unknown record type %q", rule.Name, rule.RecordType)
 		}
 	}
+	return rules, nil
+}
+
+// ParseFormBool leest een ja/nee waarde uit een formulier
+func ParseFormBool(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "ja", "yes", "true", "1", "on", "akkoord", "geaccepteerd", "aangevinkt", "x":
+		return true
+	}
+	return false
+}
 
-	return fields, nil
+// AanmeldingFromForm maakt een aanmeldingsformulier van de velden van een melding
+func AanmeldingFromForm(values map[string]string) *models.AanmeldingFormulier {
+	return &models.AanmeldingFormulier{
+		Naam:           values[FormFieldNaam],
+		Email:          values[FormFieldEmail],
+		Telefoon:       values[FormFieldTelefoon],
+		Rol:            values[FormFieldRol],
+		Afstand:        values[FormFieldAfstand],
+		Ondersteuning:  values[FormFieldOndersteuning],
+		Bijzonderheden: values[FormFieldBijzonderheden],
+		Terms:          ParseFormBool(values[FormFieldTerms]),
+	}
+}
+
+// ContactFromForm maakt een contactformulier van de velden van een melding
+func ContactFromForm(values map[string]string) *models.ContactFormulier {
+	return &models.ContactFormulier{
+		Naam:           values[FormFieldNaam],
+		Email:          values[FormFieldEmail],
+		Bericht:        values[FormFieldBericht],
+		PrivacyAkkoord: ParseFormBool(values[FormFieldPrivacy]),
+		Status:         "nieuw",
+	}
 }
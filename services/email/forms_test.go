@@ -0,0 +1,108 @@
+package email
+
+import (
+	"bytes"
+	"dklautomationgo/models"
+	"html/template"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormFields_ContactNotification(t *testing.T) {
+	// Setup
+	input, err := os.ReadFile(filepath.Join("testdata", "html2text", "form_notification.txt"))
+	require.NoError(t, err)
+
+	// Test
+	fields := ParseFormFields(string(input))
+
+	// Controleer het resultaat
+	assert.Equal(t, "Pieter Jansen", fields["Naam"])
+	assert.Equal(t, "pieter@example.nl", fields["Email"])
+	assert.Equal(t, "Ja", fields["Privacy akkoord"])
+	assert.Contains(t, fields["Bericht"], "Goedendag,\n\nIk wil    graag meelopen.")
+	assert.Contains(t, fields["Bericht"], "met mijn hond")
+	assert.NotContains(t, fields, "Verzonden via https")
+}
+
+func TestParseFormFields_ForwardedQuote(t *testing.T) {
+	// Setup
+	text := "Zie hieronder.\n\n" +
+		"---------- Forwarded message ---------\n" +
+		"Van: Website <noreply@dekoninklijkeloop.nl>\n" +
+		"> Naam | Jan de Vries\n" +
+		"> E-mail | jan@example.com (mailto:jan@example.com)\n" +
+		"> Opmerkingen: eerste regel\n" +
+		"> tweede regel\n" +
+		">\n" +
+		"> Naam: Iemand anders\n"
+
+	// Test
+	fields := ParseFormFields(text)
+
+	// Controleer het resultaat
+	assert.Equal(t, "Jan de Vries", fields["Naam"], "het eerste voorkomen telt")
+	assert.Equal(t, "eerste regel\ntweede regel", fields["Opmerkingen"])
+	assert.Equal(t, "Website <noreply@dekoninklijkeloop.nl>", fields["Van"])
+}
+
+func TestFormRule_AanmeldingTemplate(t *testing.T) {
+	// Setup: de admin melding die de API zelf verstuurt, zoals die in de mailbox binnenkomt
+	tmpl, err := template.ParseFiles(filepath.Join("..", "..", "templates", "aanmelding_admin_email.html"))
+	require.NoError(t, err)
+	var body bytes.Buffer
+	require.NoError(t, tmpl.Execute(&body, &models.AanmeldingEmailData{Aanmelding: &models.AanmeldingFormulier{
+		Naam:          "Jan de Vries",
+		Email:         "Jan@Example.com",
+		Telefoon:      "0612345678",
+		Rol:           "Vrijwilliger",
+		Afstand:       "10 KM",
+		Ondersteuning: "Rolstoel",
+		Terms:         true,
+	}}))
+	msg := &models.Email{Account: "inschrijving", Subject: "Fwd: Nieuwe aanmelding ontvangen", HTML: body.String()}
+
+	// Test
+	rule := MatchFormRule(DefaultFormRules(), msg)
+	require.NotNil(t, rule)
+	values, missing := rule.Extract(ParseFormFields(FormText(msg)))
+
+	// Controleer het resultaat
+	assert.Equal(t, "aanmelding", rule.Name)
+	assert.Empty(t, missing)
+	assert.Equal(t, &models.AanmeldingFormulier{
+		Naam:          "Jan de Vries",
+		Email:         "jan@example.com",
+		Telefoon:      "0612345678",
+		Rol:           "Vrijwilliger",
+		Afstand:       "10 KM",
+		Ondersteuning: "Rolstoel",
+		Terms:         true,
+	}, AanmeldingFromForm(values))
+}
+
+func TestFormRule_Matches(t *testing.T) {
+	rule := DefaultFormRules()[0]
+
+	assert.True(t, rule.Matches(&models.Email{Account: "inschrijving", Subject: "Nieuwe aanmelding ontvangen"}))
+	assert.True(t, rule.Matches(&models.Email{Account: "inschrijving", Subject: "Fwd: Nieuwe aanmelding ontvangen"}))
+	assert.False(t, rule.Matches(&models.Email{Account: "inschrijving", Subject: "Re: Bedankt voor je aanmelding"}), "antwoorden zijn geen meldingen")
+	assert.False(t, rule.Matches(&models.Email{Account: "info", Subject: "Nieuwe aanmelding ontvangen"}))
+}
+
+func TestFormRule_ExtractMissing(t *testing.T) {
+	// Setup
+	rule := DefaultFormRules()[1]
+	fields := map[string]string{"Naam": "Jan", "E-mail": "geen adres"}
+
+	// Test
+	values, missing := rule.Extract(fields)
+
+	// Controleer het resultaat
+	assert.Equal(t, "Jan", values[FormFieldNaam])
+	assert.ElementsMatch(t, []string{FormFieldEmail, FormFieldBericht}, missing)
+}
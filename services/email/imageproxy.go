@@ -0,0 +1,155 @@
+package email
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrInvalidProxySignature = errors.New("invalid image proxy signature")
+	ErrBlockedProxyTarget    = errors.New("image proxy target not allowed")
+)
+
+// maxProxyImageSize is de maximale grootte van een afbeelding via de proxy
+const maxProxyImageSize = 5 * 1024 * 1024
+
+// ImageProxy laadt externe afbeeldingen uit emails namens het dashboard. URLs
+// worden met een HMAC ondertekend, zodat de proxy alleen afbeeldingen ophaalt
+// die in een gesaniteerde email voorkwamen en niet als open proxy te misbruiken is.
+type ImageProxy struct {
+	secret   []byte
+	basePath string
+	client   *http.Client
+}
+
+// NewImageProxy maakt een nieuwe ImageProxy. Zonder secret wordt een willekeurig
+// secret gegenereerd; proxy URLs zijn dan alleen geldig tot een herstart.
+func NewImageProxy(secret, basePath string) *ImageProxy {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			log.Printf("[ImageProxy] Failed to generate secret: %v", err)
+		}
+	}
+
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: blockPrivateAddresses,
+	}
+
+	return &ImageProxy{
+		secret:   key,
+		basePath: basePath,
+		client: &http.Client{
+			Timeout: 15 * time.Second,
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: 10 * time.Second,
+				MaxIdleConns:        10,
+				IdleConnTimeout:     90 * time.Second,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= 3 {
+					return errors.New("too many redirects")
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// sign geeft de HMAC-SHA256 handtekening van een URL terug
+func (p *ImageProxy) sign(target string) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(target))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// URL geeft de proxy URL voor een externe afbeelding terug
+func (p *ImageProxy) URL(target string) string {
+	return p.basePath + "?" + url.Values{"url": {target}, "sig": {p.sign(target)}}.Encode()
+}
+
+// Verify controleert of een URL door deze proxy ondertekend is
+func (p *ImageProxy) Verify(target, signature string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	actual, _ := hex.DecodeString(p.sign(target))
+	return hmac.Equal(expected, actual)
+}
+
+// Fetch haalt een ondertekende afbeelding op. Alleen http(s) URLs naar publieke
+// adressen en image/* responses tot maxProxyImageSize worden doorgegeven.
+func (p *ImageProxy) Fetch(ctx context.Context, target, signature string) (string, []byte, error) {
+	if !p.Verify(target, signature) {
+		return "", nil, ErrInvalidProxySignature
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", nil, ErrBlockedProxyTarget
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create image request: %w", err)
+	}
+	req.Header.Set("User-Agent", "DKL-Image-Proxy/1.0")
+	req.Header.Set("Accept", "image/*")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to fetch image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("image server returned %s", resp.Status)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(strings.ToLower(contentType), "image/") || strings.Contains(strings.ToLower(contentType), "svg") {
+		return "", nil, fmt.Errorf("%w: content type %q", ErrBlockedProxyTarget, contentType)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxProxyImageSize+1))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if len(body) > maxProxyImageSize {
+		return "", nil, fmt.Errorf("%w: image larger than %d bytes", ErrBlockedProxyTarget, maxProxyImageSize)
+	}
+
+	return contentType, body, nil
+}
+
+// blockPrivateAddresses voorkomt dat de proxy interne adressen benadert (SSRF).
+// Wordt na DNS resolutie aangeroepen, dus ook voor redirects en DNS rebinding.
+func blockPrivateAddresses(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("%w: %s", ErrBlockedProxyTarget, host)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package email
+
+import (
+	"dklautomationgo/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IncomingEmailHandler verwerkt nieuwe emails in een INBOX, bijvoorbeeld om ze
+// aan contactformulieren en aanmeldingen te koppelen
+type IncomingEmailHandler interface {
+	HandleIncomingEmail(email *models.Email)
+}
+
+// AddIncomingHandler registreert een handler voor nieuwe emails. Handlers worden
+// aangeroepen voor berichten die een watcher binnen ziet komen en voor berichten
+// die bij het vullen van de cache nog niet bekend waren.
+func (s *EmailService) AddIncomingHandler(handler IncomingEmailHandler) {
+	s.incomingMutex.Lock()
+	defer s.incomingMutex.Unlock()
+	s.incomingHandlers = append(s.incomingHandlers, handler)
+}
+
+// notifyIncoming geeft nieuwe emails door aan de geregistreerde handlers, buiten
+// de request of watcher om zodat trage handlers het ophalen niet vertragen
+func (s *EmailService) notifyIncoming(emails []*models.Email) {
+	s.incomingMutex.RLock()
+	handlers := append([]IncomingEmailHandler(nil), s.incomingHandlers...)
+	s.incomingMutex.RUnlock()
+
+	if len(handlers) == 0 || len(emails) == 0 {
+		return
+	}
+
+	go func() {
+		for _, email := range emails {
+			for _, handler := range handlers {
+				handler.HandleIncomingEmail(email)
+			}
+		}
+	}()
+}
+
+// recordMessageIDPrefix markeert Message-IDs van bevestigingsmails die naar een record verwijzen
+const recordMessageIDPrefix = "dkl."
+
+// recordMessageID maakt een Message-ID voor een bevestigingsmail waarin het type
+// en ID van het record staan. Een antwoord noemt deze Message-ID in In-Reply-To
+// en References, waardoor het antwoord aan het record gekoppeld kan worden.
+func (s *EmailService) recordMessageID(recordType, recordID string) string {
+	domain := "dekoninklijkeloop.nl"
+	if _, config, err := s.senderFor(models.EmailPurposeConfirmation); err == nil {
+		if at := strings.LastIndex(config.Email, "@"); at != -1 {
+			domain = config.Email[at+1:]
+		}
+	}
+	return fmt.Sprintf("<%s%s.%s.%d@%s>", recordMessageIDPrefix, recordType, recordID, time.Now().UnixNano(), domain)
+}
+
+// ParseRecordMessageID haalt het type en ID van een record uit een Message-ID
+// die door recordMessageID gemaakt is
+func ParseRecordMessageID(messageID string) (recordType, recordID string, ok bool) {
+	local := strings.Trim(strings.TrimSpace(messageID), "<>")
+	if at := strings.LastIndex(local, "@"); at != -1 {
+		local = local[:at]
+	}
+	if !strings.HasPrefix(local, recordMessageIDPrefix) {
+		return "", "", false
+	}
+
+	parts := strings.Split(strings.TrimPrefix(local, recordMessageIDPrefix), ".")
+	if len(parts) != 3 {
+		return "", "", false
+	}
+	// Een Message-ID kan door iedereen verzonnen worden; alleen een geldig UUID gaat naar de database
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return "", "", false
+	}
+	switch parts[0] {
+	case models.EmailLinkContact, models.EmailLinkAanmelding:
+		return parts[0], id.String(), true
+	}
+	return "", "", false
+}
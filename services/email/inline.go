@@ -0,0 +1,403 @@
+package email
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// cssRule is een regel uit een <style> blok met één eenvoudige selector
+type cssRule struct {
+	selector    []cssCompound // van buiten naar binnen, gescheiden door spaties
+	specificity int
+	order       int
+	decls       []cssDeclaration
+}
+
+// cssCompound is een deel van een selector zoals "li" of "div.details"
+type cssCompound struct {
+	tag     string
+	id      string
+	classes []string
+}
+
+type cssDeclaration struct {
+	property string
+	value    string
+}
+
+// InlineCSS zet de regels uit de <style> blokken van een HTML email als style
+// attribuut op de elementen waar ze op van toepassing zijn. Veel mailclients
+// negeren <style> blokken. Ondersteund worden selectors met tags, classes en
+// ids, eventueel genest (".details li"). Regels met pseudo-classes en andere
+// selectors, @media en @font-face blijven in een <style> blok staan voor
+// clients die dat wel ondersteunen; @import wordt verwijderd omdat externe
+// stylesheets in email toch niet geladen worden. Een style attribuut dat al op
+// een element staat gaat voor de regels uit het <style> blok.
+func InlineCSS(input string) string {
+	if !strings.Contains(strings.ToLower(input), "<style") {
+		return input
+	}
+
+	doc, err := html.Parse(strings.NewReader(input))
+	if err != nil {
+		log.Printf("[InlineCSS] Failed to parse HTML: %v", err)
+		return input
+	}
+
+	var styles []*html.Node
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Style {
+			styles = append(styles, n)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(doc)
+
+	var rules []cssRule
+	var remaining []string
+	for _, style := range styles {
+		var css strings.Builder
+		for c := style.FirstChild; c != nil; c = c.NextSibling {
+			css.WriteString(c.Data)
+		}
+		inlined, kept := parseStylesheet(css.String(), len(rules))
+		rules = append(rules, inlined...)
+		remaining = append(remaining, kept...)
+	}
+
+	// De regels die niet inline kunnen komen samen in het eerste <style> blok
+	for i, style := range styles {
+		if i == 0 && len(remaining) > 0 {
+			for c := style.FirstChild; c != nil; c = style.FirstChild {
+				style.RemoveChild(c)
+			}
+			style.AppendChild(&html.Node{Type: html.TextNode, Data: "\n" + strings.Join(remaining, "\n") + "\n"})
+			continue
+		}
+		style.Parent.RemoveChild(style)
+	}
+
+	// Hogere specificiteit gaat voor, bij gelijke specificiteit de latere regel
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].specificity != rules[j].specificity {
+			return rules[i].specificity < rules[j].specificity
+		}
+		return rules[i].order < rules[j].order
+	})
+
+	var apply func(*html.Node)
+	apply = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom != atom.Head {
+			applyRules(n, rules)
+		}
+		if n.DataAtom == atom.Head {
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			apply(c)
+		}
+	}
+	apply(doc)
+
+	var b strings.Builder
+	if err := html.Render(&b, doc); err != nil {
+		log.Printf("[InlineCSS] Failed to render HTML: %v", err)
+		return input
+	}
+	return b.String()
+}
+
+// applyRules zet de declaraties van alle passende regels in het style attribuut van n
+func applyRules(n *html.Node, rules []cssRule) {
+	var decls []cssDeclaration
+	for _, rule := range rules {
+		if matchSelector(n, rule.selector) {
+			decls = append(decls, rule.decls...)
+		}
+	}
+	if len(decls) == 0 {
+		return
+	}
+
+	index := -1
+	for i, attr := range n.Attr {
+		if attr.Key == "style" {
+			index = i
+			decls = append(decls, parseDeclarations(attr.Val)...)
+		}
+	}
+
+	// Een latere declaratie van dezelfde property vervangt de eerdere, op de plek van de eerste
+	var properties []string
+	values := make(map[string]string)
+	for _, decl := range decls {
+		if _, ok := values[decl.property]; !ok {
+			properties = append(properties, decl.property)
+		}
+		values[decl.property] = decl.value
+	}
+	parts := make([]string, len(properties))
+	for i, property := range properties {
+		parts[i] = property + ": " + values[property]
+	}
+	style := strings.Join(parts, "; ")
+
+	if index >= 0 {
+		n.Attr[index].Val = style
+	} else {
+		n.Attr = append(n.Attr, html.Attribute{Key: "style", Val: style})
+	}
+}
+
+// matchSelector controleert of n past bij de selector; alle compounds behalve
+// de laatste moeten bij een voorouder passen
+func matchSelector(n *html.Node, selector []cssCompound) bool {
+	last := len(selector) - 1
+	if !selector[last].matches(n) {
+		return false
+	}
+	i := last - 1
+	for p := n.Parent; p != nil && i >= 0; p = p.Parent {
+		if p.Type == html.ElementNode && selector[i].matches(p) {
+			i--
+		}
+	}
+	return i < 0
+}
+
+func (c cssCompound) matches(n *html.Node) bool {
+	if c.tag != "" && c.tag != n.Data {
+		return false
+	}
+	var id, class string
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "id":
+			id = attr.Val
+		case "class":
+			class = attr.Val
+		}
+	}
+	if c.id != "" && c.id != id {
+		return false
+	}
+	classes := strings.Fields(class)
+	for _, want := range c.classes {
+		found := false
+		for _, have := range classes {
+			if have == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// parseStylesheet splitst een stylesheet in regels die inline gezet kunnen
+// worden en de tekst van de regels die in het <style> blok moeten blijven
+func parseStylesheet(css string, order int) ([]cssRule, []string) {
+	css = stripCSSComments(css)
+
+	var rules []cssRule
+	var kept []string
+	for pos := 0; pos < len(css); {
+		rest := strings.TrimLeft(css[pos:], " \t\r\n")
+		pos = len(css) - len(rest)
+		if rest == "" {
+			break
+		}
+
+		if strings.HasPrefix(rest, "@") {
+			open := indexCSS(rest, "{;")
+			if open == -1 {
+				break
+			}
+			if rest[open] == ';' {
+				// @import en @charset hebben in een email geen betekenis
+				pos += open + 1
+				continue
+			}
+			end := matchingBrace(rest, open)
+			kept = append(kept, strings.TrimSpace(rest[:end]))
+			pos += end
+			continue
+		}
+
+		open := strings.Index(rest, "{")
+		if open == -1 {
+			break
+		}
+		end := matchingBrace(rest, open)
+		selectors := rest[:open]
+		body := strings.TrimSuffix(rest[open+1:end], "}")
+		pos += end
+
+		decls := parseDeclarations(body)
+		if len(decls) == 0 {
+			continue
+		}
+		var unsupported []string
+		for _, raw := range strings.Split(selectors, ",") {
+			raw = strings.TrimSpace(raw)
+			selector, specificity, ok := parseSelector(raw)
+			if !ok {
+				unsupported = append(unsupported, raw)
+				continue
+			}
+			rules = append(rules, cssRule{selector: selector, specificity: specificity, order: order, decls: decls})
+			order++
+		}
+		if len(unsupported) > 0 {
+			kept = append(kept, strings.Join(unsupported, ", ")+" { "+strings.TrimSpace(body)+" }")
+		}
+	}
+	return rules, kept
+}
+
+// parseSelector leest een selector van tags, classes en ids, gescheiden door spaties
+func parseSelector(raw string) ([]cssCompound, int, bool) {
+	if raw == "" || strings.ContainsAny(raw, ":[>+~*()") {
+		return nil, 0, false
+	}
+
+	var selector []cssCompound
+	specificity := 0
+	for _, part := range strings.Fields(raw) {
+		var c cssCompound
+		for part != "" {
+			next := strings.IndexAny(part[1:], ".#") + 1
+			if next == 0 {
+				next = len(part)
+			}
+			token := part[:next]
+			part = part[next:]
+
+			switch token[0] {
+			case '.':
+				if len(token) == 1 {
+					return nil, 0, false
+				}
+				c.classes = append(c.classes, token[1:])
+				specificity += 10
+			case '#':
+				if len(token) == 1 {
+					return nil, 0, false
+				}
+				c.id = token[1:]
+				specificity += 100
+			default:
+				if c.tag != "" {
+					return nil, 0, false
+				}
+				c.tag = strings.ToLower(token)
+				specificity++
+			}
+		}
+		selector = append(selector, c)
+	}
+	return selector, specificity, true
+}
+
+// parseDeclarations leest "property: value" paren, gescheiden door puntkomma's
+// buiten haakjes en aanhalingstekens
+func parseDeclarations(body string) []cssDeclaration {
+	var decls []cssDeclaration
+	for _, part := range splitCSS(body, ';') {
+		colon := strings.Index(part, ":")
+		if colon == -1 {
+			continue
+		}
+		property := strings.ToLower(strings.TrimSpace(part[:colon]))
+		value := strings.Join(strings.Fields(part[colon+1:]), " ")
+		if property == "" || value == "" {
+			continue
+		}
+		decls = append(decls, cssDeclaration{property: property, value: value})
+	}
+	return decls
+}
+
+// splitCSS splitst op sep, behalve binnen haakjes en aanhalingstekens
+func splitCSS(s string, sep byte) []string {
+	var parts []string
+	for {
+		i := indexCSS(s, string(sep))
+		if i == -1 {
+			return append(parts, s)
+		}
+		parts = append(parts, s[:i])
+		s = s[i+1:]
+	}
+}
+
+// indexCSS geeft de positie van het eerste teken uit chars buiten haakjes en
+// aanhalingstekens, zodat bijv. de ; in url('...;...') overgeslagen wordt
+func indexCSS(s string, chars string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '(':
+			depth++
+		case ch == ')' && depth > 0:
+			depth--
+		case depth == 0 && strings.IndexByte(chars, ch) != -1:
+			return i
+		}
+	}
+	return -1
+}
+
+// matchingBrace geeft de positie direct na de accolade die die op open sluit
+func matchingBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(s)
+}
+
+// stripCSSComments verwijdert /* */ commentaar
+func stripCSSComments(css string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(css, "/*")
+		if start == -1 {
+			b.WriteString(css)
+			return b.String()
+		}
+		b.WriteString(css[:start])
+		end := strings.Index(css[start+2:], "*/")
+		if end == -1 {
+			return b.String()
+		}
+		css = css[start+2+end+2:]
+	}
+}
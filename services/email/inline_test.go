@@ -0,0 +1,62 @@
+package email
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInlineCSS(t *testing.T) {
+	// Setup
+	input := `<html><head><style>
+		@import url('https://fonts.googleapis.com/css2?family=Inter&display=swap');
+		/* kaart */
+		p { color: #374151; margin: 0 }
+		.details li { padding: 8px 0; border-bottom: 1px solid #ffedd5 }
+		.details li:last-child { border-bottom: none }
+		#intro, p.lead { font-weight: bold }
+		@media (max-width: 600px) { .details { padding: 8px } }
+	</style></head><body>
+	<p id="intro" style="color: red">Hallo</p>
+	<p class="lead">Welkom</p>
+	<div class="details"><ul><li>Naam</li></ul></div>
+	<li>Buiten de details</li>
+	</body></html>`
+
+	// Test
+	result := InlineCSS(input)
+
+	// Controleer het resultaat
+	assert.Contains(t, result, `<p id="intro" style="color: red; margin: 0; font-weight: bold">`)
+	assert.Contains(t, result, `<p class="lead" style="color: #374151; margin: 0; font-weight: bold">`)
+	assert.Contains(t, result, `<li style="padding: 8px 0; border-bottom: 1px solid #ffedd5">Naam</li>`)
+	assert.Contains(t, result, `<li>Buiten de details</li>`)
+	assert.NotContains(t, result, "@import")
+	assert.NotContains(t, result, "kaart")
+	// Wat niet inline kan blijft in het style blok staan
+	assert.Contains(t, result, ".details li:last-child { border-bottom: none }")
+	assert.Contains(t, result, "@media (max-width: 600px) { .details { padding: 8px } }")
+}
+
+func TestInlineCSS_WithoutStyleUnchanged(t *testing.T) {
+	input := `<p>Database {{.Naam}}</p>`
+	assert.Equal(t, input, InlineCSS(input))
+}
+
+func TestInlineCSS_Templates(t *testing.T) {
+	// Setup
+	input, err := os.ReadFile("../../templates/aanmelding_email.html")
+	require.NoError(t, err)
+
+	// Test
+	result := InlineCSS(string(input))
+
+	// Controleer het resultaat
+	assert.NotContains(t, result, "fonts.googleapis.com")
+	assert.Contains(t, result, `<div class="header" style="background-color: #ff9328; color: #ffffff; padding: 24px; text-align: center">`)
+	assert.Contains(t, result, `<body style="font-family: &#39;Inter&#39;, -apple-system`)
+	assert.Equal(t, 1, strings.Count(result, "<style>"))
+}
@@ -0,0 +1,375 @@
+package email
+
+import (
+	"dklautomationgo/models"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-imap/commands"
+)
+
+// ErrExpungeUnsupported betekent dat de server geen UID EXPUNGE (UIDPLUS) kent,
+// zodat een enkel bericht niet definitief verwijderd kan worden
+var ErrExpungeUnsupported = errors.New("IMAP server does not support UIDPLUS, cannot permanently delete a single message")
+
+// Gangbare mapnamen voor servers zonder SPECIAL-USE ondersteuning
+var (
+	sentFolderNames    = []string{"Sent", "INBOX.Sent", "Sent Items", "Sent Messages", "Verzonden items", "INBOX.Verzonden items"}
+	archiveFolderNames = []string{"Archive", "INBOX.Archive", "Archief", "INBOX.Archief"}
+	trashFolderNames   = []string{"Trash", "INBOX.Trash", "Deleted Items", "Deleted Messages", "Prullenbak", "INBOX.Prullenbak"}
+	junkFolderNames    = []string{"Junk", "INBOX.Junk", "Spam", "INBOX.Spam", "Ongewenste e-mail"}
+)
+
+// specialFolder koppelt een SPECIAL-USE attribuut aan de gangbare namen van die map
+type specialFolder struct {
+	attr       string
+	candidates []string
+}
+
+// folderAliases zijn namen die de API accepteert in plaats van de servermapnaam
+var folderAliases = map[string]specialFolder{
+	"sent":    {imap.SentAttr, sentFolderNames},
+	"archive": {imap.ArchiveAttr, archiveFolderNames},
+	"trash":   {imap.TrashAttr, trashFolderNames},
+	"junk":    {imap.JunkAttr, junkFolderNames},
+	"spam":    {imap.JunkAttr, junkFolderNames},
+}
+
+// defaultArchiveFolder wordt aangemaakt als een account nog geen archiefmap heeft
+const defaultArchiveFolder = "Archive"
+
+// findSpecialFolder zoekt een map op basis van een SPECIAL-USE attribuut (RFC 6154)
+// en valt terug op gangbare mapnamen. Geeft een lege string terug als niets gevonden is.
+func findSpecialFolder(c *client.Client, attr string, candidates []string) (string, error) {
+	mailboxes := make(chan *imap.MailboxInfo, 20)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.List("", "*", mailboxes)
+	}()
+
+	var names []string
+	found := ""
+	for mbox := range mailboxes {
+		names = append(names, mbox.Name)
+		for _, a := range mbox.Attributes {
+			if a == attr && found == "" {
+				found = mbox.Name
+			}
+		}
+	}
+
+	if err := <-done; err != nil {
+		return "", fmt.Errorf("IMAP list failed: %w", err)
+	}
+	if found != "" {
+		return found, nil
+	}
+
+	for _, candidate := range candidates {
+		for _, name := range names {
+			if strings.EqualFold(name, candidate) {
+				return name, nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// resolveFolder zet een alias als "sent" of "spam" om naar de map op de server.
+// Andere namen worden ongewijzigd teruggegeven.
+func resolveFolder(c *client.Client, folder string) (string, error) {
+	alias, ok := folderAliases[strings.ToLower(folder)]
+	if !ok {
+		return folder, nil
+	}
+
+	name, err := findSpecialFolder(c, alias.attr, alias.candidates)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return folder, nil
+	}
+	return name, nil
+}
+
+// specialUse geeft de SPECIAL-USE rol van een map terug op basis van de attributen
+func specialUse(attributes []string) string {
+	for _, attr := range attributes {
+		switch attr {
+		case imap.SentAttr:
+			return "sent"
+		case imap.ArchiveAttr:
+			return "archive"
+		case imap.TrashAttr:
+			return "trash"
+		case imap.JunkAttr:
+			return "junk"
+		case imap.DraftsAttr:
+			return "drafts"
+		}
+	}
+	return ""
+}
+
+// ListFolders geeft de mappen van een account terug, of van alle accounts als
+// accountName leeg is, inclusief het aantal (ongelezen) berichten
+func (s *EmailService) ListFolders(accountName string) ([]*models.EmailFolder, error) {
+	if accountName != "" {
+		config, ok := s.account(accountName)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownAccount, accountName)
+		}
+		return s.listAccountFolders(accountName, config)
+	}
+
+	var folders []*models.EmailFolder
+	var lastErr error
+	for name, config := range s.accountConfigs() {
+		accountFolders, err := s.listAccountFolders(name, config)
+		if err != nil {
+			log.Printf("[ListFolders] %s: %v", name, err)
+			lastErr = err
+			continue
+		}
+		folders = append(folders, accountFolders...)
+	}
+
+	if len(folders) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return folders, nil
+}
+
+func (s *EmailService) listAccountFolders(accountName string, config *EmailConfig) ([]*models.EmailFolder, error) {
+	var folders []*models.EmailFolder
+	err := s.withIMAP(accountName, config, func(c *client.Client) error {
+		var err error
+		folders, err = listFolders(c, accountName)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+// listFolders geeft de mappen van een account met het aantal (ongelezen) berichten
+func listFolders(c *client.Client, accountName string) ([]*models.EmailFolder, error) {
+	mailboxes := make(chan *imap.MailboxInfo, 20)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.List("", "*", mailboxes)
+	}()
+
+	var infos []*imap.MailboxInfo
+	for mbox := range mailboxes {
+		infos = append(infos, mbox)
+	}
+	if err := <-done; err != nil {
+		return nil, fmt.Errorf("IMAP list failed: %w", err)
+	}
+
+	folders := make([]*models.EmailFolder, 0, len(infos))
+	for _, info := range infos {
+		folder := &models.EmailFolder{
+			Account:    accountName,
+			Name:       info.Name,
+			Delimiter:  info.Delimiter,
+			Attributes: info.Attributes,
+			SpecialUse: specialUse(info.Attributes),
+		}
+
+		if !hasFlag(info.Attributes, imap.NoSelectAttr) {
+			status, err := c.Status(info.Name, []imap.StatusItem{imap.StatusMessages, imap.StatusUnseen})
+			if err != nil {
+				log.Printf("[ListFolders] %s: status of %s failed: %v", accountName, info.Name, err)
+			} else {
+				folder.Messages = status.Messages
+				folder.Unseen = status.Unseen
+			}
+		}
+
+		folders = append(folders, folder)
+	}
+
+	return folders, nil
+}
+
+// MoveEmail verplaatst een bericht naar een andere map, zie moveMessage
+func (s *EmailService) MoveEmail(emailID, targetFolder string) error {
+	if strings.TrimSpace(targetFolder) == "" {
+		return fmt.Errorf("target folder is required")
+	}
+
+	return s.withMessage(emailID, func(c *client.Client, ref emailRef, seqSet *imap.SeqSet) (bool, error) {
+		target, err := resolveFolder(c, targetFolder)
+		if err != nil {
+			return false, err
+		}
+		if target == ref.folder {
+			return false, nil
+		}
+		removed, err := moveMessage(c, seqSet, target)
+		if err != nil {
+			return false, err
+		}
+		log.Printf("[MoveEmail] %s: moved %s to %s", ref.account, ref.id(), target)
+		return removed, nil
+	})
+}
+
+// ArchiveEmail verplaatst een bericht naar de archiefmap van het account. Als
+// die nog niet bestaat wordt deze aangemaakt.
+func (s *EmailService) ArchiveEmail(emailID string) error {
+	return s.withMessage(emailID, func(c *client.Client, ref emailRef, seqSet *imap.SeqSet) (bool, error) {
+		archive, err := findSpecialFolder(c, imap.ArchiveAttr, archiveFolderNames)
+		if err != nil {
+			return false, err
+		}
+		if archive == "" {
+			archive = defaultArchiveFolder
+			if err := c.Create(archive); err != nil {
+				return false, fmt.Errorf("IMAP create %s failed: %w", archive, err)
+			}
+			log.Printf("[ArchiveEmail] %s: created folder %s", ref.account, archive)
+		}
+		if archive == ref.folder {
+			return false, nil
+		}
+		return moveMessage(c, seqSet, archive)
+	})
+}
+
+// DeleteEmail verplaatst een bericht naar de prullenbak. Berichten die al in
+// de prullenbak staan, of accounts zonder prullenbak, worden definitief verwijderd.
+func (s *EmailService) DeleteEmail(emailID string) error {
+	return s.withMessage(emailID, func(c *client.Client, ref emailRef, seqSet *imap.SeqSet) (bool, error) {
+		trash, err := findSpecialFolder(c, imap.TrashAttr, trashFolderNames)
+		if err != nil {
+			return false, err
+		}
+		if trash != "" && trash != ref.folder {
+			return moveMessage(c, seqSet, trash)
+		}
+
+		// Een gewone EXPUNGE verwijdert alle berichten met \Deleted in de map,
+		// ook die van andere clients; zonder UIDPLUS verwijderen we dus niets
+		supported, err := c.Support("UIDPLUS")
+		if err != nil {
+			return false, fmt.Errorf("IMAP capability check failed: %w", err)
+		}
+		if !supported {
+			return false, ErrExpungeUnsupported
+		}
+
+		item := imap.FormatFlagsOp(imap.AddFlags, true)
+		if err := c.UidStore(seqSet, item, []interface{}{imap.DeletedFlag}, nil); err != nil {
+			return false, fmt.Errorf("IMAP store of deleted flag failed: %w", err)
+		}
+		if err := uidExpunge(c, seqSet); err != nil {
+			return false, fmt.Errorf("IMAP UID expunge failed: %w", err)
+		}
+		log.Printf("[DeleteEmail] %s: permanently deleted %s", ref.account, ref.id())
+		return true, nil
+	})
+}
+
+// moveMessage verplaatst de berichten in seqSet naar target en geeft aan of ze
+// uit de geselecteerde map verdwenen zijn. Zonder MOVE worden ze gekopieerd en
+// gemarkeerd met \Deleted; alleen met UIDPLUS worden ze daarna ook verwijderd.
+// Een gewone EXPUNGE (de terugval van UidMove) zou ook berichten die andere
+// clients gemarkeerd hebben verwijderen, dus zonder UIDPLUS blijft het bericht
+// gemarkeerd staan tot een mailclient de map opruimt.
+func moveMessage(c *client.Client, seqSet *imap.SeqSet, target string) (bool, error) {
+	supported, err := c.Support("MOVE")
+	if err != nil {
+		return false, fmt.Errorf("IMAP capability check failed: %w", err)
+	}
+	if supported {
+		if err := c.UidMove(seqSet, target); err != nil {
+			return false, fmt.Errorf("IMAP move to %s failed: %w", target, err)
+		}
+		return true, nil
+	}
+
+	if err := c.UidCopy(seqSet, target); err != nil {
+		return false, fmt.Errorf("IMAP copy to %s failed: %w", target, err)
+	}
+	item := imap.FormatFlagsOp(imap.AddFlags, true)
+	if err := c.UidStore(seqSet, item, []interface{}{imap.DeletedFlag}, nil); err != nil {
+		return false, fmt.Errorf("IMAP store of deleted flag failed: %w", err)
+	}
+
+	supported, err = c.Support("UIDPLUS")
+	if err != nil {
+		return false, fmt.Errorf("IMAP capability check failed: %w", err)
+	}
+	if !supported {
+		log.Printf("[moveMessage] copied %s to %s; server has no UIDPLUS, original stays flagged \\Deleted", seqSet, target)
+		return false, nil
+	}
+	if err := uidExpunge(c, seqSet); err != nil {
+		return false, fmt.Errorf("IMAP UID expunge failed: %w", err)
+	}
+	return true, nil
+}
+
+// uidExpungeCommand is het UID EXPUNGE commando uit UIDPLUS (RFC 4315)
+type uidExpungeCommand struct {
+	seqSet *imap.SeqSet
+}
+
+func (cmd *uidExpungeCommand) Command() *imap.Command {
+	return &imap.Command{Name: "EXPUNGE", Arguments: []interface{}{cmd.seqSet}}
+}
+
+// uidExpunge verwijdert alleen de berichten in seqSet definitief
+func uidExpunge(c *client.Client, seqSet *imap.SeqSet) error {
+	status, err := c.Execute(&commands.Uid{Cmd: &uidExpungeCommand{seqSet: seqSet}}, nil)
+	if err != nil {
+		return err
+	}
+	return status.Err()
+}
+
+// withMessage opent de map van een bericht en voert fn uit op diens UID. Als fn
+// aangeeft dat het bericht uit de map verdwenen is, wordt het ook uit de cache verwijderd.
+func (s *EmailService) withMessage(emailID string, fn func(c *client.Client, ref emailRef, seqSet *imap.SeqSet) (bool, error)) error {
+	ref, err := parseEmailID(emailID)
+	if err != nil {
+		return err
+	}
+
+	config, ok := s.account(ref.account)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownAccount, ref.account)
+	}
+
+	var removed bool
+	err = s.withIMAP(ref.account, config, func(c *client.Client) error {
+		var seqSet *imap.SeqSet
+		var err error
+		ref, seqSet, err = s.selectMessage(c, ref, false)
+		if err != nil {
+			return err
+		}
+
+		removed, err = fn(c, ref, seqSet)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if removed && ref.isInbox() {
+		s.cache(ref.account).remove(ref.id())
+		s.publish(MailboxEvent{Type: MailboxEventExpunged, Account: ref.account, EmailID: ref.id()})
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package email
+
+import (
+	"testing"
+	"time"
+
+	"dklautomationgo/models"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/backend"
+	"github.com/emersion/go-imap/backend/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// moveBackend voegt MOVE toe aan de memory backend; de server meldt MOVE altijd,
+// maar de memory backend kan zelf geen berichten verplaatsen
+type moveBackend struct {
+	*memory.Backend
+}
+
+func (be moveBackend) Login(info *imap.ConnInfo, username, password string) (backend.User, error) {
+	user, err := be.Backend.Login(info, username, password)
+	if err != nil {
+		return nil, err
+	}
+	return moveUser{user}, nil
+}
+
+type moveUser struct {
+	backend.User
+}
+
+func (u moveUser) GetMailbox(name string) (backend.Mailbox, error) {
+	mbox, err := u.User.GetMailbox(name)
+	if err != nil {
+		return nil, err
+	}
+	return moveMailbox{mbox}, nil
+}
+
+type moveMailbox struct {
+	backend.Mailbox
+}
+
+func (mbox moveMailbox) MoveMessages(uid bool, seqSet *imap.SeqSet, dest string) error {
+	if err := mbox.CopyMessages(uid, seqSet, dest); err != nil {
+		return err
+	}
+	if err := mbox.UpdateMessagesFlags(uid, seqSet, imap.AddFlags, []string{imap.DeletedFlag}); err != nil {
+		return err
+	}
+	return mbox.Expunge()
+}
+
+func TestDeleteEmail_RequiresUIDPlusForPermanentDelete(t *testing.T) {
+	// Setup: de memory backend heeft geen prullenbak en kent geen UIDPLUS
+	port, caFile := testIMAPServer(t, true)
+	config := testIMAPConfig(port)
+	config.TLSCAFile = caFile
+
+	service := &EmailService{config: &ServiceConfig{
+		Accounts:     map[string]*EmailConfig{"info": config},
+		FetchTimeout: 10 * time.Second,
+	}}
+	defer service.Close()
+	emails, err := service.FetchEmails(&models.EmailFetchOptions{Account: "info"})
+	require.NoError(t, err)
+	require.Len(t, emails, 1)
+
+	// Test
+	err = service.DeleteEmail(emails[0].ID)
+
+	// Controleer het resultaat: er is niets gemarkeerd of verwijderd
+	assert.ErrorIs(t, err, ErrExpungeUnsupported)
+	after, err := service.FetchEmails(&models.EmailFetchOptions{Account: "info"})
+	require.NoError(t, err)
+	assert.Len(t, after, 1)
+}
+
+func TestArchiveEmail_MovesMessage(t *testing.T) {
+	// Setup: een backend met MOVE, zonder archiefmap
+	port, caFile := testIMAPServerWithBackend(t, true, moveBackend{memory.New()})
+	config := testIMAPConfig(port)
+	config.TLSCAFile = caFile
+
+	service := &EmailService{config: &ServiceConfig{
+		Accounts:     map[string]*EmailConfig{"info": config},
+		FetchTimeout: 10 * time.Second,
+	}}
+	defer service.Close()
+	emails, err := service.FetchEmails(&models.EmailFetchOptions{Account: "info"})
+	require.NoError(t, err)
+	require.Len(t, emails, 1)
+
+	// Test
+	err = service.ArchiveEmail(emails[0].ID)
+
+	// Controleer het resultaat
+	require.NoError(t, err)
+	inbox, err := service.FetchEmails(&models.EmailFetchOptions{Account: "info"})
+	require.NoError(t, err)
+	assert.Empty(t, inbox)
+	archived, err := service.FetchEmails(&models.EmailFetchOptions{Account: "info", Folder: defaultArchiveFolder})
+	require.NoError(t, err)
+	assert.Len(t, archived, 1)
+}
@@ -0,0 +1,281 @@
+package email
+
+import (
+	"dklautomationgo/models"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+)
+
+// ErrMetadataUnavailable wordt teruggegeven als er geen opslag voor metadata ingesteld is
+var ErrMetadataUnavailable = errors.New("email metadata is not configured")
+
+// EmailMetadataStore bewaart status, labels en toewijzingen van emails
+type EmailMetadataStore interface {
+	FindByMessage(account, messageKey string) (*models.EmailMetadata, error)
+	FindByEmailID(emailID string) (*models.EmailMetadata, error)
+	FindByMessageKeys(messageKeys []string) ([]*models.EmailMetadata, error)
+	SaveColumns(metadata *models.EmailMetadata, columns ...string) error
+	ClaimRules(account, messageKey, emailID string) (bool, error)
+}
+
+// EmailNoteStore bewaart interne notities bij emails
+type EmailNoteStore interface {
+	Create(note *models.EmailNote) error
+	FindByMessage(account, messageKey string) ([]*models.EmailNote, error)
+}
+
+// SetMetadataStores stelt de opslag in voor de metadata en notities van emails.
+// Zonder opslag geven de metadata functies ErrMetadataUnavailable terug.
+func (s *EmailService) SetMetadataStores(metadata EmailMetadataStore, notes EmailNoteStore) {
+	s.metadata = metadata
+	s.notes = notes
+}
+
+// GetEmailMetadata haalt de metadata van een email op. Een email zonder
+// metadata krijgt lege metadata met status open, die nog niet opgeslagen is.
+func (s *EmailService) GetEmailMetadata(emailID string) (*models.EmailMetadata, error) {
+	if s.metadata == nil {
+		return nil, ErrMetadataUnavailable
+	}
+
+	metadata, err := s.metadata.FindByEmailID(emailID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load email metadata: %w", err)
+	}
+	if metadata != nil {
+		return withDefaultStatus(metadata), nil
+	}
+
+	// De email kan verplaatst zijn sinds de metadata opgeslagen is, zoek via de Message-ID
+	email, err := s.GetEmail(emailID)
+	if err != nil {
+		return nil, err
+	}
+	return s.MetadataFor(email)
+}
+
+// MetadataFor haalt de metadata op van een email die al opgehaald is
+func (s *EmailService) MetadataFor(email *models.Email) (*models.EmailMetadata, error) {
+	if s.metadata == nil {
+		return nil, ErrMetadataUnavailable
+	}
+
+	metadata, err := s.metadata.FindByMessage(email.Account, MetadataKey(email))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load email metadata: %w", err)
+	}
+	if metadata == nil {
+		return models.NewEmailMetadata(email.Account, MetadataKey(email), email.ID), nil
+	}
+	metadata.EmailID = email.ID
+	return withDefaultStatus(metadata), nil
+}
+
+// UpdateEmailMetadata past de metadata van een email aan met update en slaat
+// deze op. Het dashboard krijgt een email.updated event met de nieuwe metadata.
+func (s *EmailService) UpdateEmailMetadata(emailID string, update func(metadata *models.EmailMetadata)) (*models.EmailMetadata, error) {
+	metadata, err := s.GetEmailMetadata(emailID)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *metadata
+	before.Labels = slices.Clone(metadata.Labels)
+	update(metadata)
+	metadata.EmailID = emailID
+	if err := s.metadata.SaveColumns(metadata, changedMetadataColumns(&before, metadata)...); err != nil {
+		return nil, fmt.Errorf("failed to save email metadata: %w", err)
+	}
+
+	// Andere kolommen kunnen intussen gewijzigd zijn; geef de opgeslagen stand terug
+	saved, err := s.metadata.FindByMessage(metadata.Account, metadata.MessageKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load email metadata: %w", err)
+	}
+	if saved != nil {
+		metadata = withDefaultStatus(saved)
+	}
+
+	s.publish(MailboxEvent{
+		Type:     MailboxEventMetadataChanged,
+		Account:  metadata.Account,
+		EmailID:  emailID,
+		Metadata: metadata,
+	})
+	return metadata, nil
+}
+
+// changedMetadataColumns geeft de kolommen die tussen before en after gewijzigd zijn
+func changedMetadataColumns(before, after *models.EmailMetadata) []string {
+	var columns []string
+	if before.Status != after.Status {
+		columns = append(columns, "status")
+	}
+	if (before.AssignedTo == nil) != (after.AssignedTo == nil) ||
+		(before.AssignedTo != nil && *before.AssignedTo != *after.AssignedTo) {
+		columns = append(columns, "assigned_to")
+	}
+	if !slices.Equal(before.Labels, after.Labels) {
+		columns = append(columns, "labels")
+	}
+	return columns
+}
+
+// AddEmailNote voegt een interne notitie toe aan een email
+func (s *EmailService) AddEmailNote(emailID string, author *models.User, body string) (*models.EmailNote, error) {
+	if s.notes == nil {
+		return nil, ErrMetadataUnavailable
+	}
+
+	metadata, err := s.GetEmailMetadata(emailID)
+	if err != nil {
+		return nil, err
+	}
+
+	note := &models.EmailNote{
+		Account:    metadata.Account,
+		MessageKey: metadata.MessageKey,
+		EmailID:    emailID,
+		UserID:     author.ID.String(),
+		Author:     author.Email,
+		Body:       strings.TrimSpace(body),
+	}
+	if err := s.notes.Create(note); err != nil {
+		return nil, fmt.Errorf("failed to save email note: %w", err)
+	}
+	return note, nil
+}
+
+// GetEmailNotes haalt de interne notities bij een email op, oudste eerst
+func (s *EmailService) GetEmailNotes(emailID string) ([]*models.EmailNote, error) {
+	if s.notes == nil {
+		return nil, ErrMetadataUnavailable
+	}
+
+	metadata, err := s.GetEmailMetadata(emailID)
+	if err != nil {
+		return nil, err
+	}
+
+	notes, err := s.notes.FindByMessage(metadata.Account, metadata.MessageKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load email notes: %w", err)
+	}
+	return notes, nil
+}
+
+// SummarizeEmails zet emails om naar de lichte weergave voor lijsten, met de
+// status, toewijzing en labels uit de metadata. Kan de metadata niet geladen
+// worden, dan worden de samenvattingen zonder metadata teruggegeven.
+func (s *EmailService) SummarizeEmails(emails []*models.Email) []*models.EmailSummary {
+	metadata, err := s.loadMetadata(emails)
+	if err != nil {
+		log.Printf("[SummarizeEmails] %v", err)
+	}
+
+	summaries := make([]*models.EmailSummary, len(emails))
+	for i, email := range emails {
+		summaries[i] = email.ToSummary()
+		if m := metadata[metadataMapKey(email.Account, MetadataKey(email))]; m != nil {
+			summaries[i].Status = withDefaultStatus(m).Status
+			summaries[i].AssignedTo = m.AssignedTo
+			summaries[i].Labels = m.Labels
+		} else if metadata != nil {
+			summaries[i].Status = models.EmailStatusOpen
+		}
+	}
+	return summaries
+}
+
+// filterByMetadata houdt de emails over die voldoen aan de status, toewijzing
+// en label filters. Emails zonder metadata zijn open en niet toegewezen.
+func (s *EmailService) filterByMetadata(emails []*models.Email, options *models.EmailFetchOptions) ([]*models.Email, error) {
+	if !options.HasMetadataFilter() {
+		return emails, nil
+	}
+	if s.metadata == nil {
+		return nil, ErrMetadataUnavailable
+	}
+
+	metadata, err := s.loadMetadata(emails)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]*models.Email, 0, len(emails))
+	for _, email := range emails {
+		m := metadata[metadataMapKey(email.Account, MetadataKey(email))]
+		if m == nil {
+			m = models.NewEmailMetadata(email.Account, MetadataKey(email), email.ID)
+		}
+		if MetadataMatches(withDefaultStatus(m), options) {
+			filtered = append(filtered, email)
+		}
+	}
+	return filtered, nil
+}
+
+// MetadataMatches controleert of metadata voldoet aan de metadata filters
+func MetadataMatches(metadata *models.EmailMetadata, options *models.EmailFetchOptions) bool {
+	if options.Status != "" && metadata.Status != options.Status {
+		return false
+	}
+
+	switch options.AssignedTo {
+	case "":
+	case models.EmailUnassigned:
+		if metadata.AssignedTo != nil {
+			return false
+		}
+	default:
+		if metadata.AssignedTo == nil || !strings.EqualFold(*metadata.AssignedTo, options.AssignedTo) {
+			return false
+		}
+	}
+
+	return options.Label == "" || metadata.HasLabel(options.Label)
+}
+
+// loadMetadata haalt de metadata van emails op, geïndexeerd op account en Message-ID.
+// Zonder ingestelde opslag is het resultaat nil.
+func (s *EmailService) loadMetadata(emails []*models.Email) (map[string]*models.EmailMetadata, error) {
+	if s.metadata == nil {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(emails))
+	seen := make(map[string]bool)
+	for _, email := range emails {
+		key := MetadataKey(email)
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	found, err := s.metadata.FindByMessageKeys(keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load email metadata: %w", err)
+	}
+
+	metadata := make(map[string]*models.EmailMetadata, len(found))
+	for _, m := range found {
+		metadata[metadataMapKey(m.Account, m.MessageKey)] = m
+	}
+	return metadata, nil
+}
+
+func metadataMapKey(account, messageKey string) string {
+	return account + "\x00" + messageKey
+}
+
+// withDefaultStatus geeft metadata zonder status (van voor de status kolom) de status open
+func withDefaultStatus(metadata *models.EmailMetadata) *models.EmailMetadata {
+	if metadata.Status == "" {
+		metadata.Status = models.EmailStatusOpen
+	}
+	return metadata
+}
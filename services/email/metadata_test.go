@@ -0,0 +1,255 @@
+package email
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"dklautomationgo/models"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/backend/memory"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryNoteStore houdt notities in het geheugen bij
+type memoryNoteStore struct {
+	notes []*models.EmailNote
+}
+
+func (m *memoryNoteStore) Create(note *models.EmailNote) error {
+	m.notes = append(m.notes, note)
+	return nil
+}
+
+func (m *memoryNoteStore) FindByMessage(account, messageKey string) ([]*models.EmailNote, error) {
+	var found []*models.EmailNote
+	for _, note := range m.notes {
+		if note.Account == account && note.MessageKey == messageKey {
+			found = append(found, note)
+		}
+	}
+	return found, nil
+}
+
+// newTestMetadataService maakt een service met een gevulde INBOX cache voor het info account
+func newTestMetadataService(emails ...*models.Email) (*EmailService, *memoryRuleStores, *memoryNoteStore) {
+	service := newTestSendService()
+	service.config.Cache = CacheConfig{Enabled: true, Duration: time.Hour}
+	service.accountCaches = map[string]*AccountCache{"info": NewAccountCache(), "inschrijving": NewAccountCache()}
+	service.accountCaches["info"].store(emails)
+
+	metadata := &memoryRuleStores{metadata: make(map[string]*models.EmailMetadata)}
+	notes := &memoryNoteStore{}
+	service.SetMetadataStores(metadata, notes)
+	return service, metadata, notes
+}
+
+func TestMetadataMatches(t *testing.T) {
+	assignee := "11111111-2222-3333-4444-555555555555"
+	metadata := &models.EmailMetadata{Status: models.EmailStatusWaiting, AssignedTo: &assignee, Labels: []string{"sponsor"}}
+
+	tests := []struct {
+		name    string
+		options models.EmailFetchOptions
+		match   bool
+	}{
+		{"geen filters", models.EmailFetchOptions{}, true},
+		{"status", models.EmailFetchOptions{Status: models.EmailStatusWaiting}, true},
+		{"andere status", models.EmailFetchOptions{Status: models.EmailStatusOpen}, false},
+		{"toegewezen", models.EmailFetchOptions{AssignedTo: assignee}, true},
+		{"aan iemand anders", models.EmailFetchOptions{AssignedTo: uuid.NewString()}, false},
+		{"niet toegewezen", models.EmailFetchOptions{AssignedTo: models.EmailUnassigned}, false},
+		{"label", models.EmailFetchOptions{Label: "sponsor"}, true},
+		{"ander label", models.EmailFetchOptions{Label: "factuur"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.match, MetadataMatches(metadata, &tt.options))
+		})
+	}
+}
+
+// newTestMetadataIMAPService maakt een service met metadata opslag voor een IMAP
+// server waarvan de INBOX alleen de gegeven berichten bevat
+func newTestMetadataIMAPService(t *testing.T, messages ...string) (*EmailService, *memoryRuleStores) {
+	t.Helper()
+
+	be := memory.New()
+	user, err := be.Login(nil, "username", "password")
+	require.NoError(t, err)
+	inbox, err := user.GetMailbox(inboxFolder)
+	require.NoError(t, err)
+	all := new(imap.SeqSet)
+	all.AddRange(1, 0)
+	require.NoError(t, inbox.UpdateMessagesFlags(false, all, imap.AddFlags, []string{imap.DeletedFlag}))
+	require.NoError(t, inbox.Expunge())
+	for _, msg := range messages {
+		require.NoError(t, inbox.CreateMessage(nil, time.Now(), bytes.NewBufferString(msg)))
+	}
+
+	port, caFile := testIMAPServerWithBackend(t, true, be)
+	config := testIMAPConfig(port)
+	config.TLSCAFile = caFile
+	service := &EmailService{config: &ServiceConfig{
+		Accounts:     map[string]*EmailConfig{"info": config},
+		FetchTimeout: 10 * time.Second,
+		Cache:        CacheConfig{Enabled: true, Duration: time.Hour},
+	}}
+	t.Cleanup(service.Close)
+
+	metadata := &memoryRuleStores{metadata: make(map[string]*models.EmailMetadata)}
+	service.SetMetadataStores(metadata, &memoryNoteStore{})
+	return service, metadata
+}
+
+// testMessage geeft een bericht met de gegeven Message-ID (zonder haken) en datum
+func testMessage(messageKey, date string) string {
+	return "From: jan@example.org\r\n" +
+		"To: info@example.org\r\n" +
+		"Subject: Vraag " + messageKey + "\r\n" +
+		"Date: " + date + "\r\n" +
+		"Message-ID: <" + messageKey + ">\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"Hoi"
+}
+
+func TestFetchEmails_FiltersOnMetadata(t *testing.T) {
+	// Setup: een verse cache met maar een deel van de INBOX wordt niet gebruikt
+	assignee := uuid.NewString()
+	service, store := newTestMetadataIMAPService(t,
+		testMessage("een@example.org", "Thu, 01 Jan 2026 10:00:00 +0000"),
+		testMessage("twee@example.org", "Fri, 02 Jan 2026 10:00:00 +0000"),
+		testMessage("drie@example.org", "Sat, 03 Jan 2026 10:00:00 +0000"),
+	)
+	service.cache("info").store([]*models.Email{{ID: "info:INBOX:1:9", Account: "info", MessageID: "<oud@example.org>"}})
+	store.metadata["info|twee@example.org"] = &models.EmailMetadata{Account: "info", MessageKey: "twee@example.org", Status: models.EmailStatusClosed}
+	store.metadata["info|drie@example.org"] = &models.EmailMetadata{Account: "info", MessageKey: "drie@example.org", Status: models.EmailStatusOpen, AssignedTo: &assignee, Labels: []string{"sponsor"}}
+
+	messageIDs := func(options *models.EmailFetchOptions) []string {
+		options.Account = "info"
+		emails, err := service.FetchEmails(options)
+		require.NoError(t, err)
+		var result []string
+		for _, email := range emails {
+			result = append(result, email.MessageID)
+		}
+		return result
+	}
+
+	// Test en controleer het resultaat: nieuwste eerst; emails zonder metadata zijn open en niet toegewezen
+	assert.Equal(t, []string{"<drie@example.org>", "<een@example.org>"}, messageIDs(&models.EmailFetchOptions{Status: models.EmailStatusOpen}))
+	assert.Equal(t, []string{"<twee@example.org>"}, messageIDs(&models.EmailFetchOptions{Status: models.EmailStatusClosed}))
+	assert.Equal(t, []string{"<drie@example.org>"}, messageIDs(&models.EmailFetchOptions{AssignedTo: assignee}))
+	assert.Equal(t, []string{"<twee@example.org>", "<een@example.org>"}, messageIDs(&models.EmailFetchOptions{AssignedTo: models.EmailUnassigned}))
+	assert.Equal(t, []string{"<drie@example.org>"}, messageIDs(&models.EmailFetchOptions{Label: "sponsor", Limit: 1}))
+}
+
+func TestFetchEmails_MetadataFilterPagesAfterFiltering(t *testing.T) {
+	// Setup: de twee nieuwste berichten zijn gesloten
+	service, store := newTestMetadataIMAPService(t,
+		testMessage("een@example.org", "Thu, 01 Jan 2026 10:00:00 +0000"),
+		testMessage("twee@example.org", "Fri, 02 Jan 2026 10:00:00 +0000"),
+		testMessage("drie@example.org", "Sat, 03 Jan 2026 10:00:00 +0000"),
+		testMessage("vier@example.org", "Sun, 04 Jan 2026 10:00:00 +0000"),
+	)
+	store.metadata["info|drie@example.org"] = &models.EmailMetadata{Account: "info", MessageKey: "drie@example.org", Status: models.EmailStatusClosed}
+	store.metadata["info|vier@example.org"] = &models.EmailMetadata{Account: "info", MessageKey: "vier@example.org", Status: models.EmailStatusClosed}
+
+	// Test: de tweede pagina van één open email
+	emails, err := service.FetchEmails(&models.EmailFetchOptions{Account: "info", Status: models.EmailStatusOpen, Limit: 1, Offset: 1})
+
+	// Controleer het resultaat: offset en limit gelden één keer, na het filteren
+	require.NoError(t, err)
+	require.Len(t, emails, 1)
+	assert.Equal(t, "<een@example.org>", emails[0].MessageID)
+}
+
+func TestFetchEmails_MetadataFilterWithoutStore(t *testing.T) {
+	// Setup
+	service, _, _ := newTestMetadataService(&models.Email{ID: "info:INBOX:1:1", Account: "info"})
+	service.SetMetadataStores(nil, nil)
+
+	// Test
+	_, err := service.FetchEmails(&models.EmailFetchOptions{Status: models.EmailStatusOpen})
+
+	// Controleer het resultaat
+	assert.ErrorIs(t, err, ErrMetadataUnavailable)
+}
+
+func TestUpdateEmailMetadata_FollowsMessageID(t *testing.T) {
+	// Setup: de metadata is opgeslagen onder een oud email ID
+	assignee := uuid.NewString()
+	service, store, _ := newTestMetadataService(&models.Email{ID: "info:INBOX:2:7", Account: "info", MessageID: "<vraag@example.org>"})
+	store.metadata["info|vraag@example.org"] = &models.EmailMetadata{ID: "m1", Account: "info", MessageKey: "vraag@example.org", EmailID: "info:Archief:1:3", Labels: []string{"vraag"}}
+
+	// Test
+	metadata, err := service.UpdateEmailMetadata("info:INBOX:2:7", func(metadata *models.EmailMetadata) {
+		metadata.AssignedTo = &assignee
+		metadata.Status = models.EmailStatusWaiting
+	})
+
+	// Controleer het resultaat
+	require.NoError(t, err)
+	assert.Equal(t, "m1", metadata.ID)
+	assert.Equal(t, "info:INBOX:2:7", metadata.EmailID)
+	assert.Equal(t, assignee, *metadata.AssignedTo)
+	assert.Equal(t, models.EmailStatusWaiting, metadata.Status)
+	assert.Equal(t, []string{"vraag"}, metadata.Labels)
+	assert.Equal(t, 1, store.saves)
+}
+
+func TestUpdateEmailMetadata_KeepsConcurrentChanges(t *testing.T) {
+	// Setup
+	service, store, _ := newTestMetadataService(&models.Email{ID: "info:INBOX:1:1", Account: "info", MessageID: "<vraag@example.org>"})
+	store.metadata["info|vraag@example.org"] = &models.EmailMetadata{ID: "m1", Account: "info", MessageKey: "vraag@example.org", Status: models.EmailStatusOpen}
+
+	// Test: een collega voegt een label toe terwijl de status gewijzigd wordt
+	metadata, err := service.UpdateEmailMetadata("info:INBOX:1:1", func(metadata *models.EmailMetadata) {
+		store.metadata["info|vraag@example.org"].Labels = []string{"sponsor"}
+		metadata.Status = models.EmailStatusClosed
+	})
+
+	// Controleer het resultaat
+	require.NoError(t, err)
+	assert.Equal(t, []string{"status"}, store.columns)
+	assert.Equal(t, models.EmailStatusClosed, metadata.Status)
+	assert.Equal(t, []string{"sponsor"}, metadata.Labels)
+}
+
+func TestEmailNotesAndSummaries(t *testing.T) {
+	// Setup
+	emails := []*models.Email{
+		{ID: "info:INBOX:1:1", Account: "info", MessageID: "<een@example.org>"},
+		{ID: "info:INBOX:1:2", Account: "info"},
+	}
+	service, _, notes := newTestMetadataService(emails...)
+	author := &models.User{ID: uuid.New(), Email: "beheerder@dekoninklijkeloop.nl"}
+
+	// Test
+	_, err := service.UpdateEmailMetadata("info:INBOX:1:1", func(metadata *models.EmailMetadata) {
+		metadata.Labels = []string{"sponsor"}
+	})
+	require.NoError(t, err)
+	note, err := service.AddEmailNote("info:INBOX:1:1", author, "  Ik bel ze morgen  ")
+	require.NoError(t, err)
+	found, err := service.GetEmailNotes("info:INBOX:1:1")
+	require.NoError(t, err)
+	summaries := service.SummarizeEmails(emails)
+
+	// Controleer het resultaat
+	assert.Equal(t, "Ik bel ze morgen", note.Body)
+	assert.Equal(t, author.ID.String(), note.UserID)
+	assert.Equal(t, "een@example.org", note.MessageKey)
+	assert.Equal(t, notes.notes, found)
+
+	require.Len(t, summaries, 2)
+	assert.Equal(t, []string{"sponsor"}, summaries[0].Labels)
+	assert.Equal(t, models.EmailStatusOpen, summaries[0].Status)
+	assert.Equal(t, models.EmailStatusOpen, summaries[1].Status)
+	assert.Nil(t, summaries[1].Labels)
+}
@@ -0,0 +1,174 @@
+package email
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+const (
+	// poolMaxIdle is het maximum aantal vrije verbindingen per account. Mailservers
+	// beperken het aantal gelijktijdige verbindingen, dus houd dit laag.
+	poolMaxIdle = 2
+	// poolIdleTimeout sluit verbindingen die langer niet gebruikt zijn,
+	// ruim voor de autologout van de server (minimaal 30 minuten)
+	poolIdleTimeout = 5 * time.Minute
+	// poolHealthCheckAfter is de tijd waarna een vrije verbinding eerst met
+	// een NOOP gecontroleerd wordt voordat hij hergebruikt wordt
+	poolHealthCheckAfter = 30 * time.Second
+)
+
+// pooledClient is een vrije, ingelogde IMAP verbinding
+type pooledClient struct {
+	client   imapClient
+	config   *EmailConfig // Configuratie waarmee verbonden is
+	lastUsed time.Time
+}
+
+// imapClient is het deel van *client.Client dat de pool gebruikt
+type imapClient interface {
+	State() imap.ConnState
+	Noop() error
+	Logout() error
+	Terminate() error
+}
+
+var _ imapClient = (*client.Client)(nil)
+
+// imapPool houdt per account een aantal ingelogde IMAP verbindingen open, zodat
+// niet elke actie opnieuw hoeft te verbinden en in te loggen. De zero value is bruikbaar.
+type imapPool struct {
+	mu     sync.Mutex
+	idle   map[string][]*pooledClient
+	closed bool
+
+	// dial maakt een nieuwe verbinding; standaard dialIMAP
+	dial func(config *EmailConfig) (imapClient, error)
+}
+
+// get geeft een vrije verbinding van het account of maakt een nieuwe. Een
+// verbinding die een tijd niet gebruikt is wordt eerst gecontroleerd met een
+// NOOP; faalt die, dan wordt hij gesloten en opnieuw verbonden.
+func (p *imapPool) get(account string, config *EmailConfig) (imapClient, error) {
+	for {
+		pc := p.take(account)
+		if pc == nil {
+			break
+		}
+
+		if pc.client.State() == imap.LogoutState {
+			continue
+		}
+		// Een gewijzigde configuratie of een verlopen verbinding niet hergebruiken
+		if pc.config != config || time.Since(pc.lastUsed) > poolIdleTimeout {
+			pc.client.Terminate()
+			continue
+		}
+		if time.Since(pc.lastUsed) > poolHealthCheckAfter {
+			if err := pc.client.Noop(); err != nil {
+				log.Printf("[IMAPPool] %s: idle connection is no longer healthy, reconnecting: %v", account, err)
+				pc.client.Terminate()
+				continue
+			}
+		}
+		return pc.client, nil
+	}
+
+	if p.dial != nil {
+		return p.dial(config)
+	}
+	c, err := dialIMAP(config)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// take haalt de meest recent gebruikte vrije verbinding van een account uit de pool
+func (p *imapPool) take(account string) *pooledClient {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	idle := p.idle[account]
+	if len(idle) == 0 {
+		return nil
+	}
+	pc := idle[len(idle)-1]
+	p.idle[account] = idle[:len(idle)-1]
+	return pc
+}
+
+// put geeft een verbinding terug aan de pool. Als de pool vol of gesloten is,
+// of de verbinding al uitgelogd is, wordt hij gesloten.
+func (p *imapPool) put(account string, config *EmailConfig, c imapClient) {
+	if c.State() == imap.LogoutState {
+		return
+	}
+
+	p.mu.Lock()
+	if p.closed || len(p.idle[account]) >= poolMaxIdle {
+		p.mu.Unlock()
+		c.Logout()
+		return
+	}
+	if p.idle == nil {
+		p.idle = make(map[string][]*pooledClient)
+	}
+	p.idle[account] = append(p.idle[account], &pooledClient{client: c, config: config, lastUsed: time.Now()})
+	p.mu.Unlock()
+}
+
+// closeAccount sluit de vrije verbindingen van een account, bijvoorbeeld na
+// een gewijzigde configuratie
+func (p *imapPool) closeAccount(account string) {
+	p.mu.Lock()
+	idle := p.idle[account]
+	delete(p.idle, account)
+	p.mu.Unlock()
+
+	for _, pc := range idle {
+		pc.client.Logout()
+	}
+}
+
+// close sluit alle vrije verbindingen; teruggegeven verbindingen worden daarna direct gesloten
+func (p *imapPool) close() {
+	p.mu.Lock()
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	for _, clients := range idle {
+		for _, pc := range clients {
+			pc.client.Logout()
+		}
+	}
+}
+
+// withIMAP voert fn uit met een verbinding uit de pool van het account. Als fn
+// een fout geeft is de staat van de verbinding onbekend (een fetch kan nog
+// lopen na een time-out), dus wordt hij dan gesloten in plaats van teruggegeven.
+func (s *EmailService) withIMAP(accountName string, config *EmailConfig, fn func(c *client.Client) error) error {
+	conn, err := s.pool.get(accountName, config)
+	if err != nil {
+		return err
+	}
+	c := conn.(*client.Client)
+
+	if err := fn(c); err != nil {
+		c.Terminate()
+		return err
+	}
+
+	s.pool.put(accountName, config, c)
+	return nil
+}
+
+// Close sluit de open IMAP verbindingen van de service
+func (s *EmailService) Close() {
+	s.pool.close()
+}
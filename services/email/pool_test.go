@@ -0,0 +1,273 @@
+package email
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/backend"
+	"github.com/emersion/go-imap/backend/memory"
+	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-imap/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testIMAPServer start een IMAP server met de memory backend (username/password)
+// en een zelf ondertekend certificaat. Het pad naar het certificaat kan als CA
+// bundel gebruikt worden.
+func testIMAPServer(t *testing.T, implicitTLS bool) (port int, caFile string) {
+	t.Helper()
+	return testIMAPServerWithBackend(t, implicitTLS, memory.New())
+}
+
+// testIMAPServerWithBackend start een IMAP server voor een eigen backend
+func testIMAPServerWithBackend(t *testing.T, implicitTLS bool, be backend.Backend) (port int, caFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	caFile = filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+
+	srv := server.New(be)
+	srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	srv.ErrorLog = log.New(io.Discard, "", 0)
+
+	var listener net.Listener
+	if implicitTLS {
+		listener, err = tls.Listen("tcp", "127.0.0.1:0", srv.TLSConfig)
+	} else {
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	require.NoError(t, err)
+	go srv.Serve(listener)
+	t.Cleanup(func() { srv.Close() })
+
+	return listener.Addr().(*net.TCPAddr).Port, caFile
+}
+
+func testIMAPConfig(port int) *EmailConfig {
+	return &EmailConfig{
+		Email:    "username",
+		Password: "password",
+		IMAPHost: "127.0.0.1",
+		IMAPPort: port,
+	}
+}
+
+func TestEmailConfig_IMAPSecurity(t *testing.T) {
+	assert.Equal(t, IMAPSecurityTLS, (&EmailConfig{IMAPPort: 993}).imapSecurity())
+	assert.Equal(t, IMAPSecurityStartTLS, (&EmailConfig{IMAPPort: 143}).imapSecurity())
+	assert.Equal(t, IMAPSecurityNone, (&EmailConfig{IMAPPort: 143, IMAPSecurity: IMAPSecurityNone}).imapSecurity())
+}
+
+func TestEmailConfig_TLSConfig(t *testing.T) {
+	// Setup
+	_, caFile := testIMAPServer(t, true)
+	invalid := filepath.Join(t.TempDir(), "invalid.pem")
+	require.NoError(t, os.WriteFile(invalid, []byte("geen certificaat"), 0o600))
+
+	// Test
+	defaults, err := (&EmailConfig{}).tlsConfig("imap.example.com")
+	require.NoError(t, err)
+	withCA, err := (&EmailConfig{TLSCAFile: caFile}).tlsConfig("imap.example.com")
+	require.NoError(t, err)
+	_, invalidErr := (&EmailConfig{TLSCAFile: invalid}).tlsConfig("imap.example.com")
+
+	// Controleer het resultaat
+	assert.False(t, defaults.InsecureSkipVerify, "certificaten worden standaard gecontroleerd")
+	assert.Equal(t, uint16(tls.VersionTLS12), defaults.MinVersion)
+	assert.Equal(t, "imap.example.com", defaults.ServerName)
+	assert.Nil(t, defaults.RootCAs)
+	assert.NotNil(t, withCA.RootCAs)
+	assert.Error(t, invalidErr)
+}
+
+func TestDialIMAP_VerifiesCertificate(t *testing.T) {
+	// Setup
+	port, caFile := testIMAPServer(t, true)
+	config := testIMAPConfig(port)
+
+	// Test: zonder CA bundel is het zelf ondertekende certificaat onbekend
+	_, err := dialIMAP(config)
+
+	// Controleer het resultaat
+	assert.ErrorIs(t, err, ErrCertificate)
+	assert.Equal(t, ErrorCodeCertificate, ErrorCode(err))
+
+	// Met de CA bundel wordt het certificaat vertrouwd
+	config.TLSCAFile = caFile
+	c, err := dialIMAP(config)
+	require.NoError(t, err)
+	defer c.Logout()
+	assert.Equal(t, imap.ConnState(imap.AuthenticatedState), c.State())
+}
+
+func TestDialIMAP_StartTLS(t *testing.T) {
+	// Setup: de server staat inloggen pas toe na STARTTLS
+	port, caFile := testIMAPServer(t, false)
+	config := testIMAPConfig(port)
+	config.IMAPSecurity = IMAPSecurityStartTLS
+	config.TLSCAFile = caFile
+
+	// Test
+	c, err := dialIMAP(config)
+
+	// Controleer het resultaat
+	require.NoError(t, err)
+	defer c.Logout()
+	assert.True(t, c.IsTLS())
+	assert.Equal(t, imap.ConnState(imap.AuthenticatedState), c.State())
+}
+
+func TestWithIMAP_ReusesConnections(t *testing.T) {
+	// Setup
+	port, caFile := testIMAPServer(t, true)
+	config := testIMAPConfig(port)
+	config.TLSCAFile = caFile
+
+	dials := 0
+	s := &EmailService{}
+	s.pool.dial = func(config *EmailConfig) (imapClient, error) {
+		dials++
+		return dialIMAP(config)
+	}
+	defer s.Close()
+
+	// Test
+	var first, second *client.Client
+	require.NoError(t, s.withIMAP("info", config, func(c *client.Client) error {
+		first = c
+		return nil
+	}))
+	assert.Error(t, s.withIMAP("info", config, func(c *client.Client) error {
+		second = c
+		return errors.New("mislukt")
+	}))
+	require.NoError(t, s.withIMAP("info", config, func(c *client.Client) error {
+		return nil
+	}))
+
+	// Controleer het resultaat: een verbinding na een fout wordt niet hergebruikt
+	assert.Same(t, first, second)
+	assert.Equal(t, 2, dials)
+}
+
+// fakeIMAPClient is een imapClient waarvan de NOOP kan falen
+type fakeIMAPClient struct {
+	noopErr    error
+	noops      int
+	terminated bool
+	loggedOut  bool
+}
+
+func (f *fakeIMAPClient) State() imap.ConnState {
+	if f.terminated || f.loggedOut {
+		return imap.LogoutState
+	}
+	return imap.AuthenticatedState
+}
+
+func (f *fakeIMAPClient) Noop() error {
+	f.noops++
+	return f.noopErr
+}
+
+func (f *fakeIMAPClient) Logout() error {
+	f.loggedOut = true
+	return nil
+}
+
+func (f *fakeIMAPClient) Terminate() error {
+	f.terminated = true
+	return nil
+}
+
+func TestIMAPPool_HealthCheck(t *testing.T) {
+	// Setup
+	config := &EmailConfig{}
+	fresh := &fakeIMAPClient{}
+	pool := &imapPool{dial: func(*EmailConfig) (imapClient, error) { return fresh, nil }}
+
+	healthy := &fakeIMAPClient{}
+	broken := &fakeIMAPClient{noopErr: errors.New("connection reset")}
+	pool.put("info", config, healthy)
+	pool.put("info", config, broken)
+	pool.idle["info"][0].lastUsed = time.Now().Add(-time.Minute)
+	pool.idle["info"][1].lastUsed = time.Now().Add(-time.Minute)
+
+	// Test: de meest recente (kapotte) verbinding faalt de NOOP, daarna volgt de gezonde
+	got, err := pool.get("info", config)
+	require.NoError(t, err)
+
+	// Controleer het resultaat
+	assert.Same(t, healthy, got)
+	assert.True(t, broken.terminated)
+	assert.Equal(t, 1, healthy.noops)
+
+	// Zonder vrije verbindingen wordt opnieuw verbonden
+	got, err = pool.get("info", config)
+	require.NoError(t, err)
+	assert.Same(t, fresh, got)
+}
+
+func TestIMAPPool_LimitsAndExpiry(t *testing.T) {
+	// Setup
+	config := &EmailConfig{}
+	pool := &imapPool{dial: func(*EmailConfig) (imapClient, error) { return &fakeIMAPClient{}, nil }}
+
+	clients := []*fakeIMAPClient{{}, {}, {}}
+	for _, c := range clients {
+		pool.put("info", config, c)
+	}
+
+	// Controleer het resultaat: boven poolMaxIdle wordt een verbinding gesloten
+	assert.Len(t, pool.idle["info"], poolMaxIdle)
+	assert.True(t, clients[2].loggedOut)
+
+	// Een verlopen verbinding of een andere configuratie wordt niet hergebruikt
+	pool.idle["info"][1].lastUsed = time.Now().Add(-2 * poolIdleTimeout)
+	got, err := pool.get("info", &EmailConfig{})
+	require.NoError(t, err)
+	assert.NotSame(t, clients[0], got)
+	assert.NotSame(t, clients[1], got)
+	assert.True(t, clients[0].terminated)
+	assert.True(t, clients[1].terminated)
+
+	// Na het sluiten worden teruggegeven verbindingen direct gesloten
+	pool.close()
+	late := &fakeIMAPClient{}
+	pool.put("info", config, late)
+	assert.True(t, late.loggedOut)
+	assert.Empty(t, pool.idle)
+}
@@ -3,19 +3,58 @@ package email
 import (
 	"bytes"
 	"dklautomationgo/models"
+	"encoding/base64"
 	"fmt"
 	"io"
 	"log"
+	"regexp"
 	"strings"
 	"time"
 	"unicode/utf8"
 
 	"github.com/emersion/go-imap"
 	"github.com/emersion/go-message"
+	_ "github.com/emersion/go-message/charset" // Registreert charset decoders (ISO-8859-1, Windows-1252, ...)
+	"github.com/emersion/go-message/mail"
 	"golang.org/x/text/encoding/charmap"
 )
 
-func (s *EmailService) processMessage(msg *imap.Message, accountName string) (*models.Email, error) {
+// partKind geeft aan hoe een MIME part van een bericht verwerkt wordt
+type partKind int
+
+const (
+	partContainer  partKind = iota // multipart/*, alleen de kinderen tellen
+	partTextBody                   // text/plain of text/html inhoud van het bericht
+	partAttachment                 // bijlage of inline afbeelding
+)
+
+// classifyPart bepaalt de rol van een MIME part. Zowel processMessage als
+// attachmentParts (op basis van de BODYSTRUCTURE) gebruiken deze regels,
+// zodat de index van een bijlage in beide gevallen naar dezelfde part verwijst.
+func classifyPart(mimeType, disposition, filename string) partKind {
+	mimeType = strings.ToLower(mimeType)
+	switch {
+	case strings.HasPrefix(mimeType, "multipart/"):
+		return partContainer
+	case strings.EqualFold(disposition, "attachment"):
+		return partAttachment
+	case (mimeType == "text/plain" || mimeType == "text/html") && filename == "":
+		return partTextBody
+	default:
+		return partAttachment
+	}
+}
+
+// commonHeaders zijn de headers die in Email.Headers bewaard worden
+var commonHeaders = []string{
+	"From", "To", "Cc", "Reply-To", "Subject", "Date",
+	"Message-ID", "In-Reply-To", "References",
+	"Content-Type", "Content-Transfer-Encoding",
+	"Auto-Submitted", "Precedence", "List-Id", "List-Unsubscribe",
+	"X-Auto-Response-Suppress", "Return-Path",
+}
+
+func (s *EmailService) processMessage(msg *imap.Message, mailbox mailboxRef) (*models.Email, error) {
 	// Only log message ID and subject
 	if msg.Envelope != nil && msg.Envelope.Subject != "" {
 		log.Printf("[EMAIL] Processing: %s", msg.Envelope.Subject)
@@ -33,105 +72,96 @@ func (s *EmailService) processMessage(msg *imap.Message, accountName string) (*m
 	if _, err := io.Copy(&buf, r); err != nil {
 		return nil, fmt.Errorf("failed to read message body: %w", err)
 	}
+	raw := buf.Bytes()
 
-	// Create mail reader
-	mr, err := message.Read(&buf)
-	if err != nil {
-		// Try content recovery if mail reader fails
-		body := buf.Bytes()
-		if !utf8.Valid(body) {
-			if decoded, err := charmap.ISO8859_1.NewDecoder().Bytes(body); err == nil {
-				body = decoded
-			}
-		}
-		return &models.Email{
-			ID:        fmt.Sprintf("%s:%d", accountName, msg.Uid),
-			Subject:   msg.Envelope.Subject,
-			Body:      string(body),
-			Account:   accountName,
-			CreatedAt: msg.Envelope.Date.Format(time.RFC3339),
-			Read:      hasFlag(msg.Flags, imap.SeenFlag),
-		}, nil
-	}
-
-	// Extract headers
-	headers := make(map[string]string)
-	commonHeaders := []string{
-		"From", "To", "Cc", "Subject", "Date",
-		"Message-ID", "In-Reply-To", "References",
-		"Content-Type", "Content-Transfer-Encoding",
+	email := &models.Email{
+		ID:      mailbox.emailID(msg.Uid),
+		Folder:  mailbox.folder,
+		Account: mailbox.account,
+		Read:    hasFlag(msg.Flags, imap.SeenFlag),
+		Flagged: hasFlag(msg.Flags, imap.FlaggedFlag),
 	}
-	for _, name := range commonHeaders {
-		if value := mr.Header.Get(name); value != "" {
-			headers[name] = value
+	if msg.Envelope != nil {
+		email.Subject = msg.Envelope.Subject
+		email.MessageID = msg.Envelope.MessageId
+		email.CreatedAt = msg.Envelope.Date.Format(time.RFC3339)
+		if len(msg.Envelope.From) > 0 {
+			email.Sender = msg.Envelope.From[0].Address()
 		}
 	}
 
-	// Get message parts
-	var textBody, htmlBody string
-	var attachments []models.EmailAttachment
+	// Create mail reader; een onbekende charset in de headers is geen reden om op te geven
+	entity, err := message.Read(bytes.NewReader(raw))
+	if err != nil && !message.IsUnknownCharset(err) {
+		// Try content recovery if mail reader fails
+		email.Body = decodeFallback(raw)
+		return email, nil
+	}
 
-	// Process each part
-	mpr := mr.MultipartReader()
-	if mpr != nil {
-		for {
-			part, err := mpr.NextPart()
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				continue
-			}
+	fillHeaderFields(email, mail.Header{Header: entity.Header})
+
+	var textParts, htmlParts []string
+	var inline []models.EmailAttachment
 
-			contentType := part.Header.Get("Content-Type")
-			contentDisposition := part.Header.Get("Content-Disposition")
-			contentID := part.Header.Get("Content-ID")
+	walkErr := entity.Walk(func(path []int, part *message.Entity, err error) error {
+		if err != nil && !message.IsUnknownCharset(err) && !message.IsUnknownEncoding(err) {
+			log.Printf("[EMAIL] Skipping unreadable part %s: %v", sectionName(imapPath(path)), err)
+			return nil
+		}
+
+		mimeType, _, _ := part.Header.ContentType()
+		if mimeType == "" {
+			mimeType = "text/plain"
+		}
+		disposition, _, _ := part.Header.ContentDisposition()
+		filename, _ := (&mail.AttachmentHeader{Header: part.Header}).Filename()
 
-			// Read part content
-			var partBuf bytes.Buffer
-			if _, err := io.Copy(&partBuf, part.Body); err != nil {
-				continue
+		kind := classifyPart(mimeType, disposition, filename)
+		if kind == partContainer {
+			return nil
+		}
+
+		content, readErr := io.ReadAll(part.Body)
+		if readErr != nil && !message.IsUnknownCharset(readErr) {
+			log.Printf("[EMAIL] Failed to read part %s: %v", sectionName(imapPath(path)), readErr)
+			return nil
+		}
+
+		switch kind {
+		case partTextBody:
+			text := decodeText(content, err != nil || readErr != nil)
+			if strings.EqualFold(mimeType, "text/html") {
+				htmlParts = append(htmlParts, text)
+			} else {
+				textParts = append(textParts, text)
+			}
+		case partAttachment:
+			if filename == "" {
+				filename = defaultFilename(mimeType)
+			}
+			contentID := strings.Trim(part.Header.Get("Content-ID"), "<> ")
+			attachment := models.EmailAttachment{
+				Filename:    filename,
+				ContentType: strings.ToLower(mimeType),
+				Size:        int64(len(content)),
+				Content:     content,
+				ContentID:   contentID,
+				Section:     sectionName(imapPath(path)),
+				Inline:      strings.EqualFold(disposition, "inline") || (disposition == "" && contentID != ""),
 			}
-			content := partBuf.String()
-
-			// Handle different content types
-			switch {
-			case strings.HasPrefix(contentType, "text/plain"):
-				textBody = content
-			case strings.HasPrefix(contentType, "text/html"):
-				htmlBody = content
-			case contentDisposition != "" && strings.Contains(contentDisposition, "attachment"):
-				filename := s.extractFilename(contentDisposition, contentType)
-				attachments = append(attachments, models.EmailAttachment{
-					Filename:    filename,
-					ContentType: contentType,
-					Size:        int64(len(content)),
-					Content:     partBuf.Bytes(),
-					ContentID:   strings.Trim(contentID, "<>"),
-				})
+			email.Attachments = append(email.Attachments, attachment)
+			if attachment.Inline && contentID != "" {
+				inline = append(inline, attachment)
 			}
 		}
-	} else {
-		// Single part message
-		var buf bytes.Buffer
-		io.Copy(&buf, mr.Body)
-		textBody = buf.String()
+		return nil
+	})
+	if walkErr != nil {
+		log.Printf("[EMAIL] Failed to walk message parts: %v", walkErr)
 	}
 
-	// Create email object
-	email := &models.Email{
-		ID:          fmt.Sprintf("%s:%d", accountName, msg.Uid),
-		Sender:      msg.Envelope.From[0].Address(),
-		Subject:     msg.Envelope.Subject,
-		Body:        textBody,
-		HTML:        htmlBody,
-		Account:     accountName,
-		MessageID:   msg.Envelope.MessageId,
-		CreatedAt:   msg.Envelope.Date.Format(time.RFC3339),
-		Read:        hasFlag(msg.Flags, imap.SeenFlag),
-		Headers:     headers,
-		Attachments: attachments,
-	}
+	email.Body = strings.Join(textParts, "\n")
+	email.HTML = rewriteCIDReferences(strings.Join(htmlParts, "\n"), inline)
 
 	// Only log total attachments if there are any
 	if len(email.Attachments) > 0 {
@@ -141,47 +171,141 @@ func (s *EmailService) processMessage(msg *imap.Message, accountName string) (*m
 	return email, nil
 }
 
-func hasFlag(flags []string, flag string) bool {
-	for _, f := range flags {
-		if f == flag {
-			return true
+// fillHeaderFields vult de adres-, threading- en header velden van een email
+func fillHeaderFields(email *models.Email, header mail.Header) {
+	email.Headers = make(map[string]string)
+	for _, name := range commonHeaders {
+		if value := header.Get(name); value != "" {
+			email.Headers[name] = value
 		}
 	}
-	return false
-}
 
-func (s *EmailService) extractFilename(disposition, contentType string) string {
-	// Try Content-Disposition first
-	if disposition != "" {
-		if start := strings.Index(disposition, "filename="); start != -1 {
-			filename := disposition[start+9:]
-			if strings.HasPrefix(filename, `"`) {
-				if end := strings.Index(filename[1:], `"`); end != -1 {
-					return filename[1 : end+1]
-				}
-			}
-			if end := strings.Index(filename, `;`); end != -1 {
-				return filename[:end]
-			}
-			return filename
+	if email.Sender == "" {
+		if from := addressList(header, "From"); len(from) > 0 {
+			email.Sender = from[0]
+		}
+	}
+	// De Subject header uit go-message is RFC 2047 gedecodeerd in elke charset
+	if subject, err := header.Subject(); err == nil && subject != "" {
+		email.Subject = subject
+	}
+	if email.MessageID == "" {
+		if id, err := header.MessageID(); err == nil && id != "" {
+			email.MessageID = "<" + id + ">"
+		}
+	}
+	if email.CreatedAt == "" {
+		if date, err := header.Date(); err == nil {
+			email.CreatedAt = date.Format(time.RFC3339)
 		}
 	}
 
-	// Try Content-Type as fallback
-	if contentType != "" {
-		if start := strings.Index(contentType, "name="); start != -1 {
-			filename := contentType[start+5:]
-			if strings.HasPrefix(filename, `"`) {
-				if end := strings.Index(filename[1:], `"`); end != -1 {
-					return filename[1 : end+1]
-				}
-			}
-			if end := strings.Index(filename, `;`); end != -1 {
-				return filename[:end]
-			}
-			return filename
+	email.To = addressList(header, "To")
+	email.Cc = addressList(header, "Cc")
+	email.Bcc = addressList(header, "Bcc")
+	email.ReplyTo = addressList(header, "Reply-To")
+
+	if ids := msgIDList(header, "In-Reply-To"); len(ids) > 0 {
+		email.InReplyTo = ids[0]
+	}
+	email.References = msgIDList(header, "References")
+}
+
+// addressList geeft de adressen uit een adres header terug
+func addressList(header mail.Header, key string) []string {
+	addresses, err := header.AddressList(key)
+	if err != nil {
+		return nil
+	}
+	result := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		result = append(result, address.Address)
+	}
+	return result
+}
+
+// msgIDList geeft de Message-IDs uit een header terug, met punthaken
+func msgIDList(header mail.Header, key string) []string {
+	ids, err := header.MsgIDList(key)
+	if err != nil {
+		return nil
+	}
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, "<"+id+">")
+	}
+	return result
+}
+
+// imapPath zet een go-message part pad (0-based, nil voor een enkelvoudig
+// bericht) om naar een IMAP part pad (1-based, {1} voor een enkelvoudig bericht)
+func imapPath(path []int) []int {
+	if len(path) == 0 {
+		return []int{1}
+	}
+	result := make([]int, len(path))
+	for i, n := range path {
+		result[i] = n + 1
+	}
+	return result
+}
+
+// decodeText geeft tekst terug als geldige UTF-8. go-message decodeert bekende
+// charsets; bij een onbekende charset wordt ISO-8859-1 aangenomen.
+func decodeText(content []byte, charsetFailed bool) string {
+	if charsetFailed || !utf8.Valid(content) {
+		return decodeFallback(content)
+	}
+	return string(content)
+}
+
+// decodeFallback decodeert inhoud die geen geldige UTF-8 is als ISO-8859-1
+func decodeFallback(content []byte) string {
+	if !utf8.Valid(content) {
+		if decoded, err := charmap.ISO8859_1.NewDecoder().Bytes(content); err == nil {
+			return string(decoded)
 		}
 	}
+	return string(content)
+}
 
+// defaultFilename geeft een bestandsnaam voor bijlagen zonder naam
+func defaultFilename(mimeType string) string {
+	if strings.EqualFold(mimeType, "message/rfc822") {
+		return "bericht.eml"
+	}
 	return "unnamed-attachment"
 }
+
+// cidRegex vindt cid: verwijzingen in src en background attributen
+var cidRegex = regexp.MustCompile(`(?i)cid:([^"'\s)>]+)`)
+
+// rewriteCIDReferences vervangt cid: verwijzingen naar inline afbeeldingen door
+// data: URIs, zodat de HTML zonder extra requests met afbeeldingen rendert
+func rewriteCIDReferences(html string, inline []models.EmailAttachment) string {
+	if html == "" || len(inline) == 0 {
+		return html
+	}
+
+	byID := make(map[string]models.EmailAttachment, len(inline))
+	for _, attachment := range inline {
+		byID[strings.ToLower(attachment.ContentID)] = attachment
+	}
+
+	return cidRegex.ReplaceAllStringFunc(html, func(ref string) string {
+		attachment, ok := byID[strings.ToLower(ref[len("cid:"):])]
+		if !ok {
+			return ref
+		}
+		return "data:" + attachment.ContentType + ";base64," + base64.StdEncoding.EncodeToString(attachment.Content)
+	})
+}
+
+func hasFlag(flags []string, flag string) bool {
+	for _, f := range flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package email
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+// nestedMessage is een multipart/mixed bericht met een multipart/related deel
+// (alternative tekst/HTML plus inline afbeelding) en een bijlage met een RFC 2231
+// gecodeerde bestandsnaam
+const nestedMessage = "From: =?ISO-8859-1?Q?Jos=E9_Peters?= <jose@example.org>\r\n" +
+	"To: info@dekoninklijkeloop.nl, Anna <anna@example.org>\r\n" +
+	"Cc: cc@example.org\r\n" +
+	"Reply-To: antwoord@example.org\r\n" +
+	"Subject: =?UTF-8?Q?Inschrijving_=E2=82=AC?=\r\n" +
+	"Message-ID: <nieuw@example.org>\r\n" +
+	"In-Reply-To: <vorige@example.org>\r\n" +
+	"References: <eerste@example.org> <vorige@example.org>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=outer\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/related; boundary=related\r\n" +
+	"\r\n" +
+	"--related\r\n" +
+	"Content-Type: multipart/alternative; boundary=alt\r\n" +
+	"\r\n" +
+	"--alt\r\n" +
+	"Content-Type: text/plain; charset=ISO-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Caf=E9 om 10 uur\r\n" +
+	"--alt\r\n" +
+	"Content-Type: text/html; charset=UTF-8\r\n" +
+	"\r\n" +
+	"<p>Caf\xc3\xa9 <img src=\"cid:logo@dkl\"></p>\r\n" +
+	"--alt--\r\n" +
+	"--related\r\n" +
+	"Content-Type: image/png\r\n" +
+	"Content-ID: <logo@dkl>\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"iVBORw==\r\n" +
+	"--related--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/pdf\r\n" +
+	"Content-Disposition: attachment; filename*=UTF-8''route%20%E2%82%AC.pdf\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERg==\r\n" +
+	"--outer--\r\n"
+
+func newRawMessage(raw string) *imap.Message {
+	msg := imap.NewMessage(1, []imap.FetchItem{imap.FetchUid, imap.FetchFlags})
+	msg.Uid = 42
+	msg.Flags = []string{imap.FlaggedFlag}
+	msg.Body = map[*imap.BodySectionName]imap.Literal{
+		{}: bytes.NewBufferString(raw),
+	}
+	return msg
+}
+
+func TestProcessMessage_NestedMultipart(t *testing.T) {
+	// Setup
+	service := &EmailService{}
+	mailbox := mailboxRef{account: "info", folder: inboxFolder, uidValidity: 7}
+
+	// Test
+	email, err := service.processMessage(newRawMessage(nestedMessage), mailbox)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "info:INBOX:7:42", email.ID)
+	assert.Equal(t, "Inschrijving €", email.Subject)
+	assert.Equal(t, "jose@example.org", email.Sender)
+	assert.Equal(t, "<nieuw@example.org>", email.MessageID)
+	assert.Equal(t, []string{"info@dekoninklijkeloop.nl", "anna@example.org"}, email.To)
+	assert.Equal(t, []string{"cc@example.org"}, email.Cc)
+	assert.Equal(t, []string{"antwoord@example.org"}, email.ReplyTo)
+	assert.Equal(t, "<vorige@example.org>", email.InReplyTo)
+	assert.Equal(t, []string{"<eerste@example.org>", "<vorige@example.org>"}, email.References)
+	assert.False(t, email.Read)
+	assert.True(t, email.Flagged)
+
+	// Charset van de tekst is gedecodeerd
+	assert.Equal(t, "Café om 10 uur", strings.TrimSpace(email.Body))
+
+	// Inline afbeelding is als data URI in de HTML gezet
+	assert.Contains(t, email.HTML, `src="data:image/png;base64,iVBORw=="`)
+	assert.NotContains(t, email.HTML, "cid:")
+
+	if assert.Len(t, email.Attachments, 2) {
+		assert.True(t, email.Attachments[0].Inline)
+		assert.Equal(t, "logo@dkl", email.Attachments[0].ContentID)
+		assert.Equal(t, "1.2", email.Attachments[0].Section)
+
+		assert.False(t, email.Attachments[1].Inline)
+		assert.Equal(t, "route €.pdf", email.Attachments[1].Filename)
+		assert.Equal(t, "application/pdf", email.Attachments[1].ContentType)
+		assert.Equal(t, "2", email.Attachments[1].Section)
+		assert.Equal(t, "%PDF", string(email.Attachments[1].Content))
+	}
+}
+
+func TestProcessMessage_SinglePart(t *testing.T) {
+	// Setup
+	raw := "From: a@example.org\r\nSubject: Hoi\r\nContent-Type: text/plain; charset=windows-1252\r\n\r\nPrijs \x80 5\r\n"
+
+	// Test
+	email, err := (&EmailService{}).processMessage(newRawMessage(raw), mailboxRef{account: "info", folder: inboxFolder})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Prijs € 5", strings.TrimSpace(email.Body))
+	assert.Empty(t, email.Attachments)
+}
@@ -0,0 +1,372 @@
+package email
+
+import (
+	"dklautomationgo/models"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// autoReplyInterval is de minimale tijd tussen twee automatische antwoorden van
+// dezelfde regel aan dezelfde afzender
+const autoReplyInterval = 24 * time.Hour
+
+// ruleBacklogWindow is hoe ver voor het opstarten een email ontvangen mag zijn om
+// nog door de regels verwerkt te worden, bijvoorbeeld mail die tijdens een
+// herstart binnenkwam
+const ruleBacklogWindow = 24 * time.Hour
+
+// defaultAutoReplyTemplate is het template voor automatische antwoorden
+const defaultAutoReplyTemplate = "auto_reply_email.html"
+
+// automatedSenders zijn lokale delen van adressen waar nooit automatisch op geantwoord wordt
+var automatedSenders = []string{"noreply", "no-reply", "donotreply", "do-not-reply", "mailer-daemon", "postmaster", "bounce", "bounces"}
+
+// InboxRuleStore levert de actieve inbox regels, in de volgorde van toepassen
+type InboxRuleStore interface {
+	FindEnabled() ([]*models.InboxRule, error)
+}
+
+// AutoReplyData is de data waarmee het template van een automatisch antwoord gerenderd wordt
+type AutoReplyData struct {
+	Sender  string // Adres van de afzender van de oorspronkelijke email
+	Subject string // Onderwerp van de oorspronkelijke email
+	Account string // Account waarop de email ontvangen is
+}
+
+// Controleer of RuleEngine de IncomingEmailHandler interface implementeert
+var _ IncomingEmailHandler = (*RuleEngine)(nil)
+
+// RuleEngine past de inbox regels toe op nieuwe emails
+type RuleEngine struct {
+	service  *EmailService
+	rules    InboxRuleStore
+	metadata EmailMetadataStore
+
+	started      time.Time
+	replied      map[string]time.Time
+	repliedMutex sync.Mutex
+}
+
+// NewRuleEngine maakt een nieuwe RuleEngine
+func NewRuleEngine(service *EmailService, rules InboxRuleStore, metadata EmailMetadataStore) *RuleEngine {
+	return &RuleEngine{
+		service:  service,
+		rules:    rules,
+		metadata: metadata,
+		started:  time.Now(),
+		replied:  make(map[string]time.Time),
+	}
+}
+
+// HandleIncomingEmail past de regels toe op een nieuwe email
+func (e *RuleEngine) HandleIncomingEmail(email *models.Email) {
+	applied, err := e.Apply(email)
+	if err != nil {
+		log.Printf("[RuleEngine] Failed to apply rules to %s: %v", email.ID, err)
+		return
+	}
+	if len(applied) > 0 {
+		log.Printf("[RuleEngine] Applied rules to %s: %s", email.ID, strings.Join(applied, ", "))
+	}
+}
+
+// Apply past de actieve regels in volgorde toe en geeft de namen van de
+// toegepaste regels terug. Een mislukte actie wordt gelogd, de overige acties
+// en regels gaan door. Na een verplaatsing stopt het toepassen, omdat de email
+// dan niet meer in de INBOX staat.
+//
+// Regels worden één keer per email toegepast: voor het toepassen wordt de email
+// in de metadata geclaimd, zodat een email die tegelijk door de watcher en het
+// ophalen van de INBOX aangeboden wordt geen twee automatische antwoorden krijgt.
+// Emails van ruim voor het opstarten worden overgeslagen, zodat
+// het vullen van de cache geen automatische antwoorden op oude mail verstuurt.
+func (e *RuleEngine) Apply(email *models.Email) ([]string, error) {
+	if received := parseEmailTime(email.CreatedAt); !received.IsZero() && received.Before(e.started.Add(-ruleBacklogWindow)) {
+		return nil, nil
+	}
+
+	key := MetadataKey(email)
+	metadata, err := e.metadata.FindByMessage(email.Account, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load email metadata: %w", err)
+	}
+	if metadata != nil && metadata.RulesAppliedAt != nil {
+		return nil, nil
+	}
+
+	claimed, err := e.metadata.ClaimRules(email.Account, key, email.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim email for inbox rules: %w", err)
+	}
+	if !claimed {
+		return nil, nil
+	}
+	// Na de claim bestaat de metadata altijd
+	metadata, err = e.metadata.FindByMessage(email.Account, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load email metadata: %w", err)
+	}
+	if metadata == nil {
+		return nil, fmt.Errorf("email metadata for %s disappeared after claiming it", key)
+	}
+
+	rules, err := e.rules.FindEnabled()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load inbox rules: %w", err)
+	}
+
+	var applied []string
+	for _, rule := range rules {
+		if !RuleMatches(rule, email) {
+			continue
+		}
+		applied = append(applied, rule.Name)
+		applyMetadataActions(rule, metadata)
+		e.applyMailboxActions(rule, email)
+
+		if rule.StopAfter || rule.MoveTo != "" {
+			break
+		}
+	}
+
+	// Alleen de kolommen die regels wijzigen, zodat een gelijktijdige wijziging
+	// van bijvoorbeeld de status in het dashboard niet overschreven wordt
+	metadata.EmailID = email.ID
+	if err := e.metadata.SaveColumns(metadata, "labels", "assigned_to"); err != nil {
+		return applied, fmt.Errorf("failed to save email metadata: %w", err)
+	}
+	return applied, nil
+}
+
+// applyMetadataActions voegt het label van een regel toe en wijst de email toe
+func applyMetadataActions(rule *models.InboxRule, metadata *models.EmailMetadata) {
+	if rule.Label != "" && !metadata.HasLabel(rule.Label) {
+		metadata.Labels = append(metadata.Labels, rule.Label)
+	}
+	// Een eerdere toewijzing wordt niet door een regel overschreven
+	if rule.AssignTo != nil && metadata.AssignedTo == nil {
+		assignee := *rule.AssignTo
+		metadata.AssignedTo = &assignee
+	}
+}
+
+// applyMailboxActions voert de acties van een regel op de mailbox uit; de
+// verplaatsing als laatste, omdat het ID van de email daarna niet meer geldig is
+func (e *RuleEngine) applyMailboxActions(rule *models.InboxRule, email *models.Email) {
+	if rule.AutoReplyTemplate != "" {
+		if err := e.autoReply(rule, email); err != nil {
+			log.Printf("[RuleEngine] %s: failed to send auto-reply to %s: %v", rule.Name, email.Sender, err)
+		}
+	}
+
+	if rule.MarkRead && !email.Read {
+		if err := e.service.MarkEmailAsRead(email.ID); err != nil {
+			log.Printf("[RuleEngine] %s: failed to mark %s as read: %v", rule.Name, email.ID, err)
+		}
+	}
+
+	if rule.MoveTo != "" {
+		if err := e.service.MoveEmail(email.ID, rule.MoveTo); err != nil {
+			log.Printf("[RuleEngine] %s: failed to move %s to %s: %v", rule.Name, email.ID, rule.MoveTo, err)
+		}
+	}
+}
+
+// autoReply stuurt een automatisch antwoord, tenzij de email zelf automatisch
+// verstuurd is of de afzender recent al een antwoord van deze regel kreeg
+func (e *RuleEngine) autoReply(rule *models.InboxRule, email *models.Email) error {
+	if ok, reason := ShouldAutoReply(email, e.service.accountAddresses()); !ok {
+		log.Printf("[RuleEngine] %s: no auto-reply to %s: %s", rule.Name, email.Sender, reason)
+		return nil
+	}
+
+	key := rule.ID + ":" + strings.ToLower(email.Sender)
+	e.repliedMutex.Lock()
+	if last, ok := e.replied[key]; ok && time.Since(last) < autoReplyInterval {
+		e.repliedMutex.Unlock()
+		log.Printf("[RuleEngine] %s: no auto-reply to %s: already replied at %s", rule.Name, email.Sender, last.Format(time.RFC3339))
+		return nil
+	}
+	e.replied[key] = time.Now()
+	e.repliedMutex.Unlock()
+
+	_, err := e.service.SendAutoReply(email, rule.AutoReplyTemplate, rule.AutoReplySubject)
+	if err != nil {
+		// Een volgende email mag het opnieuw proberen
+		e.repliedMutex.Lock()
+		delete(e.replied, key)
+		e.repliedMutex.Unlock()
+	}
+	return err
+}
+
+// RuleMatches geeft aan of een email aan alle condities van een regel voldoet
+func RuleMatches(rule *models.InboxRule, email *models.Email) bool {
+	if rule.Account != "" && !strings.EqualFold(rule.Account, email.Account) {
+		return false
+	}
+	if rule.SenderContains != "" && !containsFold(email.Sender, rule.SenderContains) {
+		return false
+	}
+	if rule.SubjectContains != "" && !containsFold(email.Subject, rule.SubjectContains) {
+		return false
+	}
+
+	if len(rule.BodyKeywords) > 0 {
+		text := email.Body
+		if strings.TrimSpace(text) == "" {
+			text = HTMLToText(email.HTML)
+		}
+		found := false
+		for _, keyword := range rule.BodyKeywords {
+			if keyword = strings.TrimSpace(keyword); keyword != "" && containsFold(text, keyword) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if rule.AttachmentType != "" && !hasAttachmentType(email, rule.AttachmentType) {
+		return false
+	}
+	return true
+}
+
+// hasAttachmentType geeft aan of de email een bijlage met het gegeven MIME type
+// (of prefix daarvan, zoals "image/") of de gegeven extensie (".pdf") heeft
+func hasAttachmentType(email *models.Email, attachmentType string) bool {
+	attachmentType = strings.ToLower(strings.TrimSpace(attachmentType))
+	for _, attachment := range email.Attachments {
+		if attachment.Inline {
+			continue
+		}
+		if strings.HasPrefix(attachmentType, ".") {
+			if strings.EqualFold(path.Ext(attachment.Filename), attachmentType) {
+				return true
+			}
+		} else if strings.HasPrefix(strings.ToLower(attachment.ContentType), attachmentType) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// ShouldAutoReply bepaalt of er automatisch op een email geantwoord mag worden
+// (RFC 3834). Antwoorden op automatische berichten, mailinglijsten, bounces en
+// de eigen accounts zouden een lus of ongewenste mail veroorzaken. Geeft bij
+// false de reden terug.
+func ShouldAutoReply(email *models.Email, ownAddresses []string) (bool, string) {
+	headers := email.Headers
+	if headers == nil {
+		headers = map[string]string{}
+	}
+
+	if value := strings.ToLower(strings.TrimSpace(headers["Auto-Submitted"])); value != "" && value != "no" {
+		return false, "Auto-Submitted: " + value
+	}
+	switch strings.ToLower(strings.TrimSpace(headers["Precedence"])) {
+	case "bulk", "junk", "list", "auto_reply":
+		return false, "Precedence: " + headers["Precedence"]
+	}
+	if headers["List-Id"] != "" || headers["List-Unsubscribe"] != "" {
+		return false, "mailing list"
+	}
+	if suppress := strings.ToLower(headers["X-Auto-Response-Suppress"]); strings.Contains(suppress, "all") || strings.Contains(suppress, "autoreply") {
+		return false, "X-Auto-Response-Suppress: " + headers["X-Auto-Response-Suppress"]
+	}
+	if strings.TrimSpace(headers["Return-Path"]) == "<>" {
+		return false, "empty Return-Path"
+	}
+
+	sender := strings.ToLower(strings.TrimSpace(email.Sender))
+	at := strings.LastIndex(sender, "@")
+	if at <= 0 {
+		return false, "no sender address"
+	}
+	local := sender[:at]
+	for _, automated := range automatedSenders {
+		if local == automated || strings.HasPrefix(local, automated+"+") || strings.HasPrefix(local, automated+"-") {
+			return false, "automated sender"
+		}
+	}
+	for _, own := range ownAddresses {
+		if strings.EqualFold(sender, own) {
+			return false, "own account"
+		}
+	}
+	return true, ""
+}
+
+// SendAutoReply stuurt een automatisch antwoord op een email, vanuit het account
+// waarop deze ontvangen is. Het antwoord krijgt Auto-Submitted: auto-replied,
+// zodat andere systemen er op hun beurt niet automatisch op antwoorden.
+func (s *EmailService) SendAutoReply(original *models.Email, templateName, subject string) (*models.Email, error) {
+	config, ok := s.account(original.Account)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAccount, original.Account)
+	}
+	if original.Sender == "" {
+		return nil, ErrNoRecipients
+	}
+
+	if templateName == "" {
+		templateName = defaultAutoReplyTemplate
+	}
+	html, err := s.renderTemplate(templateName, &AutoReplyData{
+		Sender:  original.Sender,
+		Subject: original.Subject,
+		Account: original.Account,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if subject == "" {
+		subject = prefixSubject("Re:", original.Subject)
+	}
+
+	return s.sendOutgoing(config, &outgoingMessage{
+		account:    original.Account,
+		to:         []string{original.Sender},
+		subject:    subject,
+		text:       HTMLToText(html),
+		html:       html,
+		inReplyTo:  original.MessageID,
+		references: threadReferences(original),
+		headers: map[string]string{
+			"Auto-Submitted":           "auto-replied",
+			"X-Auto-Response-Suppress": "All",
+		},
+	})
+}
+
+// accountAddresses geeft de adressen van alle geconfigureerde accounts
+func (s *EmailService) accountAddresses() []string {
+	accounts := s.accountConfigs()
+	addresses := make([]string, 0, len(accounts))
+	for _, config := range accounts {
+		if config.Email != "" {
+			addresses = append(addresses, config.Email)
+		}
+	}
+	return addresses
+}
+
+// MetadataKey geeft de sleutel waaronder de metadata van een email bewaard
+// wordt: de Message-ID, of het email ID als de email geen Message-ID heeft
+func MetadataKey(email *models.Email) string {
+	if id := normalizeMessageID(email.MessageID); id != "" {
+		return id
+	}
+	return "email:" + email.ID
+}
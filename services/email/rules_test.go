@@ -0,0 +1,292 @@
+package email
+
+import (
+	"html/template"
+	"sync"
+	"testing"
+	"time"
+
+	"dklautomationgo/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRuleStores houdt regels en metadata in het geheugen bij voor de RuleEngine en metadata tests
+type memoryRuleStores struct {
+	rules    []*models.InboxRule
+	metadata map[string]*models.EmailMetadata
+	saves    int
+	columns  []string // Kolommen van de laatste SaveColumns
+	mu       sync.Mutex
+}
+
+func (m *memoryRuleStores) FindEnabled() ([]*models.InboxRule, error) {
+	return m.rules, nil
+}
+
+func (m *memoryRuleStores) FindByMessage(account, messageKey string) (*models.EmailMetadata, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Een kopie, zoals een database die ook geeft
+	metadata := m.metadata[account+"|"+messageKey]
+	if metadata == nil {
+		return nil, nil
+	}
+	found := *metadata
+	return &found, nil
+}
+
+func (m *memoryRuleStores) FindByEmailID(emailID string) (*models.EmailMetadata, error) {
+	for _, metadata := range m.metadata {
+		if metadata.EmailID == emailID {
+			return metadata, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryRuleStores) FindByMessageKeys(messageKeys []string) ([]*models.EmailMetadata, error) {
+	var found []*models.EmailMetadata
+	for _, key := range messageKeys {
+		for _, metadata := range m.metadata {
+			if metadata.MessageKey == key {
+				found = append(found, metadata)
+			}
+		}
+	}
+	return found, nil
+}
+
+func (m *memoryRuleStores) SaveColumns(metadata *models.EmailMetadata, columns ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saves++
+	m.columns = columns
+	existing := m.metadata[metadata.Account+"|"+metadata.MessageKey]
+	if existing == nil {
+		saved := *metadata
+		m.metadata[metadata.Account+"|"+metadata.MessageKey] = &saved
+		return nil
+	}
+	existing.EmailID = metadata.EmailID
+	for _, column := range columns {
+		switch column {
+		case "status":
+			existing.Status = metadata.Status
+		case "assigned_to":
+			existing.AssignedTo = metadata.AssignedTo
+		case "labels":
+			existing.Labels = metadata.Labels
+		}
+	}
+	return nil
+}
+
+func (m *memoryRuleStores) ClaimRules(account, messageKey, emailID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	metadata := m.metadata[account+"|"+messageKey]
+	if metadata == nil {
+		metadata = models.NewEmailMetadata(account, messageKey, emailID)
+		m.metadata[account+"|"+messageKey] = metadata
+	}
+	if metadata.RulesAppliedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	metadata.RulesAppliedAt = &now
+	return true, nil
+}
+
+func newTestRuleEngine(rules ...*models.InboxRule) (*RuleEngine, *memoryRuleStores) {
+	service := newTestSendService()
+	service.templates[defaultAutoReplyTemplate] = template.Must(template.New(defaultAutoReplyTemplate).Parse("<p>Ontvangen: {{.Subject}}</p>"))
+	stores := &memoryRuleStores{rules: rules, metadata: make(map[string]*models.EmailMetadata)}
+	return NewRuleEngine(service, stores, stores), stores
+}
+
+func TestRuleMatches(t *testing.T) {
+	email := &models.Email{
+		Account: "info",
+		Sender:  "Sponsor@Bedrijf.nl",
+		Subject: "Factuur maart",
+		Body:    "In de bijlage vindt u de factuur.",
+		Attachments: []models.EmailAttachment{
+			{Filename: "logo.png", ContentType: "image/png", Inline: true},
+			{Filename: "Factuur-2024.PDF", ContentType: "application/pdf"},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		rule  models.InboxRule
+		match bool
+	}{
+		{"geen condities", models.InboxRule{}, true},
+		{"account", models.InboxRule{Account: "INFO"}, true},
+		{"ander account", models.InboxRule{Account: "inschrijving"}, false},
+		{"afzender", models.InboxRule{SenderContains: "@bedrijf.nl"}, true},
+		{"onderwerp", models.InboxRule{SubjectContains: "offerte"}, false},
+		{"een van de woorden", models.InboxRule{BodyKeywords: []string{"betaling", "FACTUUR"}}, true},
+		{"geen van de woorden", models.InboxRule{BodyKeywords: []string{"betaling"}}, false},
+		{"mime type", models.InboxRule{AttachmentType: "application/pdf"}, true},
+		{"extensie", models.InboxRule{AttachmentType: ".pdf"}, true},
+		{"inline afbeelding telt niet", models.InboxRule{AttachmentType: "image/"}, false},
+		{"alle condities", models.InboxRule{Account: "info", SubjectContains: "factuur", AttachmentType: ".docx"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.match, RuleMatches(&tt.rule, email))
+		})
+	}
+}
+
+func TestShouldAutoReply(t *testing.T) {
+	own := []string{"info@dekoninklijkeloop.nl"}
+
+	tests := []struct {
+		name    string
+		sender  string
+		headers map[string]string
+		reply   bool
+	}{
+		{"gewone email", "jan@example.org", nil, true},
+		{"auto-submitted no", "jan@example.org", map[string]string{"Auto-Submitted": "no"}, true},
+		{"automatisch antwoord", "jan@example.org", map[string]string{"Auto-Submitted": "auto-replied"}, false},
+		{"bulk", "nieuws@example.org", map[string]string{"Precedence": "bulk"}, false},
+		{"mailinglijst", "lijst@example.org", map[string]string{"List-Id": "<lijst.example.org>"}, false},
+		{"exchange onderdrukking", "jan@example.org", map[string]string{"X-Auto-Response-Suppress": "OOF, AutoReply"}, false},
+		{"bounce", "jan@example.org", map[string]string{"Return-Path": "<>"}, false},
+		{"noreply", "no-reply@example.org", nil, false},
+		{"mailer daemon", "MAILER-DAEMON@example.org", nil, false},
+		{"eigen account", "info@dekoninklijkeloop.nl", nil, false},
+		{"geen afzender", "", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, reason := ShouldAutoReply(&models.Email{Sender: tt.sender, Headers: tt.headers}, own)
+			assert.Equal(t, tt.reply, ok, reason)
+		})
+	}
+}
+
+func TestRuleEngine_AppliesRulesOnce(t *testing.T) {
+	// Setup
+	assignee := "11111111-2222-3333-4444-555555555555"
+	engine, stores := newTestRuleEngine(
+		&models.InboxRule{ID: "1", Name: "sponsors", SenderContains: "@example.org", Label: "sponsor", AssignTo: &assignee},
+		&models.InboxRule{ID: "2", Name: "bevestiging", Label: "beantwoord", AutoReplyTemplate: defaultAutoReplyTemplate, StopAfter: true},
+		&models.InboxRule{ID: "3", Name: "nooit", Label: "overgeslagen"},
+	)
+	email := &models.Email{
+		ID:        "info:INBOX:1:9",
+		Account:   "info",
+		Sender:    "jan@example.org",
+		Subject:   "Sponsoring",
+		MessageID: "<abc@example.org>",
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}
+
+	// Test
+	applied, err := engine.Apply(email)
+	require.NoError(t, err)
+	again, err := engine.Apply(email)
+	require.NoError(t, err)
+
+	// Controleer het resultaat
+	assert.Equal(t, []string{"sponsors", "bevestiging"}, applied)
+	assert.Empty(t, again, "regels worden één keer per email toegepast")
+	assert.Equal(t, 1, stores.saves)
+	assert.Equal(t, []string{"labels", "assigned_to"}, stores.columns, "regels overschrijven de status niet")
+
+	metadata := stores.metadata["info|abc@example.org"]
+	require.NotNil(t, metadata)
+	assert.Equal(t, []string{"sponsor", "beantwoord"}, metadata.Labels)
+	assert.Equal(t, assignee, *metadata.AssignedTo)
+	assert.NotNil(t, metadata.RulesAppliedAt)
+	assert.Contains(t, engine.replied, "2:jan@example.org")
+}
+
+func TestRuleEngine_ConcurrentApplySendsOneAutoReply(t *testing.T) {
+	// Setup: de watcher en het ophalen van de INBOX bieden dezelfde email tegelijk aan
+	engine, stores := newTestRuleEngine(
+		&models.InboxRule{ID: "1", Name: "bevestiging", Label: "beantwoord", AutoReplyTemplate: defaultAutoReplyTemplate},
+	)
+	email := &models.Email{
+		ID:        "info:INBOX:1:11",
+		Account:   "info",
+		Sender:    "jan@example.org",
+		Subject:   "Vraag",
+		MessageID: "<def@example.org>",
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}
+
+	// Test
+	var wg sync.WaitGroup
+	results := make([][]string, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			applied, err := engine.Apply(email)
+			assert.NoError(t, err)
+			results[i] = applied
+		}(i)
+	}
+	wg.Wait()
+
+	// Controleer het resultaat: precies één keer toegepast
+	appliedCount := 0
+	for _, applied := range results {
+		if len(applied) > 0 {
+			appliedCount++
+		}
+	}
+	assert.Equal(t, 1, appliedCount)
+	assert.Equal(t, 1, stores.saves)
+}
+
+func TestRuleEngine_SkipsOldEmails(t *testing.T) {
+	// Setup
+	engine, stores := newTestRuleEngine(&models.InboxRule{ID: "1", Name: "alles", Label: "gezien"})
+	email := &models.Email{
+		ID:        "info:INBOX:1:10",
+		Account:   "info",
+		Sender:    "jan@example.org",
+		CreatedAt: time.Now().Add(-30 * 24 * time.Hour).Format(time.RFC3339),
+	}
+
+	// Test
+	applied, err := engine.Apply(email)
+
+	// Controleer het resultaat
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+	assert.Zero(t, stores.saves)
+}
+
+func TestSendAutoReply_SetsLoopProtectionHeaders(t *testing.T) {
+	// Setup
+	engine, _ := newTestRuleEngine()
+	original := &models.Email{
+		Account:    "info",
+		Sender:     "jan@example.org",
+		Subject:    "Vraag",
+		MessageID:  "<vraag@example.org>",
+		References: []string{"<eerder@example.org>"},
+	}
+
+	// Test
+	sent, err := engine.service.SendAutoReply(original, "", "")
+
+	// Controleer het resultaat
+	require.NoError(t, err)
+	assert.Equal(t, "Re: Vraag", sent.Subject)
+	assert.Equal(t, []string{"jan@example.org"}, sent.To)
+	assert.Equal(t, "<vraag@example.org>", sent.InReplyTo)
+	assert.Equal(t, []string{"<eerder@example.org>", "<vraag@example.org>"}, sent.References)
+	assert.Contains(t, sent.Body, "Ontvangen: Vraag")
+}
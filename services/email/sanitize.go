@@ -0,0 +1,310 @@
+package email
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// RemoteContentPolicy bepaalt wat er met externe afbeeldingen in een email gebeurt
+type RemoteContentPolicy int
+
+const (
+	// RemoteContentBlock verwijdert externe afbeeldingen, zodat afzenders niet
+	// kunnen zien wanneer en door wie een email geopend wordt
+	RemoteContentBlock RemoteContentPolicy = iota
+	// RemoteContentProxy laadt externe afbeeldingen via de image proxy van de API
+	RemoteContentProxy
+	// RemoteContentAllow laat externe afbeeldingen ongewijzigd, bijv. voor het citeren in een antwoord
+	RemoteContentAllow
+)
+
+// allowedTags zijn de elementen die in gesaniteerde HTML behouden blijven
+var allowedTags = map[string]bool{
+	"a": true, "abbr": true, "address": true, "b": true, "big": true, "blockquote": true,
+	"br": true, "caption": true, "center": true, "cite": true, "code": true, "col": true,
+	"colgroup": true, "dd": true, "del": true, "div": true, "dl": true, "dt": true,
+	"em": true, "font": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+	"h6": true, "hr": true, "i": true, "img": true, "ins": true, "kbd": true, "li": true,
+	"ol": true, "p": true, "pre": true, "q": true, "s": true, "small": true, "span": true,
+	"strike": true, "strong": true, "sub": true, "sup": true, "table": true, "tbody": true,
+	"td": true, "tfoot": true, "th": true, "thead": true, "tr": true, "tt": true, "u": true,
+	"ul": true,
+}
+
+// droppedWithContent zijn elementen die inclusief hun inhoud verwijderd worden
+var droppedWithContent = map[string]bool{
+	"script": true, "style": true, "head": true, "title": true, "iframe": true,
+	"frame": true, "frameset": true, "object": true, "embed": true, "applet": true,
+	"template": true, "noembed": true, "noframes": true, "textarea": true, "select": true,
+	"svg": true, "math": true, "xmp": true, "plaintext": true,
+}
+
+// allowedAttributes zijn de attributen die behouden blijven, per element of voor alle elementen ("*")
+var allowedAttributes = map[string]map[string]bool{
+	"*": {
+		"align": true, "bgcolor": true, "border": true, "class": true, "color": true,
+		"dir": true, "height": true, "lang": true, "style": true, "title": true,
+		"valign": true, "width": true,
+	},
+	"a":     {"href": true, "name": true},
+	"img":   {"src": true, "alt": true},
+	"font":  {"face": true, "size": true},
+	"table": {"cellpadding": true, "cellspacing": true, "summary": true},
+	"td":    {"colspan": true, "rowspan": true, "nowrap": true},
+	"th":    {"colspan": true, "rowspan": true, "nowrap": true, "scope": true},
+	"col":   {"span": true},
+	"ol":    {"start": true, "type": true},
+	"ul":    {"type": true},
+	"q":     {"cite": true},
+}
+
+// voidTags zijn elementen zonder sluit-tag
+var voidTags = map[string]bool{"br": true, "col": true, "hr": true, "img": true}
+
+// unsafeStyle zijn CSS constructies die scripts kunnen uitvoeren of externe bronnen
+// laden. Backslashes worden geweigerd omdat CSS escapes deze patronen kunnen verbergen.
+var unsafeStyle = []string{"expression", "javascript:", "vbscript:", "behavior", "-moz-binding", "@import", "\\"}
+
+// SanitizeHTML filtert HTML uit een email zodat deze veilig in het dashboard
+// getoond kan worden. Alleen elementen en attributen uit een allow-list blijven
+// behouden; event handlers, scripts en javascript: URLs worden verwijderd.
+// Externe afbeeldingen worden afhankelijk van policy verwijderd, via proxy
+// geladen of behouden. De bool geeft aan of de email externe inhoud bevatte.
+func SanitizeHTML(input string, policy RemoteContentPolicy, proxy func(string) string) (string, bool) {
+	var out strings.Builder
+	hasRemote := false
+
+	tokenizer := html.NewTokenizer(strings.NewReader(input))
+	skipTag := ""
+	skipDepth := 0
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			// io.EOF of een te lange token; in beide gevallen stoppen
+			break
+		}
+		token := tokenizer.Token()
+
+		// Binnen een verwijderd element wordt alles overgeslagen tot de bijbehorende sluit-tag
+		if skipDepth > 0 {
+			switch {
+			case tokenType == html.StartTagToken && token.Data == skipTag:
+				skipDepth++
+			case tokenType == html.EndTagToken && token.Data == skipTag:
+				skipDepth--
+			}
+			continue
+		}
+
+		switch tokenType {
+		case html.TextToken:
+			out.WriteString(html.EscapeString(token.Data))
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedWithContent[token.Data] {
+				if tokenType == html.StartTagToken {
+					skipTag = token.Data
+					skipDepth = 1
+				}
+				continue
+			}
+			if !allowedTags[token.Data] {
+				continue
+			}
+
+			attrs, remote := sanitizeAttributes(token.Data, token.Attr, policy, proxy)
+			hasRemote = hasRemote || remote
+
+			// Een afbeelding zonder bron heeft geen nut meer
+			if token.Data == "img" && !hasAttr(attrs, "src") {
+				continue
+			}
+
+			out.WriteString("<" + token.Data)
+			for _, attr := range attrs {
+				out.WriteString(" " + attr.Key + `="` + html.EscapeString(attr.Val) + `"`)
+			}
+			out.WriteString(">")
+
+		case html.EndTagToken:
+			if allowedTags[token.Data] && !voidTags[token.Data] {
+				out.WriteString("</" + token.Data + ">")
+			}
+		}
+		// Comments en doctypes worden weggelaten
+	}
+
+	return out.String(), hasRemote
+}
+
+// sanitizeAttributes filtert de attributen van een toegestaan element
+func sanitizeAttributes(tag string, attrs []html.Attribute, policy RemoteContentPolicy, proxy func(string) string) ([]html.Attribute, bool) {
+	var result []html.Attribute
+	hasRemote := false
+
+	for _, attr := range attrs {
+		key := strings.ToLower(attr.Key)
+		if attr.Namespace != "" || !(allowedAttributes["*"][key] || allowedAttributes[tag][key]) {
+			continue
+		}
+
+		switch key {
+		case "href", "cite":
+			if !isSafeLink(attr.Val) {
+				continue
+			}
+		case "src":
+			src, remote := sanitizeImageSource(attr.Val, policy, proxy)
+			hasRemote = hasRemote || remote
+			if src == "" {
+				continue
+			}
+			attr.Val = src
+		case "style":
+			style, remote := sanitizeStyle(attr.Val)
+			hasRemote = hasRemote || remote
+			if style == "" {
+				continue
+			}
+			attr.Val = style
+		}
+
+		result = append(result, html.Attribute{Key: key, Val: attr.Val})
+	}
+
+	// Links openen buiten het dashboard, zonder toegang tot window.opener
+	if tag == "a" && hasAttr(result, "href") {
+		result = append(result,
+			html.Attribute{Key: "target", Val: "_blank"},
+			html.Attribute{Key: "rel", Val: "noopener noreferrer"},
+		)
+	}
+
+	return result, hasRemote
+}
+
+// normalizeURL verwijdert whitespace en control karakters, die browsers in een
+// URL negeren en die anders gebruikt kunnen worden om "java\tscript:" te verbergen
+func normalizeURL(raw string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, raw)
+}
+
+// isSafeLink geeft aan of een link een toegestaan schema heeft
+func isSafeLink(raw string) bool {
+	cleaned := normalizeURL(raw)
+	if strings.HasPrefix(cleaned, "#") {
+		return true
+	}
+
+	u, err := url.Parse(cleaned)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto", "tel":
+		return true
+	}
+	return false
+}
+
+// sanitizeImageSource geeft de te gebruiken bron van een afbeelding terug, of
+// een lege string als de afbeelding verwijderd moet worden
+func sanitizeImageSource(raw string, policy RemoteContentPolicy, proxy func(string) string) (string, bool) {
+	cleaned := normalizeURL(raw)
+	lower := strings.ToLower(cleaned)
+
+	// Inline afbeeldingen (cid: verwijzingen die naar data URIs herschreven zijn)
+	if strings.HasPrefix(lower, "data:image/") && !strings.HasPrefix(lower, "data:image/svg") {
+		return cleaned, false
+	}
+
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+		return "", false
+	}
+
+	switch {
+	case policy == RemoteContentAllow:
+		return cleaned, true
+	case policy == RemoteContentProxy && proxy != nil:
+		return proxy(cleaned), true
+	default:
+		return "", true
+	}
+}
+
+// sanitizeStyle verwijdert CSS declaraties die scripts of externe bronnen kunnen laden
+func sanitizeStyle(style string) (string, bool) {
+	var kept []string
+	hasRemote := false
+
+	for _, declaration := range strings.Split(style, ";") {
+		declaration = strings.TrimSpace(declaration)
+		if declaration == "" {
+			continue
+		}
+
+		lower := strings.ToLower(normalizeURL(declaration))
+		if strings.Contains(lower, "url(") {
+			if strings.Contains(lower, "http:") || strings.Contains(lower, "https:") || strings.Contains(lower, "url(//") {
+				hasRemote = true
+			}
+			continue
+		}
+
+		unsafe := false
+		for _, pattern := range unsafeStyle {
+			if strings.Contains(lower, pattern) {
+				unsafe = true
+				break
+			}
+		}
+		if !unsafe {
+			kept = append(kept, declaration)
+		}
+	}
+
+	return strings.Join(kept, "; "), hasRemote
+}
+
+func hasAttr(attrs []html.Attribute, key string) bool {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// ImageProxyPath is het pad van de image proxy endpoint in de API
+const ImageProxyPath = "/api/emails/image-proxy"
+
+// SanitizeEmail geeft een kopie van een email terug die veilig in het dashboard
+// getoond kan worden: gesaniteerde HTML, zonder inhoud van de bijlagen
+func (s *EmailService) SanitizeEmail(email *models.Email, policy RemoteContentPolicy) *models.Email {
+	sanitized := email.WithoutAttachmentContent()
+
+	var proxy func(string) string
+	if s.imageProxy != nil {
+		proxy = s.imageProxy.URL
+	}
+	sanitized.HTML, sanitized.HasRemoteContent = SanitizeHTML(email.HTML, policy, proxy)
+	return sanitized
+}
+
+// ProxyImage haalt een externe afbeelding op via de ondertekende image proxy
+func (s *EmailService) ProxyImage(ctx context.Context, target, signature string) (string, []byte, error) {
+	if s.imageProxy == nil {
+		return "", nil, ErrInvalidProxySignature
+	}
+	return s.imageProxy.Fetch(ctx, target, signature)
+}
@@ -0,0 +1,107 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeHTMLRemovesScripts(t *testing.T) {
+	// Setup
+	input := `<div onclick="steal()"><script>alert(1)</script><p>Hallo <b>wereld</b></p>` +
+		`<a href="javascript:alert(1)">klik</a><a href=" java&#x09;script:alert(1)">tab</a>` +
+		`<iframe src="https://evil.example"></iframe><form action="/x"><input name="q"></form></div>`
+
+	// Test
+	output, hasRemote := SanitizeHTML(input, RemoteContentBlock, nil)
+
+	// Controleer het resultaat
+	assert.False(t, hasRemote)
+	assert.Equal(t, `<div><p>Hallo <b>wereld</b></p><a>klik</a><a>tab</a></div>`, output)
+}
+
+func TestSanitizeHTMLAttributes(t *testing.T) {
+	// Setup
+	input := `<img src="x" onerror="alert(1)"><a href="https://dekoninklijkeloop.nl" target="_self" onmouseover="x()">site</a>` +
+		`<p style="color: red; background: url(https://tracker.example/p.gif); width: expression(alert(1))">tekst</p>`
+
+	// Test
+	output, hasRemote := SanitizeHTML(input, RemoteContentBlock, nil)
+
+	// Controleer het resultaat: img zonder geldige bron verdwijnt, link krijgt target en rel
+	assert.True(t, hasRemote)
+	assert.Equal(t, `<a href="https://dekoninklijkeloop.nl" target="_blank" rel="noopener noreferrer">site</a>`+
+		`<p style="color: red">tekst</p>`, output)
+}
+
+func TestSanitizeHTMLRemoteImages(t *testing.T) {
+	// Setup
+	input := `<img src="https://tracker.example/open.gif" alt="pixel"><img src="data:image/png;base64,AAAA">` +
+		`<img src="data:image/svg+xml;base64,AAAA">`
+	proxy := func(target string) string { return "/proxy?url=" + target }
+
+	// Test: blokkeren, via proxy laden en ongewijzigd laten
+	blocked, blockedRemote := SanitizeHTML(input, RemoteContentBlock, proxy)
+	proxied, proxiedRemote := SanitizeHTML(input, RemoteContentProxy, proxy)
+	allowed, allowedRemote := SanitizeHTML(input, RemoteContentAllow, proxy)
+
+	// Controleer het resultaat
+	assert.True(t, blockedRemote)
+	assert.True(t, proxiedRemote)
+	assert.True(t, allowedRemote)
+	assert.Equal(t, `<img src="data:image/png;base64,AAAA">`, blocked)
+	assert.Equal(t, `<img src="/proxy?url=https://tracker.example/open.gif" alt="pixel"><img src="data:image/png;base64,AAAA">`, proxied)
+	assert.Equal(t, `<img src="https://tracker.example/open.gif" alt="pixel"><img src="data:image/png;base64,AAAA">`, allowed)
+}
+
+func TestSanitizeHTMLEscapesText(t *testing.T) {
+	// Test: tekst en attribuutwaarden worden opnieuw ge-escaped
+	output, _ := SanitizeHTML(`<p title="&quot;><script>">1 &lt; 2 &amp; <unknown>3</unknown></p>`, RemoteContentBlock, nil)
+
+	// Controleer het resultaat
+	assert.Equal(t, `<p title="&#34;&gt;&lt;script&gt;">1 &lt; 2 &amp; 3</p>`, output)
+}
+
+func TestImageProxySignature(t *testing.T) {
+	// Setup
+	proxy := NewImageProxy("test-secret", ImageProxyPath)
+	other := NewImageProxy("ander-secret", ImageProxyPath)
+	target := "https://example.com/logo.png"
+
+	// Test
+	proxyURL := proxy.URL(target)
+	signature := proxy.sign(target)
+
+	// Controleer het resultaat
+	assert.True(t, strings.HasPrefix(proxyURL, ImageProxyPath+"?"))
+	assert.Contains(t, proxyURL, "sig="+signature)
+	assert.True(t, proxy.Verify(target, signature))
+	assert.False(t, proxy.Verify(target+"?x", signature))
+	assert.False(t, other.Verify(target, signature))
+	assert.False(t, proxy.Verify(target, "geen-hex"))
+}
+
+func TestImageProxyBlocksPrivateAddresses(t *testing.T) {
+	// Setup: een server op localhost mag niet via de proxy bereikbaar zijn
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png"))
+	}))
+	defer server.Close()
+	proxy := NewImageProxy("test-secret", ImageProxyPath)
+
+	// Test
+	_, _, err := proxy.Fetch(context.Background(), server.URL, proxy.sign(server.URL))
+	_, _, unsigned := proxy.Fetch(context.Background(), server.URL, "00")
+	_, _, scheme := proxy.Fetch(context.Background(), "file:///etc/passwd", proxy.sign("file:///etc/passwd"))
+
+	// Controleer het resultaat
+	assert.True(t, errors.Is(err, ErrBlockedProxyTarget), "got %v", err)
+	assert.ErrorIs(t, unsigned, ErrInvalidProxySignature)
+	assert.ErrorIs(t, scheme, ErrBlockedProxyTarget)
+}
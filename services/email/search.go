@@ -0,0 +1,150 @@
+package email
+
+import (
+	"dklautomationgo/models"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+)
+
+// maxSearchResults is het maximaal aantal treffers per account dat bij een
+// zoekopdracht via IMAP SEARCH, of bij een metadata filter, opgehaald wordt; de
+// nieuwste berichten gaan voor
+const maxSearchResults = 500
+
+// SearchMatches controleert of een email voldoet aan de zoekvelden van options.
+// Alle opgegeven velden moeten overeenkomen; bij Query moet elk woord ergens in
+// de afzender, ontvangers, het onderwerp of de tekst voorkomen.
+func SearchMatches(email *models.Email, options *models.EmailFetchOptions) bool {
+	if options.From != "" && !containsFold(email.Sender, options.From) {
+		return false
+	}
+	if options.To != "" && !containsAnyFold(recipientsOf(email), options.To) {
+		return false
+	}
+	if options.Subject != "" && !containsFold(email.Subject, options.Subject) {
+		return false
+	}
+	if options.Body != "" && !containsFold(originalText(email), options.Body) {
+		return false
+	}
+
+	if options.Query != "" {
+		fields := append([]string{email.Sender, email.Subject, originalText(email)}, recipientsOf(email)...)
+		for _, word := range strings.Fields(options.Query) {
+			if !containsAnyFold(fields, word) {
+				return false
+			}
+		}
+	}
+
+	if options.Since != nil || options.Before != nil {
+		date, err := time.Parse(time.RFC3339, email.CreatedAt)
+		if err != nil {
+			return false
+		}
+		if options.Since != nil && date.Before(*options.Since) {
+			return false
+		}
+		if options.Before != nil && !date.Before(*options.Before) {
+			return false
+		}
+	}
+
+	if options.HasAttachment != nil && hasAttachments(email) != *options.HasAttachment {
+		return false
+	}
+	return true
+}
+
+// searchEmails houdt de emails over die voldoen aan de zoekvelden van options
+func searchEmails(emails []*models.Email, options *models.EmailFetchOptions) []*models.Email {
+	if !options.HasSearch() {
+		return emails
+	}
+
+	found := make([]*models.Email, 0, len(emails))
+	for _, email := range emails {
+		if SearchMatches(email, options) {
+			found = append(found, email)
+		}
+	}
+	return found
+}
+
+// searchCriteria vertaalt de zoekvelden naar IMAP SEARCH criteria. IMAP zoekt
+// op hele dagen en kent geen criterium voor bijlagen; de treffers worden daarom
+// daarna nog met SearchMatches gefilterd.
+func searchCriteria(options *models.EmailFetchOptions) *imap.SearchCriteria {
+	criteria := imap.NewSearchCriteria()
+
+	if options.From != "" {
+		criteria.Header.Add("From", options.From)
+	}
+	if options.To != "" {
+		to := imap.NewSearchCriteria()
+		to.Header.Add("To", options.To)
+		cc := imap.NewSearchCriteria()
+		cc.Header.Add("Cc", options.To)
+		criteria.Or = append(criteria.Or, [2]*imap.SearchCriteria{to, cc})
+	}
+	if options.Subject != "" {
+		criteria.Header.Add("Subject", options.Subject)
+	}
+	if options.Body != "" {
+		criteria.Body = append(criteria.Body, options.Body)
+	}
+	criteria.Text = append(criteria.Text, strings.Fields(options.Query)...)
+
+	if options.Since != nil {
+		criteria.SentSince = startOfDay(*options.Since)
+	}
+	if options.Before != nil {
+		before := startOfDay(*options.Before)
+		if before.Before(*options.Before) {
+			before = before.AddDate(0, 0, 1)
+		}
+		criteria.SentBefore = before
+	}
+
+	return criteria
+}
+
+// newestUIDs geeft de hoogste (nieuwste) UIDs terug, oplopend gesorteerd
+func newestUIDs(uids []uint32, max int) []uint32 {
+	sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+	if len(uids) > max {
+		uids = uids[len(uids)-max:]
+	}
+	return uids
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// recipientsOf geeft de To en Cc ontvangers van een email
+func recipientsOf(email *models.Email) []string {
+	return append(append([]string{}, email.To...), email.Cc...)
+}
+
+// hasAttachments geeft aan of de email bijlagen heeft die niet inline in de HTML staan
+func hasAttachments(email *models.Email) bool {
+	for _, attachment := range email.Attachments {
+		if !attachment.Inline {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAnyFold(values []string, substr string) bool {
+	for _, value := range values {
+		if containsFold(value, substr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package email
+
+import (
+	"testing"
+	"time"
+
+	"dklautomationgo/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func searchTestEmail() *models.Email {
+	return &models.Email{
+		ID:        "info:INBOX:1:4",
+		Account:   "info",
+		Sender:    "Penningmeester@Sponsor.nl",
+		To:        []string{"info@dekoninklijkeloop.nl"},
+		Cc:        []string{"bestuur@sponsor.nl"},
+		Subject:   "Factuur sponsoring 2025",
+		HTML:      "<p>In de bijlage de <b>factuur</b> voor de hoofdsponsor.</p>",
+		CreatedAt: "2025-03-10T14:30:00+01:00",
+		Attachments: []models.EmailAttachment{
+			{Filename: "logo.png", ContentType: "image/png", Inline: true},
+		},
+	}
+}
+
+func TestSearchMatches(t *testing.T) {
+	email := searchTestEmail()
+	date := func(value string) *time.Time {
+		parsed, err := time.Parse(time.RFC3339, value)
+		require.NoError(t, err)
+		return &parsed
+	}
+	yes, no := true, false
+
+	tests := []struct {
+		name    string
+		options models.EmailFetchOptions
+		match   bool
+	}{
+		{"afzender", models.EmailFetchOptions{From: "penningmeester@sponsor"}, true},
+		{"andere afzender", models.EmailFetchOptions{From: "jan@"}, false},
+		{"ontvanger in cc", models.EmailFetchOptions{To: "BESTUUR"}, true},
+		{"onderwerp", models.EmailFetchOptions{Subject: "sponsoring"}, true},
+		{"tekst uit html", models.EmailFetchOptions{Body: "hoofdsponsor"}, true},
+		{"alle woorden", models.EmailFetchOptions{Query: "factuur hoofdsponsor"}, true},
+		{"niet alle woorden", models.EmailFetchOptions{Query: "factuur herinnering"}, false},
+		{"vanaf", models.EmailFetchOptions{Since: date("2025-03-10T00:00:00+01:00")}, true},
+		{"voor", models.EmailFetchOptions{Before: date("2025-03-10T14:30:00+01:00")}, false},
+		{"periode", models.EmailFetchOptions{Since: date("2025-03-01T00:00:00Z"), Before: date("2025-04-01T00:00:00Z")}, true},
+		{"inline afbeelding is geen bijlage", models.EmailFetchOptions{HasAttachment: &yes}, false},
+		{"zonder bijlage", models.EmailFetchOptions{HasAttachment: &no}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.match, SearchMatches(email, &tt.options))
+		})
+	}
+}
+
+func TestSearchCriteria(t *testing.T) {
+	// Setup
+	since := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	before := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	options := &models.EmailFetchOptions{
+		Query:   "factuur  2025",
+		From:    "sponsor.nl",
+		To:      "info@",
+		Subject: "sponsoring",
+		Since:   &since,
+		Before:  &before,
+	}
+
+	// Test
+	criteria := searchCriteria(options)
+
+	// Controleer het resultaat: IMAP zoekt op hele dagen, dus de dag van before telt mee
+	assert.Equal(t, "sponsor.nl", criteria.Header.Get("From"))
+	assert.Equal(t, "sponsoring", criteria.Header.Get("Subject"))
+	assert.Equal(t, []string{"factuur", "2025"}, criteria.Text)
+	require.Len(t, criteria.Or, 1)
+	assert.Equal(t, "info@", criteria.Or[0][0].Header.Get("To"))
+	assert.Equal(t, "info@", criteria.Or[0][1].Header.Get("Cc"))
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), criteria.SentSince)
+	assert.Equal(t, time.Date(2025, 3, 11, 0, 0, 0, 0, time.UTC), criteria.SentBefore)
+}
+
+func TestNewestUIDs(t *testing.T) {
+	assert.Equal(t, []uint32{7, 9}, newestUIDs([]uint32{9, 2, 7, 4}, 2))
+	assert.Equal(t, []uint32{2, 4}, newestUIDs([]uint32{4, 2}, 5))
+}
+
+func TestFetchEmails_SearchBypassesWatchedCache(t *testing.T) {
+	// Setup: een watcher houdt de cache actueel, maar de cache kent het bericht
+	// op de server (uit de memory backend) niet
+	port, caFile := testIMAPServer(t, true)
+	config := testIMAPConfig(port)
+	config.TLSCAFile = caFile
+
+	service := &EmailService{config: &ServiceConfig{
+		Accounts:     map[string]*EmailConfig{"info": config},
+		Cache:        CacheConfig{Enabled: true, Duration: time.Hour},
+		FetchTimeout: 10 * time.Second,
+	}}
+	defer service.Close()
+	cache := service.cache("info")
+	cache.store([]*models.Email{searchTestEmail()})
+	cache.setWatched(true)
+
+	// Test
+	found, err := service.FetchEmails(&models.EmailFetchOptions{Account: "info", Subject: "little message"})
+	require.NoError(t, err)
+	all, err := service.FetchEmails(&models.EmailFetchOptions{Account: "info"})
+	require.NoError(t, err)
+
+	// Controleer het resultaat: de zoekopdracht ging naar de server en de cache zelf blijft ongewijzigd
+	require.Len(t, found, 1)
+	assert.Equal(t, "A little message, just for you", found[0].Subject)
+	require.Len(t, all, 1)
+	assert.Equal(t, "info:INBOX:1:4", all[0].ID)
+}
@@ -0,0 +1,80 @@
+package email
+
+import (
+	"dklautomationgo/models"
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// SendNewEmail stelt een nieuw bericht op en verstuurt het vanuit het gekozen
+// account. Als een template is opgegeven wordt deze met de meegegeven data
+// gerenderd en als HTML versie gebruikt.
+func (s *EmailService) SendNewEmail(req *models.EmailSendRequest) (*models.Email, error) {
+	accountName := req.Account
+	var config *EmailConfig
+	if accountName == "" {
+		var err error
+		if accountName, config, err = s.senderFor(models.EmailPurposeReply); err != nil {
+			return nil, err
+		}
+	} else {
+		var ok bool
+		if config, ok = s.account(accountName); !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownAccount, accountName)
+		}
+	}
+
+	if len(req.To) == 0 {
+		return nil, ErrNoRecipients
+	}
+	for _, list := range [][]string{req.To, req.Cc, req.Bcc} {
+		for _, address := range list {
+			if _, err := mail.ParseAddress(address); err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidRecipient, address)
+			}
+		}
+	}
+
+	htmlBody := req.HTML
+	if req.Template != "" {
+		rendered, err := s.renderTemplate(req.Template, req.TemplateData)
+		if err != nil {
+			return nil, err
+		}
+		htmlBody = rendered
+	}
+
+	text := req.Body
+	if strings.TrimSpace(text) == "" && htmlBody != "" {
+		text = s.ProcessHTML(htmlBody)
+	}
+
+	return s.sendOutgoing(config, &outgoingMessage{
+		account:     accountName,
+		to:          req.To,
+		cc:          req.Cc,
+		bcc:         req.Bcc,
+		subject:     req.Subject,
+		text:        text,
+		html:        htmlBody,
+		attachments: req.Attachments,
+	})
+}
+
+// renderTemplate rendert de body van een email template met de gegeven data
+func (s *EmailService) renderTemplate(name string, data interface{}) (string, error) {
+	_, body, err := s.renderEmail(name, data)
+	return body, err
+}
+
+// HasTemplate geeft aan of een email template bestaat, in de database of als bestand
+func (s *EmailService) HasTemplate(name string) bool {
+	return s.lookupTemplate(name) != nil
+}
+
+// HasAccount geeft aan of een account geconfigureerd is
+func (s *EmailService) HasAccount(name string) bool {
+	_, ok := s.account(name)
+	return ok
+}
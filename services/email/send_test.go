@@ -0,0 +1,140 @@
+package email
+
+import (
+	"bytes"
+	"dklautomationgo/models"
+	"html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSendService() *EmailService {
+	return &EmailService{
+		config: &ServiceConfig{
+			Accounts: map[string]*EmailConfig{
+				"info":         {Email: "info@dekoninklijkeloop.nl"},
+				"inschrijving": {Email: "inschrijving@dekoninklijkeloop.nl"},
+			},
+			DevMode: true,
+		},
+		templates: map[string]*template.Template{
+			"groet.html": template.Must(template.New("groet.html").Parse("<p>Hallo {{.Naam}}</p>")),
+		},
+	}
+}
+
+func TestSendNewEmail_RendersTemplateFromChosenAccount(t *testing.T) {
+	// Setup
+	service := newTestSendService()
+	req := &models.EmailSendRequest{
+		Account:      "inschrijving",
+		To:           []string{"deelnemer@example.org"},
+		Subject:      "Welkom",
+		Template:     "groet.html",
+		TemplateData: map[string]interface{}{"Naam": "Jan"},
+	}
+
+	// Test
+	sent, err := service.SendNewEmail(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "inschrijving@dekoninklijkeloop.nl", sent.Sender)
+	assert.Equal(t, "<p>Hallo Jan</p>", sent.HTML)
+	assert.Contains(t, sent.Body, "Hallo Jan")
+	assert.NotEmpty(t, sent.MessageID)
+}
+
+func TestSendNewEmail_ValidationErrors(t *testing.T) {
+	// Setup
+	service := newTestSendService()
+
+	// Test
+	_, err := service.SendNewEmail(&models.EmailSendRequest{Subject: "Test", Body: "x"})
+	assert.ErrorIs(t, err, ErrNoRecipients)
+
+	_, err = service.SendNewEmail(&models.EmailSendRequest{Account: "onbekend", To: []string{"a@example.org"}})
+	assert.ErrorIs(t, err, ErrUnknownAccount)
+
+	_, err = service.SendNewEmail(&models.EmailSendRequest{To: []string{"geen adres"}})
+	assert.ErrorIs(t, err, ErrInvalidRecipient)
+
+	_, err = service.SendNewEmail(&models.EmailSendRequest{To: []string{"a@example.org"}, Template: "bestaat_niet.html"})
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+}
+
+func TestBuildEmail_MultipartWithHeaders(t *testing.T) {
+	// Setup
+	service := newTestSendService()
+	config := &EmailConfig{Email: "noreply@dekoninklijkeloop.nl", DisplayName: "De Koninklijke Loop"}
+
+	// Test
+	m := service.buildEmail(config, "deelnemer@example.org", "info@dekoninklijkeloop.nl", "Bedankt", `<p>Beste <b>Jan</b>,</p><p>Tot ziens!</p>`, "")
+	var raw bytes.Buffer
+	_, err := m.WriteTo(&raw)
+	require.NoError(t, err)
+	msg, err := mail.ReadMessage(&raw)
+	require.NoError(t, err)
+
+	// Controleer het resultaat
+	assert.Regexp(t, `^<\d+\.[0-9a-f]+@dekoninklijkeloop\.nl>$`, msg.Header.Get("Message-ID"))
+	assert.Equal(t, "info@dekoninklijkeloop.nl", msg.Header.Get("Reply-To"))
+	assert.NotEmpty(t, msg.Header.Get("Date"))
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	var types, bodies []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		body, err := io.ReadAll(part)
+		require.NoError(t, err)
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		types = append(types, contentType)
+		bodies = append(bodies, string(body))
+	}
+	// De platte tekst eerst, de HTML als voorkeursversie als laatste
+	assert.Equal(t, []string{"text/plain", "text/html"}, types)
+	assert.Equal(t, "Beste *Jan*,\r\n\r\nTot ziens!", bodies[0])
+	assert.Contains(t, bodies[1], "<b>Jan</b>")
+}
+
+func TestBuildEmail_ReplyToAndMessageID(t *testing.T) {
+	// Setup
+	service := newTestSendService()
+	config := &EmailConfig{Email: "info@dekoninklijkeloop.nl"}
+
+	// Test
+	m := service.buildEmail(config, "info@dekoninklijkeloop.nl", "INFO@dekoninklijkeloop.nl", "Nieuw", "<p>Hoi</p>", "<dkl.contact.1.2@dekoninklijkeloop.nl>")
+
+	// Controleer het resultaat
+	assert.Equal(t, []string{"<dkl.contact.1.2@dekoninklijkeloop.nl>"}, m.GetHeader("Message-ID"))
+	// Een Reply-To gelijk aan de afzender is overbodig
+	assert.Empty(t, m.GetHeader("Reply-To"))
+}
+
+func TestListUnsubscribe(t *testing.T) {
+	// Setup
+	service := newTestSendService()
+
+	// Test: zonder afmeld URL geen header
+	withoutURL := service.listUnsubscribe(models.EmailPurposeConfirmation)
+	service.config.UnsubscribeURL = "https://dekoninklijkeloop.nl/afmelden"
+	confirmation := service.listUnsubscribe(models.EmailPurposeConfirmation)
+	notification := service.listUnsubscribe(models.EmailPurposeNotification)
+
+	// Controleer het resultaat: de mailto gaat naar het account voor antwoorden, niet naar de afzender
+	assert.Empty(t, withoutURL)
+	assert.Equal(t, "<mailto:info@dekoninklijkeloop.nl?subject=unsubscribe>, <https://dekoninklijkeloop.nl/afmelden>", confirmation)
+	assert.Empty(t, notification, "beheerders krijgen nooit een List-Unsubscribe header")
+}
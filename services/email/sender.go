@@ -1,12 +1,13 @@
 package email
 
 import (
-	"bytes"
 	"crypto/tls"
 	"dklautomationgo/models"
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"net/mail"
 	"strings"
 	"time"
 
@@ -15,73 +16,69 @@ import (
 
 func (s *EmailService) SendContactEmail(data *models.ContactEmailData) error {
 	var templateName string
-	var subject string
 	var recipient string
+	var replyTo string
+	var messageID string
 
 	if data.ToAdmin {
-		templateName = "contact_admin_email.html"
-		subject = "Nieuw contactformulier ontvangen"
+		templateName = TemplateContactAdmin
 		recipient = data.AdminEmail
+		// Een antwoord van de beheerder gaat direct naar de contactpersoon
+		replyTo = data.Contact.Email
 		log.Printf("Sending admin email to: %s using template: %s", recipient, templateName)
 	} else {
-		templateName = "contact_email.html"
-		subject = "Bedankt voor je bericht"
+		// De bevestiging gaat in de taal van de contactpersoon; admins krijgen altijd DefaultLocale
+		templateName = s.localizedTemplate(TemplateContactUser, data.Contact.Locale)
 		recipient = data.Contact.Email
 		log.Printf("Sending user email to: %s using template: %s", recipient, templateName)
+		// Antwoorden op de bevestiging kunnen via de Message-ID aan het contactformulier gekoppeld worden
+		if data.Contact.ID != "" {
+			messageID = s.recordMessageID(models.EmailLinkContact, data.Contact.ID)
+		}
 	}
 
-	template := s.templates[templateName]
-	if template == nil {
-		log.Printf("Template not found: %s", templateName)
-		return fmt.Errorf("template not found: %s", templateName)
-	}
-
-	var body bytes.Buffer
-	if err := template.Execute(&body, data); err != nil {
-		log.Printf("Failed to execute template: %v", err)
-		return fmt.Errorf("failed to execute template: %v", err)
+	subject, body, err := s.renderEmail(templateName, data)
+	if err != nil {
+		log.Printf("Failed to render template %s: %v", templateName, err)
+		return err
 	}
 
 	log.Printf("Successfully generated email body for template: %s", templateName)
-	return s.sendEmail(recipient, subject, body.String())
+	return s.sendEmail(purposeFor(data.ToAdmin), recipient, replyTo, subject, body, messageID)
 }
 
 func (s *EmailService) SendAanmeldingEmail(data *models.AanmeldingEmailData) error {
 	var templateName string
-	var subject string
 	var recipient string
+	var replyTo string
+	var messageID string
 
 	if data.ToAdmin {
-		templateName = "aanmelding_admin_email.html"
-		subject = "Nieuwe aanmelding ontvangen"
+		templateName = TemplateAanmeldingAdmin
 		recipient = data.AdminEmail
+		replyTo = data.Aanmelding.Email
 		log.Printf("[SendAanmeldingEmail] Preparing admin email - Template: %s, Recipient: %s", templateName, recipient)
 	} else {
-		templateName = "aanmelding_email.html"
-		subject = "Bedankt voor je aanmelding"
+		templateName = s.localizedTemplate(TemplateAanmeldingUser, data.Aanmelding.Locale)
 		recipient = data.Aanmelding.Email
 		log.Printf("[SendAanmeldingEmail] Preparing user email - Template: %s, Recipient: %s", templateName, recipient)
+		if data.AanmeldingID != "" {
+			messageID = s.recordMessageID(models.EmailLinkAanmelding, data.AanmeldingID)
+		}
 	}
 
-	template := s.templates[templateName]
-	if template == nil {
-		log.Printf("[SendAanmeldingEmail] Template not found: %s", templateName)
-		return fmt.Errorf("template not found: %s", templateName)
-	}
-	log.Printf("[SendAanmeldingEmail] Found template: %s", templateName)
-
 	// Log template data for debugging
 	log.Printf("[SendAanmeldingEmail] Template data: ToAdmin=%v, Naam=%s, Email=%s, Rol=%s, Afstand=%s",
 		data.ToAdmin, data.Aanmelding.Naam, data.Aanmelding.Email, data.Aanmelding.Rol, data.Aanmelding.Afstand)
 
-	var body bytes.Buffer
-	if err := template.Execute(&body, data); err != nil {
-		log.Printf("[SendAanmeldingEmail] Failed to execute template: %v", err)
-		return fmt.Errorf("failed to execute template: %v", err)
+	subject, body, err := s.renderEmail(templateName, data)
+	if err != nil {
+		log.Printf("[SendAanmeldingEmail] Failed to render template %s: %v", templateName, err)
+		return err
 	}
-	log.Printf("[SendAanmeldingEmail] Successfully executed template, generated body length: %d", body.Len())
+	log.Printf("[SendAanmeldingEmail] Successfully executed template, generated body length: %d", len(body))
 
-	if err := s.sendEmail(recipient, subject, body.String()); err != nil {
+	if err := s.sendEmail(purposeFor(data.ToAdmin), recipient, replyTo, subject, body, messageID); err != nil {
 		log.Printf("[SendAanmeldingEmail] Failed to send email: %v", err)
 		return fmt.Errorf("failed to send email: %v", err)
 	}
@@ -90,10 +87,89 @@ func (s *EmailService) SendAanmeldingEmail(data *models.AanmeldingEmailData) err
 	return nil
 }
 
-func (s *EmailService) sendEmail(to, subject, body string) error {
+// purposeFor geeft het afzenderdoel van een formuliermail
+func purposeFor(toAdmin bool) string {
+	if toAdmin {
+		return models.EmailPurposeNotification
+	}
+	return models.EmailPurposeConfirmation
+}
+
+// sendEmail verstuurt een HTML email vanaf het account dat de afzender is voor
+// het gegeven doel. Met een messageID wordt die als Message-ID header gebruikt,
+// anders wordt er een gegenereerd. Zonder replyTo gaan antwoorden op een
+// bevestiging naar het account voor antwoorden als dat niet de afzender is.
+func (s *EmailService) sendEmail(purpose, to, replyTo, subject, body, messageID string) error {
 	log.Printf("[sendEmail] Starting email send process to: %s with subject: %s", to, subject)
 
-	// Check if we should simulate email delivery
+	accountName, emailConfig, err := s.senderFor(purpose)
+	if err != nil {
+		log.Printf("[sendEmail] No sender configured for %s: %v", purpose, err)
+		return err
+	}
+	log.Printf("[sendEmail] Sending %s from account %s", purpose, accountName)
+
+	if replyTo == "" && purpose == models.EmailPurposeConfirmation {
+		if _, replyConfig, err := s.senderFor(models.EmailPurposeReply); err == nil {
+			replyTo = replyConfig.Email
+		}
+	}
+
+	m := s.buildEmail(emailConfig, to, replyTo, subject, body, messageID)
+	if unsubscribe := s.listUnsubscribe(purpose); unsubscribe != "" {
+		m.SetHeader("List-Unsubscribe", unsubscribe)
+		m.SetHeader("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	}
+	_, err = s.deliver(emailConfig, m, []string{to})
+	return err
+}
+
+// buildEmail bouwt een multipart/alternative bericht met de HTML body en een
+// daarvan afgeleide platte tekst versie. Mailclients zonder HTML tonen de
+// tekst, en spamfilters wantrouwen berichten die alleen uit HTML bestaan.
+func (s *EmailService) buildEmail(emailConfig *EmailConfig, to, replyTo, subject, body, messageID string) *gomail.Message {
+	if messageID == "" {
+		messageID = generateMessageID(emailConfig.Email)
+	}
+
+	m := gomail.NewMessage()
+	// Use the same email address for From header as the SMTP authentication
+	m.SetHeader("From", emailConfig.fromHeader(m))
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", subject)
+	m.SetHeader("Message-ID", messageID)
+	m.SetDateHeader("Date", time.Now())
+	if address, err := mail.ParseAddress(replyTo); err == nil && !strings.EqualFold(address.Address, emailConfig.Email) {
+		m.SetHeader("Reply-To", address.Address)
+	}
+
+	m.SetBody("text/plain", HTMLToText(body))
+	m.AddAlternative("text/html", body)
+	return m
+}
+
+// listUnsubscribe geeft de List-Unsubscribe header voor een formuliermail. Alleen
+// bevestigingen aan inzenders krijgen er een, en alleen als EMAIL_UNSUBSCRIBE_URL
+// ingesteld is: die URL voor one-click afmelden (RFC 8058) en een mailto naar het
+// account voor antwoorden, waarvan de INBOX gelezen wordt. De afzender kan een
+// noreply adres zijn dat niemand leest. Notificaties aan beheerders krijgen er nooit een.
+func (s *EmailService) listUnsubscribe(purpose string) string {
+	if purpose != models.EmailPurposeConfirmation || s.config.UnsubscribeURL == "" {
+		return ""
+	}
+
+	var targets []string
+	if _, replyConfig, err := s.senderFor(models.EmailPurposeReply); err == nil && replyConfig.Email != "" {
+		targets = append(targets, "<mailto:"+replyConfig.Email+"?subject=unsubscribe>")
+	}
+	targets = append(targets, "<"+s.config.UnsubscribeURL+">")
+	return strings.Join(targets, ", ")
+}
+
+// shouldSimulate bepaalt of een bericht niet echt verstuurd moet worden. In
+// ontwikkelingsmodus wordt gesimuleerd zodra een ontvanger buiten de toegestane
+// domeinen valt; in productie alleen als alle ontvangers testadressen zijn.
+func (s *EmailService) shouldSimulate(recipients []string) bool {
 	if s.config.DevMode {
 		// In development mode, only send to allowed domains
 		allowedDomains := []string{
@@ -102,44 +178,48 @@ func (s *EmailService) sendEmail(to, subject, body string) error {
 			"127.0.0.1",
 		}
 
-		shouldSimulate := true
-		for _, domain := range allowedDomains {
-			if strings.HasSuffix(to, "@"+domain) {
-				shouldSimulate = false
-				break
+		for _, to := range recipients {
+			allowed := false
+			for _, domain := range allowedDomains {
+				if strings.HasSuffix(to, "@"+domain) {
+					allowed = true
+					break
+				}
+			}
+			if !allowed {
+				return true
 			}
 		}
+		return false
+	}
 
-		if shouldSimulate {
-			log.Printf("[sendEmail] DEV MODE: Simulating email delivery to %s", to)
-			log.Printf("[sendEmail] DEV MODE: Email subject: %s", subject)
-			log.Printf("[sendEmail] DEV MODE: Email body length: %d bytes", len(body))
-			return nil
-		}
-	} else {
-		// In production mode, still simulate for obvious test domains
-		testDomains := []string{"@example.com", "@test.com", "@example.org"}
+	// In production mode, still simulate for obvious test domains
+	testDomains := []string{"@example.com", "@test.com", "@example.org"}
+	for _, to := range recipients {
+		isTest := false
 		for _, domain := range testDomains {
 			if strings.HasSuffix(to, domain) {
-				log.Printf("[sendEmail] Detected test email address: %s. Simulating successful delivery.", to)
-				return nil
+				isTest = true
+				break
 			}
 		}
+		if !isTest {
+			return false
+		}
 	}
+	return len(recipients) > 0
+}
 
-	m := gomail.NewMessage()
-	// Use the same email address for From header as the SMTP authentication
-	emailConfig := s.config.Accounts["info"]
-	if emailConfig == nil {
-		log.Printf("[sendEmail] Email configuration not found for info account")
-		return fmt.Errorf("email configuration not found for info account")
+// deliver verstuurt een bericht via de SMTP server van het opgegeven account,
+// met retries voor tijdelijke fouten. De bool geeft aan of de verzending gesimuleerd is.
+func (s *EmailService) deliver(emailConfig *EmailConfig, m *gomail.Message, recipients []string) (bool, error) {
+	// Check if we should simulate email delivery
+	if s.shouldSimulate(recipients) {
+		log.Printf("[sendEmail] Simulating email delivery to %s", strings.Join(recipients, ", "))
+		log.Printf("[sendEmail] Simulated email subject: %s", strings.Join(m.GetHeader("Subject"), ""))
+		return true, nil
 	}
 
-	m.SetHeader("From", emailConfig.Email) // Use the authenticated email address
-	m.SetHeader("To", to)
-	m.SetHeader("Subject", subject)
-	m.SetBody("text/html", body)
-
 	log.Printf("[sendEmail] Using SMTP Configuration - Host: %s, Port: %d, Username: %s, Password length: %d",
 		emailConfig.SMTPHost, emailConfig.SMTPPort, emailConfig.Email, len(emailConfig.Password))
 
@@ -154,12 +234,12 @@ func (s *EmailService) sendEmail(to, subject, body string) error {
 		d.SSL = false
 	}
 
-	// TLS configuration
-	d.TLSConfig = &tls.Config{
-		ServerName:         emailConfig.SMTPHost,
-		InsecureSkipVerify: true,             // Allow invalid certificates for testing
-		MinVersion:         tls.VersionTLS10, // Allow older TLS versions
+	// TLS configuration: certificaat controleren en minimaal TLS 1.2
+	tlsConfig, err := emailConfig.tlsConfig(emailConfig.SMTPHost)
+	if err != nil {
+		return false, fmt.Errorf("failed to configure TLS: %w", err)
 	}
+	d.TLSConfig = tlsConfig
 
 	// Add retry logic for transient errors
 	maxRetries := 3
@@ -174,7 +254,7 @@ func (s *EmailService) sendEmail(to, subject, body string) error {
 		log.Printf("[sendEmail] Attempt %d/%d: Connecting to SMTP server %s:%d with %s...",
 			i+1, maxRetries, emailConfig.SMTPHost, emailConfig.SMTPPort, connectionType)
 
-		if err := d.DialAndSend(m); err != nil {
+		if err := s.dialAndSend(d, emailConfig, m); err != nil {
 			log.Printf("[sendEmail] Attempt %d/%d failed: %v", i+1, maxRetries, err)
 
 			// Check if it's a network error
@@ -188,13 +268,19 @@ func (s *EmailService) sendEmail(to, subject, body string) error {
 				log.Printf("[sendEmail] TLS error details: %v", tlsErr)
 			}
 
-			// Check if it's an authentication error
-			if strings.Contains(err.Error(), "authentication") {
+			// Opnieuw proberen helpt niet als de credentials of het certificaat niet kloppen
+			err = smtpError(err)
+			if errors.Is(err, ErrAuthFailed) {
 				log.Printf("[sendEmail] Authentication error detected. Please verify SMTP credentials.")
+				return false, fmt.Errorf("failed to send email: %w", err)
+			}
+			if errors.Is(err, ErrCertificate) {
+				log.Printf("[sendEmail] Certificate of %s could not be verified. Configure EMAIL_TLS_CA_FILE for a private CA.", emailConfig.SMTPHost)
+				return false, fmt.Errorf("failed to send email: %w", err)
 			}
 
 			if i == maxRetries-1 {
-				return fmt.Errorf("failed to send email after %d attempts: %v", maxRetries, err)
+				return false, fmt.Errorf("failed to send email after %d attempts: %w", maxRetries, err)
 			}
 
 			// Exponential backoff with a maximum of 5 seconds
@@ -207,9 +293,9 @@ func (s *EmailService) sendEmail(to, subject, body string) error {
 			continue
 		}
 
-		log.Printf("[sendEmail] Successfully sent email to: %s", to)
-		return nil
+		log.Printf("[sendEmail] Successfully sent email to: %s", strings.Join(recipients, ", "))
+		return false, nil
 	}
 
-	return fmt.Errorf("failed to send email after %d retries", maxRetries)
+	return false, fmt.Errorf("failed to send email after %d retries", maxRetries)
 }
@@ -1,14 +1,19 @@
 package email
 
 import (
-	"crypto/tls"
+	"context"
 	"dklautomationgo/models"
+	"dklautomationgo/services/events"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/emersion/go-imap"
 	"github.com/emersion/go-imap/client"
@@ -24,14 +29,36 @@ type IEmailService interface {
 var _ IEmailService = (*EmailService)(nil)
 
 type EmailService struct {
-	templates     map[string]*template.Template
-	config        *ServiceConfig
-	accountCaches map[string]*AccountCache
+	templates      map[string]*template.Template // Templates uit templates/, de fallback voor de database
+	config         *ServiceConfig
+	accountCaches  map[string]*AccountCache
+	eventPublisher events.Publisher
+	imageProxy     *ImageProxy
+	metadata       EmailMetadataStore
+	notes          EmailNoteStore
+	statuses       accountStatusTracker
+	pool           imapPool
+	dkim           *DKIMSigner // nil als DKIM niet ingesteld is
+
+	// Actieve templates uit de database, die bij wijzigingen herladen worden
+	templatesMu     sync.RWMutex
+	templateStore   EmailTemplateStore
+	storedTemplates map[string]*storedTemplate
+
+	// accountsMu beschermt config.Accounts en accountCaches, die bij het herladen van de accounts wijzigen
+	accountsMu    sync.RWMutex
+	accountSource AccountSource
+
+	// Watchers per account, zodat ze na het herladen van de accounts gestopt en gestart kunnen worden
+	watchMu  sync.Mutex
+	watchCtx context.Context
+	watchers map[string]context.CancelFunc
+
+	incomingHandlers []IncomingEmailHandler
+	incomingMutex    sync.RWMutex
 }
 
 func NewEmailService() (*EmailService, error) {
-	templates := make(map[string]*template.Template)
-
 	// Get the current working directory
 	cwd, err := os.Getwd()
 	if err != nil {
@@ -39,39 +66,12 @@ func NewEmailService() (*EmailService, error) {
 	}
 	log.Printf("[NewEmailService] Current working directory: %s", cwd)
 
-	// Load contact email templates
-	contactAdminTemplate, err := template.ParseFiles(fmt.Sprintf("%s/templates/contact_admin_email.html", cwd))
+	// Templates uit de bestanden; versies in de database gaan hier later voor
+	templates, err := loadFileTemplates(filepath.Join(cwd, "templates"))
 	if err != nil {
-		log.Printf("[NewEmailService] Failed to parse contact admin template: %v", err)
-		return nil, fmt.Errorf("failed to parse contact admin template: %v", err)
+		log.Printf("[NewEmailService] Failed to load templates: %v", err)
+		return nil, err
 	}
-	templates["contact_admin_email.html"] = contactAdminTemplate
-	log.Printf("[NewEmailService] Successfully loaded contact_admin_email.html template")
-
-	contactUserTemplate, err := template.ParseFiles(fmt.Sprintf("%s/templates/contact_email.html", cwd))
-	if err != nil {
-		log.Printf("[NewEmailService] Failed to parse contact user template: %v", err)
-		return nil, fmt.Errorf("failed to parse contact user template: %v", err)
-	}
-	templates["contact_email.html"] = contactUserTemplate
-	log.Printf("[NewEmailService] Successfully loaded contact_email.html template")
-
-	// Load aanmelding email templates
-	aanmeldingAdminTemplate, err := template.ParseFiles(fmt.Sprintf("%s/templates/aanmelding_admin_email.html", cwd))
-	if err != nil {
-		log.Printf("[NewEmailService] Failed to parse aanmelding admin template: %v", err)
-		return nil, fmt.Errorf("failed to parse aanmelding admin template: %v", err)
-	}
-	templates["aanmelding_admin_email.html"] = aanmeldingAdminTemplate
-	log.Printf("[NewEmailService] Successfully loaded aanmelding_admin_email.html template")
-
-	aanmeldingUserTemplate, err := template.ParseFiles(fmt.Sprintf("%s/templates/aanmelding_email.html", cwd))
-	if err != nil {
-		log.Printf("[NewEmailService] Failed to parse aanmelding user template: %v", err)
-		return nil, fmt.Errorf("failed to parse aanmelding user template: %v", err)
-	}
-	templates["aanmelding_email.html"] = aanmeldingUserTemplate
-	log.Printf("[NewEmailService] Successfully loaded aanmelding_email.html template")
 
 	// Get configuration
 	config := GetDefaultConfig()
@@ -84,80 +84,205 @@ func NewEmailService() (*EmailService, error) {
 		log.Printf("[NewEmailService] Initialized cache for account: %s", accountName)
 	}
 
+	// DKIM is optioneel; met een ongeldige key wordt zonder handtekening verstuurd
+	var dkim *DKIMSigner
+	if config.DKIM.KeyFile != "" {
+		if dkim, err = NewDKIMSigner(config.DKIM); err != nil {
+			log.Printf("[NewEmailService] Warning: DKIM signing disabled: %v", err)
+		} else {
+			log.Printf("[NewEmailService] Signing mail for %s with DKIM selector %s, DNS record: %s", config.DKIM.Domain, config.DKIM.Selector, dkim.DNSRecord())
+		}
+	}
+
 	return &EmailService{
 		templates:     templates,
 		config:        config,
 		accountCaches: accountCaches,
+		imageProxy:    NewImageProxy(config.ImageProxySecret, ImageProxyPath),
+		dkim:          dkim,
 	}, nil
 }
 
-// MarkEmailAsRead marks an email as read in the IMAP server and updates the cache
-func (s *EmailService) MarkEmailAsRead(emailID string) error {
-	// Parse the email ID to get account name and message number
-	parts := strings.Split(emailID, ":")
-	if len(parts) != 2 {
-		return fmt.Errorf("invalid email ID format")
+var (
+	ErrInvalidEmailID   = errors.New("invalid email ID format")
+	ErrEmailNotFound    = errors.New("email not found")
+	ErrUnknownAccount   = errors.New("unknown account")
+	ErrTemplateNotFound = errors.New("template not found")
+	ErrInvalidRecipient = errors.New("invalid recipient address")
+	ErrStaleEmailID     = errors.New("email ID is no longer valid")
+)
+
+// inboxFolder is de map die gecachet en door de watchers bijgehouden wordt
+const inboxFolder = "INBOX"
+
+// mailboxRef identificeert een map van een account, met de UIDVALIDITY waarbinnen
+// de UIDs van die map geldig zijn
+type mailboxRef struct {
+	account     string
+	folder      string
+	uidValidity uint32
+}
+
+// emailID geeft het ID van het bericht met de gegeven UID in deze map
+func (m mailboxRef) emailID(uid uint32) string {
+	return formatEmailID(m.account, m.folder, m.uidValidity, uid)
+}
+
+// isInbox geeft aan of de map de (gecachte) INBOX is
+func (m mailboxRef) isInbox() bool {
+	return strings.EqualFold(m.folder, inboxFolder)
+}
+
+// emailRef identificeert een bericht op de IMAP server
+type emailRef struct {
+	mailboxRef
+	uid uint32
+}
+
+// id geeft het canonieke email ID van een referentie terug
+func (r emailRef) id() string {
+	return r.emailID(r.uid)
+}
+
+// formatEmailID maakt een email ID in het formaat account:map:uidvalidity:uid.
+// Mapnamen met tekens buiten [A-Za-z0-9._-] worden base64url gecodeerd met een
+// ~ prefix, zodat het ID zonder escaping in een URL pad past.
+func formatEmailID(account, folder string, uidValidity, uid uint32) string {
+	return fmt.Sprintf("%s:%s:%d:%d", account, encodeFolder(folder), uidValidity, uid)
+}
+
+func encodeFolder(folder string) string {
+	for _, r := range folder {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-') {
+			return "~" + base64.RawURLEncoding.EncodeToString([]byte(folder))
+		}
+	}
+	return folder
+}
+
+func decodeFolder(encoded string) (string, error) {
+	if !strings.HasPrefix(encoded, "~") {
+		return encoded, nil
 	}
-	accountName := parts[0]
-	messageNum, err := strconv.ParseUint(parts[1], 10, 32)
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded[1:])
 	if err != nil {
-		return fmt.Errorf("invalid message number: %w", err)
+		return "", err
+	}
+	return string(decoded), nil
+}
+
+// parseEmailID splitst een email ID in account, map, UIDVALIDITY en UID. Oudere
+// IDs zonder UIDVALIDITY (account:map:uid) of zonder map (account:uid, de INBOX)
+// worden nog geaccepteerd; hun UIDVALIDITY is 0 en wordt niet gecontroleerd.
+func parseEmailID(emailID string) (emailRef, error) {
+	parts := strings.Split(emailID, ":")
+	if len(parts) < 2 || len(parts) > 4 {
+		return emailRef{}, ErrInvalidEmailID
 	}
 
-	// Get account config
-	config, ok := s.config.Accounts[accountName]
-	if !ok {
-		return fmt.Errorf("unknown account: %s", accountName)
+	ref := emailRef{mailboxRef: mailboxRef{account: parts[0], folder: inboxFolder}}
+	if len(parts) >= 3 {
+		folder, err := decodeFolder(parts[1])
+		if err != nil || folder == "" {
+			return emailRef{}, fmt.Errorf("%w: invalid folder", ErrInvalidEmailID)
+		}
+		ref.folder = folder
+	}
+	if len(parts) == 4 {
+		uidValidity, err := strconv.ParseUint(parts[2], 10, 32)
+		if err != nil {
+			return emailRef{}, fmt.Errorf("%w: invalid uidvalidity: %v", ErrInvalidEmailID, parts[2])
+		}
+		ref.uidValidity = uint32(uidValidity)
 	}
 
-	// Connect to IMAP server
-	tlsConfig := &tls.Config{
-		ServerName:         config.IMAPHost,
-		InsecureSkipVerify: true,
-		MinVersion:         tls.VersionTLS12,
+	uid, err := strconv.ParseUint(parts[len(parts)-1], 10, 32)
+	if err != nil || uid == 0 {
+		return emailRef{}, fmt.Errorf("%w: invalid message number: %v", ErrInvalidEmailID, parts[len(parts)-1])
 	}
+	ref.uid = uint32(uid)
+	return ref, nil
+}
 
-	c, err := client.DialTLS(fmt.Sprintf("%s:%d", config.IMAPHost, config.IMAPPort), tlsConfig)
+// selectMessage selecteert de map van een bericht en controleert of de
+// UIDVALIDITY nog overeenkomt met die uit het ID. Is de map op de server
+// opnieuw genummerd, dan verwijst het ID niet meer naar hetzelfde bericht.
+func (s *EmailService) selectMessage(c *client.Client, ref emailRef, readOnly bool) (emailRef, *imap.SeqSet, error) {
+	mbox, err := c.Select(ref.folder, readOnly)
 	if err != nil {
-		return fmt.Errorf("IMAP connection failed: %w", err)
+		return ref, nil, fmt.Errorf("IMAP select %s failed: %w", ref.folder, err)
 	}
-	defer c.Logout()
 
-	// Login
-	if err := c.Login(config.Email, config.Password); err != nil {
-		return fmt.Errorf("IMAP login failed: %w", err)
+	if ref.isInbox() && s.cache(ref.account).checkUIDValidity(mbox.UidValidity) {
+		log.Printf("[selectMessage] %s: UIDVALIDITY of INBOX changed, cache invalidated", ref.account)
 	}
 
-	// Select INBOX
-	_, err = c.Select("INBOX", false)
-	if err != nil {
-		return fmt.Errorf("IMAP select inbox failed: %w", err)
+	if ref.uidValidity == 0 {
+		ref.uidValidity = mbox.UidValidity
+	} else if ref.uidValidity != mbox.UidValidity {
+		return ref, nil, fmt.Errorf("%w: %s (server uidvalidity %d)", ErrStaleEmailID, ref.id(), mbox.UidValidity)
 	}
 
-	// Create sequence set for the message
 	seqSet := new(imap.SeqSet)
-	seqSet.AddNum(uint32(messageNum))
+	seqSet.AddNum(ref.uid)
+	return ref, seqSet, nil
+}
 
-	// Add the \Seen flag
-	item := imap.FormatFlagsOp(imap.AddFlags, true)
-	flags := []interface{}{imap.SeenFlag}
-	err = c.Store(seqSet, item, flags, nil)
+// MarkEmailAsRead marks an email as read in the IMAP server and updates the cache
+func (s *EmailService) MarkEmailAsRead(emailID string) error {
+	return s.setEmailFlag(emailID, imap.SeenFlag, true)
+}
+
+// MarkEmailAsUnread verwijdert de \Seen flag van een email
+func (s *EmailService) MarkEmailAsUnread(emailID string) error {
+	return s.setEmailFlag(emailID, imap.SeenFlag, false)
+}
+
+// SetEmailFlagged zet of verwijdert de \Flagged (ster) markering van een email
+func (s *EmailService) SetEmailFlagged(emailID string, flagged bool) error {
+	return s.setEmailFlag(emailID, imap.FlaggedFlag, flagged)
+}
+
+// SetEmailKeyword zet of verwijdert een IMAP keyword (een eigen flag zoals
+// FormProcessedKeyword) op een email. Mailclients tonen keywords als labels.
+func (s *EmailService) SetEmailKeyword(emailID, keyword string, value bool) error {
+	return s.setEmailFlag(emailID, keyword, value)
+}
+
+// setEmailFlag zet of verwijdert een flag via UID STORE en werkt de cache bij
+func (s *EmailService) setEmailFlag(emailID, flag string, value bool) error {
+	var updated emailRef
+	err := s.withMessage(emailID, func(c *client.Client, ref emailRef, seqSet *imap.SeqSet) (bool, error) {
+		var op imap.FlagsOp = imap.AddFlags
+		if !value {
+			op = imap.RemoveFlags
+		}
+		if err := c.UidStore(seqSet, imap.FormatFlagsOp(op, true), []interface{}{flag}, nil); err != nil {
+			return false, fmt.Errorf("failed to update %s flag: %w", flag, err)
+		}
+		updated = ref
+		return false, nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to mark message as read: %w", err)
+		return err
 	}
 
 	// Update cache if enabled
-	if s.config.Cache.Enabled {
-		cache := s.accountCaches[accountName]
-		cache.cacheMutex.Lock()
-		for _, email := range cache.emails {
-			if email.ID == emailID {
-				email.Read = true
-				break
-			}
-		}
-		cache.cacheMutex.Unlock()
+	if s.config.Cache.Enabled && updated.isInbox() {
+		s.cache(updated.account).update(updated.id(), func(email *models.Email) {
+			applyFlag(email, flag, value)
+		})
 	}
 
 	return nil
 }
+
+// applyFlag werkt de velden van een email bij die een IMAP flag weerspiegelen
+func applyFlag(email *models.Email, flag string, value bool) {
+	switch flag {
+	case imap.SeenFlag:
+		email.Read = value
+	case imap.FlaggedFlag:
+		email.Flagged = value
+	}
+}
@@ -0,0 +1,66 @@
+package email
+
+import (
+	"dklautomationgo/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatEmailID_RoundTrip(t *testing.T) {
+	folders := []string{"INBOX", "INBOX.Sent", "Sent Items", "INBOX/Archief", "Ongewenste e-mail"}
+
+	for _, folder := range folders {
+		id := formatEmailID("info", folder, 1700000000, 42)
+		assert.NotContains(t, id, "/", "ID moet in een URL pad passen")
+		assert.NotContains(t, id, " ")
+
+		ref, err := parseEmailID(id)
+		assert.NoError(t, err)
+		assert.Equal(t, emailRef{mailboxRef: mailboxRef{account: "info", folder: folder, uidValidity: 1700000000}, uid: 42}, ref)
+		assert.Equal(t, id, ref.id())
+	}
+}
+
+func TestParseEmailID_LegacyFormats(t *testing.T) {
+	// Test: account:uid verwijst naar de INBOX
+	ref, err := parseEmailID("inschrijving:7")
+	assert.NoError(t, err)
+	assert.Equal(t, "inschrijving", ref.account)
+	assert.True(t, ref.isInbox())
+	assert.Equal(t, uint32(0), ref.uidValidity)
+	assert.Equal(t, uint32(7), ref.uid)
+
+	// Test: account:map:uid zonder UIDVALIDITY
+	ref, err = parseEmailID("info:INBOX.Sent:9")
+	assert.NoError(t, err)
+	assert.Equal(t, "INBOX.Sent", ref.folder)
+	assert.Equal(t, uint32(0), ref.uidValidity)
+	assert.Equal(t, uint32(9), ref.uid)
+}
+
+func TestParseEmailID_Invalid(t *testing.T) {
+	for _, id := range []string{"", "info", "info:INBOX:abc", "info:INBOX:0", "info:~!!:1", "info:INBOX:x:1", "a:b:1:2:3"} {
+		_, err := parseEmailID(id)
+		assert.ErrorIs(t, err, ErrInvalidEmailID, id)
+	}
+}
+
+func TestRecordMessageID_RoundTrip(t *testing.T) {
+	s := &EmailService{config: &ServiceConfig{Accounts: map[string]*EmailConfig{
+		"info": {Email: "info@dekoninklijkeloop.nl"},
+	}}}
+
+	id := s.recordMessageID(models.EmailLinkAanmelding, "11111111-2222-3333-4444-555555555555")
+	assert.Regexp(t, `^<dkl\.aanmelding\.11111111-2222-3333-4444-555555555555\.\d+@dekoninklijkeloop\.nl>$`, id)
+
+	recordType, recordID, ok := ParseRecordMessageID(id)
+	assert.True(t, ok)
+	assert.Equal(t, models.EmailLinkAanmelding, recordType)
+	assert.Equal(t, "11111111-2222-3333-4444-555555555555", recordID)
+
+	for _, other := range []string{"", "<abc@mail.example.com>", "<dkl.onbekend.123.1@dekoninklijkeloop.nl>", "<dkl.contact..1@dekoninklijkeloop.nl>", "<dkl.contact.1';DROP.1@dekoninklijkeloop.nl>", "<dkl.aanmelding.aanmelding-1.1@dekoninklijkeloop.nl>"} {
+		_, _, ok := ParseRecordMessageID(other)
+		assert.False(t, ok, other)
+	}
+}
@@ -0,0 +1,279 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"dklautomationgo/models"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+// Fouten bij het verbinden met een mailserver. Ze worden om de oorspronkelijke
+// fout heen gezet, zodat errors.Is werkt en de details behouden blijven.
+var (
+	ErrAuthFailed        = errors.New("email authentication failed")
+	ErrServerUnreachable = errors.New("email server unreachable")
+	ErrTimeout           = errors.New("email server timed out")
+	ErrCertificate       = errors.New("email server certificate could not be verified")
+)
+
+// Foutcodes van een account in EmailAccountStatus.ErrorCode
+const (
+	ErrorCodeAuthFailed  = "auth_failed"
+	ErrorCodeUnreachable = "unreachable"
+	ErrorCodeTimeout     = "timeout"
+	ErrorCodeCertificate = "certificate_invalid"
+	ErrorCodeOther       = "error"
+)
+
+// AccountError is een fout bij het ophalen van de emails van één account
+type AccountError struct {
+	Account string
+	Err     error
+}
+
+func (e *AccountError) Error() string {
+	return fmt.Sprintf("account %s: %v", e.Account, e.Err)
+}
+
+func (e *AccountError) Unwrap() error {
+	return e.Err
+}
+
+// connectionError typeert een fout bij het verbinden als time-out, ongeldig
+// certificaat of onbereikbare server. Een al getypeerde fout wordt ongewijzigd teruggegeven.
+func connectionError(err error) error {
+	if err == nil || errors.Is(err, ErrAuthFailed) || errors.Is(err, ErrServerUnreachable) || errors.Is(err, ErrTimeout) || errors.Is(err, ErrCertificate) {
+		return err
+	}
+
+	if isCertificateError(err) {
+		return fmt.Errorf("%w: %w", ErrCertificate, err)
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	return fmt.Errorf("%w: %w", ErrServerUnreachable, err)
+}
+
+// isCertificateError geeft aan of de TLS handshake faalde op de controle van het certificaat
+func isCertificateError(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	return errors.As(err, &verifyErr) || errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
+
+// loginError typeert een fout bij het inloggen. Een NO antwoord van de server
+// betekent dat de credentials niet kloppen; een netwerkfout is een verbindingsfout.
+func loginError(err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return connectionError(err)
+	}
+	return fmt.Errorf("%w: %w", ErrAuthFailed, err)
+}
+
+// smtpError typeert een fout van de SMTP server. De 53x codes betekenen dat de
+// authenticatie mislukt is (RFC 4954).
+func smtpError(err error) error {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		if protoErr.Code == 530 || protoErr.Code == 534 || protoErr.Code == 535 {
+			return fmt.Errorf("%w: %w", ErrAuthFailed, err)
+		}
+		return err
+	}
+
+	var netErr net.Error
+	var opErr *net.OpError
+	if isCertificateError(err) || errors.As(err, &netErr) || errors.As(err, &opErr) {
+		return connectionError(err)
+	}
+	return err
+}
+
+// ErrorCode geeft de foutcode voor de API bij een fout van een account
+func ErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrAuthFailed):
+		return ErrorCodeAuthFailed
+	case errors.Is(err, ErrTimeout):
+		return ErrorCodeTimeout
+	case errors.Is(err, ErrCertificate):
+		return ErrorCodeCertificate
+	case errors.Is(err, ErrServerUnreachable):
+		return ErrorCodeUnreachable
+	default:
+		return ErrorCodeOther
+	}
+}
+
+// accountStatusTracker houdt per account de uitkomst van de laatste
+// synchronisatie bij. De zero value is bruikbaar.
+type accountStatusTracker struct {
+	mu       sync.RWMutex
+	statuses map[string]*models.EmailAccountStatus
+}
+
+// entry geeft de status van een account, aangemaakt indien nodig. De aanroeper houdt mu vast.
+func (t *accountStatusTracker) entry(account string) *models.EmailAccountStatus {
+	if t.statuses == nil {
+		t.statuses = make(map[string]*models.EmailAccountStatus)
+	}
+	status, ok := t.statuses[account]
+	if !ok {
+		status = &models.EmailAccountStatus{Account: account, Status: models.AccountStatusUnknown}
+		t.statuses[account] = status
+	}
+	return status
+}
+
+// recordSuccess legt een geslaagde synchronisatie vast
+func (t *accountStatusTracker) recordSuccess(account string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	status := t.entry(account)
+	status.Status = models.AccountStatusOK
+	status.ErrorCode = ""
+	status.Error = ""
+	status.LastAttempt = &now
+	status.LastSync = &now
+}
+
+// recordCounts legt het aantal berichten in de INBOX vast, en het aantal
+// ongelezen berichten als dat bekend is
+func (t *accountStatusTracker) recordCounts(account string, messages uint32, unread *uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := t.entry(account)
+	status.Messages = messages
+	if unread != nil {
+		status.Unread = *unread
+	}
+}
+
+// recordFailure legt een mislukte synchronisatie vast
+func (t *accountStatusTracker) recordFailure(account string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	status := t.entry(account)
+	status.Status = models.AccountStatusError
+	status.ErrorCode = ErrorCode(err)
+	status.Error = err.Error()
+	status.LastAttempt = &now
+}
+
+// get geeft een kopie van de status van een account
+func (t *accountStatusTracker) get(account string) models.EmailAccountStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return *t.entry(account)
+}
+
+// remove vergeet de status van een account, bijvoorbeeld na een gewijzigde configuratie
+func (t *accountStatusTracker) remove(account string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.statuses, account)
+}
+
+// accountStatus geeft de status van een account, aangevuld met het adres en
+// of een watcher de INBOX bijhoudt
+func (s *EmailService) accountStatus(accountName string) *models.EmailAccountStatus {
+	status := s.statuses.get(accountName)
+	if config, ok := s.account(accountName); ok {
+		status.Email = config.Email
+	}
+	if cache := s.cache(accountName); cache != nil {
+		status.Watching = cache.isWatched()
+		// Een actuele cache weet precies hoeveel berichten ongelezen zijn
+		if s.config.Cache.Enabled && cache.isFresh(s.config.Cache.Duration) {
+			status.Unread = 0
+			for _, email := range cache.snapshot() {
+				if !email.Read {
+					status.Unread++
+				}
+			}
+		}
+	}
+	return &status
+}
+
+// AccountStatuses geeft de status van alle geconfigureerde accounts, op naam gesorteerd
+func (s *EmailService) AccountStatuses() []*models.EmailAccountStatus {
+	accounts := s.accountConfigs()
+	names := make([]string, 0, len(accounts))
+	for name := range accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	statuses := make([]*models.EmailAccountStatus, len(names))
+	for i, name := range names {
+		statuses[i] = s.accountStatus(name)
+	}
+	return statuses
+}
+
+// CheckAccounts maakt voor elk account verbinding met de IMAP server, vraagt
+// het aantal (ongelezen) berichten in de INBOX op en geeft de bijgewerkte
+// statussen terug
+func (s *EmailService) CheckAccounts(ctx context.Context) []*models.EmailAccountStatus {
+	var wg sync.WaitGroup
+	for accountName, config := range s.accountConfigs() {
+		wg.Add(1)
+		go func(accountName string, config *EmailConfig) {
+			defer wg.Done()
+			if err := s.checkAccount(ctx, accountName, config); err != nil {
+				s.statuses.recordFailure(accountName, err)
+			}
+		}(accountName, config)
+	}
+	wg.Wait()
+
+	return s.AccountStatuses()
+}
+
+func (s *EmailService) checkAccount(ctx context.Context, accountName string, config *EmailConfig) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- s.withIMAP(accountName, config, func(c *client.Client) error {
+			mbox, err := c.Status(inboxFolder, []imap.StatusItem{imap.StatusMessages, imap.StatusUnseen})
+			if err != nil {
+				return fmt.Errorf("IMAP status failed: %w", err)
+			}
+			s.statuses.recordCounts(accountName, mbox.Messages, &mbox.Unseen)
+			s.statuses.recordSuccess(accountName)
+			return nil
+		})
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return connectionError(ctx.Err())
+	}
+}
@@ -0,0 +1,123 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"dklautomationgo/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// timeoutError is een net.Error die een time-out meldt
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestErrorClassification(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+		code string
+	}{
+		{"verbinding geweigerd", connectionError(refused), ErrServerUnreachable, ErrorCodeUnreachable},
+		{"netwerk time-out", connectionError(timeoutError{}), ErrTimeout, ErrorCodeTimeout},
+		{"context verlopen", connectionError(context.DeadlineExceeded), ErrTimeout, ErrorCodeTimeout},
+		{"al getypeerd", connectionError(loginError(errors.New("Invalid credentials"))), ErrAuthFailed, ErrorCodeAuthFailed},
+		{"login geweigerd", loginError(errors.New("[AUTHENTICATIONFAILED] Authentication failed.")), ErrAuthFailed, ErrorCodeAuthFailed},
+		{"verbinding verbroken tijdens login", loginError(io.EOF), ErrServerUnreachable, ErrorCodeUnreachable},
+		{"smtp authenticatie", smtpError(&textproto.Error{Code: 535, Msg: "5.7.8 Authentication failed"}), ErrAuthFailed, ErrorCodeAuthFailed},
+		{"smtp verbinding", smtpError(refused), ErrServerUnreachable, ErrorCodeUnreachable},
+		{"onbekende CA", connectionError(x509.UnknownAuthorityError{}), ErrCertificate, ErrorCodeCertificate},
+		{"smtp certificaat", smtpError(&tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}), ErrCertificate, ErrorCodeCertificate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.err, tt.want)
+			assert.Equal(t, tt.code, ErrorCode(tt.err))
+		})
+	}
+
+	// Andere SMTP fouten blijven ongetypeerd
+	rejected := smtpError(&textproto.Error{Code: 550, Msg: "Mailbox unavailable"})
+	assert.Equal(t, ErrorCodeOther, ErrorCode(rejected))
+}
+
+// closedPort geeft een lokale poort waarop niets luistert
+func closedPort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+	return port
+}
+
+func TestFetchEmailsWithStatus_ReportsFailingAccount(t *testing.T) {
+	// Setup: info staat in de cache, inschrijving is onbereikbaar
+	service, _, _ := newTestMetadataService(&models.Email{ID: "info:INBOX:1:1", Account: "info"})
+	service.config.FetchTimeout = 10 * time.Second
+	service.config.Accounts["inschrijving"].IMAPHost = "127.0.0.1"
+	service.config.Accounts["inschrijving"].IMAPPort = closedPort(t)
+
+	// Test
+	emails, statuses, err := service.FetchEmailsWithStatus(&models.EmailFetchOptions{})
+
+	// Controleer het resultaat: de emails van info komen door, inschrijving meldt de fout
+	require.NoError(t, err)
+	assert.Len(t, emails, 1)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "info", statuses[0].Account)
+	assert.Equal(t, "inschrijving", statuses[1].Account)
+	assert.Equal(t, models.AccountStatusError, statuses[1].Status)
+	assert.Equal(t, ErrorCodeUnreachable, statuses[1].ErrorCode)
+	assert.Equal(t, "inschrijving@dekoninklijkeloop.nl", statuses[1].Email)
+	assert.NotNil(t, statuses[1].LastAttempt)
+	assert.Nil(t, statuses[1].LastSync)
+
+	// Test: als alle accounts falen is de fout getypeerd
+	_, _, err = service.FetchEmailsWithStatus(&models.EmailFetchOptions{Account: "inschrijving"})
+
+	// Controleer het resultaat
+	var accountErr *AccountError
+	require.ErrorAs(t, err, &accountErr)
+	assert.Equal(t, "inschrijving", accountErr.Account)
+	assert.ErrorIs(t, err, ErrServerUnreachable)
+}
+
+func TestAccountStatuses(t *testing.T) {
+	// Setup
+	service, _, _ := newTestMetadataService(
+		&models.Email{ID: "info:INBOX:1:1", Account: "info", Read: true},
+		&models.Email{ID: "info:INBOX:1:2", Account: "info"},
+	)
+	unread := uint32(7)
+	service.statuses.recordCounts("info", 2, &unread)
+	service.statuses.recordSuccess("info")
+	service.statuses.recordFailure("inschrijving", loginError(errors.New("Invalid credentials")))
+
+	// Test
+	statuses := service.AccountStatuses()
+
+	// Controleer het resultaat: het aantal ongelezen berichten komt uit de actuele cache
+	require.Len(t, statuses, 2)
+	assert.Equal(t, models.AccountStatusOK, statuses[0].Status)
+	assert.Equal(t, uint32(2), statuses[0].Messages)
+	assert.Equal(t, uint32(1), statuses[0].Unread)
+	assert.NotNil(t, statuses[0].LastSync)
+	assert.Equal(t, models.AccountStatusError, statuses[1].Status)
+	assert.Equal(t, ErrorCodeAuthFailed, statuses[1].ErrorCode)
+}
@@ -0,0 +1,385 @@
+package email
+
+import (
+	"bytes"
+	"dklautomationgo/models"
+	"dklautomationgo/services/i18n"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Templates van de formuliermails; deze moeten als bestand in templates/ staan.
+// Vertalingen hebben de taal voor de extensie, bijv. "aanmelding_email.en.html".
+const (
+	TemplateContactAdmin    = "contact_admin_email.html"
+	TemplateContactUser     = "contact_email.html"
+	TemplateAanmeldingAdmin = "aanmelding_admin_email.html"
+	TemplateAanmeldingUser  = "aanmelding_email.html"
+)
+
+// requiredTemplates zijn de templates die als bestand aanwezig moeten zijn, zodat
+// de formuliermails ook zonder database verstuurd kunnen worden
+var requiredTemplates = []string{TemplateContactAdmin, TemplateContactUser, TemplateAanmeldingAdmin, TemplateAanmeldingUser}
+
+// Bronnen van een template in TemplateInfo
+const (
+	TemplateSourceFile     = "file"
+	TemplateSourceDatabase = "database"
+)
+
+var (
+	ErrInvalidTemplate = errors.New("invalid template")
+)
+
+// templateNamePattern beperkt de namen van templates, omdat ze in URLs gebruikt
+// worden; een vertaling heeft een taalcode voor de extensie
+var templateNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,60}(\.[a-z]{2})?\.html$`)
+
+// EmailTemplateStore levert de actieve versies van de templates in de database
+type EmailTemplateStore interface {
+	FindActive() ([]*models.EmailTemplate, error)
+}
+
+// storedTemplate is een geparste versie van een template uit de database
+type storedTemplate struct {
+	version int
+	subject *texttemplate.Template // nil als het standaard onderwerp gebruikt wordt
+	body    *template.Template
+}
+
+// TemplateInfo beschrijft een template dat gebruikt kan worden
+type TemplateInfo struct {
+	Name          string `json:"name"`
+	Source        string `json:"source"`                   // file of database
+	ActiveVersion int    `json:"active_version,omitempty"` // Actieve versie uit de database
+	HasFile       bool   `json:"has_file"`                 // Of er een bestand is om op terug te vallen
+}
+
+// TemplatePreview is een gerenderd template
+type TemplatePreview struct {
+	Subject string      `json:"subject"`
+	HTML    string      `json:"html"`
+	Text    string      `json:"text"`
+	Data    interface{} `json:"data"` // De voorbeelddata waarmee gerenderd is
+}
+
+// loadFileTemplates laadt alle *.html bestanden uit een map
+func loadFileTemplates(dir string) (map[string]*template.Template, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+
+	templates := make(map[string]*template.Template, len(files))
+	for _, file := range files {
+		name := filepath.Base(file)
+		tmpl, err := template.ParseFiles(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+		}
+		templates[name] = tmpl
+		log.Printf("[NewEmailService] Successfully loaded %s template", name)
+	}
+
+	for _, name := range requiredTemplates {
+		if templates[name] == nil {
+			return nil, fmt.Errorf("required template %s not found in %s", name, dir)
+		}
+	}
+	return templates, nil
+}
+
+// SetTemplateStore stelt de opslag van de templates in de database in en laadt ze direct
+func (s *EmailService) SetTemplateStore(store EmailTemplateStore) error {
+	s.templatesMu.Lock()
+	s.templateStore = store
+	s.templatesMu.Unlock()
+
+	return s.ReloadTemplates()
+}
+
+// ReloadTemplates laadt de actieve templates opnieuw uit de database. Een
+// template dat niet (meer) geparst kan worden wordt overgeslagen, zodat het
+// bestand met dezelfde naam gebruikt wordt.
+func (s *EmailService) ReloadTemplates() error {
+	s.templatesMu.RLock()
+	store := s.templateStore
+	s.templatesMu.RUnlock()
+	if store == nil {
+		return nil
+	}
+
+	rows, err := store.FindActive()
+	if err != nil {
+		return fmt.Errorf("failed to load email templates: %w", err)
+	}
+
+	stored := make(map[string]*storedTemplate, len(rows))
+	for _, row := range rows {
+		parsed, err := parseStoredTemplate(row.Name, row.Subject, row.Body)
+		if err != nil {
+			log.Printf("[Templates] Skipping %s version %d: %v", row.Name, row.Version, err)
+			continue
+		}
+		parsed.version = row.Version
+		stored[row.Name] = parsed
+	}
+
+	s.templatesMu.Lock()
+	s.storedTemplates = stored
+	s.templatesMu.Unlock()
+	log.Printf("[Templates] Loaded %d templates from the database", len(stored))
+	return nil
+}
+
+// parseStoredTemplate parst het onderwerp en de body van een template
+func parseStoredTemplate(name, subject, body string) (*storedTemplate, error) {
+	if strings.TrimSpace(body) == "" {
+		return nil, fmt.Errorf("%w: body is empty", ErrInvalidTemplate)
+	}
+
+	parsed := &storedTemplate{}
+	var err error
+	if parsed.body, err = template.New(name).Parse(body); err != nil {
+		return nil, fmt.Errorf("%w: body: %v", ErrInvalidTemplate, err)
+	}
+	if strings.TrimSpace(subject) != "" {
+		if parsed.subject, err = texttemplate.New(name + ".subject").Parse(subject); err != nil {
+			return nil, fmt.Errorf("%w: subject: %v", ErrInvalidTemplate, err)
+		}
+	}
+	return parsed, nil
+}
+
+// ValidateTemplateName controleert de naam van een nieuw template
+func ValidateTemplateName(name string) error {
+	if !templateNamePattern.MatchString(name) {
+		return fmt.Errorf("%w: name must be lowercase letters, digits, - and _ ending in .html", ErrInvalidTemplate)
+	}
+	if _, locale := splitTemplateLocale(name); locale == "" && strings.Count(name, ".") > 1 {
+		return fmt.Errorf("%w: unsupported language in %s", ErrInvalidTemplate, name)
+	}
+	return nil
+}
+
+// splitTemplateLocale splitst de naam van een vertaling in de naam van het
+// basistemplate en de taal, bijv. "aanmelding_email.en.html" in
+// "aanmelding_email.html" en "en". Zonder taal is de taal leeg.
+func splitTemplateLocale(name string) (string, string) {
+	stem := strings.TrimSuffix(name, ".html")
+	i := strings.LastIndex(stem, ".")
+	if i < 0 || i == len(stem)-1 {
+		return name, ""
+	}
+	locale := i18n.Normalize(stem[i+1:])
+	if locale == "" {
+		return name, ""
+	}
+	return stem[:i] + ".html", locale
+}
+
+// localizedTemplate geeft de naam van de vertaling van een template als die
+// bestaat, en anders de naam zelf. DefaultLocale gebruikt altijd het basistemplate.
+func (s *EmailService) localizedTemplate(name, locale string) string {
+	locale = i18n.Normalize(locale)
+	if locale == "" || locale == i18n.DefaultLocale {
+		return name
+	}
+	localized := strings.TrimSuffix(name, ".html") + "." + locale + ".html"
+	if s.lookupTemplate(localized) == nil {
+		return name
+	}
+	return localized
+}
+
+// ValidateTemplate controleert een nieuwe versie van een template: het moet
+// parsen en met de voorbeelddata van het template te renderen zijn
+func (s *EmailService) ValidateTemplate(name, subject, body string) error {
+	if err := ValidateTemplateName(name); err != nil {
+		return err
+	}
+	if strings.TrimSpace(body) == "" {
+		return fmt.Errorf("%w: body is empty", ErrInvalidTemplate)
+	}
+	_, err := s.PreviewTemplate(name, subject, body)
+	return err
+}
+
+// PreviewTemplate rendert een template met voorbeelddata. Zonder body wordt het
+// template gebruikt dat nu actief is.
+func (s *EmailService) PreviewTemplate(name, subject, body string) (*TemplatePreview, error) {
+	data := SampleTemplateData(name)
+
+	var tmpl *storedTemplate
+	if strings.TrimSpace(body) == "" {
+		tmpl = s.lookupTemplate(name)
+		if tmpl == nil {
+			return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+		}
+	} else {
+		var err error
+		if tmpl, err = parseStoredTemplate(name, subject, body); err != nil {
+			return nil, err
+		}
+	}
+
+	renderedSubject, html, err := tmpl.render(defaultSubject(name), data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return &TemplatePreview{Subject: renderedSubject, HTML: html, Text: HTMLToText(html), Data: data}, nil
+}
+
+// lookupTemplate geeft de actieve versie van een template uit de database, of anders het bestand
+func (s *EmailService) lookupTemplate(name string) *storedTemplate {
+	s.templatesMu.RLock()
+	defer s.templatesMu.RUnlock()
+
+	if tmpl := s.storedTemplates[name]; tmpl != nil {
+		return tmpl
+	}
+	if tmpl := s.templates[name]; tmpl != nil {
+		return &storedTemplate{body: tmpl}
+	}
+	return nil
+}
+
+// render rendert het onderwerp en de body. Zonder eigen onderwerp wordt
+// defaultSubject gebruikt. De CSS van de body wordt inline gezet.
+func (t *storedTemplate) render(defaultSubject string, data interface{}) (string, string, error) {
+	var body bytes.Buffer
+	if err := t.body.Execute(&body, data); err != nil {
+		return "", "", fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	subject := defaultSubject
+	if t.subject != nil {
+		var rendered bytes.Buffer
+		if err := t.subject.Execute(&rendered, data); err != nil {
+			return "", "", fmt.Errorf("failed to execute subject: %w", err)
+		}
+		// Een onderwerp is één regel
+		subject = strings.Join(strings.Fields(rendered.String()), " ")
+	}
+	return subject, InlineCSS(body.String()), nil
+}
+
+// renderEmail rendert een template tot onderwerp en HTML body
+func (s *EmailService) renderEmail(name string, data interface{}) (string, string, error) {
+	tmpl := s.lookupTemplate(name)
+	if tmpl == nil {
+		return "", "", fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return tmpl.render(defaultSubject(name), data)
+}
+
+// Templates geeft de beschikbare templates, uit de bestanden en de database
+func (s *EmailService) Templates() []TemplateInfo {
+	s.templatesMu.RLock()
+	defer s.templatesMu.RUnlock()
+
+	infos := make(map[string]*TemplateInfo)
+	for name := range s.templates {
+		infos[name] = &TemplateInfo{Name: name, Source: TemplateSourceFile, HasFile: true}
+	}
+	for name, tmpl := range s.storedTemplates {
+		info := infos[name]
+		if info == nil {
+			info = &TemplateInfo{Name: name}
+			infos[name] = info
+		}
+		info.Source = TemplateSourceDatabase
+		info.ActiveVersion = tmpl.version
+	}
+
+	result := make([]TemplateInfo, 0, len(infos))
+	for _, info := range infos {
+		result = append(result, *info)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// subjectKeys zijn de berichten met het standaard onderwerp van de formuliermails
+var subjectKeys = map[string]i18n.Key{
+	TemplateContactAdmin:    i18n.SubjectContactAdmin,
+	TemplateContactUser:     i18n.SubjectContactUser,
+	TemplateAanmeldingAdmin: i18n.SubjectAanmeldingAdmin,
+	TemplateAanmeldingUser:  i18n.SubjectAanmeldingUser,
+}
+
+// defaultSubject geeft het onderwerp van een template zonder eigen onderwerp,
+// in de taal van het template
+func defaultSubject(name string) string {
+	base, locale := splitTemplateLocale(name)
+	key, ok := subjectKeys[base]
+	if !ok {
+		return ""
+	}
+	if locale == "" {
+		locale = i18n.DefaultLocale
+	}
+	return i18n.T(locale, key)
+}
+
+// SampleTemplateData geeft voorbeelddata voor een template, met dezelfde
+// structuur als de data waarmee het template verstuurd wordt
+func SampleTemplateData(name string) interface{} {
+	name, locale := splitTemplateLocale(name)
+	if locale == "" {
+		locale = i18n.DefaultLocale
+	}
+	switch name {
+	case TemplateContactAdmin, TemplateContactUser:
+		notities := "Teruggebeld op dinsdag"
+		return &models.ContactEmailData{
+			ToAdmin:    name == TemplateContactAdmin,
+			AdminEmail: "info@dekoninklijkeloop.nl",
+			Contact: &models.ContactFormulier{
+				ID:             "00000000-0000-0000-0000-000000000001",
+				CreatedAt:      time.Date(2026, time.March, 14, 10, 30, 0, 0, time.Local),
+				UpdatedAt:      time.Date(2026, time.March, 14, 10, 30, 0, 0, time.Local),
+				Naam:           "Jan de Vries",
+				Email:          "jan@example.org",
+				Bericht:        "Hallo, ik wil graag meer weten over de route van de Koninklijke Loop.",
+				PrivacyAkkoord: true,
+				Status:         "nieuw",
+				Notities:       &notities,
+				Locale:         locale,
+			},
+		}
+	case TemplateAanmeldingAdmin, TemplateAanmeldingUser:
+		return &models.AanmeldingEmailData{
+			ToAdmin:      name == TemplateAanmeldingAdmin,
+			AdminEmail:   "info@dekoninklijkeloop.nl",
+			AanmeldingID: "00000000-0000-0000-0000-000000000002",
+			Aanmelding: &models.AanmeldingFormulier{
+				Naam:           "Marieke Jansen",
+				Email:          "marieke@example.org",
+				Telefoon:       "0612345678",
+				Rol:            "Vrijwilliger",
+				Afstand:        "5 KM",
+				Ondersteuning:  "Rolstoelbus",
+				Bijzonderheden: "Graag een plek bij de finish",
+				Terms:          true,
+				Locale:         locale,
+			},
+		}
+	case defaultAutoReplyTemplate:
+		return &AutoReplyData{
+			Sender:  "jan@example.org",
+			Subject: "Vraag over de route",
+			Account: "info",
+		}
+	}
+	// Templates voor SendNewEmail krijgen vrije data
+	return map[string]interface{}{}
+}
@@ -0,0 +1,176 @@
+package email
+
+import (
+	"dklautomationgo/models"
+	"html/template"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryTemplateStore is een EmailTemplateStore in het geheugen
+type memoryTemplateStore struct {
+	templates []*models.EmailTemplate
+}
+
+func (m *memoryTemplateStore) FindActive() ([]*models.EmailTemplate, error) {
+	return m.templates, nil
+}
+
+func newTemplateTestService(t *testing.T) *EmailService {
+	t.Helper()
+	return &EmailService{
+		templates: map[string]*template.Template{
+			TemplateAanmeldingUser: template.Must(template.New(TemplateAanmeldingUser).Parse(`<p>Bestand {{.Aanmelding.Naam}}</p>`)),
+			TemplateContactUser:    template.Must(template.New(TemplateContactUser).Parse(`<p>Contact {{.Contact.Naam}}</p>`)),
+		},
+	}
+}
+
+func TestRenderEmail_DatabaseTemplateOverridesFile(t *testing.T) {
+	// Setup
+	s := newTemplateTestService(t)
+	store := &memoryTemplateStore{templates: []*models.EmailTemplate{{
+		Name:    TemplateAanmeldingUser,
+		Version: 3,
+		Subject: "Welkom {{.Aanmelding.Naam}}\n",
+		Body:    `<p>Database {{.Aanmelding.Naam}}</p>`,
+		Active:  true,
+	}}}
+	require.NoError(t, s.SetTemplateStore(store))
+	data := SampleTemplateData(TemplateAanmeldingUser)
+
+	// Test
+	subject, body, err := s.renderEmail(TemplateAanmeldingUser, data)
+	require.NoError(t, err)
+	contactSubject, contactBody, err := s.renderEmail(TemplateContactUser, SampleTemplateData(TemplateContactUser))
+	require.NoError(t, err)
+
+	// Controleer het resultaat
+	assert.Equal(t, "Welkom Marieke Jansen", subject)
+	assert.Equal(t, "<p>Database Marieke Jansen</p>", body)
+	assert.Equal(t, defaultSubject(TemplateContactUser), contactSubject)
+	assert.Equal(t, "<p>Contact Jan de Vries</p>", contactBody)
+}
+
+func TestReloadTemplates_InvalidDatabaseTemplateFallsBackToFile(t *testing.T) {
+	// Setup
+	s := newTemplateTestService(t)
+	store := &memoryTemplateStore{templates: []*models.EmailTemplate{{
+		Name:    TemplateAanmeldingUser,
+		Version: 1,
+		Body:    `<p>{{.Aanmelding.Naam</p>`,
+		Active:  true,
+	}}}
+
+	// Test
+	require.NoError(t, s.SetTemplateStore(store))
+	subject, body, err := s.renderEmail(TemplateAanmeldingUser, SampleTemplateData(TemplateAanmeldingUser))
+
+	// Controleer het resultaat
+	require.NoError(t, err)
+	assert.Equal(t, defaultSubject(TemplateAanmeldingUser), subject)
+	assert.Equal(t, "<p>Bestand Marieke Jansen</p>", body)
+}
+
+func TestValidateTemplate(t *testing.T) {
+	s := newTemplateTestService(t)
+
+	tests := []struct {
+		name    string
+		tmpl    string
+		subject string
+		body    string
+		wantErr bool
+	}{
+		{"geldig", TemplateAanmeldingUser, "Hoi {{.Aanmelding.Naam}}", `<p>{{.Aanmelding.Afstand}}</p>`, false},
+		{"nieuw template met vrije data", "nieuwsbrief.html", "", `<p>{{.Titel}}</p>`, false},
+		{"syntaxfout in body", TemplateAanmeldingUser, "", `<p>{{if .ToAdmin}}</p>`, true},
+		{"syntaxfout in onderwerp", TemplateAanmeldingUser, "{{.Aanmelding.Naam", `<p>ok</p>`, true},
+		{"onbekend veld", TemplateAanmeldingUser, "", `<p>{{.Aanmelding.Onbekend}}</p>`, true},
+		{"lege body", TemplateContactUser, "", "  ", true},
+		{"ongeldige naam", "../contact_email.html", "", `<p>ok</p>`, true},
+		{"naam zonder .html", "contact_email", "", `<p>ok</p>`, true},
+		{"vertaling", "aanmelding_email.en.html", "", `<p>Dear {{.Aanmelding.Naam}}</p>`, false},
+		{"niet ondersteunde taal", "aanmelding_email.fr.html", "", `<p>ok</p>`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.ValidateTemplate(tt.tmpl, tt.subject, tt.body)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidTemplate)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestLocalizedTemplate(t *testing.T) {
+	// Setup
+	s := newTemplateTestService(t)
+	store := &memoryTemplateStore{templates: []*models.EmailTemplate{{
+		Name:    "aanmelding_email.en.html",
+		Version: 1,
+		Body:    `<p>Dear {{.Aanmelding.Naam}}</p>`,
+		Active:  true,
+	}}}
+	require.NoError(t, s.SetTemplateStore(store))
+
+	// Test
+	english := s.localizedTemplate(TemplateAanmeldingUser, "en-GB")
+	subject, body, err := s.renderEmail(english, SampleTemplateData(english))
+	require.NoError(t, err)
+
+	// Controleer het resultaat
+	assert.Equal(t, "aanmelding_email.en.html", english)
+	assert.Equal(t, "Thank you for registering", subject)
+	assert.Equal(t, "<p>Dear Marieke Jansen</p>", body)
+	// Zonder vertaling, voor de standaardtaal en voor onbekende talen blijft het basistemplate
+	assert.Equal(t, TemplateContactUser, s.localizedTemplate(TemplateContactUser, "en"))
+	assert.Equal(t, TemplateAanmeldingUser, s.localizedTemplate(TemplateAanmeldingUser, "nl"))
+	assert.Equal(t, TemplateAanmeldingUser, s.localizedTemplate(TemplateAanmeldingUser, "fr"))
+	assert.Equal(t, "Bedankt voor je aanmelding", defaultSubject(TemplateAanmeldingUser))
+}
+
+func TestPreviewTemplate(t *testing.T) {
+	// Setup
+	s := newTemplateTestService(t)
+
+	// Test
+	draft, err := s.PreviewTemplate(TemplateContactUser, "Re: {{.Contact.Naam}}", `<p>Beste {{.Contact.Naam}},</p><p>{{.Contact.Bericht}}</p>`)
+	require.NoError(t, err)
+	active, err := s.PreviewTemplate(TemplateAanmeldingUser, "", "")
+	require.NoError(t, err)
+	_, err = s.PreviewTemplate("onbekend.html", "", "")
+
+	// Controleer het resultaat
+	assert.Equal(t, "Re: Jan de Vries", draft.Subject)
+	assert.Contains(t, draft.HTML, "<p>Beste Jan de Vries,</p>")
+	assert.Contains(t, draft.Text, "Beste Jan de Vries,")
+	assert.IsType(t, &models.ContactEmailData{}, draft.Data)
+	assert.Equal(t, "<p>Bestand Marieke Jansen</p>", active.HTML)
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+}
+
+func TestTemplates_ListsSources(t *testing.T) {
+	// Setup
+	s := newTemplateTestService(t)
+	store := &memoryTemplateStore{templates: []*models.EmailTemplate{
+		{Name: TemplateContactUser, Version: 2, Body: `<p>v2</p>`, Active: true},
+		{Name: "nieuwsbrief.html", Version: 1, Body: `<p>nieuws</p>`, Active: true},
+	}}
+	require.NoError(t, s.SetTemplateStore(store))
+
+	// Test
+	infos := s.Templates()
+
+	// Controleer het resultaat
+	assert.Equal(t, []TemplateInfo{
+		{Name: TemplateAanmeldingUser, Source: TemplateSourceFile, HasFile: true},
+		{Name: TemplateContactUser, Source: TemplateSourceDatabase, ActiveVersion: 2, HasFile: true},
+		{Name: "nieuwsbrief.html", Source: TemplateSourceDatabase, ActiveVersion: 1},
+	}, infos)
+}
@@ -0,0 +1,404 @@
+package email
+
+import (
+	"crypto/sha256"
+	"dklautomationgo/models"
+	"encoding/hex"
+	"errors"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrThreadNotFound = errors.New("thread not found")
+
+// threadSentLimit is het aantal recente berichten per account uit de Sent map
+// dat in gesprekken meegenomen wordt
+const threadSentLimit = 200
+
+// subjectPrefixRegex vindt antwoord- en doorstuur prefixen, ook herhaald of
+// genummerd ("Re: Re:", "Re[2]:") en in het Nederlands en Duits
+var subjectPrefixRegex = regexp.MustCompile(`(?i)^\s*((re|fw|fwd|aw|wg|antw|doorst|sv|vs|tr)\s*(\[\d+\])?\s*:\s*)+`)
+
+// baseSubject geeft het onderwerp zonder prefixen terug en of er een prefix stond
+func baseSubject(subject string) (string, bool) {
+	prefix := subjectPrefixRegex.FindString(subject)
+	return strings.Join(strings.Fields(subject[len(prefix):]), " "), prefix != ""
+}
+
+// FetchThreads groepeert de emails uit de INBOX en de Sent map van alle (of
+// het gegeven) account(s) tot gesprekken, het meest recente gesprek eerst.
+// De berichten zelf staan niet in het resultaat, zie GetThread.
+func (s *EmailService) FetchThreads(options *models.EmailFetchOptions) ([]*models.EmailThread, error) {
+	if options == nil {
+		options = &models.EmailFetchOptions{}
+	}
+
+	emails, err := s.threadEmails(options.Account)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bij een zoekopdracht blijven de gesprekken over waarin een bericht voldoet
+	var matches map[string]bool
+	if options.HasSearch() {
+		matches = make(map[string]bool)
+		for _, email := range searchEmails(emails, options) {
+			matches[email.ID] = true
+		}
+	}
+
+	threads := make([]*models.EmailThread, 0)
+	for _, thread := range BuildThreads(emails) {
+		if options.Read != nil && (thread.UnreadCount == 0) != *options.Read {
+			continue
+		}
+		if matches != nil && !threadMatches(thread, matches) {
+			continue
+		}
+		thread.Messages = nil
+		threads = append(threads, thread)
+	}
+
+	start := max(options.Offset, 0)
+	if start >= len(threads) {
+		return []*models.EmailThread{}, nil
+	}
+	end := len(threads)
+	if options.Limit > 0 && start+options.Limit < end {
+		end = start + options.Limit
+	}
+	return threads[start:end], nil
+}
+
+// threadMatches geeft aan of een van de berichten van het gesprek in matches staat
+func threadMatches(thread *models.EmailThread, matches map[string]bool) bool {
+	for _, message := range thread.Messages {
+		if matches[message.ID] {
+			return true
+		}
+	}
+	return false
+}
+
+// GetThread geeft één gesprek terug met al zijn berichten, oudste eerst
+func (s *EmailService) GetThread(threadID string) (*models.EmailThread, error) {
+	emails, err := s.threadEmails("")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, thread := range BuildThreads(emails) {
+		if thread.ID == threadID {
+			return thread, nil
+		}
+	}
+	return nil, ErrThreadNotFound
+}
+
+// threadEmails haalt de emails op waaruit gesprekken opgebouwd worden: de
+// (gecachte) INBOX en de recente berichten uit de Sent map van elk account
+func (s *EmailService) threadEmails(account string) ([]*models.Email, error) {
+	emails, err := s.FetchEmails(&models.EmailFetchOptions{Account: account})
+	if err != nil {
+		return nil, err
+	}
+
+	for accountName := range s.accountConfigs() {
+		if account != "" && accountName != account {
+			continue
+		}
+		// De Sent map wordt niet door een watcher bijgehouden; de recente berichten
+		// worden net zo lang gecachet als de INBOX, zodat niet elk gesprek ze ophaalt
+		cache := s.cache(accountName)
+		if s.config.Cache.Enabled {
+			if sent, ok := cache.sentSnapshot(s.config.Cache.Duration); ok {
+				emails = append(emails, sent...)
+				continue
+			}
+		}
+
+		sent, err := s.FetchEmails(&models.EmailFetchOptions{Account: accountName, Folder: "sent", Limit: threadSentLimit})
+		if err != nil {
+			// Zonder Sent map zijn de gesprekken onvolledig, maar nog bruikbaar
+			log.Printf("[Threads] %s: Failed to fetch sent messages: %v", accountName, err)
+			continue
+		}
+		if s.config.Cache.Enabled {
+			cache.storeSent(sent)
+		}
+		emails = append(emails, sent...)
+	}
+	return emails, nil
+}
+
+// threadContainer is een knoop in de boom van een gesprek. Een container
+// zonder emails staat voor een bericht waarnaar verwezen wordt, maar dat we
+// (nog) niet hebben. Dezelfde email in meerdere accounts deelt één container.
+type threadContainer struct {
+	id       string
+	emails   []*models.Email
+	parent   *threadContainer
+	children []*threadContainer
+}
+
+// isAncestorOf geeft aan of c boven other in de boom staat (of other zelf is)
+func (c *threadContainer) isAncestorOf(other *threadContainer) bool {
+	for node := other; node != nil; node = node.parent {
+		if node == c {
+			return true
+		}
+	}
+	return false
+}
+
+// setParent verplaatst c naar een nieuwe parent (of naar de root bij nil)
+func (c *threadContainer) setParent(parent *threadContainer) {
+	if c.parent == parent {
+		return
+	}
+	if c.parent != nil {
+		siblings := c.parent.children
+		for i, sibling := range siblings {
+			if sibling == c {
+				c.parent.children = append(siblings[:i:i], siblings[i+1:]...)
+				break
+			}
+		}
+	}
+	c.parent = parent
+	if parent != nil {
+		parent.children = append(parent.children, c)
+	}
+}
+
+// subject geeft het onderwerp van de eerste email in of onder deze container
+func (c *threadContainer) subject() string {
+	if len(c.emails) > 0 {
+		return c.emails[0].Subject
+	}
+	for _, child := range c.children {
+		if subject := child.subject(); subject != "" {
+			return subject
+		}
+	}
+	return ""
+}
+
+// collect geeft alle emails in en onder deze container terug
+func (c *threadContainer) collect() []*models.Email {
+	emails := append([]*models.Email(nil), c.emails...)
+	for _, child := range c.children {
+		emails = append(emails, child.collect()...)
+	}
+	return emails
+}
+
+// BuildThreads groepeert emails tot gesprekken volgens het threading algoritme
+// van Jamie Zawinski (JWZ): berichten worden via Message-ID, In-Reply-To en
+// References aan elkaar gekoppeld, ook als tussenliggende berichten ontbreken.
+// Antwoorden zonder bruikbare headers ("Re: ...") worden op onderwerp bij het
+// oorspronkelijke gesprek gezet. Berichten met hetzelfde onderwerp die geen
+// antwoord zijn, zoals formuliermeldingen, blijven losse gesprekken.
+func BuildThreads(emails []*models.Email) []*models.EmailThread {
+	containers := make(map[string]*threadContainer)
+	var order []*threadContainer
+	container := func(id string) *threadContainer {
+		if c, ok := containers[id]; ok {
+			return c
+		}
+		c := &threadContainer{id: id}
+		containers[id] = c
+		order = append(order, c)
+		return c
+	}
+
+	// 1. Koppel elk bericht aan zijn voorgangers uit References en In-Reply-To
+	for _, email := range emails {
+		id := normalizeMessageID(email.MessageID)
+		if id == "" {
+			id = "email:" + email.ID
+		}
+		current := container(id)
+		current.emails = append(current.emails, email)
+
+		var previous *threadContainer
+		for _, ref := range messageReferences(email) {
+			if ref == id {
+				continue
+			}
+			next := container(ref)
+			if previous != nil && next.parent == nil && !next.isAncestorOf(previous) {
+				next.setParent(previous)
+			}
+			previous = next
+		}
+
+		// De headers van het bericht zelf zijn betrouwbaarder dan een parent die
+		// uit de References van een ander bericht afgeleid is
+		if previous == nil || !current.isAncestorOf(previous) {
+			current.setParent(previous)
+		}
+	}
+
+	// 2. Verwijder lege containers
+	var unpruned, roots []*threadContainer
+	for _, c := range order {
+		if c.parent == nil {
+			unpruned = append(unpruned, c)
+		}
+	}
+	for _, c := range unpruned {
+		roots = append(roots, pruneContainer(c, true)...)
+	}
+
+	// 3. Zet antwoorden zonder gekoppelde voorganger bij het laatste gesprek met
+	// hetzelfde onderwerp dat vóór het antwoord begon
+	originals := make(map[string][]*threadContainer)
+	for _, root := range roots {
+		if subject, isReply := baseSubject(root.subject()); subject != "" && !isReply {
+			originals[subject] = append(originals[subject], root)
+		}
+	}
+
+	merged := roots[:0]
+	for _, root := range roots {
+		subject, isReply := baseSubject(root.subject())
+		if !isReply {
+			merged = append(merged, root)
+			continue
+		}
+
+		var original *threadContainer
+		start := threadStart(root)
+		for _, candidate := range originals[subject] {
+			candidateStart := threadStart(candidate)
+			if !candidateStart.After(start) && (original == nil || candidateStart.After(threadStart(original))) {
+				original = candidate
+			}
+		}
+		if original == nil {
+			merged = append(merged, root)
+			continue
+		}
+		root.setParent(original)
+	}
+
+	// 4. Zet elke boom om naar een gesprek
+	threads := make([]*models.EmailThread, 0, len(merged))
+	for _, root := range merged {
+		threads = append(threads, newThread(root))
+	}
+
+	sort.SliceStable(threads, func(i, j int) bool {
+		return parseEmailTime(threads[i].LastMessageAt).After(parseEmailTime(threads[j].LastMessageAt))
+	})
+	return threads
+}
+
+// pruneContainer verwijdert lege containers onder c. Een lege container wordt
+// vervangen door zijn kinderen, behalve op het hoogste niveau met meerdere
+// kinderen: die container houdt de antwoorden op een ontbrekend bericht bij elkaar.
+func pruneContainer(c *threadContainer, root bool) []*threadContainer {
+	var children []*threadContainer
+	for _, child := range c.children {
+		children = append(children, pruneContainer(child, false)...)
+	}
+	c.children = nil
+	for _, child := range children {
+		child.parent = c
+		c.children = append(c.children, child)
+	}
+
+	if len(c.emails) > 0 || (root && len(children) > 1) {
+		return []*threadContainer{c}
+	}
+	for _, child := range children {
+		child.parent = c.parent
+	}
+	return children
+}
+
+// newThread maakt een gesprek van de emails in een boom
+func newThread(root *threadContainer) *models.EmailThread {
+	emails := root.collect()
+	sort.SliceStable(emails, func(i, j int) bool {
+		return parseEmailTime(emails[i].CreatedAt).Before(parseEmailTime(emails[j].CreatedAt))
+	})
+
+	hash := sha256.Sum256([]byte(root.id))
+	thread := &models.EmailThread{
+		ID:           hex.EncodeToString(hash[:8]),
+		MessageCount: len(emails),
+		Messages:     make([]*models.EmailSummary, 0, len(emails)),
+	}
+
+	participants := make(map[string]bool)
+	accounts := make(map[string]bool)
+	for _, email := range emails {
+		if thread.Subject == "" {
+			thread.Subject, _ = baseSubject(email.Subject)
+		}
+		if !email.Read {
+			thread.UnreadCount++
+		}
+		for _, address := range append(append([]string{email.Sender}, email.To...), email.Cc...) {
+			address = strings.ToLower(strings.TrimSpace(address))
+			if address != "" && !participants[address] {
+				participants[address] = true
+				thread.Participants = append(thread.Participants, address)
+			}
+		}
+		if email.Account != "" && !accounts[email.Account] {
+			accounts[email.Account] = true
+			thread.Accounts = append(thread.Accounts, email.Account)
+		}
+		thread.Messages = append(thread.Messages, email.ToSummary())
+	}
+	sort.Strings(thread.Accounts)
+
+	if len(thread.Messages) > 0 {
+		thread.Latest = thread.Messages[len(thread.Messages)-1]
+		thread.LastMessageAt = thread.Latest.CreatedAt
+	}
+	return thread
+}
+
+// threadStart geeft het tijdstip van het eerste bericht in een boom
+func threadStart(c *threadContainer) time.Time {
+	var start time.Time
+	for _, email := range c.collect() {
+		if t := parseEmailTime(email.CreatedAt); start.IsZero() || t.Before(start) {
+			start = t
+		}
+	}
+	return start
+}
+
+// messageReferences geeft de Message-IDs waar een email op reageert, oudste
+// eerst. In-Reply-To wordt achteraan gezet als die niet al in References staat.
+func messageReferences(email *models.Email) []string {
+	var refs []string
+	seen := make(map[string]bool)
+	for _, ref := range append(append([]string(nil), email.References...), email.InReplyTo) {
+		ref = normalizeMessageID(ref)
+		if ref != "" && !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// normalizeMessageID verwijdert punthaken en witruimte rond een Message-ID
+func normalizeMessageID(id string) string {
+	return strings.Trim(strings.TrimSpace(id), "<>")
+}
+
+// parseEmailTime leest een RFC3339 tijdstip van een email; ongeldige datums worden de nultijd
+func parseEmailTime(value string) time.Time {
+	t, _ := time.Parse(time.RFC3339, value)
+	return t
+}
@@ -0,0 +1,155 @@
+package email
+
+import (
+	"dklautomationgo/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// threadEmail maakt een email voor de threading tests
+func threadEmail(id, messageID, subject, createdAt string, references ...string) *models.Email {
+	email := &models.Email{
+		ID:         "info:INBOX:1:" + id,
+		Account:    "info",
+		Sender:     "sponsor@example.com",
+		Subject:    subject,
+		MessageID:  messageID,
+		CreatedAt:  createdAt,
+		References: references,
+	}
+	if len(references) > 0 {
+		email.InReplyTo = references[len(references)-1]
+	}
+	return email
+}
+
+// threadMessageIDs geeft de Message-IDs van de berichten in een gesprek, in volgorde
+func threadMessageIDs(thread *models.EmailThread) []string {
+	ids := make([]string, 0, len(thread.Messages))
+	for _, message := range thread.Messages {
+		ids = append(ids, message.MessageID)
+	}
+	return ids
+}
+
+func TestBuildThreadsReferences(t *testing.T) {
+	// Setup: een gesprek met een verzonden antwoord uit een ander account en een losse email
+	original := threadEmail("1", "<a@example.com>", "Sponsoring 2025", "2025-03-01T10:00:00Z")
+	reply := threadEmail("2", "<b@dkl>", "Re: Sponsoring 2025", "2025-03-01T12:00:00Z", "<a@example.com>")
+	reply.Account = "inschrijving"
+	reply.Folder = "Sent"
+	reply.Sender = "inschrijving@dekoninklijkeloop.nl"
+	reply.To = []string{"Sponsor@Example.com"}
+	reply.Read = true
+	answer := threadEmail("3", "<c@example.com>", "RE: Re: Sponsoring 2025", "2025-03-02T09:00:00Z", "<a@example.com>", "<b@dkl>")
+	other := threadEmail("4", "<d@example.com>", "Vrijwilligers", "2025-03-01T11:00:00Z")
+
+	// Test: de volgorde van binnenkomst maakt niet uit
+	threads := BuildThreads([]*models.Email{answer, other, reply, original})
+
+	// Controleer het resultaat
+	require.Len(t, threads, 2)
+	thread := threads[0]
+	assert.Equal(t, "Sponsoring 2025", thread.Subject)
+	assert.Equal(t, []string{"<a@example.com>", "<b@dkl>", "<c@example.com>"}, threadMessageIDs(thread))
+	assert.Equal(t, 3, thread.MessageCount)
+	assert.Equal(t, 2, thread.UnreadCount)
+	assert.Equal(t, []string{"info", "inschrijving"}, thread.Accounts)
+	assert.Equal(t, []string{"sponsor@example.com", "inschrijving@dekoninklijkeloop.nl"}, thread.Participants)
+	assert.Equal(t, "2025-03-02T09:00:00Z", thread.LastMessageAt)
+	assert.Equal(t, "<c@example.com>", thread.Latest.MessageID)
+	assert.Equal(t, []string{"<d@example.com>"}, threadMessageIDs(threads[1]))
+}
+
+func TestBuildThreadsMissingParent(t *testing.T) {
+	// Setup: twee antwoorden op een bericht dat niet in de mailbox staat
+	first := threadEmail("1", "<x@example.com>", "Re: Route", "2025-03-01T10:00:00Z", "<root@example.com>")
+	second := threadEmail("2", "<y@example.com>", "Re: Route", "2025-03-01T11:00:00Z", "<root@example.com>")
+
+	// Test
+	threads := BuildThreads([]*models.Email{first, second})
+
+	// Controleer het resultaat: één gesprek, met een ID dat van het ontbrekende bericht afhangt
+	require.Len(t, threads, 1)
+	assert.Equal(t, []string{"<x@example.com>", "<y@example.com>"}, threadMessageIDs(threads[0]))
+	assert.Equal(t, "Route", threads[0].Subject)
+
+	// Het ID blijft gelijk als het ontbrekende bericht later toch gevonden wordt
+	root := threadEmail("3", "<root@example.com>", "Route", "2025-03-01T09:00:00Z")
+	withRoot := BuildThreads([]*models.Email{first, second, root})
+	require.Len(t, withRoot, 1)
+	assert.Equal(t, threads[0].ID, withRoot[0].ID)
+	assert.Equal(t, 3, withRoot[0].MessageCount)
+}
+
+func TestBuildThreadsSubjectFallback(t *testing.T) {
+	// Setup: een antwoord zonder In-Reply-To en References, en twee formuliermeldingen met hetzelfde onderwerp
+	question := threadEmail("1", "<q@example.com>", "Vraag over parkeren", "2025-03-01T10:00:00Z")
+	reply := threadEmail("2", "<r@example.com>", "Antw: Vraag over  parkeren", "2025-03-01T12:00:00Z")
+	formA := threadEmail("3", "<f1@dkl>", "Nieuw contactformulier", "2025-03-01T10:00:00Z")
+	formB := threadEmail("4", "<f2@dkl>", "Nieuw contactformulier", "2025-03-01T11:00:00Z")
+
+	// Test
+	threads := BuildThreads([]*models.Email{question, reply, formA, formB})
+
+	// Controleer het resultaat
+	require.Len(t, threads, 3)
+	assert.Equal(t, []string{"<q@example.com>", "<r@example.com>"}, threadMessageIDs(threads[0]))
+	assert.Equal(t, []string{"<f2@dkl>"}, threadMessageIDs(threads[1]))
+	assert.Equal(t, []string{"<f1@dkl>"}, threadMessageIDs(threads[2]))
+}
+
+func TestBuildThreadsReferenceLoop(t *testing.T) {
+	// Setup: berichten die (foutief) naar elkaar verwijzen, en een bericht zonder Message-ID
+	a := threadEmail("1", "<a@example.com>", "Lus", "2025-03-01T10:00:00Z", "<b@example.com>")
+	b := threadEmail("2", "<b@example.com>", "Re: Lus", "2025-03-01T11:00:00Z", "<a@example.com>")
+	anonymous := threadEmail("3", "", "Zonder ID", "2025-03-01T12:00:00Z")
+
+	// Test
+	threads := BuildThreads([]*models.Email{a, b, anonymous})
+
+	// Controleer het resultaat: geen oneindige lus en geen verloren berichten
+	require.Len(t, threads, 2)
+	assert.Equal(t, []string{""}, threadMessageIDs(threads[0]))
+	assert.Equal(t, []string{"<a@example.com>", "<b@example.com>"}, threadMessageIDs(threads[1]))
+}
+
+func TestBaseSubject(t *testing.T) {
+	tests := []struct {
+		subject string
+		base    string
+		isReply bool
+	}{
+		{"Sponsoring", "Sponsoring", false},
+		{"Re: Sponsoring", "Sponsoring", true},
+		{"RE: Fwd: re:Sponsoring", "Sponsoring", true},
+		{"Re[2]: Sponsoring", "Sponsoring", true},
+		{"Antw: Doorst: Sponsoring", "Sponsoring", true},
+		{"Regels voor deelname", "Regels voor deelname", false},
+	}
+
+	for _, tt := range tests {
+		base, isReply := baseSubject(tt.subject)
+		assert.Equal(t, tt.base, base, tt.subject)
+		assert.Equal(t, tt.isReply, isReply, tt.subject)
+	}
+}
+
+func TestFetchThreads_UsesCachedSentMessages(t *testing.T) {
+	// Setup: de INBOX en de Sent map staan in de cache, de IMAP server is niet ingesteld
+	question := threadEmail("1", "<a@example.com>", "Sponsoring", "2025-03-01T10:00:00Z")
+	service, _, _ := newTestMetadataService(question)
+	reply := threadEmail("2", "<b@dkl>", "Re: Sponsoring", "2025-03-01T12:00:00Z", "<a@example.com>")
+	reply.Folder = "Sent"
+	service.cache("info").storeSent([]*models.Email{reply})
+
+	// Test: een negatieve offset telt als 0
+	threads, err := service.FetchThreads(&models.EmailFetchOptions{Account: "info", Offset: -1})
+
+	// Controleer het resultaat
+	require.NoError(t, err)
+	require.Len(t, threads, 1)
+	assert.Equal(t, 2, threads[0].MessageCount)
+}
@@ -0,0 +1,334 @@
+package email
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+// StartWatching start voor elk account met credentials een langlevende IMAP
+// verbinding die via IDLE (of NOOP polling als fallback) nieuwe berichten en
+// flag-wijzigingen in de INBOX detecteert. De watchers stoppen zodra ctx afloopt.
+func (s *EmailService) StartWatching(ctx context.Context) {
+	if !s.config.Watch.Enabled {
+		log.Printf("[EmailWatcher] Watchers disabled by configuration")
+		return
+	}
+
+	s.watchMu.Lock()
+	s.watchCtx = ctx
+	s.watchMu.Unlock()
+
+	for accountName, config := range s.accountConfigs() {
+		s.startWatcher(accountName, config)
+	}
+}
+
+// startWatcher start de watcher van een account, of herstart hem met een nieuwe
+// configuratie. Voordat StartWatching aangeroepen is doet dit niets.
+func (s *EmailService) startWatcher(accountName string, config *EmailConfig) {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+
+	if s.watchCtx == nil {
+		return
+	}
+	if cancel := s.watchers[accountName]; cancel != nil {
+		cancel()
+		delete(s.watchers, accountName)
+	}
+	if config.Email == "" || config.Password == "" {
+		log.Printf("[EmailWatcher] %s: no credentials configured, not watching", accountName)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(s.watchCtx)
+	if s.watchers == nil {
+		s.watchers = make(map[string]context.CancelFunc)
+	}
+	s.watchers[accountName] = cancel
+	go s.watchAccount(ctx, accountName, config)
+}
+
+// stopWatcher stopt de watcher van een account, bijvoorbeeld als het account verwijderd is
+func (s *EmailService) stopWatcher(accountName string) {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+
+	if cancel := s.watchers[accountName]; cancel != nil {
+		cancel()
+		delete(s.watchers, accountName)
+	}
+}
+
+// watchAccount houdt een watch sessie voor een account in stand en maakt na
+// een verbroken verbinding opnieuw verbinding met exponentiële backoff
+func (s *EmailService) watchAccount(ctx context.Context, accountName string, config *EmailConfig) {
+	delay := s.config.Watch.ReconnectDelay
+
+	for {
+		established, err := s.runWatchSession(ctx, accountName, config)
+		s.cache(accountName).setWatched(false)
+
+		if ctx.Err() != nil {
+			log.Printf("[EmailWatcher] %s: stopped", accountName)
+			return
+		}
+		if !established {
+			// Verbinden of inloggen mislukt; zichtbaar in /api/emails/accounts
+			s.statuses.recordFailure(accountName, err)
+		}
+
+		if established {
+			delay = s.config.Watch.ReconnectDelay
+		}
+		log.Printf("[EmailWatcher] %s: session ended: %v, reconnecting in %v", accountName, err, delay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > s.config.Watch.MaxReconnectDelay {
+			delay = s.config.Watch.MaxReconnectDelay
+		}
+	}
+}
+
+// runWatchSession voert één watch sessie uit. De bool geeft aan of de sessie
+// succesvol opgezet was, zodat de backoff gereset kan worden.
+func (s *EmailService) runWatchSession(ctx context.Context, accountName string, config *EmailConfig) (bool, error) {
+	// Een eigen verbinding buiten de pool, want IDLE houdt hem de hele sessie bezet
+	c, err := dialIMAP(config)
+	if err != nil {
+		return false, err
+	}
+	defer c.Logout()
+
+	// De client blokkeert op het Updates kanaal, dus ruim bufferen
+	updates := make(chan client.Update, 100)
+	c.Updates = updates
+
+	// Read-only selecteren zodat de watcher nooit flags wijzigt
+	mbox, err := c.Select(inboxFolder, true)
+	if err != nil {
+		return false, fmt.Errorf("IMAP select inbox failed: %w", err)
+	}
+
+	mailbox := mailboxRef{account: accountName, folder: inboxFolder, uidValidity: mbox.UidValidity}
+	if s.cache(accountName).checkUIDValidity(mbox.UidValidity) {
+		// De server heeft de INBOX opnieuw genummerd; alle bekende IDs zijn ongeldig
+		log.Printf("[EmailWatcher] %s: UIDVALIDITY changed to %d, cache invalidated", accountName, mbox.UidValidity)
+		s.publish(MailboxEvent{Type: MailboxEventExpunged, Account: accountName})
+	}
+
+	known := mbox.Messages
+	s.cache(accountName).setWatched(true)
+	s.statuses.recordCounts(accountName, mbox.Messages, nil)
+	s.statuses.recordSuccess(accountName)
+	log.Printf("[EmailWatcher] %s: watching INBOX (%d messages)", accountName, known)
+
+	for {
+		stop := make(chan struct{})
+		idleDone := make(chan error, 1)
+		go func() {
+			idleDone <- c.Idle(stop, &client.IdleOptions{PollInterval: s.config.Watch.PollInterval})
+		}()
+
+		var pending []client.Update
+		select {
+		case <-ctx.Done():
+			close(stop)
+			<-idleDone
+			return true, ctx.Err()
+		case err := <-idleDone:
+			return true, fmt.Errorf("IMAP idle failed: %w", err)
+		case update := <-updates:
+			pending = append(pending, update)
+		}
+
+		close(stop)
+		if err := <-idleDone; err != nil {
+			return true, fmt.Errorf("IMAP idle failed: %w", err)
+		}
+
+		// Verzamel updates die tegelijk binnenkwamen
+		pending = drainUpdates(updates, pending)
+
+		if err := s.handleWatchUpdates(c, mailbox, &known, pending); err != nil {
+			return true, err
+		}
+	}
+}
+
+// drainUpdates haalt alle direct beschikbare updates van het kanaal
+func drainUpdates(updates <-chan client.Update, pending []client.Update) []client.Update {
+	for {
+		select {
+		case update := <-updates:
+			pending = append(pending, update)
+		default:
+			return pending
+		}
+	}
+}
+
+// handleWatchUpdates verwerkt een batch unilaterale IMAP updates: nieuwe
+// berichten worden opgehaald, flag-wijzigingen en expunges bijgewerkt in de cache
+func (s *EmailService) handleWatchUpdates(c *client.Client, mailbox mailboxRef, known *uint32, updates []client.Update) error {
+	accountName := mailbox.account
+	cache := s.cache(accountName)
+
+	var exists uint32
+	sawExists := false
+	expunged := 0
+	var flagMessages []*imap.Message
+
+	for _, update := range updates {
+		switch u := update.(type) {
+		case *client.MailboxUpdate:
+			if u.Mailbox != nil && u.Mailbox.UidValidity != 0 && u.Mailbox.UidValidity != mailbox.uidValidity {
+				// Opnieuw verbinden zodat de sessie met de nieuwe UIDVALIDITY start
+				return fmt.Errorf("UIDVALIDITY changed from %d to %d", mailbox.uidValidity, u.Mailbox.UidValidity)
+			}
+			if u.Mailbox != nil {
+				exists = u.Mailbox.Messages
+				sawExists = true
+			}
+		case *client.ExpungeUpdate:
+			expunged++
+			if *known > 0 {
+				*known--
+			}
+		case *client.MessageUpdate:
+			flagMessages = append(flagMessages, u.Message)
+		}
+	}
+
+	if expunged > 0 {
+		// Sequence nummers zijn verschoven; laat de volgende fetch de cache opnieuw vullen
+		cache.invalidate()
+		s.publish(MailboxEvent{Type: MailboxEventExpunged, Account: accountName})
+		log.Printf("[EmailWatcher] %s: %d messages expunged", accountName, expunged)
+	}
+
+	if len(flagMessages) > 0 {
+		if err := s.handleFlagUpdates(c, mailbox, flagMessages); err != nil {
+			return err
+		}
+	}
+
+	if sawExists && exists > *known {
+		if err := s.fetchNewMessages(c, mailbox, *known+1, exists); err != nil {
+			return err
+		}
+	}
+	if sawExists {
+		*known = exists
+	}
+
+	return nil
+}
+
+// fetchNewMessages haalt de berichten in het opgegeven sequence bereik op,
+// voegt ze toe aan de cache en meldt ze aan subscribers
+func (s *EmailService) fetchNewMessages(c *client.Client, mailbox mailboxRef, from, to uint32) error {
+	accountName := mailbox.account
+	seqSet := new(imap.SeqSet)
+	seqSet.AddRange(from, to)
+
+	messages := make(chan *imap.Message, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Fetch(seqSet, messageFetchItems, messages)
+	}()
+
+	emails := make([]*models.Email, 0)
+	for msg := range messages {
+		email, err := s.processMessage(msg, mailbox)
+		if err != nil {
+			log.Printf("[EmailWatcher] %s: failed to process new message %d: %v", accountName, msg.SeqNum, err)
+			continue
+		}
+		emails = append(emails, email)
+	}
+
+	if err := <-done; err != nil {
+		return fmt.Errorf("IMAP fetch of new messages failed: %w", err)
+	}
+
+	if s.config.Cache.Enabled {
+		s.cache(accountName).add(emails)
+	}
+	s.notifyIncoming(emails)
+
+	for _, email := range emails {
+		s.publish(MailboxEvent{
+			Type:    MailboxEventNewMessage,
+			Account: accountName,
+			EmailID: email.ID,
+			Email:   email.ToSummary(),
+		})
+	}
+
+	log.Printf("[EmailWatcher] %s: %d new messages", accountName, len(emails))
+	return nil
+}
+
+// handleFlagUpdates verwerkt gewijzigde flags. Servers sturen niet altijd de UID
+// mee, in dat geval wordt deze alsnog opgehaald om het bericht te identificeren.
+func (s *EmailService) handleFlagUpdates(c *client.Client, mailbox mailboxRef, updated []*imap.Message) error {
+	accountName := mailbox.account
+	withoutUID := new(imap.SeqSet)
+	var resolved []*imap.Message
+
+	for _, msg := range updated {
+		if msg.Uid != 0 {
+			resolved = append(resolved, msg)
+		} else {
+			withoutUID.AddNum(msg.SeqNum)
+		}
+	}
+
+	if !withoutUID.Empty() {
+		messages := make(chan *imap.Message, 10)
+		done := make(chan error, 1)
+		go func() {
+			done <- c.Fetch(withoutUID, []imap.FetchItem{imap.FetchUid, imap.FetchFlags}, messages)
+		}()
+		for msg := range messages {
+			resolved = append(resolved, msg)
+		}
+		if err := <-done; err != nil {
+			return fmt.Errorf("IMAP fetch of flags failed: %w", err)
+		}
+	}
+
+	cache := s.cache(accountName)
+	for _, msg := range resolved {
+		emailID := mailbox.emailID(msg.Uid)
+		read := hasFlag(msg.Flags, imap.SeenFlag)
+		flagged := hasFlag(msg.Flags, imap.FlaggedFlag)
+		cache.update(emailID, func(email *models.Email) {
+			email.Read = read
+			email.Flagged = flagged
+		})
+
+		s.publish(MailboxEvent{
+			Type:    MailboxEventFlagsChanged,
+			Account: accountName,
+			EmailID: emailID,
+			Read:    read,
+			Flagged: flagged,
+		})
+	}
+
+	return nil
+}
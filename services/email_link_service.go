@@ -0,0 +1,136 @@
+package services
+
+import (
+	"dklautomationgo/database/repository"
+	"dklautomationgo/models"
+	"dklautomationgo/services/email"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// IEmailLinkService definieert de interface voor het koppelen van emails aan records
+type IEmailLinkService interface {
+	LinkEmail(email *models.Email) ([]*models.EmailLink, error)
+	GetLinksForRecord(recordType, recordID string) ([]*models.EmailLink, error)
+	GetLinksForEmail(emailID string) ([]*models.EmailLink, error)
+}
+
+// Controleer of EmailLinkService de IEmailLinkService en IncomingEmailHandler interfaces implementeert
+var (
+	_ IEmailLinkService          = (*EmailLinkService)(nil)
+	_ email.IncomingEmailHandler = (*EmailLinkService)(nil)
+)
+
+// EmailLinkService koppelt inkomende emails aan contactformulieren en aanmeldingen
+type EmailLinkService struct {
+	links        repository.IEmailLinkRepository
+	contacts     repository.IContactRepository
+	aanmeldingen repository.IAanmeldingRepository
+}
+
+// NewEmailLinkService maakt een nieuwe EmailLinkService
+func NewEmailLinkService(links repository.IEmailLinkRepository, contacts repository.IContactRepository, aanmeldingen repository.IAanmeldingRepository) *EmailLinkService {
+	return &EmailLinkService{
+		links:        links,
+		contacts:     contacts,
+		aanmeldingen: aanmeldingen,
+	}
+}
+
+// HandleIncomingEmail koppelt een nieuwe email uit de INBOX
+func (s *EmailLinkService) HandleIncomingEmail(msg *models.Email) {
+	links, err := s.LinkEmail(msg)
+	if err != nil {
+		log.Printf("[EmailLinkService] Failed to link email %s: %v", msg.ID, err)
+		return
+	}
+	if len(links) > 0 {
+		log.Printf("[EmailLinkService] Linked email %s to %d record(s)", msg.ID, len(links))
+	}
+}
+
+// LinkEmail zoekt de contactformulieren en aanmeldingen waar een email bij hoort
+// en slaat de koppelingen op. Een antwoord op een bevestigingsmail wordt via de
+// Message-ID in In-Reply-To of References aan dat record gekoppeld; daarnaast
+// worden alle records met het email adres van de afzender gekoppeld.
+func (s *EmailLinkService) LinkEmail(msg *models.Email) ([]*models.EmailLink, error) {
+	var links []*models.EmailLink
+	seen := make(map[string]bool)
+	add := func(recordType, recordID, matchedBy string) {
+		key := recordType + ":" + recordID
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		links = append(links, &models.EmailLink{
+			CreatedAt:  time.Now(),
+			EmailID:    msg.ID,
+			MessageID:  msg.MessageID,
+			Account:    msg.Account,
+			Sender:     msg.Sender,
+			Subject:    msg.Subject,
+			ReceivedAt: msg.CreatedAt,
+			RecordType: recordType,
+			RecordID:   recordID,
+			MatchedBy:  matchedBy,
+		})
+	}
+
+	// Eigen thread headers gaan voor, die wijzen precies het juiste record aan
+	for _, ref := range append([]string{msg.InReplyTo}, msg.References...) {
+		if recordType, recordID, ok := email.ParseRecordMessageID(ref); ok {
+			add(recordType, recordID, models.EmailLinkMatchedByThread)
+		}
+	}
+
+	sender := strings.TrimSpace(msg.Sender)
+	if sender != "" {
+		contacts, err := s.contacts.FindByEmail(sender)
+		if err != nil {
+			return nil, fmt.Errorf("fout bij zoeken contactformulieren: %w", err)
+		}
+		for _, contact := range contacts {
+			add(models.EmailLinkContact, contact.ID, models.EmailLinkMatchedBySender)
+		}
+
+		aanmeldingen, err := s.aanmeldingen.FindByEmail(sender)
+		if err != nil {
+			return nil, fmt.Errorf("fout bij zoeken aanmeldingen: %w", err)
+		}
+		for _, aanmelding := range aanmeldingen {
+			add(models.EmailLinkAanmelding, aanmelding.ID, models.EmailLinkMatchedBySender)
+		}
+	}
+
+	// Een koppeling die niet opgeslagen kan worden, bijvoorbeeld naar een
+	// verwijderd record, houdt de andere koppelingen niet tegen
+	created := make([]*models.EmailLink, 0, len(links))
+	for _, link := range links {
+		if err := s.links.Create(link); err != nil {
+			log.Printf("[EmailLinkService] Failed to link email %s to %s %s: %v", link.EmailID, link.RecordType, link.RecordID, err)
+			continue
+		}
+		created = append(created, link)
+	}
+	return created, nil
+}
+
+// GetLinksForRecord haalt de emails op die aan een contactformulier of aanmelding gekoppeld zijn
+func (s *EmailLinkService) GetLinksForRecord(recordType, recordID string) ([]*models.EmailLink, error) {
+	links, err := s.links.FindByRecord(recordType, recordID)
+	if err != nil {
+		return nil, fmt.Errorf("fout bij ophalen gekoppelde emails: %w", err)
+	}
+	return links, nil
+}
+
+// GetLinksForEmail haalt de records op waaraan een email gekoppeld is
+func (s *EmailLinkService) GetLinksForEmail(emailID string) ([]*models.EmailLink, error) {
+	links, err := s.links.FindByEmailID(emailID)
+	if err != nil {
+		return nil, fmt.Errorf("fout bij ophalen gekoppelde records: %w", err)
+	}
+	return links, nil
+}
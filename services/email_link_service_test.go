@@ -0,0 +1,252 @@
+package services_test
+
+import (
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockContactRepository is een mock implementatie van de IContactRepository interface
+type MockContactRepository struct {
+	mock.Mock
+}
+
+// Create is een mock implementatie van de Create methode
+func (m *MockContactRepository) Create(contact *models.ContactFormulier) error {
+	args := m.Called(contact)
+	return args.Error(0)
+}
+
+// CreateFromSource is een mock implementatie van de CreateFromSource methode
+func (m *MockContactRepository) CreateFromSource(contact *models.ContactFormulier) (bool, error) {
+	args := m.Called(contact)
+	return args.Bool(0), args.Error(1)
+}
+
+// FindByID is een mock implementatie van de FindByID methode
+func (m *MockContactRepository) FindByID(id string) (*models.ContactFormulier, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ContactFormulier), args.Error(1)
+}
+
+// FindByEmail is een mock implementatie van de FindByEmail methode
+func (m *MockContactRepository) FindByEmail(email string) ([]*models.ContactFormulier, error) {
+	args := m.Called(email)
+	if args.Get(0) == nil {
+		return []*models.ContactFormulier{}, args.Error(1)
+	}
+	return args.Get(0).([]*models.ContactFormulier), args.Error(1)
+}
+
+// FindAll is een mock implementatie van de FindAll methode
+func (m *MockContactRepository) FindAll(limit, offset int) ([]*models.ContactFormulier, error) {
+	args := m.Called(limit, offset)
+	if args.Get(0) == nil {
+		return []*models.ContactFormulier{}, args.Error(1)
+	}
+	return args.Get(0).([]*models.ContactFormulier), args.Error(1)
+}
+
+// Update is een mock implementatie van de Update methode
+func (m *MockContactRepository) Update(contact *models.ContactFormulier) error {
+	args := m.Called(contact)
+	return args.Error(0)
+}
+
+// MarkBounced is een mock implementatie van de MarkBounced methode
+func (m *MockContactRepository) MarkBounced(id string, bouncedAt time.Time, reason string) error {
+	args := m.Called(id, bouncedAt, reason)
+	return args.Error(0)
+}
+
+// Count is een mock implementatie van de Count methode
+func (m *MockContactRepository) Count() (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// MockEmailLinkRepository is een mock implementatie van de IEmailLinkRepository interface
+type MockEmailLinkRepository struct {
+	mock.Mock
+}
+
+// Create is een mock implementatie van de Create methode
+func (m *MockEmailLinkRepository) Create(link *models.EmailLink) error {
+	args := m.Called(link)
+	return args.Error(0)
+}
+
+// FindByRecord is een mock implementatie van de FindByRecord methode
+func (m *MockEmailLinkRepository) FindByRecord(recordType, recordID string) ([]*models.EmailLink, error) {
+	args := m.Called(recordType, recordID)
+	if args.Get(0) == nil {
+		return []*models.EmailLink{}, args.Error(1)
+	}
+	return args.Get(0).([]*models.EmailLink), args.Error(1)
+}
+
+// FindByEmailID is een mock implementatie van de FindByEmailID methode
+func (m *MockEmailLinkRepository) FindByEmailID(emailID string) ([]*models.EmailLink, error) {
+	args := m.Called(emailID)
+	if args.Get(0) == nil {
+		return []*models.EmailLink{}, args.Error(1)
+	}
+	return args.Get(0).([]*models.EmailLink), args.Error(1)
+}
+
+// setupEmailLinkServiceTest maakt een EmailLinkService met mock repositories
+func setupEmailLinkServiceTest() (*services.EmailLinkService, *MockEmailLinkRepository, *MockContactRepository, *MockAanmeldingRepository) {
+	linkRepo := new(MockEmailLinkRepository)
+	contactRepo := new(MockContactRepository)
+	aanmeldingRepo := new(MockAanmeldingRepository)
+	service := services.NewEmailLinkService(linkRepo, contactRepo, aanmeldingRepo)
+	return service, linkRepo, contactRepo, aanmeldingRepo
+}
+
+func TestLinkEmail_ThreadMatch(t *testing.T) {
+	// Setup
+	service, linkRepo, contactRepo, aanmeldingRepo := setupEmailLinkServiceTest()
+	incoming := &models.Email{
+		ID:         "info:INBOX:1:42",
+		Account:    "info",
+		Sender:     "ander@example.com",
+		Subject:    "Re: Bedankt voor je bericht",
+		InReplyTo:  "<dkl.contact.11111111-2222-3333-4444-555555555555.1700000000@dekoninklijkeloop.nl>",
+		References: []string{"<dkl.contact.11111111-2222-3333-4444-555555555555.1700000000@dekoninklijkeloop.nl>"},
+	}
+
+	// Mock verwachtingen
+	contactRepo.On("FindByEmail", "ander@example.com").Return(nil, nil)
+	aanmeldingRepo.On("FindByEmail", "ander@example.com").Return(nil, nil)
+	linkRepo.On("Create", mock.AnythingOfType("*models.EmailLink")).Return(nil).Once()
+
+	// Voer de test uit
+	links, err := service.LinkEmail(incoming)
+
+	// Controleer het resultaat
+	assert.NoError(t, err)
+	assert.Len(t, links, 1)
+	assert.Equal(t, models.EmailLinkContact, links[0].RecordType)
+	assert.Equal(t, "11111111-2222-3333-4444-555555555555", links[0].RecordID)
+	assert.Equal(t, models.EmailLinkMatchedByThread, links[0].MatchedBy)
+	assert.Equal(t, "info:INBOX:1:42", links[0].EmailID)
+	linkRepo.AssertExpectations(t)
+}
+
+func TestLinkEmail_SenderMatch(t *testing.T) {
+	// Setup
+	service, linkRepo, contactRepo, aanmeldingRepo := setupEmailLinkServiceTest()
+	incoming := &models.Email{ID: "info:INBOX:1:43", Account: "info", Sender: "jan@example.com"}
+
+	// Mock verwachtingen
+	contactRepo.On("FindByEmail", "jan@example.com").Return([]*models.ContactFormulier{{ID: "contact-1"}}, nil)
+	aanmeldingRepo.On("FindByEmail", "jan@example.com").Return([]*models.Aanmelding{{ID: "22222222-3333-4444-5555-666666666666"}}, nil)
+	linkRepo.On("Create", mock.AnythingOfType("*models.EmailLink")).Return(nil).Twice()
+
+	// Voer de test uit
+	links, err := service.LinkEmail(incoming)
+
+	// Controleer het resultaat
+	assert.NoError(t, err)
+	assert.Len(t, links, 2)
+	assert.Equal(t, models.EmailLinkContact, links[0].RecordType)
+	assert.Equal(t, models.EmailLinkAanmelding, links[1].RecordType)
+	assert.Equal(t, models.EmailLinkMatchedBySender, links[1].MatchedBy)
+	linkRepo.AssertExpectations(t)
+}
+
+func TestLinkEmail_ThreadMatchTakesPrecedence(t *testing.T) {
+	// Setup
+	service, linkRepo, contactRepo, aanmeldingRepo := setupEmailLinkServiceTest()
+	incoming := &models.Email{
+		ID:        "info:INBOX:1:44",
+		Sender:    "jan@example.com",
+		InReplyTo: "<dkl.aanmelding.22222222-3333-4444-5555-666666666666.1700000000@dekoninklijkeloop.nl>",
+	}
+
+	// Mock verwachtingen
+	contactRepo.On("FindByEmail", "jan@example.com").Return(nil, nil)
+	aanmeldingRepo.On("FindByEmail", "jan@example.com").Return([]*models.Aanmelding{{ID: "22222222-3333-4444-5555-666666666666"}}, nil)
+	linkRepo.On("Create", mock.AnythingOfType("*models.EmailLink")).Return(nil).Once()
+
+	// Voer de test uit
+	links, err := service.LinkEmail(incoming)
+
+	// Controleer het resultaat
+	assert.NoError(t, err)
+	assert.Len(t, links, 1)
+	assert.Equal(t, models.EmailLinkMatchedByThread, links[0].MatchedBy)
+	linkRepo.AssertExpectations(t)
+}
+
+func TestLinkEmail_NoMatch(t *testing.T) {
+	// Setup
+	service, linkRepo, contactRepo, aanmeldingRepo := setupEmailLinkServiceTest()
+	incoming := &models.Email{ID: "info:INBOX:1:45", Sender: "onbekend@example.com", InReplyTo: "<abc@mail.example.com>"}
+
+	// Mock verwachtingen
+	contactRepo.On("FindByEmail", "onbekend@example.com").Return(nil, nil)
+	aanmeldingRepo.On("FindByEmail", "onbekend@example.com").Return(nil, nil)
+
+	// Voer de test uit
+	links, err := service.LinkEmail(incoming)
+
+	// Controleer het resultaat
+	assert.NoError(t, err)
+	assert.Empty(t, links)
+	linkRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestLinkEmail_SkipsFailingLink(t *testing.T) {
+	// Setup: het record uit de thread headers bestaat niet meer
+	service, linkRepo, contactRepo, aanmeldingRepo := setupEmailLinkServiceTest()
+	incoming := &models.Email{
+		ID:        "info:INBOX:1:47",
+		Sender:    "jan@example.com",
+		InReplyTo: "<dkl.contact.33333333-4444-5555-6666-777777777777.1700000000@dekoninklijkeloop.nl>",
+	}
+
+	// Mock verwachtingen
+	contactRepo.On("FindByEmail", "jan@example.com").Return(nil, nil)
+	aanmeldingRepo.On("FindByEmail", "jan@example.com").Return([]*models.Aanmelding{{ID: "22222222-3333-4444-5555-666666666666"}}, nil)
+	linkRepo.On("Create", mock.MatchedBy(func(link *models.EmailLink) bool {
+		return link.RecordType == models.EmailLinkContact
+	})).Return(errors.New("foreign key violation")).Once()
+	linkRepo.On("Create", mock.MatchedBy(func(link *models.EmailLink) bool {
+		return link.RecordType == models.EmailLinkAanmelding
+	})).Return(nil).Once()
+
+	// Voer de test uit
+	links, err := service.LinkEmail(incoming)
+
+	// Controleer het resultaat
+	assert.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, models.EmailLinkAanmelding, links[0].RecordType)
+	linkRepo.AssertExpectations(t)
+}
+
+func TestLinkEmail_RepositoryError(t *testing.T) {
+	// Setup
+	service, _, contactRepo, _ := setupEmailLinkServiceTest()
+	incoming := &models.Email{ID: "info:INBOX:1:46", Sender: "jan@example.com"}
+
+	// Mock verwachtingen
+	contactRepo.On("FindByEmail", "jan@example.com").Return(nil, errors.New("database error"))
+
+	// Voer de test uit
+	links, err := service.LinkEmail(incoming)
+
+	// Controleer het resultaat
+	assert.Error(t, err)
+	assert.Nil(t, links)
+}
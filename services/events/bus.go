@@ -0,0 +1,122 @@
+package events
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventType beschrijft het soort gebeurtenis op de event bus
+type EventType string
+
+const (
+	EventContactCreated    EventType = "contact.created"
+	EventAanmeldingCreated EventType = "aanmelding.created"
+	EventEmailReceived     EventType = "email.received"
+	EventEmailUpdated      EventType = "email.updated"
+	EventEmailRemoved      EventType = "email.removed"
+)
+
+// Event is een gebeurtenis die naar subscribers wordt verstuurd
+type Event struct {
+	ID        string      `json:"id"`
+	Type      EventType   `json:"type"`
+	Data      interface{} `json:"data"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// Publisher definieert de interface voor componenten die events publiceren
+type Publisher interface {
+	Publish(eventType EventType, data interface{})
+}
+
+// Controleer of Bus de Publisher interface implementeert
+var _ Publisher = (*Bus)(nil)
+
+// subscriptionBuffer is de buffergrootte per subscriber
+const subscriptionBuffer = 64
+
+// Bus is een in-process event bus die events naar alle subscribers verstuurt
+type Bus struct {
+	mu            sync.RWMutex
+	subscriptions map[int]*Subscription
+	nextID        int
+	sequence      uint64
+}
+
+// Subscription is een registratie op de bus. Events komen binnen op C.
+type Subscription struct {
+	C      <-chan Event
+	ch     chan Event
+	id     int
+	filter func(Event) bool
+	bus    *Bus
+	once   sync.Once
+}
+
+// NewBus maakt een nieuwe Bus
+func NewBus() *Bus {
+	return &Bus{
+		subscriptions: make(map[int]*Subscription),
+	}
+}
+
+// Publish verstuurt een event naar alle subscribers waarvan het filter het event
+// toelaat. Trage subscribers blokkeren de publisher niet; voor hen wordt het event
+// overgeslagen.
+func (b *Bus) Publish(eventType EventType, data interface{}) {
+	event := Event{
+		ID:        fmt.Sprintf("%d", atomic.AddUint64(&b.sequence, 1)),
+		Type:      eventType,
+		Data:      data,
+		Timestamp: time.Now(),
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for id, sub := range b.subscriptions {
+		if sub.filter != nil && !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			log.Printf("[EventBus] Subscriber %d is not keeping up, dropping %s event", id, event.Type)
+		}
+	}
+}
+
+// Subscribe registreert een nieuwe subscriber. Met een nil filter worden alle
+// events ontvangen.
+func (b *Bus) Subscribe(filter func(Event) bool) *Subscription {
+	ch := make(chan Event, subscriptionBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	sub := &Subscription{
+		C:      ch,
+		ch:     ch,
+		id:     b.nextID,
+		filter: filter,
+		bus:    b,
+	}
+	b.subscriptions[sub.id] = sub
+
+	return sub
+}
+
+// Close meldt de subscriber af en sluit het kanaal
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		defer s.bus.mu.Unlock()
+
+		delete(s.bus.subscriptions, s.id)
+		close(s.ch)
+	})
+}
@@ -0,0 +1,85 @@
+package events
+
+import (
+	"dklautomationgo/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func receive(t *testing.T, sub *Subscription) (Event, bool) {
+	t.Helper()
+	select {
+	case event := <-sub.C:
+		return event, true
+	case <-time.After(100 * time.Millisecond):
+		return Event{}, false
+	}
+}
+
+func TestPublish_DeliversToSubscribers(t *testing.T) {
+	// Setup
+	bus := NewBus()
+	sub := bus.Subscribe(nil)
+	defer sub.Close()
+
+	// Test
+	bus.Publish(EventContactCreated, "data")
+
+	// Assertions
+	event, ok := receive(t, sub)
+	assert.True(t, ok)
+	assert.Equal(t, EventContactCreated, event.Type)
+	assert.Equal(t, "data", event.Data)
+	assert.NotEmpty(t, event.ID)
+}
+
+func TestRoleFilter_HidesEventsForRole(t *testing.T) {
+	// Setup
+	bus := NewBus()
+	beheerder := bus.Subscribe(RoleFilter(models.RoleBeheerder))
+	defer beheerder.Close()
+	vrijwilliger := bus.Subscribe(RoleFilter(models.RoleVrijwilliger))
+	defer vrijwilliger.Close()
+
+	// Test
+	bus.Publish(EventAanmeldingCreated, nil)
+
+	// Assertions
+	_, ok := receive(t, beheerder)
+	assert.True(t, ok)
+	_, ok = receive(t, vrijwilliger)
+	assert.False(t, ok)
+}
+
+func TestRoleFilter_RestrictsToRequestedTypes(t *testing.T) {
+	// Setup
+	bus := NewBus()
+	sub := bus.Subscribe(RoleFilter(models.RoleAdmin, EventEmailReceived))
+	defer sub.Close()
+
+	// Test
+	bus.Publish(EventContactCreated, nil)
+	bus.Publish(EventEmailReceived, nil)
+
+	// Assertions
+	event, ok := receive(t, sub)
+	assert.True(t, ok)
+	assert.Equal(t, EventEmailReceived, event.Type)
+}
+
+func TestSubscriptionClose_StopsDelivery(t *testing.T) {
+	// Setup
+	bus := NewBus()
+	sub := bus.Subscribe(nil)
+
+	// Test
+	sub.Close()
+	sub.Close()
+	bus.Publish(EventContactCreated, nil)
+
+	// Assertions
+	_, open := <-sub.C
+	assert.False(t, open)
+}
@@ -0,0 +1,46 @@
+package events
+
+import (
+	"dklautomationgo/models"
+)
+
+// allowedRoles bepaalt welke rollen een event type mogen ontvangen. Event types
+// die hier niet staan zijn alleen zichtbaar voor beheerders.
+var allowedRoles = map[EventType][]models.UserRole{
+	EventContactCreated:    {models.RoleBeheerder, models.RoleAdmin},
+	EventAanmeldingCreated: {models.RoleBeheerder, models.RoleAdmin},
+	EventEmailReceived:     {models.RoleBeheerder, models.RoleAdmin},
+	EventEmailUpdated:      {models.RoleBeheerder, models.RoleAdmin},
+	EventEmailRemoved:      {models.RoleBeheerder, models.RoleAdmin},
+}
+
+// CanReceive controleert of een gebruiker met de opgegeven rol een event type mag zien
+func CanReceive(role models.UserRole, eventType EventType) bool {
+	roles, ok := allowedRoles[eventType]
+	if !ok {
+		return role == models.RoleBeheerder
+	}
+
+	for _, allowed := range roles {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleFilter geeft een filter terug dat alleen events doorlaat die de rol mag zien.
+// Als types niet leeg is worden daarnaast alleen die event types doorgelaten.
+func RoleFilter(role models.UserRole, types ...EventType) func(Event) bool {
+	wanted := make(map[EventType]bool, len(types))
+	for _, t := range types {
+		wanted[t] = true
+	}
+
+	return func(event Event) bool {
+		if len(wanted) > 0 && !wanted[event.Type] {
+			return false
+		}
+		return CanReceive(role, event.Type)
+	}
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"dklautomationgo/database/repository"
+	"dklautomationgo/models"
+	"dklautomationgo/services/email"
+	"dklautomationgo/services/events"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// IFormIntakeService definieert de interface voor het verwerken van formuliermeldingen
+type IFormIntakeService interface {
+	ProcessEmail(msg *models.Email) (*models.FormIntakeResult, error)
+	GetResults(status string, limit, offset int) ([]*models.FormIntakeResult, int64, error)
+}
+
+// EmailTagger markeert emails in de mailbox
+type EmailTagger interface {
+	SetEmailKeyword(emailID, keyword string, value bool) error
+}
+
+// Controleer of FormIntakeService de IFormIntakeService en IncomingEmailHandler interfaces implementeert
+var (
+	_ IFormIntakeService         = (*FormIntakeService)(nil)
+	_ email.IncomingEmailHandler = (*FormIntakeService)(nil)
+)
+
+// FormIntakeService zet formuliermeldingen uit de mailbox om naar aanmeldingen
+// en contactformulieren, bijvoorbeeld meldingen die doorgestuurd zijn omdat de
+// website ze niet zelf kon opslaan
+type FormIntakeService struct {
+	rules        []email.FormRule
+	results      repository.IFormIntakeRepository
+	contacts     repository.IContactRepository
+	aanmeldingen repository.IAanmeldingRepository
+	tagger       EmailTagger
+	publisher    events.Publisher
+}
+
+// NewFormIntakeService maakt een nieuwe FormIntakeService
+func NewFormIntakeService(rules []email.FormRule, results repository.IFormIntakeRepository, contacts repository.IContactRepository, aanmeldingen repository.IAanmeldingRepository, tagger EmailTagger, publisher events.Publisher) *FormIntakeService {
+	return &FormIntakeService{
+		rules:        rules,
+		results:      results,
+		contacts:     contacts,
+		aanmeldingen: aanmeldingen,
+		tagger:       tagger,
+		publisher:    publisher,
+	}
+}
+
+// HandleIncomingEmail verwerkt een nieuwe email als die aan een regel voldoet
+func (s *FormIntakeService) HandleIncomingEmail(msg *models.Email) {
+	result, err := s.ProcessEmail(msg)
+	if err != nil {
+		log.Printf("[FormIntakeService] Failed to process email %s: %v", msg.ID, err)
+		return
+	}
+	if result != nil {
+		log.Printf("[FormIntakeService] Email %s (%s): %s", msg.ID, result.Rule, result.Status)
+	}
+}
+
+// ProcessEmail zet een formuliermelding om naar een record. Geeft nil terug als
+// de email aan geen enkele regel voldoet. Een melding die al verwerkt is wordt
+// niet opnieuw verwerkt; een melding die niet gelezen kon worden wel, zodat
+// deze na het aanpassen van de regels opnieuw geprobeerd kan worden.
+func (s *FormIntakeService) ProcessEmail(msg *models.Email) (*models.FormIntakeResult, error) {
+	rule := email.MatchFormRule(s.rules, msg)
+	if rule == nil {
+		return nil, nil
+	}
+
+	result, err := s.results.FindByEmail(msg.ID, msg.MessageID)
+	if err != nil {
+		return nil, fmt.Errorf("fout bij zoeken eerdere verwerking: %w", err)
+	}
+	if result != nil && result.Status != models.FormIntakeUnparsed {
+		return result, nil
+	}
+	if result == nil {
+		result = &models.FormIntakeResult{CreatedAt: time.Now()}
+	}
+	result.UpdatedAt = time.Now()
+	result.EmailID = msg.ID
+	result.MessageID = msg.MessageID
+	result.Account = msg.Account
+	result.Sender = msg.Sender
+	result.Subject = msg.Subject
+	result.ReceivedAt = msg.CreatedAt
+	result.Rule = rule.Name
+	result.RecordType = rule.RecordType
+	result.RecordID = nil
+	result.Error = ""
+
+	fields := email.ParseFormFields(email.FormText(msg))
+	values, missing := rule.Extract(fields)
+	result.Fields = fields
+	result.Missing = strings.Join(missing, ", ")
+
+	if len(missing) > 0 {
+		result.Status = models.FormIntakeUnparsed
+	} else {
+		var recordID string
+		var created bool
+		// De Message-ID is de sleutel van de melding; zonder Message-ID het email ID
+		source := msg.MessageID
+		if source == "" {
+			source = msg.ID
+		}
+		switch rule.RecordType {
+		case models.EmailLinkAanmelding:
+			recordID, created, err = s.createAanmelding(email.AanmeldingFromForm(values), source)
+		case models.EmailLinkContact:
+			recordID, created, err = s.createContact(email.ContactFromForm(values), source)
+		default:
+			err = fmt.Errorf("onbekend record type: %s", rule.RecordType)
+		}
+		if err != nil {
+			// De melding blijft onverwerkt staan, met de fout, zodat deze na het
+			// oplossen opnieuw verwerkt kan worden
+			log.Printf("[FormIntakeService] Failed to create record from email %s: %v", msg.ID, err)
+			result.Status = models.FormIntakeUnparsed
+			result.Error = err.Error()
+		} else {
+			result.RecordID = &recordID
+			result.Status = models.FormIntakeDuplicate
+			if created {
+				result.Status = models.FormIntakeProcessed
+			}
+		}
+	}
+
+	if err := s.results.Save(result); err != nil {
+		return nil, fmt.Errorf("fout bij opslaan verwerking: %w", err)
+	}
+
+	// Het keyword is een hulpmiddel voor wie de mailbox in een mailclient bekijkt;
+	// de database blijft leidend, dus een server zonder keywords is geen fout
+	if result.Status != models.FormIntakeUnparsed && s.tagger != nil {
+		if err := s.tagger.SetEmailKeyword(msg.ID, email.FormProcessedKeyword, true); err != nil {
+			log.Printf("[FormIntakeService] Failed to tag email %s as processed: %v", msg.ID, err)
+		}
+	}
+
+	return result, nil
+}
+
+// createAanmelding slaat een aanmelding op, tenzij er al een aanmelding met
+// hetzelfde email adres en dezelfde naam, of uit dezelfde melding, bestaat
+func (s *FormIntakeService) createAanmelding(formulier *models.AanmeldingFormulier, source string) (string, bool, error) {
+	existing, err := s.aanmeldingen.FindByEmail(formulier.Email)
+	if err != nil {
+		return "", false, fmt.Errorf("fout bij zoeken bestaande aanmeldingen: %w", err)
+	}
+	for _, aanmelding := range existing {
+		if strings.EqualFold(strings.TrimSpace(aanmelding.Naam), strings.TrimSpace(formulier.Naam)) {
+			return aanmelding.ID, false, nil
+		}
+	}
+
+	aanmelding := formulier.ToDatabase()
+	aanmelding.BronEmail = &source
+	created, err := s.aanmeldingen.CreateFromSource(aanmelding)
+	if err != nil {
+		return "", false, fmt.Errorf("fout bij opslaan aanmelding: %w", err)
+	}
+	if created && s.publisher != nil {
+		s.publisher.Publish(events.EventAanmeldingCreated, aanmelding)
+	}
+	return aanmelding.ID, created, nil
+}
+
+// createContact slaat een contactformulier op, tenzij er al een formulier met
+// hetzelfde email adres en hetzelfde bericht, of uit dezelfde melding, bestaat
+func (s *FormIntakeService) createContact(contact *models.ContactFormulier, source string) (string, bool, error) {
+	existing, err := s.contacts.FindByEmail(contact.Email)
+	if err != nil {
+		return "", false, fmt.Errorf("fout bij zoeken bestaande contactformulieren: %w", err)
+	}
+	for _, other := range existing {
+		if strings.Join(strings.Fields(other.Bericht), " ") == strings.Join(strings.Fields(contact.Bericht), " ") {
+			return other.ID, false, nil
+		}
+	}
+
+	contact.CreatedAt = time.Now()
+	contact.UpdatedAt = time.Now()
+	contact.BronEmail = &source
+	created, err := s.contacts.CreateFromSource(contact)
+	if err != nil {
+		return "", false, fmt.Errorf("fout bij opslaan contactformulier: %w", err)
+	}
+	if created && s.publisher != nil {
+		s.publisher.Publish(events.EventContactCreated, contact)
+	}
+	return contact.ID, created, nil
+}
+
+// GetResults haalt verwerkte meldingen op, bijvoorbeeld alleen de meldingen die
+// niet gelezen konden worden, met het totaal aantal
+func (s *FormIntakeService) GetResults(status string, limit, offset int) ([]*models.FormIntakeResult, int64, error) {
+	results, err := s.results.FindAll(status, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("fout bij ophalen verwerkte meldingen: %w", err)
+	}
+	total, err := s.results.Count(status)
+	if err != nil {
+		return nil, 0, fmt.Errorf("fout bij tellen verwerkte meldingen: %w", err)
+	}
+	return results, total, nil
+}
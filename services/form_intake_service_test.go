@@ -0,0 +1,289 @@
+package services_test
+
+import (
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"dklautomationgo/services/email"
+	"dklautomationgo/services/events"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockFormIntakeRepository is een mock implementatie van de IFormIntakeRepository interface
+type MockFormIntakeRepository struct {
+	mock.Mock
+}
+
+// Save is een mock implementatie van de Save methode
+func (m *MockFormIntakeRepository) Save(result *models.FormIntakeResult) error {
+	args := m.Called(result)
+	return args.Error(0)
+}
+
+// FindByEmail is een mock implementatie van de FindByEmail methode
+func (m *MockFormIntakeRepository) FindByEmail(emailID, messageID string) (*models.FormIntakeResult, error) {
+	args := m.Called(emailID, messageID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FormIntakeResult), args.Error(1)
+}
+
+// FindAll is een mock implementatie van de FindAll methode
+func (m *MockFormIntakeRepository) FindAll(status string, limit, offset int) ([]*models.FormIntakeResult, error) {
+	args := m.Called(status, limit, offset)
+	if args.Get(0) == nil {
+		return []*models.FormIntakeResult{}, args.Error(1)
+	}
+	return args.Get(0).([]*models.FormIntakeResult), args.Error(1)
+}
+
+// Count is een mock implementatie van de Count methode
+func (m *MockFormIntakeRepository) Count(status string) (int64, error) {
+	args := m.Called(status)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// MockEmailTagger is een mock implementatie van de EmailTagger interface
+type MockEmailTagger struct {
+	mock.Mock
+}
+
+// SetEmailKeyword is een mock implementatie van de SetEmailKeyword methode
+func (m *MockEmailTagger) SetEmailKeyword(emailID, keyword string, value bool) error {
+	args := m.Called(emailID, keyword, value)
+	return args.Error(0)
+}
+
+// formIntakeTestMocks bundelt de mocks van een FormIntakeService test
+type formIntakeTestMocks struct {
+	results      *MockFormIntakeRepository
+	contacts     *MockContactRepository
+	aanmeldingen *MockAanmeldingRepository
+	tagger       *MockEmailTagger
+	publisher    *MockPublisher
+}
+
+func setupFormIntakeServiceTest() (*services.FormIntakeService, *formIntakeTestMocks) {
+	mocks := &formIntakeTestMocks{
+		results:      new(MockFormIntakeRepository),
+		contacts:     new(MockContactRepository),
+		aanmeldingen: new(MockAanmeldingRepository),
+		tagger:       new(MockEmailTagger),
+		publisher:    new(MockPublisher),
+	}
+	service := services.NewFormIntakeService(email.DefaultFormRules(), mocks.results, mocks.contacts, mocks.aanmeldingen, mocks.tagger, mocks.publisher)
+	return service, mocks
+}
+
+// aanmeldingNotification geeft een doorgestuurde aanmeldingsmelding in de inschrijving mailbox
+func aanmeldingNotification(body string) *models.Email {
+	return &models.Email{
+		ID:        "inschrijving:INBOX:1:7",
+		Account:   "inschrijving",
+		MessageID: "<melding-7@dekoninklijkeloop.nl>",
+		Sender:    "bestuur@dekoninklijkeloop.nl",
+		Subject:   "Fwd: Nieuwe aanmelding ontvangen",
+		Body:      body,
+	}
+}
+
+func TestProcessEmail_CreatesAanmelding(t *testing.T) {
+	// Setup
+	service, mocks := setupFormIntakeServiceTest()
+	msg := aanmeldingNotification("Naam: Jan de Vries\nE-mail: jan@example.com\nTelefoon: 0612345678\nRol: Vrijwilliger\nAfstand: 10 KM\nAlgemene voorwaarden: Geaccepteerd\n")
+
+	// Mock verwachtingen
+	mocks.results.On("FindByEmail", msg.ID, msg.MessageID).Return(nil, nil)
+	mocks.aanmeldingen.On("FindByEmail", "jan@example.com").Return(nil, nil)
+	mocks.aanmeldingen.On("CreateFromSource", mock.MatchedBy(func(a *models.Aanmelding) bool {
+		return a.Naam == "Jan de Vries" && a.Rol == "Vrijwilliger" && a.Terms && *a.BronEmail == msg.MessageID
+	})).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.Aanmelding).ID = "aanmelding-1"
+	}).Return(true, nil)
+	mocks.publisher.On("Publish", events.EventAanmeldingCreated, mock.AnythingOfType("*models.Aanmelding")).Return()
+	mocks.results.On("Save", mock.AnythingOfType("*models.FormIntakeResult")).Return(nil)
+	mocks.tagger.On("SetEmailKeyword", msg.ID, email.FormProcessedKeyword, true).Return(nil)
+
+	// Test
+	result, err := service.ProcessEmail(msg)
+
+	// Controleer het resultaat
+	assert.NoError(t, err)
+	assert.Equal(t, models.FormIntakeProcessed, result.Status)
+	assert.Equal(t, "aanmelding-1", *result.RecordID)
+	assert.Equal(t, "aanmelding", result.Rule)
+	mocks.aanmeldingen.AssertExpectations(t)
+	mocks.publisher.AssertExpectations(t)
+	mocks.tagger.AssertExpectations(t)
+}
+
+func TestProcessEmail_DuplicateAanmelding(t *testing.T) {
+	// Setup
+	service, mocks := setupFormIntakeServiceTest()
+	msg := aanmeldingNotification("Naam: Jan de Vries\nE-mail: jan@example.com\nTelefoon: 0612345678\nRol: Vrijwilliger\nAfstand: 10 KM\n")
+
+	// Mock verwachtingen
+	mocks.results.On("FindByEmail", msg.ID, msg.MessageID).Return(nil, nil)
+	mocks.aanmeldingen.On("FindByEmail", "jan@example.com").Return([]*models.Aanmelding{{ID: "bestaand", Naam: "jan de vries"}}, nil)
+	mocks.results.On("Save", mock.AnythingOfType("*models.FormIntakeResult")).Return(nil)
+	mocks.tagger.On("SetEmailKeyword", msg.ID, email.FormProcessedKeyword, true).Return(errors.New("keywords not supported"))
+
+	// Test
+	result, err := service.ProcessEmail(msg)
+
+	// Controleer het resultaat
+	assert.NoError(t, err, "een mislukt keyword is geen fout")
+	assert.Equal(t, models.FormIntakeDuplicate, result.Status)
+	assert.Equal(t, "bestaand", *result.RecordID)
+	mocks.aanmeldingen.AssertNotCalled(t, "CreateFromSource", mock.Anything)
+}
+
+func TestProcessEmail_ConcurrentlyCreatedAanmelding(t *testing.T) {
+	// Setup: een ander proces heeft de aanmelding uit dezelfde melding net aangemaakt
+	service, mocks := setupFormIntakeServiceTest()
+	msg := aanmeldingNotification("Naam: Jan de Vries\nE-mail: jan@example.com\nTelefoon: 0612345678\nRol: Vrijwilliger\nAfstand: 10 KM\n")
+
+	// Mock verwachtingen
+	mocks.results.On("FindByEmail", msg.ID, msg.MessageID).Return(nil, nil)
+	mocks.aanmeldingen.On("FindByEmail", "jan@example.com").Return(nil, nil)
+	mocks.aanmeldingen.On("CreateFromSource", mock.AnythingOfType("*models.Aanmelding")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.Aanmelding).ID = "gelijktijdig"
+	}).Return(false, nil)
+	mocks.results.On("Save", mock.AnythingOfType("*models.FormIntakeResult")).Return(nil)
+	mocks.tagger.On("SetEmailKeyword", msg.ID, email.FormProcessedKeyword, true).Return(nil)
+
+	// Test
+	result, err := service.ProcessEmail(msg)
+
+	// Controleer het resultaat
+	assert.NoError(t, err)
+	assert.Equal(t, models.FormIntakeDuplicate, result.Status)
+	assert.Equal(t, "gelijktijdig", *result.RecordID)
+	mocks.publisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+}
+
+func TestProcessEmail_Unparsed(t *testing.T) {
+	// Setup
+	service, mocks := setupFormIntakeServiceTest()
+	msg := aanmeldingNotification("Hoi,\n\nKunnen jullie mij ook inschrijven?\n")
+
+	// Mock verwachtingen
+	mocks.results.On("FindByEmail", msg.ID, msg.MessageID).Return(nil, nil)
+	mocks.results.On("Save", mock.AnythingOfType("*models.FormIntakeResult")).Return(nil)
+
+	// Test
+	result, err := service.ProcessEmail(msg)
+
+	// Controleer het resultaat
+	assert.NoError(t, err)
+	assert.Equal(t, models.FormIntakeUnparsed, result.Status)
+	assert.Equal(t, "naam, email, telefoon, rol, afstand", result.Missing)
+	assert.Nil(t, result.RecordID)
+	mocks.tagger.AssertNotCalled(t, "SetEmailKeyword", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestProcessEmail_NormalizesChoices(t *testing.T) {
+	// Setup
+	service, mocks := setupFormIntakeServiceTest()
+	msg := aanmeldingNotification("Naam: Jan de Vries\nE-mail: jan@example.com\nTelefoon: 0612345678\nRol: chauffeur\nAfstand: 2,5 km\n")
+
+	// Mock verwachtingen
+	mocks.results.On("FindByEmail", msg.ID, msg.MessageID).Return(nil, nil)
+	mocks.aanmeldingen.On("FindByEmail", "jan@example.com").Return(nil, nil)
+	mocks.aanmeldingen.On("CreateFromSource", mock.MatchedBy(func(a *models.Aanmelding) bool {
+		return a.Rol == "Chauffeur" && a.Afstand == "2.5 KM"
+	})).Return(true, nil)
+	mocks.publisher.On("Publish", events.EventAanmeldingCreated, mock.AnythingOfType("*models.Aanmelding")).Return()
+	mocks.results.On("Save", mock.AnythingOfType("*models.FormIntakeResult")).Return(nil)
+	mocks.tagger.On("SetEmailKeyword", msg.ID, email.FormProcessedKeyword, true).Return(nil)
+
+	// Test
+	result, err := service.ProcessEmail(msg)
+
+	// Controleer het resultaat
+	assert.NoError(t, err)
+	assert.Equal(t, models.FormIntakeProcessed, result.Status)
+	mocks.aanmeldingen.AssertExpectations(t)
+}
+
+func TestProcessEmail_UnknownChoices(t *testing.T) {
+	// Setup: waarden die de database niet toestaat
+	service, mocks := setupFormIntakeServiceTest()
+	msg := aanmeldingNotification("Naam: Jan de Vries\nE-mail: jan@example.com\nTelefoon: 0612345678\nRol: Fotograaf\nAfstand: 42 km\n")
+
+	// Mock verwachtingen
+	mocks.results.On("FindByEmail", msg.ID, msg.MessageID).Return(nil, nil)
+	mocks.results.On("Save", mock.AnythingOfType("*models.FormIntakeResult")).Return(nil)
+
+	// Test
+	result, err := service.ProcessEmail(msg)
+
+	// Controleer het resultaat
+	assert.NoError(t, err)
+	assert.Equal(t, models.FormIntakeUnparsed, result.Status)
+	assert.Equal(t, "rol, afstand", result.Missing)
+	assert.Equal(t, "Fotograaf", result.Fields["Rol"], "de oorspronkelijke waarde blijft zichtbaar")
+	mocks.aanmeldingen.AssertNotCalled(t, "CreateFromSource", mock.Anything)
+}
+
+func TestProcessEmail_CreateFails(t *testing.T) {
+	// Setup
+	service, mocks := setupFormIntakeServiceTest()
+	msg := aanmeldingNotification("Naam: Jan de Vries\nE-mail: jan@example.com\nTelefoon: 0612345678\nRol: Vrijwilliger\nAfstand: 10 KM\n")
+
+	// Mock verwachtingen
+	mocks.results.On("FindByEmail", msg.ID, msg.MessageID).Return(nil, nil)
+	mocks.aanmeldingen.On("FindByEmail", "jan@example.com").Return(nil, nil)
+	mocks.aanmeldingen.On("CreateFromSource", mock.AnythingOfType("*models.Aanmelding")).Return(false, errors.New("check constraint"))
+	mocks.results.On("Save", mock.MatchedBy(func(r *models.FormIntakeResult) bool {
+		return r.Status == models.FormIntakeUnparsed && strings.Contains(r.Error, "check constraint")
+	})).Return(nil)
+
+	// Test
+	result, err := service.ProcessEmail(msg)
+
+	// Controleer het resultaat
+	assert.NoError(t, err)
+	assert.Equal(t, models.FormIntakeUnparsed, result.Status)
+	assert.Nil(t, result.RecordID)
+	mocks.results.AssertExpectations(t)
+	mocks.tagger.AssertNotCalled(t, "SetEmailKeyword", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestProcessEmail_AlreadyProcessed(t *testing.T) {
+	// Setup
+	service, mocks := setupFormIntakeServiceTest()
+	msg := aanmeldingNotification("Naam: Jan de Vries\nE-mail: jan@example.com\nTelefoon: 0612345678\nRol: Vrijwilliger\nAfstand: 10 KM\n")
+	previous := &models.FormIntakeResult{EmailID: msg.ID, Status: models.FormIntakeProcessed}
+
+	// Mock verwachtingen
+	mocks.results.On("FindByEmail", msg.ID, msg.MessageID).Return(previous, nil)
+
+	// Test
+	result, err := service.ProcessEmail(msg)
+
+	// Controleer het resultaat
+	assert.NoError(t, err)
+	assert.Same(t, previous, result)
+	mocks.results.AssertNotCalled(t, "Save", mock.Anything)
+	mocks.aanmeldingen.AssertNotCalled(t, "FindByEmail", mock.Anything)
+}
+
+func TestProcessEmail_NoMatchingRule(t *testing.T) {
+	// Setup
+	service, mocks := setupFormIntakeServiceTest()
+	msg := &models.Email{ID: "info:INBOX:1:8", Account: "info", Subject: "Vraag over de route"}
+
+	// Test
+	result, err := service.ProcessEmail(msg)
+
+	// Controleer het resultaat
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+	mocks.results.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+}
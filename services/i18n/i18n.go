@@ -0,0 +1,136 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultLocale is de taal als een verzoek of formulier geen ondersteunde taal opgeeft
+const DefaultLocale = "nl"
+
+// SupportedLocales zijn de talen waarvoor berichten en templates bestaan
+var SupportedLocales = []string{"nl", "en"}
+
+// LocaleQueryParam overschrijft de Accept-Language header, bijv. voor links in emails
+const LocaleQueryParam = "lang"
+
+// contextKey is de sleutel waaronder Middleware de taal in de gin context zet
+const contextKey = "locale"
+
+// catalogues bevat per taal de berichten
+var catalogues = map[string]map[Key]string{
+	"nl": messagesNL,
+	"en": messagesEN,
+}
+
+// Normalize geeft de ondersteunde taal voor een taalcode zoals "en-GB" of
+// "NL", of een lege string als de taal niet ondersteund wordt
+func Normalize(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		locale = locale[:i]
+	}
+	for _, supported := range SupportedLocales {
+		if locale == supported {
+			return supported
+		}
+	}
+	return ""
+}
+
+// Negotiate kiest de best passende ondersteunde taal uit een Accept-Language
+// header, rekening houdend met de q-waarden
+func Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		locale string
+		q      float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if parsed, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if locale := Normalize(fields[0]); locale != "" && q > 0 {
+			candidates = append(candidates, candidate{locale, q})
+		}
+	}
+
+	// Bij gelijke q-waarden wint de volgorde in de header
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	if len(candidates) > 0 {
+		return candidates[0].locale
+	}
+	return DefaultLocale
+}
+
+// T geeft het bericht voor een sleutel in de gegeven taal. Ontbreekt het in
+// die taal, dan wordt DefaultLocale gebruikt en anders de sleutel zelf.
+// Met args wordt het bericht als fmt format gebruikt.
+func T(locale string, key Key, args ...interface{}) string {
+	message, ok := catalogues[Normalize(locale)][key]
+	if !ok {
+		if message, ok = catalogues[DefaultLocale][key]; !ok {
+			message = string(key)
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
+
+// Middleware bepaalt de taal van een verzoek en zet de Content-Language header
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locale := negotiateRequest(c)
+		c.Set(contextKey, locale)
+		c.Header("Content-Language", locale)
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		c.Next()
+	}
+}
+
+// Locale geeft de taal van een verzoek: de ?lang= parameter, of anders de
+// Accept-Language header
+func Locale(c *gin.Context) string {
+	if locale, ok := c.Get(contextKey); ok {
+		if s, ok := locale.(string); ok {
+			return s
+		}
+	}
+	return negotiateRequest(c)
+}
+
+func negotiateRequest(c *gin.Context) string {
+	if locale := Normalize(c.Query(LocaleQueryParam)); locale != "" {
+		return locale
+	}
+	return Negotiate(c.GetHeader("Accept-Language"))
+}
+
+// Message geeft een bericht in de taal van het verzoek
+func Message(c *gin.Context, key Key, args ...interface{}) string {
+	return T(Locale(c), key, args...)
+}
+
+// Error stuurt een foutmelding in de taal van het verzoek als {"error": ...}
+func Error(c *gin.Context, status int, key Key, args ...interface{}) {
+	c.JSON(status, gin.H{"error": Message(c, key, args...)})
+}
+
+// Abort is Error voor middleware: de volgende handlers worden niet uitgevoerd
+func Abort(c *gin.Context, status int, key Key, args ...interface{}) {
+	c.AbortWithStatusJSON(status, gin.H{"error": Message(c, key, args...)})
+}
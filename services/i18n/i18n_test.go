@@ -0,0 +1,122 @@
+package i18n
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", DefaultLocale},
+		{"en", "en"},
+		{"en-GB,en;q=0.9", "en"},
+		{"nl-NL,nl;q=0.9,en-US;q=0.8,en;q=0.7", "nl"},
+		{"de-DE,de;q=0.9,en;q=0.8", "en"},
+		{"nl;q=0.5, en;q=0.8", "en"},
+		{"en;q=0, nl", "nl"},
+		{"fr, de", DefaultLocale},
+		{"*", DefaultLocale},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			assert.Equal(t, tt.want, Negotiate(tt.header))
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "en", Normalize("EN-us"))
+	assert.Equal(t, "nl", Normalize(" nl_BE "))
+	assert.Equal(t, "", Normalize("fr"))
+	assert.Equal(t, "", Normalize(""))
+}
+
+func TestT(t *testing.T) {
+	assert.Equal(t, "Ongeldige parameter limit", T("nl", MsgInvalidParameter, "limit"))
+	assert.Equal(t, "Invalid limit parameter", T("en-GB", MsgInvalidParameter, "limit"))
+	// Een niet ondersteunde taal valt terug op DefaultLocale
+	assert.Equal(t, "Aanmelding niet gevonden", T("fr", MsgAanmeldingNotFound))
+	// Een onbekende sleutel wordt zelf teruggegeven
+	assert.Equal(t, "unknown_key", T("en", Key("unknown_key")))
+}
+
+// Elke taal moet dezelfde sleutels hebben, met dezelfde format verbs
+func TestCataloguesComplete(t *testing.T) {
+	verbs := regexp.MustCompile(`%[vsdq]`)
+	for locale, messages := range catalogues {
+		for key, message := range messagesNL {
+			translated, ok := messages[key]
+			if !assert.Truef(t, ok, "%s: missing %s", locale, key) {
+				continue
+			}
+			assert.Equalf(t, verbs.FindAllString(message, -1), verbs.FindAllString(translated, -1), "%s: format of %s", locale, key)
+		}
+		assert.Lenf(t, messages, len(messagesNL), "%s has keys that are not in nl", locale)
+	}
+}
+
+func TestErrorUsesRequestLocale(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/test", func(c *gin.Context) {
+		Error(c, http.StatusNotFound, MsgEmailNotFound)
+	})
+
+	tests := []struct {
+		name       string
+		url        string
+		header     string
+		wantBody   string
+		wantLocale string
+	}{
+		{"standaard", "/test", "", `{"error":"Email niet gevonden"}`, "nl"},
+		{"accept-language", "/test", "en-US,en;q=0.9", `{"error":"Email not found"}`, "en"},
+		{"query gaat voor header", "/test?lang=nl", "en", `{"error":"Email niet gevonden"}`, "nl"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.header != "" {
+				req.Header.Set("Accept-Language", tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusNotFound, w.Code)
+			assert.JSONEq(t, tt.wantBody, w.Body.String())
+			assert.Equal(t, tt.wantLocale, w.Header().Get("Content-Language"))
+		})
+	}
+}
+
+func TestMiddleware_KeepsExistingVary(t *testing.T) {
+	// Setup: de CORS middleware zet eerder al Vary: Origin
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Origin")
+		c.Next()
+	})
+	router.Use(Middleware())
+	router.GET("/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	// Test
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	// Controleer het resultaat
+	assert.Equal(t, []string{"Origin", "Accept-Language"}, w.Header().Values("Vary"))
+}
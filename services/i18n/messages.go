@@ -0,0 +1,151 @@
+package i18n
+
+// Key identificeert een bericht in de catalogus
+type Key string
+
+// Algemene berichten
+const (
+	MsgInvalidRequest       Key = "invalid_request"
+	MsgInvalidParameter     Key = "invalid_parameter"      // %s: naam van de parameter
+	MsgInvalidDateParameter Key = "invalid_date_parameter" // %s: naam van de parameter
+	MsgSinceAfterBefore     Key = "since_after_before"
+	MsgIDRequired           Key = "id_required"
+	MsgServerError          Key = "server_error"
+	MsgRequestFailed        Key = "request_failed"
+)
+
+// Authenticatie en gebruikers
+const (
+	MsgAuthRequired            Key = "auth_required"
+	MsgInvalidAuthHeader       Key = "invalid_auth_header"
+	MsgInvalidOrExpiredToken   Key = "invalid_or_expired_token"
+	MsgInvalidToken            Key = "invalid_token"
+	MsgTokenExpired            Key = "token_expired"
+	MsgInsufficientPermissions Key = "insufficient_permissions"
+	MsgInvalidCredentials      Key = "invalid_credentials"
+	MsgUserNotFound            Key = "user_not_found"
+	MsgUserNotActive           Key = "user_not_active"
+	MsgUnknownUser             Key = "unknown_user"
+	MsgInvalidUserID           Key = "invalid_user_id"
+	MsgEmailInUse              Key = "email_in_use"
+	MsgAdminOnly               Key = "admin_only"
+	MsgEmailParamRequired      Key = "email_param_required"
+	MsgUsersFetchFailed        Key = "users_fetch_failed"
+	MsgUserFetchFailed         Key = "user_fetch_failed"
+	MsgUserDeleted             Key = "user_deleted"
+	MsgLogoutFailed            Key = "logout_failed"
+	MsgLoggedOut               Key = "logged_out"
+	MsgPasswordResetSent       Key = "password_reset_sent"
+	MsgPasswordResetExpired    Key = "password_reset_expired"
+	MsgPasswordChanged         Key = "password_changed"
+	MsgPasswordTooWeak         Key = "password_too_weak"
+	MsgPasswordTooShort        Key = "password_too_short" // %d: minimale lengte
+	MsgPasswordNeedsUppercase  Key = "password_needs_uppercase"
+	MsgPasswordNeedsLowercase  Key = "password_needs_lowercase"
+	MsgPasswordNeedsNumber     Key = "password_needs_number"
+	MsgPasswordNeedsSpecial    Key = "password_needs_special"
+)
+
+// Aanmeldingen en contactformulieren
+const (
+	MsgAanmeldingNotFound        Key = "aanmelding_not_found"
+	MsgAanmeldingSaveFailed      Key = "aanmelding_save_failed"
+	MsgAanmeldingUpdateFailed    Key = "aanmelding_update_failed"
+	MsgAanmeldingenFetchFailed   Key = "aanmeldingen_fetch_failed"
+	MsgAanmeldingCreated         Key = "aanmelding_created"
+	MsgAanmeldingUpdated         Key = "aanmelding_updated"
+	MsgAanmeldingDeleted         Key = "aanmelding_deleted"
+	MsgContactNotFound           Key = "contact_not_found"
+	MsgContactSaveFailed         Key = "contact_save_failed"
+	MsgContactsFetchFailed       Key = "contacts_fetch_failed"
+	MsgContactsCountFailed       Key = "contacts_count_failed"
+	MsgContactStatusUpdateFailed Key = "contact_status_update_failed"
+	MsgAdminEmailNotConfigured   Key = "admin_email_not_configured"
+	MsgAdminNotificationFailed   Key = "admin_notification_failed"
+	MsgContactSubmitted          Key = "contact_submitted"
+	MsgContactSubmittedAdminOnly Key = "contact_submitted_admin_only"
+	MsgContactSubmittedSimulated Key = "contact_submitted_simulated"
+	MsgConfirmationSimulated     Key = "confirmation_simulated"
+	MsgConfirmationFailed        Key = "confirmation_failed"
+)
+
+// Emails en mailboxen
+const (
+	MsgInvalidEmailID          Key = "invalid_email_id"
+	MsgEmailNotFound           Key = "email_not_found"
+	MsgEmailIDStale            Key = "email_id_stale"
+	MsgAttachmentNotFound      Key = "attachment_not_found"
+	MsgInvalidAttachmentIndex  Key = "invalid_attachment_index"
+	MsgAttachmentTooLarge      Key = "attachment_too_large"  // %q: bestandsnaam, %d: maximum in MB
+	MsgAttachmentsTooLarge     Key = "attachments_too_large" // %d: maximum in MB
+	MsgThreadNotFound          Key = "thread_not_found"
+	MsgUnknownAccount          Key = "unknown_account"
+	MsgUnknownTemplate         Key = "unknown_template"
+	MsgRecipientRequired       Key = "recipient_required"
+	MsgInvalidRecipient        Key = "invalid_recipient"
+	MsgSubjectRequired         Key = "subject_required"
+	MsgMessageBodyRequired     Key = "message_body_required"
+	MsgBodyOrTemplateRequired  Key = "body_or_template_required"
+	MsgFlaggedRequired         Key = "flagged_required"
+	MsgTargetFolderRequired    Key = "target_folder_required"
+	MsgInvalidEmailStatus      Key = "invalid_email_status"
+	MsgNoteBodyRequired        Key = "note_body_required"
+	MsgSendFailed              Key = "send_failed" // %v: de fout
+	MsgMailboxFailed           Key = "mailbox_failed"
+	MsgMetadataNotConfigured   Key = "metadata_not_configured"
+	MsgMailAuthFailed          Key = "mail_auth_failed"
+	MsgMailTimeout             Key = "mail_timeout"
+	MsgMailCertificate         Key = "mail_certificate"
+	MsgMailUnreachable         Key = "mail_unreachable"
+	MsgFormIntakeNotConfigured Key = "form_intake_not_configured"
+	MsgFormIntakeFetchFailed   Key = "form_intake_fetch_failed"
+	MsgFormIntakeFailed        Key = "form_intake_failed"
+	MsgNoMatchingFormRule      Key = "no_matching_form_rule"
+	MsgBouncesNotConfigured    Key = "bounces_not_configured"
+	MsgBouncesFetchFailed      Key = "bounces_fetch_failed"
+	MsgBounceFailed            Key = "bounce_failed"
+	MsgNotABounce              Key = "not_a_bounce"
+	MsgInvalidSignature        Key = "invalid_signature"
+	MsgImageNotAllowed         Key = "image_not_allowed"
+	MsgImageFetchFailed        Key = "image_fetch_failed"
+)
+
+// Beheer van accounts, templates en inbox regels
+const (
+	MsgAccountsFetchFailed     Key = "accounts_fetch_failed"
+	MsgAccountNotFound         Key = "account_not_found"
+	MsgAccountSaveFailed       Key = "account_save_failed"
+	MsgAccountUpdateFailed     Key = "account_update_failed"
+	MsgAccountDeleteFailed     Key = "account_delete_failed"
+	MsgAccountsReloadFailed    Key = "accounts_reload_failed"
+	MsgAccountsReadOnly        Key = "accounts_read_only" // %s: het configuratiebestand
+	MsgInvalidAccount          Key = "invalid_account"
+	MsgInvalidEmailAddress     Key = "invalid_email_address"
+	MsgEncryptionNotConfigured Key = "encryption_not_configured" // %s: de omgevingsvariabele
+	MsgTemplateFetchFailed     Key = "template_fetch_failed"
+	MsgTemplateNotFound        Key = "template_not_found"
+	MsgTemplateVersionNotFound Key = "template_version_not_found"
+	MsgTemplateSaveFailed      Key = "template_save_failed"
+	MsgTemplateActivateFailed  Key = "template_activate_failed"
+	MsgTemplateDeleteFailed    Key = "template_delete_failed"
+	MsgInvalidTemplate         Key = "invalid_template"
+	MsgInvalidVersion          Key = "invalid_version"
+	MsgRulesFetchFailed        Key = "rules_fetch_failed"
+	MsgRuleNotFound            Key = "rule_not_found"
+	MsgRuleSaveFailed          Key = "rule_save_failed"
+	MsgRuleUpdateFailed        Key = "rule_update_failed"
+	MsgRuleDeleteFailed        Key = "rule_delete_failed"
+	MsgRuleNameRequired        Key = "rule_name_required"
+	MsgRuleActionRequired      Key = "rule_action_required"
+	MsgUnknownAutoReply        Key = "unknown_auto_reply"
+	MsgInvalidAssignTo         Key = "invalid_assign_to"
+	MsgUnknownAssignTo         Key = "unknown_assign_to"
+)
+
+// Standaard onderwerpen van de formuliermails
+const (
+	SubjectContactAdmin    Key = "subject_contact_admin"
+	SubjectContactUser     Key = "subject_contact_user"
+	SubjectAanmeldingAdmin Key = "subject_aanmelding_admin"
+	SubjectAanmeldingUser  Key = "subject_aanmelding_user"
+)
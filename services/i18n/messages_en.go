@@ -0,0 +1,133 @@
+package i18n
+
+// messagesEN bevat de Engelse berichten
+var messagesEN = map[Key]string{
+	MsgInvalidRequest:       "Invalid request body",
+	MsgInvalidParameter:     "Invalid %s parameter",
+	MsgInvalidDateParameter: "Invalid %s parameter, use YYYY-MM-DD or RFC3339",
+	MsgSinceAfterBefore:     "since must be earlier than before",
+	MsgIDRequired:           "ID is required",
+	MsgServerError:          "Internal server error",
+	MsgRequestFailed:        "Failed to process request",
+
+	MsgAuthRequired:            "Authentication required",
+	MsgInvalidAuthHeader:       "Invalid authorization header",
+	MsgInvalidOrExpiredToken:   "Invalid or expired token",
+	MsgInvalidToken:            "Invalid token",
+	MsgTokenExpired:            "Token has expired",
+	MsgInsufficientPermissions: "Insufficient permissions",
+	MsgInvalidCredentials:      "Invalid email or password",
+	MsgUserNotFound:            "User not found",
+	MsgUserNotActive:           "User is not active",
+	MsgUnknownUser:             "Unknown user",
+	MsgInvalidUserID:           "Invalid user ID",
+	MsgEmailInUse:              "Email is already in use",
+	MsgAdminOnly:               "Only administrators can perform this action",
+	MsgEmailParamRequired:      "Email parameter is required",
+	MsgUsersFetchFailed:        "Failed to fetch users",
+	MsgUserFetchFailed:         "Failed to fetch user",
+	MsgUserDeleted:             "User deleted successfully",
+	MsgLogoutFailed:            "Failed to log out",
+	MsgLoggedOut:               "Logged out successfully",
+	MsgPasswordResetSent:       "Password reset link has been sent",
+	MsgPasswordResetExpired:    "Password reset link has expired",
+	MsgPasswordChanged:         "Password changed successfully",
+	MsgPasswordTooWeak:         "Password does not meet the requirements",
+	MsgPasswordTooShort:        "Password must contain at least %d characters",
+	MsgPasswordNeedsUppercase:  "Password must contain at least one uppercase letter",
+	MsgPasswordNeedsLowercase:  "Password must contain at least one lowercase letter",
+	MsgPasswordNeedsNumber:     "Password must contain at least one digit",
+	MsgPasswordNeedsSpecial:    "Password must contain at least one special character",
+
+	MsgAanmeldingNotFound:        "Registration not found",
+	MsgAanmeldingSaveFailed:      "Failed to save registration",
+	MsgAanmeldingUpdateFailed:    "Failed to update registration",
+	MsgAanmeldingenFetchFailed:   "Failed to fetch registrations",
+	MsgAanmeldingCreated:         "Registration created successfully",
+	MsgAanmeldingUpdated:         "Registration updated successfully",
+	MsgAanmeldingDeleted:         "Registration deleted successfully",
+	MsgContactNotFound:           "Contact not found",
+	MsgContactSaveFailed:         "Failed to save contact form",
+	MsgContactsFetchFailed:       "Failed to fetch contacts",
+	MsgContactsCountFailed:       "Failed to count contacts",
+	MsgContactStatusUpdateFailed: "Failed to update contact status",
+	MsgAdminEmailNotConfigured:   "Admin email not configured",
+	MsgAdminNotificationFailed:   "Failed to send admin notification",
+	MsgContactSubmitted:          "Contact form submitted successfully. Confirmation emails sent.",
+	MsgContactSubmittedAdminOnly: "Contact form submitted successfully. Admin notification sent.",
+	MsgContactSubmittedSimulated: "Contact form submitted successfully. Admin notification sent. User email simulated.",
+	MsgConfirmationSimulated:     "User email would normally be sent, but was simulated for test domain.",
+	MsgConfirmationFailed:        "Could not send confirmation email to user.",
+
+	MsgInvalidEmailID:          "Invalid email ID format",
+	MsgEmailNotFound:           "Email not found",
+	MsgEmailIDStale:            "Email ID is no longer valid, refresh the mailbox",
+	MsgAttachmentNotFound:      "Attachment not found",
+	MsgInvalidAttachmentIndex:  "Invalid attachment index",
+	MsgAttachmentTooLarge:      "Attachment %q is larger than %d MB",
+	MsgAttachmentsTooLarge:     "Attachments are larger than %d MB in total",
+	MsgThreadNotFound:          "Thread not found",
+	MsgUnknownAccount:          "Unknown email account",
+	MsgUnknownTemplate:         "Unknown email template",
+	MsgRecipientRequired:       "At least one recipient is required",
+	MsgInvalidRecipient:        "Invalid recipient address",
+	MsgSubjectRequired:         "Subject is required",
+	MsgMessageBodyRequired:     "Message body is required",
+	MsgBodyOrTemplateRequired:  "Message body or template is required",
+	MsgFlaggedRequired:         "Flagged is required",
+	MsgTargetFolderRequired:    "Target folder is required",
+	MsgInvalidEmailStatus:      "Status must be open, waiting or closed",
+	MsgNoteBodyRequired:        "Note body is required",
+	MsgSendFailed:              "Failed to send email: %v",
+	MsgMailboxFailed:           "Mailbox operation failed: %v",
+	MsgMetadataNotConfigured:   "Email metadata is not configured",
+	MsgMailAuthFailed:          "Email authentication failed",
+	MsgMailTimeout:             "Email server did not respond in time",
+	MsgMailCertificate:         "Email server certificate could not be verified",
+	MsgMailUnreachable:         "Could not connect to email server",
+	MsgFormIntakeNotConfigured: "Form intake is not configured",
+	MsgFormIntakeFetchFailed:   "Failed to get form intake results",
+	MsgFormIntakeFailed:        "Failed to process form submission",
+	MsgNoMatchingFormRule:      "Email does not match any form rule",
+	MsgBouncesNotConfigured:    "Bounce processing is not configured",
+	MsgBouncesFetchFailed:      "Failed to get bounced emails",
+	MsgBounceFailed:            "Failed to process bounce",
+	MsgNotABounce:              "Email is not a bounce",
+	MsgInvalidSignature:        "Invalid signature",
+	MsgImageNotAllowed:         "Image not allowed",
+	MsgImageFetchFailed:        "Failed to fetch image",
+
+	MsgAccountsFetchFailed:     "Failed to fetch email accounts",
+	MsgAccountNotFound:         "Email account not found",
+	MsgAccountSaveFailed:       "Failed to save email account",
+	MsgAccountUpdateFailed:     "Failed to update email account",
+	MsgAccountDeleteFailed:     "Failed to delete email account",
+	MsgAccountsReloadFailed:    "Failed to reload email accounts",
+	MsgAccountsReadOnly:        "Email accounts are managed in %s",
+	MsgInvalidAccount:          "Invalid email account",
+	MsgInvalidEmailAddress:     "Invalid email address",
+	MsgEncryptionNotConfigured: "Password encryption is not configured (%s)",
+	MsgTemplateFetchFailed:     "Failed to fetch email template",
+	MsgTemplateNotFound:        "Email template not found",
+	MsgTemplateVersionNotFound: "Email template version not found",
+	MsgTemplateSaveFailed:      "Failed to save email template",
+	MsgTemplateActivateFailed:  "Failed to activate email template",
+	MsgTemplateDeleteFailed:    "Failed to delete email template",
+	MsgInvalidTemplate:         "Invalid email template",
+	MsgInvalidVersion:          "Invalid version",
+	MsgRulesFetchFailed:        "Failed to fetch inbox rules",
+	MsgRuleNotFound:            "Inbox rule not found",
+	MsgRuleSaveFailed:          "Failed to save inbox rule",
+	MsgRuleUpdateFailed:        "Failed to update inbox rule",
+	MsgRuleDeleteFailed:        "Failed to delete inbox rule",
+	MsgRuleNameRequired:        "Name is required",
+	MsgRuleActionRequired:      "At least one action is required",
+	MsgUnknownAutoReply:        "Unknown auto-reply template",
+	MsgInvalidAssignTo:         "Invalid assign_to user ID",
+	MsgUnknownAssignTo:         "Unknown assign_to user",
+
+	SubjectContactAdmin:    "New contact form received",
+	SubjectContactUser:     "Thank you for your message",
+	SubjectAanmeldingAdmin: "New registration received",
+	SubjectAanmeldingUser:  "Thank you for registering",
+}
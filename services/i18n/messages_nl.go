@@ -0,0 +1,133 @@
+package i18n
+
+// messagesNL bevat de Nederlandse berichten; dit is ook de fallback voor ontbrekende vertalingen
+var messagesNL = map[Key]string{
+	MsgInvalidRequest:       "Ongeldige invoer",
+	MsgInvalidParameter:     "Ongeldige parameter %s",
+	MsgInvalidDateParameter: "Ongeldige parameter %s, gebruik YYYY-MM-DD of RFC3339",
+	MsgSinceAfterBefore:     "since moet eerder zijn dan before",
+	MsgIDRequired:           "ID is verplicht",
+	MsgServerError:          "Serverfout",
+	MsgRequestFailed:        "Fout bij verwerken van verzoek",
+
+	MsgAuthRequired:            "Authenticatie vereist",
+	MsgInvalidAuthHeader:       "Ongeldige authenticatie header",
+	MsgInvalidOrExpiredToken:   "Ongeldige of verlopen token",
+	MsgInvalidToken:            "Ongeldige token",
+	MsgTokenExpired:            "Token is verlopen",
+	MsgInsufficientPermissions: "Onvoldoende rechten",
+	MsgInvalidCredentials:      "ongeldige inloggegevens",
+	MsgUserNotFound:            "Gebruiker niet gevonden",
+	MsgUserNotActive:           "Gebruiker is niet actief",
+	MsgUnknownUser:             "Onbekende gebruiker",
+	MsgInvalidUserID:           "Ongeldige gebruiker ID",
+	MsgEmailInUse:              "Email is al in gebruik",
+	MsgAdminOnly:               "Alleen beheerders kunnen deze actie uitvoeren",
+	MsgEmailParamRequired:      "Email parameter is vereist",
+	MsgUsersFetchFailed:        "Fout bij ophalen gebruikers",
+	MsgUserFetchFailed:         "Fout bij ophalen gebruiker",
+	MsgUserDeleted:             "Gebruiker succesvol verwijderd",
+	MsgLogoutFailed:            "Fout bij uitloggen",
+	MsgLoggedOut:               "Succesvol uitgelogd",
+	MsgPasswordResetSent:       "Wachtwoord reset link is verzonden",
+	MsgPasswordResetExpired:    "Wachtwoord reset link is verlopen",
+	MsgPasswordChanged:         "Wachtwoord succesvol gewijzigd",
+	MsgPasswordTooWeak:         "Wachtwoord voldoet niet aan de vereisten",
+	MsgPasswordTooShort:        "Wachtwoord moet minimaal %d karakters bevatten",
+	MsgPasswordNeedsUppercase:  "Wachtwoord moet minimaal één hoofdletter bevatten",
+	MsgPasswordNeedsLowercase:  "Wachtwoord moet minimaal één kleine letter bevatten",
+	MsgPasswordNeedsNumber:     "Wachtwoord moet minimaal één cijfer bevatten",
+	MsgPasswordNeedsSpecial:    "Wachtwoord moet minimaal één speciaal teken bevatten",
+
+	MsgAanmeldingNotFound:        "Aanmelding niet gevonden",
+	MsgAanmeldingSaveFailed:      "Fout bij opslaan aanmelding",
+	MsgAanmeldingUpdateFailed:    "Fout bij bijwerken aanmelding",
+	MsgAanmeldingenFetchFailed:   "Fout bij ophalen aanmeldingen",
+	MsgAanmeldingCreated:         "Aanmelding succesvol aangemaakt",
+	MsgAanmeldingUpdated:         "Aanmelding succesvol bijgewerkt",
+	MsgAanmeldingDeleted:         "Aanmelding succesvol verwijderd",
+	MsgContactNotFound:           "Contactformulier niet gevonden",
+	MsgContactSaveFailed:         "Fout bij opslaan contactformulier",
+	MsgContactsFetchFailed:       "Fout bij ophalen contactformulieren",
+	MsgContactsCountFailed:       "Fout bij tellen contactformulieren",
+	MsgContactStatusUpdateFailed: "Fout bij bijwerken status contactformulier",
+	MsgAdminEmailNotConfigured:   "Admin email is niet ingesteld",
+	MsgAdminNotificationFailed:   "Fout bij versturen notificatie naar admin",
+	MsgContactSubmitted:          "Contactformulier succesvol verzonden. Bevestigingsemails zijn verstuurd.",
+	MsgContactSubmittedAdminOnly: "Contactformulier succesvol verzonden. Notificatie naar admin is verstuurd.",
+	MsgContactSubmittedSimulated: "Contactformulier succesvol verzonden. Notificatie naar admin is verstuurd, email naar gebruiker gesimuleerd.",
+	MsgConfirmationSimulated:     "De email naar de gebruiker is gesimuleerd voor een testdomein.",
+	MsgConfirmationFailed:        "De bevestigingsemail kon niet verstuurd worden.",
+
+	MsgInvalidEmailID:          "Ongeldig email ID",
+	MsgEmailNotFound:           "Email niet gevonden",
+	MsgEmailIDStale:            "Email ID is niet meer geldig, ververs de mailbox",
+	MsgAttachmentNotFound:      "Bijlage niet gevonden",
+	MsgInvalidAttachmentIndex:  "Ongeldige index van bijlage",
+	MsgAttachmentTooLarge:      "Bijlage %q is groter dan %d MB",
+	MsgAttachmentsTooLarge:     "Bijlagen zijn samen groter dan %d MB",
+	MsgThreadNotFound:          "Conversatie niet gevonden",
+	MsgUnknownAccount:          "Onbekend email account",
+	MsgUnknownTemplate:         "Onbekend email template",
+	MsgRecipientRequired:       "Minimaal één ontvanger is verplicht",
+	MsgInvalidRecipient:        "Ongeldig adres van ontvanger",
+	MsgSubjectRequired:         "Onderwerp is verplicht",
+	MsgMessageBodyRequired:     "Bericht is verplicht",
+	MsgBodyOrTemplateRequired:  "Bericht of template is verplicht",
+	MsgFlaggedRequired:         "flagged is verplicht",
+	MsgTargetFolderRequired:    "Doelmap is verplicht",
+	MsgInvalidEmailStatus:      "Status moet open, waiting of closed zijn",
+	MsgNoteBodyRequired:        "Notitie is verplicht",
+	MsgSendFailed:              "Fout bij versturen email: %v",
+	MsgMailboxFailed:           "Bewerking op de mailbox mislukt: %v",
+	MsgMetadataNotConfigured:   "Email metadata is niet ingesteld",
+	MsgMailAuthFailed:          "Inloggen op de mailserver mislukt",
+	MsgMailTimeout:             "De mailserver reageerde niet op tijd",
+	MsgMailCertificate:         "Het certificaat van de mailserver kon niet gecontroleerd worden",
+	MsgMailUnreachable:         "Kan geen verbinding maken met de mailserver",
+	MsgFormIntakeNotConfigured: "Verwerking van formuliermeldingen is niet ingesteld",
+	MsgFormIntakeFetchFailed:   "Fout bij ophalen verwerkte formuliermeldingen",
+	MsgFormIntakeFailed:        "Fout bij verwerken formuliermelding",
+	MsgNoMatchingFormRule:      "Email past bij geen enkele formulierregel",
+	MsgBouncesNotConfigured:    "Verwerking van bounces is niet ingesteld",
+	MsgBouncesFetchFailed:      "Fout bij ophalen onbestelbare emails",
+	MsgBounceFailed:            "Fout bij verwerken bounce",
+	MsgNotABounce:              "Email is geen bounce",
+	MsgInvalidSignature:        "Ongeldige handtekening",
+	MsgImageNotAllowed:         "Afbeelding niet toegestaan",
+	MsgImageFetchFailed:        "Fout bij ophalen afbeelding",
+
+	MsgAccountsFetchFailed:     "Fout bij ophalen email accounts",
+	MsgAccountNotFound:         "Email account niet gevonden",
+	MsgAccountSaveFailed:       "Fout bij opslaan email account",
+	MsgAccountUpdateFailed:     "Fout bij bijwerken email account",
+	MsgAccountDeleteFailed:     "Fout bij verwijderen email account",
+	MsgAccountsReloadFailed:    "Fout bij herladen email accounts",
+	MsgAccountsReadOnly:        "Email accounts worden beheerd in %s",
+	MsgInvalidAccount:          "Ongeldig email account",
+	MsgInvalidEmailAddress:     "Ongeldig email adres",
+	MsgEncryptionNotConfigured: "Versleuteling van wachtwoorden is niet ingesteld (%s)",
+	MsgTemplateFetchFailed:     "Fout bij ophalen email template",
+	MsgTemplateNotFound:        "Email template niet gevonden",
+	MsgTemplateVersionNotFound: "Versie van email template niet gevonden",
+	MsgTemplateSaveFailed:      "Fout bij opslaan email template",
+	MsgTemplateActivateFailed:  "Fout bij activeren email template",
+	MsgTemplateDeleteFailed:    "Fout bij verwijderen email template",
+	MsgInvalidTemplate:         "Ongeldig email template",
+	MsgInvalidVersion:          "Ongeldige versie",
+	MsgRulesFetchFailed:        "Fout bij ophalen inbox regels",
+	MsgRuleNotFound:            "Inbox regel niet gevonden",
+	MsgRuleSaveFailed:          "Fout bij opslaan inbox regel",
+	MsgRuleUpdateFailed:        "Fout bij bijwerken inbox regel",
+	MsgRuleDeleteFailed:        "Fout bij verwijderen inbox regel",
+	MsgRuleNameRequired:        "Naam is verplicht",
+	MsgRuleActionRequired:      "Minimaal één actie is verplicht",
+	MsgUnknownAutoReply:        "Onbekend template voor automatisch antwoord",
+	MsgInvalidAssignTo:         "Ongeldige gebruiker ID in assign_to",
+	MsgUnknownAssignTo:         "Onbekende gebruiker in assign_to",
+
+	SubjectContactAdmin:    "Nieuw contactformulier ontvangen",
+	SubjectContactUser:     "Bedankt voor je bericht",
+	SubjectAanmeldingAdmin: "Nieuwe aanmelding ontvangen",
+	SubjectAanmeldingUser:  "Bedankt voor je aanmelding",
+}
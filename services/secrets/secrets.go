@@ -0,0 +1,328 @@
+// Package secrets versleutelt gegevens die in de database bewaard worden, zoals
+// de wachtwoorden van email accounts en persoonsgegevens uit formulieren.
+//
+// Elke waarde krijgt een eigen data key (AES-256-GCM). Die data key wordt
+// versleuteld met een key-encryption key (KEK) uit de configuratie en samen met
+// het id van die KEK bij de waarde opgeslagen. Bij het roteren van een KEK
+// hoeft daardoor alleen de data key opnieuw versleuteld te worden.
+package secrets
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Omgevingsvariabelen met de sleutels
+const (
+	// KeysEnv bevat de KEKs als komma-gescheiden lijst van id:base64
+	KeysEnv = "SECRETS_KEYS"
+	// KeysFileEnv wijst naar een bestand met één id:base64 per regel
+	KeysFileEnv = "SECRETS_KEYS_FILE"
+	// ActiveKeyEnv is het id van de KEK waarmee nieuwe waarden versleuteld worden
+	ActiveKeyEnv = "SECRETS_ACTIVE_KEY"
+)
+
+// prefix markeert het formaat van een versleutelde waarde:
+//
+//	v1:<key id>:<versleutelde data key>:<nonce+ciphertext>
+const prefix = "v1:"
+
+var (
+	ErrNoKey             = errors.New("no encryption key configured")
+	ErrUnknownKey        = errors.New("unknown encryption key")
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,32}$`)
+
+// Keyring bevat de KEKs op id. Nieuwe waarden worden met de actieve KEK
+// versleuteld; de andere KEKs zijn nodig om oudere waarden te lezen.
+type Keyring struct {
+	keys   map[string]cipher.AEAD
+	active string
+}
+
+// NewKeyring maakt een Keyring met KEKs van 32 bytes op id
+func NewKeyring(keys map[string][]byte, active string) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKey
+	}
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("%w: active key %q", ErrUnknownKey, active)
+	}
+
+	k := &Keyring{keys: make(map[string]cipher.AEAD, len(keys)), active: active}
+	for id, key := range keys {
+		if !keyIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("invalid key id %q: use letters, digits, '.', '-' and '_'", id)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", id, err)
+		}
+		k.keys[id] = aead
+	}
+	return k, nil
+}
+
+// LoadKeyring laadt de KEKs uit SECRETS_KEYS en SECRETS_KEYS_FILE. Zonder SECRETS_ACTIVE_KEY is de laatst genoemde KEK
+// actief, zodat een nieuwe sleutel achteraan toegevoegd kan worden.
+func LoadKeyring() (*Keyring, error) {
+	keys := make(map[string][]byte)
+	var order []string
+	add := func(entry, source string) error {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok {
+			return fmt.Errorf("invalid key in %s: expected id:base64", source)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return fmt.Errorf("invalid key %s in %s: %w", id, source, err)
+		}
+		if _, exists := keys[id]; exists {
+			return fmt.Errorf("key %s is configured twice", id)
+		}
+		keys[id] = key
+		order = append(order, id)
+		return nil
+	}
+
+	if path := os.Getenv(KeysFileEnv); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", KeysFileEnv, err)
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if err := add(line, path); err != nil {
+				return nil, err
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", KeysFileEnv, err)
+		}
+	}
+
+	for _, entry := range strings.Split(os.Getenv(KeysEnv), ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		if err := add(entry, KeysEnv); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(order) == 0 {
+		return nil, ErrNoKey
+	}
+	active := os.Getenv(ActiveKeyEnv)
+	if active == "" {
+		active = order[len(order)-1]
+	}
+	return NewKeyring(keys, active)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return aead, nil
+}
+
+// ActiveKeyID geeft het id van de KEK waarmee nieuwe waarden versleuteld worden
+func (k *Keyring) ActiveKeyID() string {
+	if k == nil {
+		return ""
+	}
+	return k.active
+}
+
+// Encrypt versleutelt een waarde met een nieuwe data key. Dezelfde waarde geeft
+// steeds een andere uitkomst.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if k == nil {
+		return "", ErrNoKey
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := seal(data, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := k.wrap(k.active, dataKey)
+	if err != nil {
+		return "", err
+	}
+	return prefix + k.active + ":" + wrapped + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt ontsleutelt een waarde die met Encrypt versleuteld is
+func (k *Keyring) Decrypt(ciphertext string) (string, error) {
+	if k == nil {
+		return "", ErrNoKey
+	}
+
+	keyID, wrapped, sealed, err := parse(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	dataKey, err := k.unwrap(keyID, wrapped)
+	if err != nil {
+		return "", err
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(data, sealed, nil)
+	return string(plaintext), err
+}
+
+// Rotate zorgt dat een waarde met de actieve KEK versleuteld is. Een
+// onversleutelde waarde wordt versleuteld; bij een andere KEK wordt alleen de
+// data key opnieuw versleuteld. changed geeft aan of de waarde gewijzigd is.
+func (k *Keyring) Rotate(value string) (rotated string, changed bool, err error) {
+	if k == nil {
+		return "", false, ErrNoKey
+	}
+
+	switch {
+	case value == "":
+		return value, false, nil
+	case !IsEncrypted(value):
+		rotated, err = k.Encrypt(value)
+		return rotated, err == nil, err
+	}
+
+	keyID, wrapped, sealed, err := parse(value)
+	if err != nil {
+		return "", false, err
+	}
+	if keyID == k.active {
+		return value, false, nil
+	}
+	dataKey, err := k.unwrap(keyID, wrapped)
+	if err != nil {
+		return "", false, err
+	}
+	rewrapped, err := k.wrap(k.active, dataKey)
+	if err != nil {
+		return "", false, err
+	}
+	return prefix + k.active + ":" + rewrapped + ":" + sealed, true, nil
+}
+
+// wrap versleutelt een data key met een KEK. Het id van de KEK is de
+// additional data, zodat het id niet ongemerkt vervangen kan worden.
+func (k *Keyring) wrap(keyID string, dataKey []byte) (string, error) {
+	sealed, err := seal(k.keys[keyID], dataKey, []byte(keyID))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (k *Keyring) unwrap(keyID, wrapped string) ([]byte, error) {
+	kek, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	return open(kek, wrapped, []byte(keyID))
+}
+
+func seal(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(aead cipher.AEAD, encoded string, additional []byte) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, additional)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCiphertext, err)
+	}
+	return plaintext, nil
+}
+
+func parse(ciphertext string) (keyID, wrapped, sealed string, err error) {
+	parts := strings.Split(strings.TrimPrefix(ciphertext, prefix), ":")
+	if !strings.HasPrefix(ciphertext, prefix) || len(parts) != 3 {
+		return "", "", "", ErrInvalidCiphertext
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// IsEncrypted geeft aan of een waarde in het versleutelde formaat staat
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// KeyID geeft het id van de KEK waarmee een waarde versleuteld is
+func KeyID(ciphertext string) (string, bool) {
+	keyID, _, _, err := parse(ciphertext)
+	return keyID, err == nil
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultKeyring *Keyring
+)
+
+// SetDefault stelt de Keyring in die de GORM serializer gebruikt
+func SetDefault(k *Keyring) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultKeyring = k
+}
+
+// Default geeft de Keyring van de GORM serializer, of nil als er geen sleutels zijn
+func Default() *Keyring {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultKeyring
+}
+
+// Enabled geeft aan of er sleutels ingesteld zijn om waarden te versleutelen
+func Enabled() bool {
+	return Default() != nil
+}
@@ -0,0 +1,228 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/schema"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func testKeyring(t *testing.T, active string) *Keyring {
+	k, err := NewKeyring(map[string][]byte{"2025": testKey(1), "2026": testKey(2)}, active)
+	require.NoError(t, err)
+	return k
+}
+
+func TestKeyring_RoundTrip(t *testing.T) {
+	// Setup
+	k := testKeyring(t, "2026")
+
+	// Test
+	first, err := k.Encrypt("0612345678")
+	require.NoError(t, err)
+	second, err := k.Encrypt("0612345678")
+	require.NoError(t, err)
+	plaintext, err := k.Decrypt(first)
+
+	// Controleer het resultaat
+	require.NoError(t, err)
+	assert.Equal(t, "0612345678", plaintext)
+	assert.NotEqual(t, first, second, "elke waarde krijgt een eigen data key en nonce")
+	assert.NotContains(t, first, "0612345678")
+	assert.True(t, IsEncrypted(first))
+	keyID, ok := KeyID(first)
+	assert.True(t, ok)
+	assert.Equal(t, "2026", keyID)
+}
+
+func TestKeyring_RejectsInvalidInput(t *testing.T) {
+	k := testKeyring(t, "2026")
+	encrypted, err := k.Encrypt("geheim")
+	require.NoError(t, err)
+
+	// Een onbekende sleutel, een gewijzigde waarde, een ander key id of een ander formaat worden geweigerd
+	other, err := NewKeyring(map[string][]byte{"2026": testKey(9)}, "2026")
+	require.NoError(t, err)
+	_, err = other.Decrypt(encrypted)
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+	_, err = k.Decrypt(encrypted[:len(encrypted)-4] + "AAAA")
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+	_, err = k.Decrypt(strings.Replace(encrypted, "v1:2026:", "v1:2025:", 1))
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+	_, err = k.Decrypt(strings.Replace(encrypted, "v1:2026:", "v1:2024:", 1))
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	_, err = k.Decrypt("geheim")
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	// Zonder sleutel of met een ongeldige configuratie
+	var missing *Keyring
+	_, err = missing.Encrypt("geheim")
+	assert.ErrorIs(t, err, ErrNoKey)
+	_, err = NewKeyring(map[string][]byte{"2026": []byte("te kort")}, "2026")
+	assert.Error(t, err)
+	_, err = NewKeyring(map[string][]byte{"2026": testKey(1)}, "2027")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	_, err = NewKeyring(map[string][]byte{"a:b": testKey(1)}, "a:b")
+	assert.Error(t, err)
+}
+
+func TestKeyring_Rotate(t *testing.T) {
+	// Setup
+	old := testKeyring(t, "2025")
+	encrypted, err := old.Encrypt("Allergisch voor noten")
+	require.NoError(t, err)
+	k := testKeyring(t, "2026")
+
+	// Test
+	rotated, changed, err := k.Rotate(encrypted)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	unchanged, changed, err := k.Rotate(rotated)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, rotated, unchanged)
+
+	// Controleer het resultaat: alleen de nieuwe sleutel is nog nodig
+	onlyNew, err := NewKeyring(map[string][]byte{"2026": testKey(2)}, "2026")
+	require.NoError(t, err)
+	plaintext, err := onlyNew.Decrypt(rotated)
+	require.NoError(t, err)
+	assert.Equal(t, "Allergisch voor noten", plaintext)
+	assert.Equal(t, encrypted[strings.LastIndex(encrypted, ":"):], rotated[strings.LastIndex(rotated, ":"):], "de data zelf wordt niet opnieuw versleuteld")
+
+	// Onversleutelde waarden worden versleuteld, lege waarden niet
+	rotated, changed, err = k.Rotate("0612345678")
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, IsEncrypted(rotated))
+	_, changed, err = k.Rotate("")
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestLoadKeyring(t *testing.T) {
+	encode := func(b byte) string { return base64.StdEncoding.EncodeToString(testKey(b)) }
+	t.Setenv(KeysFileEnv, "")
+	t.Setenv(KeysEnv, "")
+	t.Setenv(ActiveKeyEnv, "")
+
+	// Zonder sleutels
+	_, err := LoadKeyring()
+	assert.ErrorIs(t, err, ErrNoKey)
+
+	// Sleutels uit een bestand en de omgeving; de laatste is actief
+	path := filepath.Join(t.TempDir(), "keys")
+	require.NoError(t, os.WriteFile(path, []byte("# oude sleutel\n2025:"+encode(1)+"\n"), 0600))
+	t.Setenv(KeysFileEnv, path)
+	t.Setenv(KeysEnv, "2026:"+encode(2))
+	k, err := LoadKeyring()
+	require.NoError(t, err)
+	assert.Equal(t, "2026", k.ActiveKeyID())
+	assert.Len(t, k.keys, 2)
+
+	t.Setenv(ActiveKeyEnv, "2025")
+	k, err = LoadKeyring()
+	require.NoError(t, err)
+	assert.Equal(t, "2025", k.ActiveKeyID())
+
+	// Ongeldige of dubbele sleutels
+	t.Setenv(KeysEnv, "2026")
+	_, err = LoadKeyring()
+	assert.Error(t, err)
+	t.Setenv(KeysEnv, "2025:"+encode(2))
+	_, err = LoadKeyring()
+	assert.Error(t, err)
+}
+
+type serializerModel struct {
+	ID       string
+	Telefoon string            `gorm:"serializer:encrypted"`
+	Notities *string           `gorm:"serializer:encrypted"`
+	Velden   map[string]string `gorm:"serializer:encrypted"`
+}
+
+func TestSerializer(t *testing.T) {
+	// Setup
+	s, err := schema.Parse(&serializerModel{}, &sync.Map{}, schema.NamingStrategy{})
+	require.NoError(t, err)
+	telefoon, notities := s.LookUpField("Telefoon"), s.LookUpField("Notities")
+	ctx := context.Background()
+	SetDefault(testKeyring(t, "2026"))
+	t.Cleanup(func() { SetDefault(nil) })
+
+	// Test: opslaan
+	note := "Rolstoel"
+	model := serializerModel{Telefoon: "0612345678", Notities: &note}
+	storedTelefoon, err := Serializer{}.Value(ctx, telefoon, reflect.ValueOf(&model).Elem(), model.Telefoon)
+	require.NoError(t, err)
+	storedNotities, err := Serializer{}.Value(ctx, notities, reflect.ValueOf(&model).Elem(), model.Notities)
+	require.NoError(t, err)
+	assert.True(t, IsEncrypted(storedTelefoon.(string)))
+	assert.True(t, IsEncrypted(storedNotities.(string)))
+	empty, err := Serializer{}.Value(ctx, notities, reflect.ValueOf(&model).Elem(), (*string)(nil))
+	require.NoError(t, err)
+	assert.Nil(t, empty)
+
+	// Test: lezen, ook van een waarde van voor de versleuteling
+	var loaded serializerModel
+	dst := reflect.ValueOf(&loaded).Elem()
+	require.NoError(t, Serializer{}.Scan(ctx, telefoon, dst, []byte(storedTelefoon.(string))))
+	require.NoError(t, Serializer{}.Scan(ctx, notities, dst, storedNotities))
+	assert.Equal(t, "0612345678", loaded.Telefoon)
+	require.NotNil(t, loaded.Notities)
+	assert.Equal(t, "Rolstoel", *loaded.Notities)
+
+	require.NoError(t, Serializer{}.Scan(ctx, telefoon, dst, "0687654321"))
+	require.NoError(t, Serializer{}.Scan(ctx, notities, dst, nil))
+	assert.Equal(t, "0687654321", loaded.Telefoon)
+	assert.Nil(t, loaded.Notities)
+
+	// Zonder sleutels kan een versleutelde waarde niet gelezen worden
+	SetDefault(nil)
+	assert.ErrorIs(t, Serializer{}.Scan(ctx, telefoon, dst, storedTelefoon), ErrNoKey)
+}
+
+func TestSerializer_JSONField(t *testing.T) {
+	// Setup
+	s, err := schema.Parse(&serializerModel{}, &sync.Map{}, schema.NamingStrategy{})
+	require.NoError(t, err)
+	velden := s.LookUpField("Velden")
+	ctx := context.Background()
+	SetDefault(testKeyring(t, "2026"))
+	t.Cleanup(func() { SetDefault(nil) })
+
+	// Test: opslaan
+	model := serializerModel{Velden: map[string]string{"telefoon": "0612345678"}}
+	stored, err := Serializer{}.Value(ctx, velden, reflect.ValueOf(&model).Elem(), model.Velden)
+	require.NoError(t, err)
+	empty, err := Serializer{}.Value(ctx, velden, reflect.ValueOf(&model).Elem(), map[string]string(nil))
+	require.NoError(t, err)
+
+	// Controleer het resultaat
+	assert.True(t, IsEncrypted(stored.(string)))
+	assert.NotContains(t, stored, "0612345678")
+	assert.Nil(t, empty)
+
+	// Lezen, ook van JSON van voor de versleuteling
+	var loaded serializerModel
+	dst := reflect.ValueOf(&loaded).Elem()
+	require.NoError(t, Serializer{}.Scan(ctx, velden, dst, stored))
+	assert.Equal(t, map[string]string{"telefoon": "0612345678"}, loaded.Velden)
+	require.NoError(t, Serializer{}.Scan(ctx, velden, dst, []byte(`{"naam":"Jan"}`)))
+	assert.Equal(t, map[string]string{"naam": "Jan"}, loaded.Velden)
+	require.NoError(t, Serializer{}.Scan(ctx, velden, dst, nil))
+	assert.Nil(t, loaded.Velden)
+}
@@ -0,0 +1,142 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm/schema"
+)
+
+// SerializerName is de naam van de GORM serializer, te gebruiken als
+// `gorm:"serializer:encrypted"` op een string of *string veld. Velden van een
+// ander type, zoals een map, worden als JSON versleuteld in een text kolom.
+const SerializerName = "encrypted"
+
+func init() {
+	schema.RegisterSerializer(SerializerName, Serializer{})
+}
+
+// Serializer versleutelt een veld met de Default Keyring bij het opslaan en
+// ontsleutelt het bij het lezen. Waarden van voor de versleuteling worden
+// ongewijzigd gelezen; cmd/reencrypt versleutelt ze alsnog.
+type Serializer struct{}
+
+var plaintextWarning sync.Once
+
+// Scan implementeert schema.SerializerInterface
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+		if !isStringField(field) {
+			field.ReflectValueOf(ctx, dst).Set(reflect.Zero(field.FieldType))
+			return nil
+		}
+		return setString(ctx, field, dst, nil)
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("unsupported type %T for encrypted field %s", dbValue, field.Name)
+	}
+
+	if IsEncrypted(value) {
+		plaintext, err := Default().Decrypt(value)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s: %w", field.Name, err)
+		}
+		value = plaintext
+	}
+	if !isStringField(field) {
+		return setJSON(ctx, field, dst, value)
+	}
+	return setString(ctx, field, dst, &value)
+}
+
+// Value implementeert schema.SerializerValuerInterface
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	var value string
+	switch v := fieldValue.(type) {
+	case string:
+		value = v
+	case *string:
+		if v == nil {
+			return nil, nil
+		}
+		value = *v
+	default:
+		if isStringField(field) {
+			return nil, fmt.Errorf("unsupported type %T for encrypted field %s", fieldValue, field.Name)
+		}
+		if rv := reflect.ValueOf(fieldValue); !rv.IsValid() || (isNillable(rv.Kind()) && rv.IsNil()) {
+			return nil, nil
+		}
+		data, err := json.Marshal(fieldValue)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", field.Name, err)
+		}
+		value = string(data)
+	}
+
+	if value == "" {
+		return value, nil
+	}
+	keyring := Default()
+	if keyring == nil {
+		plaintextWarning.Do(func() {
+			log.Printf("[Secrets] Warning: no encryption keys configured, encrypted fields are stored as plain text")
+		})
+		return value, nil
+	}
+	return keyring.Encrypt(value)
+}
+
+// isStringField geeft aan of het veld een string of *string is; andere velden worden als JSON opgeslagen
+func isStringField(field *schema.Field) bool {
+	fieldType := field.FieldType
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	return fieldType.Kind() == reflect.String
+}
+
+func isNillable(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+func setJSON(ctx context.Context, field *schema.Field, dst reflect.Value, value string) error {
+	target := reflect.New(field.FieldType)
+	if err := json.Unmarshal([]byte(value), target.Interface()); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", field.Name, err)
+	}
+	field.ReflectValueOf(ctx, dst).Set(target.Elem())
+	return nil
+}
+
+func setString(ctx context.Context, field *schema.Field, dst reflect.Value, value *string) error {
+	target := field.ReflectValueOf(ctx, dst)
+	if field.FieldType.Kind() == reflect.Ptr {
+		if value == nil {
+			target.Set(reflect.Zero(field.FieldType))
+		} else {
+			target.Set(reflect.ValueOf(value))
+		}
+		return nil
+	}
+
+	if value == nil {
+		target.SetString("")
+	} else {
+		target.SetString(*value)
+	}
+	return nil
+}